@@ -0,0 +1,171 @@
+// Package main runs golden agent scenarios and reports how the agent performed.
+//
+// Usage:
+//
+//	agent-eval -suite evals -llm stub -planner heuristic -out report.json
+//
+// The exit code is 1 when a scenario fails and 2 on invalid usage.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/antigravity/go-agent-service/internal/agent"
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+	"github.com/antigravity/go-agent-service/internal/agentengine/adapters"
+	"github.com/antigravity/go-agent-service/internal/agentengine/cassette"
+	"github.com/antigravity/go-agent-service/internal/agenteval"
+	"github.com/antigravity/go-agent-service/internal/config"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("agent-eval", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	suitePath := flags.String("suite", "evals", "suite file or directory of *.json suites")
+	llmMode := flags.String("llm", "stub", "LLM to answer with: stub, real or replay")
+	cassettePath := flags.String("cassette", "", "cassette to replay (-llm replay) or record to (-llm real)")
+	plannerMode := flags.String("planner", adapters.PlannerModeHeuristic, "planner mode: heuristic, llm, auto or plan; llm and auto need -llm real")
+	maxSteps := flags.Int("max-steps", 3, "engine step limit")
+	asJSON := flags.Bool("json", false, "print the JSON report instead of a text summary")
+	outPath := flags.String("out", "", "also write the JSON report to this file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	suites, err := agenteval.LoadSuites(*suitePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "load suites: %v\n", err)
+		return 2
+	}
+
+	heuristic := adapters.NewHeuristicPlanner()
+	var llm agentengine.LLMClient
+	var llmPlanner agentengine.Planner
+	var supports func(provider string) bool
+	var player *cassette.Player
+	var recorder *cassette.Recorder
+	switch *llmMode {
+	case "stub":
+		llm = agenteval.StubLLM{}
+	case "replay":
+		if *cassettePath == "" {
+			fmt.Fprintln(stderr, "-llm replay needs -cassette")
+			return 2
+		}
+		player, err = cassette.Load(*cassettePath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		llm = player.LLM()
+	case "real":
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(stderr, "load config: %v\n", err)
+			return 2
+		}
+		providers, errs := agent.NewRegistryFromSettings(agent.RegistrySettings{
+			GeminiAPIKey:    cfg.GeminiAPIKey,
+			OpenAIAPIKey:    cfg.OpenAIAPIKey,
+			GroqAPIKey:      cfg.GroqAPIKey,
+			ProvidersFile:   cfg.LLMProvidersFile,
+			DefaultProvider: cfg.LLMDefaultProvider,
+		})
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "configure LLM provider: %v\n", err)
+		}
+		llm = adapters.NewRegistryLLMClient(providers)
+		llmPlanner = adapters.NewLLMPlanner(providers, heuristic)
+		supports = providers.SupportsFunctionCalling
+		if *cassettePath != "" {
+			recorder, err = cassette.NewRecorder(*cassettePath)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return 2
+			}
+			defer recorder.Close()
+			llm = recorder.LLM(llm)
+		}
+	default:
+		fmt.Fprintf(stderr, "unknown -llm %q\n", *llmMode)
+		return 2
+	}
+	// Planner function calls bypass the LLM client, so only real runs can plan with the LLM.
+	// The step planner goes through the LLM client, so it also works with stub and replay.
+	if *plannerMode != adapters.PlannerModeHeuristic && *plannerMode != adapters.PlannerModePlan && llmPlanner == nil {
+		fmt.Fprintf(stderr, "-planner %s needs -llm real\n", *plannerMode)
+		return 2
+	}
+
+	runner := &agenteval.Runner{
+		LLM:      llm,
+		Planner:  adapters.NewPlannerSelector(*plannerMode, llmPlanner, heuristic, supports).WithStepPlanner(adapters.NewStepPlanner(llm, heuristic)),
+		MaxSteps: *maxSteps,
+	}
+	report := runner.Run(context.Background(), suites)
+
+	if player != nil {
+		if err := player.Verify(); err != nil {
+			fmt.Fprintf(stderr, "cassette: %v\n", err)
+		}
+	}
+	if recorder != nil && recorder.Err() != nil {
+		fmt.Fprintf(stderr, "cassette: %v\n", recorder.Err())
+	}
+	if *outPath != "" {
+		if err := writeReport(*outPath, report); err != nil {
+			fmt.Fprintf(stderr, "write report: %v\n", err)
+			return 2
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		printSummary(stdout, report)
+	}
+
+	if report.Summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeReport(path string, report agenteval.Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func printSummary(w io.Writer, report agenteval.Report) {
+	for _, suite := range report.Suites {
+		for _, result := range suite.Scenarios {
+			status := "PASS"
+			if !result.Passed {
+				status = "FAIL"
+			}
+			fmt.Fprintf(w, "%s %s/%s  steps=%d calls=[%s] p=%.2f r=%.2f %dms\n",
+				status, suite.Name, result.Name, result.Steps, strings.Join(result.ToolCalls, " "),
+				result.Precision, result.Recall, result.LatencyMs)
+			for _, failure := range result.Failures {
+				fmt.Fprintf(w, "    %s\n", failure)
+			}
+		}
+	}
+	s := report.Summary
+	fmt.Fprintf(w, "\n%d/%d passed  precision=%.2f recall=%.2f steps=%.2f latency=%.0fms tokens=%d cost=$%.4f\n",
+		s.Passed, s.Scenarios, s.Precision, s.Recall, s.Steps, s.LatencyMs, s.Tokens, s.CostUSD)
+}
@@ -10,12 +10,14 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 
 	"github.com/antigravity/go-agent-service/internal/config"
 	"github.com/antigravity/go-agent-service/internal/keystore"
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 )
 
 func main() {
@@ -28,6 +30,16 @@ func main() {
 		sugar.Fatalf("Failed to load config: %v", err)
 	}
 
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), cfg.Telemetry, "keystore")
+	if err != nil {
+		sugar.Warnw("Failed to initialize tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = shutdownTelemetry(ctx)
+	}()
+
 	port := getEnvInt("KEYSTORE_PORT", 9200)
 
 	db, err := sql.Open("postgres", cfg.KeyStore.DatabaseURL)
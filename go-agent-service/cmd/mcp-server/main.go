@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/antigravity/go-agent-service/internal/keystore"
 	"github.com/antigravity/go-agent-service/internal/mcp"
 	"github.com/antigravity/go-agent-service/internal/nucleus"
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 	"github.com/antigravity/go-agent-service/internal/tools"
 )
 
@@ -76,6 +78,16 @@ func main() {
 		sugar.Fatalf("Failed to load config: %v", err)
 	}
 
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), cfg.Telemetry, "mcp-server")
+	if err != nil {
+		sugar.Warnw("Failed to initialize tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = shutdownTelemetry(ctx)
+	}()
+
 	port := getEnvInt("MCP_PORT", 9100)
 	keystoreURL := getEnv("KEYSTORE_URL", "http://localhost:9200")
 
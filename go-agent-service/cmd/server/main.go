@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/antigravity/go-agent-service/internal/config"
 	"github.com/antigravity/go-agent-service/internal/server"
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 	"github.com/antigravity/go-agent-service/internal/ucl"
 	"github.com/antigravity/go-agent-service/internal/workflow"
 )
@@ -36,6 +38,16 @@ func main() {
 		sugar.Fatalf("Failed to load config: %v", err)
 	}
 
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), cfg.Telemetry, "agent-service")
+	if err != nil {
+		sugar.Warnw("Failed to initialize tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = shutdownTelemetry(ctx)
+	}()
+
 	sugar.Infow("Starting Go Agent Service",
 		"grpc_port", cfg.GRPCPort,
 		"http_port", cfg.GRPCPort+1,
@@ -46,7 +58,7 @@ func main() {
 	agentServer := server.NewAgentServer(cfg, sugar)
 
 	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(telemetry.GRPCServerOption())
 	server.RegisterAgentServiceServer(grpcServer, agentServer)
 	reflection.Register(grpcServer)
 
@@ -59,6 +71,7 @@ func main() {
 	httpMux.HandleFunc("/workflows/cancel", httpHandler.HandleCancelWorkflow)
 	httpMux.HandleFunc("/tools", httpHandler.HandleListTools)
 	httpMux.HandleFunc("/tools/execute", httpHandler.HandleExecuteTool)
+	httpMux.HandleFunc("/llm-providers", httpHandler.HandleListLLMProviders)
 	httpMux.HandleFunc("/action", httpHandler.HandleExecuteAction)
 	httpMux.HandleFunc("/brain/search", httpHandler.HandleBrainSearch)
 	httpMux.HandleFunc("/projects", httpHandler.HandleListProjects)
@@ -70,6 +83,34 @@ func main() {
 		}
 		httpHandler.HandleGetProject(w, r, id)
 	})
+	httpMux.HandleFunc("/traces/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/traces/")
+		if id == "" {
+			http.NotFound(w, r)
+			return
+		}
+		httpHandler.HandleGetTrace(w, r, id)
+	})
+	httpMux.HandleFunc("/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/sessions/"), "/traces")
+		if !ok || id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		httpHandler.HandleListSessionTraces(w, r, id)
+	})
+	httpMux.HandleFunc("/agent-runs/", func(w http.ResponseWriter, r *http.Request) {
+		id, cancel := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/agent-runs/"), "/cancel")
+		if id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		httpHandler.HandleAgentRun(w, r, id, cancel)
+	})
+	httpMux.HandleFunc("/usage", httpHandler.HandleUsageReport)
+	httpMux.HandleFunc("/prompt-templates", httpHandler.HandlePromptTemplates)
+	httpMux.HandleFunc("/prompt-templates/activate", httpHandler.HandleActivatePromptTemplate)
+	httpMux.HandleFunc("/prompt-templates/preview", httpHandler.HandlePreviewPrompt)
 	httpMux.HandleFunc("/endpoints", httpHandler.HandleListEndpoints)
 	httpMux.HandleFunc("/apps/instances", httpHandler.HandleAppInstances)
 	httpMux.HandleFunc("/apps/users", httpHandler.HandleUserApps)
@@ -126,6 +167,12 @@ func main() {
 		w.RegisterActivity(activities.CallLLMActivity)
 		w.RegisterActivity(activities.RequestApprovalActivity)
 
+		// Durable agent runs execute their steps on the server's agent engine
+		if cfg.AgentDurableRuns && agentServer.GetAgentEngine() != nil {
+			w.RegisterWorkflow(workflow.AgentRunWorkflow)
+			w.RegisterActivity(workflow.NewAgentActivities(agentServer.GetAgentEngine()))
+		}
+
 		sugar.Info("Starting Temporal Worker")
 		if err := w.Run(worker.InterruptCh()); err != nil {
 			sugar.Fatalf("Worker failed: %v", err)
@@ -136,7 +183,7 @@ func main() {
 	httpPort := cfg.GRPCPort + 1
 	go func() {
 		sugar.Infof("HTTP server listening on :%d", httpPort)
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", httpPort), httpMux); err != nil && err != http.ErrServerClosed {
+		if err := http.ListenAndServe(fmt.Sprintf(":%d", httpPort), telemetry.Middleware(httpMux, "agent")); err != nil && err != http.ErrServerClosed {
 			sugar.Fatalf("HTTP serve failed: %v", err)
 		}
 	}()
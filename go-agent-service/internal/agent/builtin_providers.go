@@ -0,0 +1,176 @@
+// Package agent provides the built-in LLM provider kinds
+package agent
+
+import (
+	"context"
+	"errors"
+)
+
+func init() {
+	RegisterProviderKind(string(ProviderGemini), newGeminiProvider)
+	RegisterProviderKind(string(ProviderOpenAI), openAIKind{baseURL: defaultOpenAIURL, model: "gpt-4o-mini", caps: openAICapabilities}.factory)
+	RegisterProviderKind(string(ProviderGroq), openAIKind{baseURL: defaultGroqURL, model: "llama-3.3-70b-versatile", caps: groqCapabilities}.factory)
+	RegisterProviderKind(KindOpenAICompatible, openAIKind{caps: compatibleCapabilities}.factory)
+	RegisterProviderKind(string(ProviderLocal), newLocalProvider)
+}
+
+// KindOpenAICompatible serves any endpoint speaking the OpenAI chat completions
+// API, such as Ollama, vLLM or a llama.cpp server. Configs set the base URL and
+// model; the API key is optional.
+const KindOpenAICompatible = "openai-compatible"
+
+const (
+	defaultOpenAIURL = "https://api.openai.com/v1"
+	defaultGroqURL   = "https://api.groq.com/openai/v1"
+)
+
+var (
+	geminiCapabilities = Capabilities{Streaming: true, Tools: true, JSONMode: true, Vision: true, ContextTokens: 131072}
+	openAICapabilities = Capabilities{Streaming: true, Tools: true, JSONMode: true, Vision: true, ContextTokens: 128000}
+	groqCapabilities   = Capabilities{Streaming: true, Tools: true, ContextTokens: 131072}
+	localCapabilities  = Capabilities{ContextTokens: 4096}
+	// Self-hosted servers rarely support json_schema response formats
+	compatibleCapabilities = Capabilities{Streaming: true, Tools: true, ContextTokens: 8192}
+)
+
+// defaultGeminiFunctionModel is used for function calling and JSON mode when no model
+// is requested, since the default Gemma model supports neither
+const defaultGeminiFunctionModel = "gemini-2.0-flash"
+
+// geminiProvider serves requests with GeminiClient
+type geminiProvider struct {
+	cfg ProviderConfig
+}
+
+func newGeminiProvider(cfg ProviderConfig) (ProviderClient, Capabilities, error) {
+	if cfg.APIKey == "" {
+		return nil, Capabilities{}, errors.New("Gemini API key not configured")
+	}
+	return &geminiProvider{cfg: cfg}, geminiCapabilities, nil
+}
+
+func (p *geminiProvider) client(model string) *GeminiClient {
+	client := NewGeminiClient(p.cfg.APIKey)
+	if p.cfg.BaseURL != "" {
+		client = client.WithBaseURL(p.cfg.BaseURL)
+	}
+	if model != "" {
+		client = client.WithModel(model)
+	}
+	return client
+}
+
+func geminiHistory(history []HistoryMessage) []Content {
+	out := make([]Content, 0, len(history))
+	for _, h := range history {
+		role := h.Role
+		if role == "assistant" {
+			role = "model"
+		}
+		out = append(out, Content{
+			Parts: []Part{{Text: h.Content}},
+			Role:  role,
+		})
+	}
+	return out
+}
+
+// Complete implements ProviderClient
+func (p *geminiProvider) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	if req.ResponseSchema != nil {
+		model := req.Model
+		if model == "" {
+			model = defaultGeminiFunctionModel
+		}
+		return p.client(model).WithResponseSchema(req.ResponseSchema).ChatWithHistory(ctx, geminiHistory(req.History), req.Query, req.SystemPrompt)
+	}
+	client := p.client(req.Model)
+	if len(req.History) > 0 {
+		return client.ChatWithHistory(ctx, geminiHistory(req.History), req.Query, req.SystemPrompt)
+	}
+	return client.GenerateContent(ctx, req.Query, req.SystemPrompt)
+}
+
+// Stream implements ProviderClient
+func (p *geminiProvider) Stream(ctx context.Context, req CompletionRequest, onDelta StreamHandler) (Completion, error) {
+	return p.client(req.Model).StreamChatWithHistory(ctx, geminiHistory(req.History), req.Query, req.SystemPrompt, onDelta)
+}
+
+// CallFunctions implements ProviderClient
+func (p *geminiProvider) CallFunctions(ctx context.Context, req CompletionRequest, functions []FunctionDeclaration) (*FunctionResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = defaultGeminiFunctionModel
+	}
+	return p.client(model).GenerateWithFunctions(ctx, geminiHistory(req.History), req.Query, req.SystemPrompt, functions)
+}
+
+// openAIProvider serves requests with OpenAIClient against any endpoint
+// speaking the OpenAI chat completions API
+type openAIProvider struct {
+	cfg ProviderConfig
+}
+
+// openAIKind is an OpenAI-style kind. Hosted kinds have an endpoint and default
+// model and require an API key; without an endpoint, configs must name one and a model.
+type openAIKind struct {
+	baseURL string
+	model   string
+	caps    Capabilities
+}
+
+func (k openAIKind) factory(cfg ProviderConfig) (ProviderClient, Capabilities, error) {
+	if k.baseURL != "" && cfg.APIKey == "" {
+		return nil, Capabilities{}, errors.New("API key not configured")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = k.baseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = k.model
+	}
+	if cfg.BaseURL == "" {
+		return nil, Capabilities{}, errors.New("baseUrl is required")
+	}
+	if cfg.Model == "" {
+		return nil, Capabilities{}, errors.New("model is required")
+	}
+	return &openAIProvider{cfg: cfg}, k.caps, nil
+}
+
+func (p *openAIProvider) client(model string) *OpenAIClient {
+	if model == "" {
+		model = p.cfg.Model
+	}
+	return NewOpenAIClient(p.cfg.APIKey).WithBaseURL(p.cfg.BaseURL).WithModel(model).WithHeaders(p.cfg.Headers)
+}
+
+func openAIHistory(history []HistoryMessage) []OpenAIMessage {
+	out := make([]OpenAIMessage, 0, len(history))
+	for _, h := range history {
+		out = append(out, OpenAIMessage{
+			Role:    h.Role,
+			Content: h.Content,
+		})
+	}
+	return out
+}
+
+// Complete implements ProviderClient
+func (p *openAIProvider) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	client := p.client(req.Model)
+	if req.ResponseSchema != nil {
+		client = client.WithResponseSchema(req.ResponseSchema)
+	}
+	return client.ChatWithHistory(ctx, openAIHistory(req.History), req.Query, req.SystemPrompt)
+}
+
+// Stream implements ProviderClient
+func (p *openAIProvider) Stream(ctx context.Context, req CompletionRequest, onDelta StreamHandler) (Completion, error) {
+	return p.client(req.Model).StreamChatWithHistory(ctx, openAIHistory(req.History), req.Query, req.SystemPrompt, onDelta)
+}
+
+// CallFunctions implements ProviderClient
+func (p *openAIProvider) CallFunctions(ctx context.Context, req CompletionRequest, functions []FunctionDeclaration) (*FunctionResponse, error) {
+	return p.client(req.Model).ChatWithFunctions(ctx, openAIHistory(req.History), req.Query, req.SystemPrompt, functions)
+}
@@ -0,0 +1,93 @@
+// Package agent provides native function calling types shared by providers
+package agent
+
+// FunctionDeclaration describes a function the model may call
+type FunctionDeclaration struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON Schema object, nil when the function takes no arguments
+}
+
+// FunctionCall is a function invocation requested by the model
+type FunctionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// FunctionResponse is the model output for a function-calling request
+type FunctionResponse struct {
+	Text  string
+	Calls []FunctionCall
+	Model string
+	Usage Usage
+}
+
+// geminiSchemaKeys lists the OpenAPI schema keywords accepted by Gemini function declarations
+var geminiSchemaKeys = map[string]bool{
+	"type":        true,
+	"format":      true,
+	"description": true,
+	"nullable":    true,
+	"enum":        true,
+	"properties":  true,
+	"required":    true,
+	"items":       true,
+	"minItems":    true,
+	"maxItems":    true,
+	"minimum":     true,
+	"maximum":     true,
+}
+
+// toGeminiSchema strips JSON Schema keywords that Gemini rejects (additionalProperties, $schema, ...)
+func toGeminiSchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+	out := make(map[string]any, len(schema))
+	for key, value := range schema {
+		if !geminiSchemaKeys[key] {
+			continue
+		}
+		switch key {
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			converted := make(map[string]any, len(props))
+			for name, prop := range props {
+				propSchema, ok := prop.(map[string]any)
+				if !ok {
+					continue
+				}
+				if propOut := toGeminiSchema(propSchema); propOut != nil {
+					converted[name] = propOut
+				}
+			}
+			out[key] = converted
+		case "items":
+			itemSchema, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			if itemOut := toGeminiSchema(itemSchema); itemOut != nil {
+				out[key] = itemOut
+			}
+		case "required":
+			if list, ok := value.([]any); ok && len(list) == 0 {
+				continue
+			}
+			out[key] = value
+		default:
+			out[key] = value
+		}
+	}
+
+	// Gemini rejects OBJECT schemas without properties
+	if t, _ := out["type"].(string); t == "object" {
+		if props, ok := out["properties"].(map[string]any); !ok || len(props) == 0 {
+			return nil
+		}
+	}
+	return out
+}
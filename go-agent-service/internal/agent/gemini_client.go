@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -17,6 +18,8 @@ type GeminiClient struct {
 	model     string
 	baseURL   string
 	client    *http.Client
+
+	responseSchema map[string]any
 }
 
 // NewGeminiClient creates a new Gemini API client
@@ -37,11 +40,47 @@ func (c *GeminiClient) WithModel(model string) *GeminiClient {
 	return c
 }
 
+// WithBaseURL points the client at another API endpoint
+func (c *GeminiClient) WithBaseURL(baseURL string) *GeminiClient {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+// WithResponseSchema makes ChatWithHistory request JSON output matching the schema
+func (c *GeminiClient) WithResponseSchema(schema map[string]any) *GeminiClient {
+	c.responseSchema = schema
+	return c
+}
+
 // GenerateContentRequest for Gemini API
 type GenerateContentRequest struct {
 	Contents         []Content         `json:"contents"`
 	SystemInstruction *Content         `json:"systemInstruction,omitempty"`
 	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []GeminiTool      `json:"tools,omitempty"`
+	ToolConfig        *GeminiToolConfig `json:"toolConfig,omitempty"`
+}
+
+// GeminiTool groups function declarations for native function calling
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionDeclaration describes a callable function
+type GeminiFunctionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// GeminiToolConfig controls function calling behavior
+type GeminiToolConfig struct {
+	FunctionCallingConfig GeminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+// GeminiFunctionCallingConfig selects the function calling mode (AUTO, ANY, NONE)
+type GeminiFunctionCallingConfig struct {
+	Mode string `json:"mode"`
 }
 
 // Content represents a message content
@@ -52,15 +91,20 @@ type Content struct {
 
 // Part represents a content part
 type Part struct {
-	Text string `json:"text,omitempty"`
+	Text         string        `json:"text,omitempty"`
+	FunctionCall *FunctionCall `json:"functionCall,omitempty"`
 }
 
 // GenerationConfig for response tuning
 type GenerationConfig struct {
-	Temperature     float64 `json:"temperature,omitempty"`
+	Temperature     float64 `json:"temperature"`
 	TopK            int     `json:"topK,omitempty"`
 	TopP            float64 `json:"topP,omitempty"`
 	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+
+	// JSON mode: responseMimeType application/json with an optional OpenAPI schema
+	ResponseMimeType string         `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any `json:"responseSchema,omitempty"`
 }
 
 // GenerateContentResponse from Gemini API
@@ -84,7 +128,7 @@ type UsageMetadata struct {
 }
 
 // GenerateContent calls the Gemini API
-func (c *GeminiClient) GenerateContent(ctx context.Context, prompt string, systemPrompt string) (string, error) {
+func (c *GeminiClient) GenerateContent(ctx context.Context, prompt string, systemPrompt string) (Completion, error) {
 	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", c.baseURL, c.model, c.apiKey)
 
 	request := GenerateContentRequest{
@@ -109,41 +153,41 @@ func (c *GeminiClient) GenerateContent(ctx context.Context, prompt string, syste
 
 	body, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return Completion{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return Completion{}, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API request failed: %w", err)
+		return Completion{}, fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return Completion{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		// Check for rate limit
 		if resp.StatusCode == 429 {
-			return "", fmt.Errorf("rate limited (429): quota exceeded, retry later")
+			return Completion{}, fmt.Errorf("rate limited (429): quota exceeded, retry later")
 		}
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return Completion{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	var response GenerateContentResponse
 	if err := json.Unmarshal(respBody, &response); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return Completion{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(response.Candidates) == 0 {
-		return "", fmt.Errorf("no candidates in response")
+		return Completion{}, fmt.Errorf("no candidates in response")
 	}
 
 	// Extract text from first candidate
@@ -152,11 +196,11 @@ func (c *GeminiClient) GenerateContent(ctx context.Context, prompt string, syste
 		result += part.Text
 	}
 
-	return result, nil
+	return Completion{Text: result, Model: c.model, Usage: response.UsageMetadata.usage()}, nil
 }
 
 // ChatWithHistory maintains conversation history
-func (c *GeminiClient) ChatWithHistory(ctx context.Context, history []Content, newMessage string, systemPrompt string) (string, error) {
+func (c *GeminiClient) ChatWithHistory(ctx context.Context, history []Content, newMessage string, systemPrompt string) (Completion, error) {
 	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", c.baseURL, c.model, c.apiKey)
 
 	// Build conversation with history
@@ -174,6 +218,10 @@ func (c *GeminiClient) ChatWithHistory(ctx context.Context, history []Content, n
 			MaxOutputTokens: 2048,
 		},
 	}
+	if c.responseSchema != nil {
+		request.GenerationConfig.ResponseMimeType = "application/json"
+		request.GenerationConfig.ResponseSchema = toGeminiSchema(c.responseSchema)
+	}
 
 	if systemPrompt != "" {
 		request.SystemInstruction = &Content{
@@ -183,40 +231,40 @@ func (c *GeminiClient) ChatWithHistory(ctx context.Context, history []Content, n
 
 	body, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return Completion{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return Completion{}, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API request failed: %w", err)
+		return Completion{}, fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return Completion{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode == 429 {
-			return "", fmt.Errorf("rate limited")
+			return Completion{}, fmt.Errorf("rate limited")
 		}
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return Completion{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	var response GenerateContentResponse
 	if err := json.Unmarshal(respBody, &response); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return Completion{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(response.Candidates) == 0 {
-		return "", fmt.Errorf("no candidates in response")
+		return Completion{}, fmt.Errorf("no candidates in response")
 	}
 
 	var result string
@@ -224,5 +272,181 @@ func (c *GeminiClient) ChatWithHistory(ctx context.Context, history []Content, n
 		result += part.Text
 	}
 
+	return Completion{Text: result, Model: c.model, Usage: response.UsageMetadata.usage()}, nil
+}
+
+// StreamChatWithHistory streams a response via streamGenerateContent (SSE), calling onDelta for each text chunk.
+// It returns the full concatenated text.
+func (c *GeminiClient) StreamChatWithHistory(ctx context.Context, history []Content, newMessage string, systemPrompt string, onDelta StreamHandler) (Completion, error) {
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", c.baseURL, c.model, c.apiKey)
+
+	contents := make([]Content, len(history)+1)
+	copy(contents, history)
+	contents[len(history)] = Content{
+		Parts: []Part{{Text: newMessage}},
+		Role:  "user",
+	}
+
+	request := GenerateContentRequest{
+		Contents: contents,
+		GenerationConfig: &GenerationConfig{
+			Temperature:     0.7,
+			MaxOutputTokens: 2048,
+		},
+	}
+
+	if systemPrompt != "" {
+		request.SystemInstruction = &Content{
+			Parts: []Part{{Text: systemPrompt}},
+		}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return Completion{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return Completion{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Completion{}, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == 429 {
+			return Completion{}, fmt.Errorf("rate limited (429): quota exceeded, retry later")
+		}
+		return Completion{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result strings.Builder
+	var usage Usage
+	err = readSSE(resp.Body, func(data string) error {
+		var chunk GenerateContentResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		// Usage metadata is cumulative; the final chunk carries the totals
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata.usage()
+		}
+		if len(chunk.Candidates) == 0 {
+			return nil
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Text == "" {
+				continue
+			}
+			result.WriteString(part.Text)
+			if onDelta != nil {
+				if err := onDelta(part.Text); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	completion := Completion{Text: result.String(), Model: c.model, Usage: usage}
+	if err != nil {
+		return completion, err
+	}
+
+	return completion, nil
+}
+
+// GenerateWithFunctions calls the Gemini API with function declarations and returns text and function calls
+func (c *GeminiClient) GenerateWithFunctions(ctx context.Context, history []Content, newMessage string, systemPrompt string, functions []FunctionDeclaration) (*FunctionResponse, error) {
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", c.baseURL, c.model, c.apiKey)
+
+	contents := make([]Content, len(history)+1)
+	copy(contents, history)
+	contents[len(history)] = Content{
+		Parts: []Part{{Text: newMessage}},
+		Role:  "user",
+	}
+
+	declarations := make([]GeminiFunctionDeclaration, 0, len(functions))
+	for _, fn := range functions {
+		declarations = append(declarations, GeminiFunctionDeclaration{
+			Name:        fn.Name,
+			Description: fn.Description,
+			Parameters:  toGeminiSchema(fn.Parameters),
+		})
+	}
+
+	request := GenerateContentRequest{
+		Contents: contents,
+		GenerationConfig: &GenerationConfig{
+			Temperature:     0, // Planning must be deterministic
+			MaxOutputTokens: 2048,
+		},
+	}
+	if len(declarations) > 0 {
+		request.Tools = []GeminiTool{{FunctionDeclarations: declarations}}
+		request.ToolConfig = &GeminiToolConfig{
+			FunctionCallingConfig: GeminiFunctionCallingConfig{Mode: "AUTO"},
+		}
+	}
+	if systemPrompt != "" {
+		request.SystemInstruction = &Content{
+			Parts: []Part{{Text: systemPrompt}},
+		}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == 429 {
+			return nil, fmt.Errorf("rate limited (429): quota exceeded, retry later")
+		}
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var response GenerateContentResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(response.Candidates) == 0 {
+		return nil, fmt.Errorf("no candidates in response")
+	}
+
+	result := &FunctionResponse{Model: c.model, Usage: response.UsageMetadata.usage()}
+	for _, part := range response.Candidates[0].Content.Parts {
+		if part.FunctionCall != nil {
+			result.Calls = append(result.Calls, *part.FunctionCall)
+			continue
+		}
+		result.Text += part.Text
+	}
+
 	return result, nil
 }
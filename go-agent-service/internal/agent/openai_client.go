@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -17,6 +18,9 @@ type OpenAIClient struct {
 	model   string
 	baseURL string
 	client  *http.Client
+
+	responseSchema map[string]any
+	headers        map[string]string
 }
 
 // NewOpenAIClient creates a new OpenAI API client
@@ -37,18 +41,96 @@ func (c *OpenAIClient) WithModel(model string) *OpenAIClient {
 	return c
 }
 
+// WithBaseURL points the client at another API endpoint
+func (c *OpenAIClient) WithBaseURL(baseURL string) *OpenAIClient {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+// WithHeaders adds headers to every request, overriding the defaults
+func (c *OpenAIClient) WithHeaders(headers map[string]string) *OpenAIClient {
+	c.headers = headers
+	return c
+}
+
+// setHeaders authorizes the request when a key is set (local servers need none) and applies header overrides
+func (c *OpenAIClient) setHeaders(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// WithResponseSchema makes ChatWithHistory request JSON output matching the schema
+func (c *OpenAIClient) WithResponseSchema(schema map[string]any) *OpenAIClient {
+	c.responseSchema = schema
+	return c
+}
+
 // OpenAIRequest for chat completions
 type OpenAIRequest struct {
-	Model       string          `json:"model"`
-	Messages    []OpenAIMessage `json:"messages"`
-	Temperature float64         `json:"temperature,omitempty"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Model          string                `json:"model"`
+	Messages       []OpenAIMessage       `json:"messages"`
+	Temperature    float64               `json:"temperature"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Tools          []OpenAITool          `json:"tools,omitempty"`
+	ToolChoice     string                `json:"tool_choice,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *OpenAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// OpenAIResponseFormat requests structured output (json_schema or json_object)
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// OpenAIJSONSchema names the schema the response must follow
+type OpenAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+	Strict bool           `json:"strict"`
+}
+
+// OpenAIStreamOptions requests a final usage chunk on streamed completions
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // OpenAIMessage represents a chat message
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
+}
+
+// OpenAITool declares a function the model may call
+type OpenAITool struct {
+	Type     string         `json:"type"` // function
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction describes a callable function
+type OpenAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// OpenAIToolCall is a function invocation returned by the model
+type OpenAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function OpenAIFunctionCall `json:"function"`
+}
+
+// OpenAIFunctionCall carries the function name and JSON-encoded arguments
+type OpenAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // OpenAIResponse from chat completions
@@ -68,6 +150,21 @@ type OpenAIChoice struct {
 	FinishReason string        `json:"finish_reason"`
 }
 
+// OpenAIStreamChunk is a single chat.completion.chunk event
+type OpenAIStreamChunk struct {
+	ID      string               `json:"id"`
+	Model   string               `json:"model"`
+	Choices []OpenAIStreamChoice `json:"choices"`
+	Usage   *OpenAIUsage         `json:"usage,omitempty"`
+}
+
+// OpenAIStreamChoice carries the incremental delta for a choice
+type OpenAIStreamChoice struct {
+	Index        int           `json:"index"`
+	Delta        OpenAIMessage `json:"delta"`
+	FinishReason *string       `json:"finish_reason"`
+}
+
 // OpenAIUsage tracks token usage
 type OpenAIUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
@@ -76,7 +173,7 @@ type OpenAIUsage struct {
 }
 
 // ChatWithHistory sends a message with conversation history
-func (c *OpenAIClient) ChatWithHistory(ctx context.Context, history []OpenAIMessage, newMessage string, systemPrompt string) (string, error) {
+func (c *OpenAIClient) ChatWithHistory(ctx context.Context, history []OpenAIMessage, newMessage string, systemPrompt string) (Completion, error) {
 	url := c.baseURL + "/chat/completions"
 
 	// Build messages with system prompt and history
@@ -104,50 +201,246 @@ func (c *OpenAIClient) ChatWithHistory(ctx context.Context, history []OpenAIMess
 		Temperature: 0.7,
 		MaxTokens:   2048,
 	}
+	if c.responseSchema != nil {
+		// Strict mode would require additionalProperties: false throughout, so the engine validates instead
+		request.ResponseFormat = &OpenAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &OpenAIJSONSchema{Name: "response", Schema: c.responseSchema},
+		}
+	}
 
 	body, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return Completion{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return Completion{}, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	c.setHeaders(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API request failed: %w", err)
+		return Completion{}, fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return Completion{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode == 429 {
-			return "", fmt.Errorf("rate limited (429): OpenAI quota exceeded")
+			return Completion{}, fmt.Errorf("rate limited (429): OpenAI quota exceeded")
 		}
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return Completion{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	var response OpenAIResponse
 	if err := json.Unmarshal(respBody, &response); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return Completion{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		return Completion{}, fmt.Errorf("no choices in response")
 	}
 
-	return response.Choices[0].Message.Content, nil
+	return Completion{Text: response.Choices[0].Message.Content, Model: c.model, Usage: response.Usage.usage()}, nil
 }
 
 // GenerateContent simple single-turn generation
-func (c *OpenAIClient) GenerateContent(ctx context.Context, prompt string, systemPrompt string) (string, error) {
+func (c *OpenAIClient) GenerateContent(ctx context.Context, prompt string, systemPrompt string) (Completion, error) {
 	return c.ChatWithHistory(ctx, nil, prompt, systemPrompt)
 }
+
+// StreamChatWithHistory streams a response with stream: true, calling onDelta for each content delta.
+// It returns the full concatenated text.
+func (c *OpenAIClient) StreamChatWithHistory(ctx context.Context, history []OpenAIMessage, newMessage string, systemPrompt string, onDelta StreamHandler) (Completion, error) {
+	url := c.baseURL + "/chat/completions"
+
+	messages := make([]OpenAIMessage, 0, len(history)+2)
+	if systemPrompt != "" {
+		messages = append(messages, OpenAIMessage{
+			Role:    "system",
+			Content: systemPrompt,
+		})
+	}
+	messages = append(messages, history...)
+	messages = append(messages, OpenAIMessage{
+		Role:    "user",
+		Content: newMessage,
+	})
+
+	request := OpenAIRequest{
+		Model:       c.model,
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   2048,
+		Stream:      true,
+		StreamOptions: &OpenAIStreamOptions{
+			IncludeUsage: true,
+		},
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return Completion{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return Completion{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	c.setHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Completion{}, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == 429 {
+			return Completion{}, fmt.Errorf("rate limited (429): OpenAI quota exceeded")
+		}
+		return Completion{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result strings.Builder
+	var usage Usage
+	err = readSSE(resp.Body, func(data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		// With include_usage the last chunk before [DONE] has usage and no choices
+		if chunk.Usage != nil {
+			usage = chunk.Usage.usage()
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return nil
+		}
+		delta := chunk.Choices[0].Delta.Content
+		result.WriteString(delta)
+		if onDelta != nil {
+			return onDelta(delta)
+		}
+		return nil
+	})
+	completion := Completion{Text: result.String(), Model: c.model, Usage: usage}
+	if err != nil {
+		return completion, err
+	}
+
+	return completion, nil
+}
+
+// ChatWithFunctions sends a message with function declarations and returns text and function calls
+func (c *OpenAIClient) ChatWithFunctions(ctx context.Context, history []OpenAIMessage, newMessage string, systemPrompt string, functions []FunctionDeclaration) (*FunctionResponse, error) {
+	url := c.baseURL + "/chat/completions"
+
+	messages := make([]OpenAIMessage, 0, len(history)+2)
+	if systemPrompt != "" {
+		messages = append(messages, OpenAIMessage{
+			Role:    "system",
+			Content: systemPrompt,
+		})
+	}
+	messages = append(messages, history...)
+	messages = append(messages, OpenAIMessage{
+		Role:    "user",
+		Content: newMessage,
+	})
+
+	tools := make([]OpenAITool, 0, len(functions))
+	for _, fn := range functions {
+		params := fn.Parameters
+		if params == nil {
+			params = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tools = append(tools, OpenAITool{
+			Type: "function",
+			Function: OpenAIFunction{
+				Name:        fn.Name,
+				Description: fn.Description,
+				Parameters:  params,
+			},
+		})
+	}
+
+	request := OpenAIRequest{
+		Model:       c.model,
+		Messages:    messages,
+		Temperature: 0, // Planning must be deterministic
+		MaxTokens:   2048,
+	}
+	if len(tools) > 0 {
+		request.Tools = tools
+		request.ToolChoice = "auto"
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == 429 {
+			return nil, fmt.Errorf("rate limited (429): OpenAI quota exceeded")
+		}
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var response OpenAIResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	message := response.Choices[0].Message
+	result := &FunctionResponse{Text: message.Content, Model: c.model, Usage: response.Usage.usage()}
+	for _, call := range message.ToolCalls {
+		args := map[string]any{}
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("failed to parse arguments for %s: %w", call.Function.Name, err)
+			}
+		}
+		result.Calls = append(result.Calls, FunctionCall{
+			Name: call.Function.Name,
+			Args: args,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,520 @@
+// Package agent provides the LLM provider registry
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/antigravity/go-agent-service/internal/telemetry"
+)
+
+var tracer = telemetry.Tracer("github.com/antigravity/go-agent-service/internal/agent")
+
+// ErrUnsupported is returned for requests a provider lacks the capability for.
+var ErrUnsupported = errors.New("not supported by provider")
+
+// HistoryMessage for conversation history
+type HistoryMessage struct {
+	Role    string
+	Content string
+}
+
+// Capabilities describe what a provider supports
+type Capabilities struct {
+	Streaming     bool `json:"streaming"`
+	Tools         bool `json:"tools"`    // Native function calling
+	JSONMode      bool `json:"jsonMode"` // Output constrained to a JSON schema
+	Vision        bool `json:"vision"`   // Image inputs
+	ContextTokens int  `json:"contextTokens"`
+}
+
+// CompletionRequest is a provider-neutral chat request
+type CompletionRequest struct {
+	Model          string // Empty for the provider's default model
+	SystemPrompt   string
+	History        []HistoryMessage
+	Query          string
+	ResponseSchema map[string]any // Requests JSON output matching the schema when set
+}
+
+// ProviderClient talks to one LLM provider. Requests needing a capability the
+// provider lacks return ErrUnsupported.
+type ProviderClient interface {
+	Complete(ctx context.Context, req CompletionRequest) (Completion, error)
+	Stream(ctx context.Context, req CompletionRequest, onDelta StreamHandler) (Completion, error)
+	CallFunctions(ctx context.Context, req CompletionRequest, functions []FunctionDeclaration) (*FunctionResponse, error)
+}
+
+// ProviderConfig configures a provider to register
+type ProviderConfig struct {
+	Name         string        `json:"name"`                   // Selected by ChatRequest.provider
+	Kind         string        `json:"kind,omitempty"`         // Provider implementation; defaults to the name
+	APIKey       string        `json:"apiKey,omitempty"`       // Prefer APIKeyEnv over keys in files
+	APIKeyEnv    string        `json:"apiKeyEnv,omitempty"`    // Environment variable holding the API key
+	BaseURL      string        `json:"baseUrl,omitempty"`      // Overrides the kind's endpoint
+	Model        string        `json:"model,omitempty"`        // Default model; the first of Models when empty
+	Capabilities *Capabilities `json:"capabilities,omitempty"` // Overrides the kind's capabilities
+
+	Models      []string               `json:"models,omitempty"`      // Models the provider serves; others are rejected when set
+	Headers     map[string]string      `json:"headers,omitempty"`     // Added to every request, for OpenAI-style kinds
+	ModelQuirks map[string]ModelQuirks `json:"modelQuirks,omitempty"` // Limitations of individual models
+	Prices      map[string]ModelPrice  `json:"prices,omitempty"`      // Per-model prices; the built-in table of the kind otherwise
+}
+
+// ModelPrice is what a model costs in USD per 1M tokens
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// ModelQuirks describe what a model lacks compared to its provider
+type ModelQuirks struct {
+	NoSystemRole bool `json:"noSystemRole,omitempty"` // The system prompt is sent as part of the first user message
+	NoTools      bool `json:"noTools,omitempty"`      // Function calling is unsupported, so planners fall back
+}
+
+// ProviderFactory creates a provider from its config and reports its capabilities
+type ProviderFactory func(cfg ProviderConfig) (ProviderClient, Capabilities, error)
+
+var (
+	kindsMu sync.RWMutex
+	kinds   = make(map[string]ProviderFactory)
+)
+
+// RegisterProviderKind makes a provider implementation available to configs by kind
+func RegisterProviderKind(kind string, factory ProviderFactory) {
+	kindsMu.Lock()
+	defer kindsMu.Unlock()
+	kinds[kind] = factory
+}
+
+// ProvidersDocument is the on-disk provider configuration
+type ProvidersDocument struct {
+	Default   string           `json:"default,omitempty"`
+	Providers []ProviderConfig `json:"providers"`
+}
+
+// LoadProvidersFile reads a JSON provider configuration
+func LoadProvidersFile(filename string) (*ProvidersDocument, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read providers file: %w", err)
+	}
+	var doc ProvidersDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse providers file: %w", err)
+	}
+	return &doc, nil
+}
+
+// RegistrySettings are the provider settings of the service config
+type RegistrySettings struct {
+	GeminiAPIKey    string
+	OpenAIAPIKey    string
+	GroqAPIKey      string
+	ProvidersFile   string // Optional ProvidersDocument adding or replacing providers by name
+	DefaultProvider string // Empty for the file's default, else the first keyed built-in provider
+}
+
+// NewRegistryFromSettings registers the built-in providers that have API keys,
+// the local stub, and the providers of the providers file. Providers that fail
+// to load are skipped and reported in the returned errors.
+func NewRegistryFromSettings(s RegistrySettings) (*Registry, []error) {
+	var errs []error
+	configs := []ProviderConfig{{Name: string(ProviderLocal)}}
+	var keyed []string
+	for _, builtin := range []struct {
+		provider Provider
+		key      string
+	}{{ProviderGemini, s.GeminiAPIKey}, {ProviderOpenAI, s.OpenAIAPIKey}, {ProviderGroq, s.GroqAPIKey}} {
+		if builtin.key != "" {
+			configs = append(configs, ProviderConfig{Name: string(builtin.provider), APIKey: builtin.key})
+			keyed = append(keyed, string(builtin.provider))
+		}
+	}
+	defaultName := s.DefaultProvider
+	if s.ProvidersFile != "" {
+		doc, err := LoadProvidersFile(s.ProvidersFile)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			configs = append(configs, doc.Providers...)
+			if defaultName == "" {
+				defaultName = doc.Default
+			}
+		}
+	}
+
+	r := NewRegistry()
+	for _, cfg := range configs {
+		if err := r.Configure(cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if defaultName != "" {
+		if err := r.SetDefault(defaultName); err != nil {
+			errs = append(errs, err)
+			defaultName = ""
+		}
+	}
+	if defaultName == "" && len(keyed) > 0 {
+		_ = r.SetDefault(keyed[0])
+	}
+	return r, errs
+}
+
+// ProviderInfo describes a registered provider
+type ProviderInfo struct {
+	Name         string       `json:"name"`
+	Kind         string       `json:"kind"`
+	Model        string       `json:"model,omitempty"`
+	Default      bool         `json:"default"`
+	Capabilities Capabilities `json:"capabilities"`
+
+	Models      []string               `json:"models,omitempty"`
+	ModelQuirks map[string]ModelQuirks `json:"modelQuirks,omitempty"`
+	Prices      map[string]ModelPrice  `json:"prices,omitempty"`
+}
+
+type registeredProvider struct {
+	info   ProviderInfo
+	client ProviderClient
+}
+
+// Registry routes LLM requests to providers registered by name. Requests that
+// name no provider go to the default one.
+type Registry struct {
+	mu          sync.RWMutex
+	providers   map[string]*registeredProvider
+	defaultName string
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]*registeredProvider)}
+}
+
+// NewRegistryFromConfig registers every configured provider. The default is
+// defaultName when set, else the first provider of the list.
+func NewRegistryFromConfig(configs []ProviderConfig, defaultName string) (*Registry, error) {
+	r := NewRegistry()
+	for _, cfg := range configs {
+		if err := r.Configure(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if defaultName == "" && len(configs) > 0 {
+		defaultName = configs[0].Name
+	}
+	if defaultName != "" {
+		if err := r.SetDefault(defaultName); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Configure creates a provider with the factory of its kind and registers it
+func (r *Registry) Configure(cfg ProviderConfig) error {
+	if cfg.Name == "" {
+		return errors.New("provider name is required")
+	}
+	kind := cfg.Kind
+	if kind == "" {
+		kind = cfg.Name
+	}
+	kindsMu.RLock()
+	factory, ok := kinds[kind]
+	kindsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("provider %s: unknown kind %q", cfg.Name, kind)
+	}
+	if cfg.APIKey == "" && cfg.APIKeyEnv != "" {
+		cfg.APIKey = os.Getenv(cfg.APIKeyEnv)
+	}
+	if cfg.Model == "" && len(cfg.Models) > 0 {
+		cfg.Model = cfg.Models[0]
+	}
+	client, caps, err := factory(cfg)
+	if err != nil {
+		return fmt.Errorf("provider %s: %w", cfg.Name, err)
+	}
+	if cfg.Capabilities != nil {
+		caps = *cfg.Capabilities
+	}
+	r.Register(ProviderInfo{
+		Name:         cfg.Name,
+		Kind:         kind,
+		Model:        cfg.Model,
+		Capabilities: caps,
+		Models:       cfg.Models,
+		ModelQuirks:  cfg.ModelQuirks,
+		Prices:       cfg.Prices,
+	}, client)
+	return nil
+}
+
+// Register adds a provider, replacing one of the same name
+func (r *Registry) Register(info ProviderInfo, client ProviderClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info.Default = false
+	r.providers[info.Name] = &registeredProvider{info: info, client: client}
+}
+
+// SetDefault selects the provider for requests that name none
+func (r *Registry) SetDefault(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.providers[name]; !ok {
+		return fmt.Errorf("default provider %q is not configured", name)
+	}
+	r.defaultName = name
+	return nil
+}
+
+// Providers lists the registered providers by name
+func (r *Registry) Providers() []ProviderInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ProviderInfo, 0, len(r.providers))
+	for name, p := range r.providers {
+		info := p.info
+		info.Default = name == r.defaultName
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// HasProvider checks if a provider is configured
+func (r *Registry) HasProvider(provider string) bool {
+	_, err := r.lookup(provider)
+	return err == nil
+}
+
+// Capabilities returns what a provider supports; the default provider for an empty name
+func (r *Registry) Capabilities(provider string) (Capabilities, bool) {
+	p, err := r.lookup(provider)
+	if err != nil {
+		return Capabilities{}, false
+	}
+	return p.info.Capabilities, true
+}
+
+// SupportsFunctionCalling reports whether native function calling is available for a provider
+func (r *Registry) SupportsFunctionCalling(provider string) bool {
+	caps, ok := r.Capabilities(provider)
+	return ok && caps.Tools
+}
+
+// Cost prices usage of a provider's model, the provider's default model when
+// model is empty. The provider's own prices come first, then the built-in table
+// for its kind, so renamed and OpenAI-compatible providers are priced too. It
+// reports false for models without a known price.
+func (r *Registry) Cost(provider, model string, u Usage) (float64, bool) {
+	p, err := r.lookup(provider)
+	if err != nil {
+		m, ok := LookupModel(provider, model)
+		return m.Cost(u), ok
+	}
+	if model == "" {
+		model = p.info.Model
+	}
+	if price, ok := p.info.Prices[model]; ok {
+		return ModelConfig{InputPrice: price.Input, OutputPrice: price.Output}.Cost(u), true
+	}
+	m, ok := LookupModel(p.info.Kind, model)
+	return m.Cost(u), ok
+}
+
+func (r *Registry) lookup(provider string) (*registeredProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if provider == "" {
+		provider = r.defaultName
+		if provider == "" {
+			return nil, fmt.Errorf("no LLM provider configured")
+		}
+	}
+	p, ok := r.providers[provider]
+	if !ok {
+		return nil, fmt.Errorf("LLM provider %q is not configured", provider)
+	}
+	return p, nil
+}
+
+// request resolves the provider and model of a call and adapts it to the model's quirks
+func (r *Registry) request(provider, model, query, systemPrompt string, history []HistoryMessage) (*registeredProvider, CompletionRequest, error) {
+	p, err := r.lookup(provider)
+	if err != nil {
+		return nil, CompletionRequest{}, err
+	}
+	if model == "" {
+		model = p.info.Model
+	}
+	if len(p.info.Models) > 0 && !slices.Contains(p.info.Models, model) {
+		return nil, CompletionRequest{}, fmt.Errorf("model %q is not served by LLM provider %s", model, p.info.Name)
+	}
+	req := CompletionRequest{Model: model, SystemPrompt: systemPrompt, History: history, Query: query}
+	if p.info.ModelQuirks[model].NoSystemRole {
+		req = foldSystemPrompt(req)
+	}
+	return p, req, nil
+}
+
+// foldSystemPrompt moves the system prompt into the first user message, for
+// models whose chat templates reject the system role. A conversation opening
+// with an assistant turn gets a user turn first, keeping roles alternating.
+func foldSystemPrompt(req CompletionRequest) CompletionRequest {
+	if req.SystemPrompt == "" {
+		return req
+	}
+	system := req.SystemPrompt
+	req.SystemPrompt = ""
+	switch {
+	case len(req.History) == 0:
+		req.Query = system + "\n\n" + req.Query
+	case req.History[0].Role == "user":
+		history := slices.Clone(req.History)
+		history[0].Content = system + "\n\n" + history[0].Content
+		req.History = history
+	default:
+		req.History = append([]HistoryMessage{{Role: "user", Content: system}}, req.History...)
+	}
+	return req
+}
+
+// GenerateResponse routes to the appropriate provider
+func (r *Registry) GenerateResponse(ctx context.Context, provider, model, query, systemPrompt string, history []HistoryMessage) (out Completion, err error) {
+	ctx, span := tracer.Start(ctx, "llm.generate", telemetry.Attrs("llm.provider", provider, "llm.model", model))
+	defer func() {
+		recordUsage(span, out.Usage)
+		telemetry.End(span, err)
+	}()
+
+	p, req, err := r.request(provider, model, query, systemPrompt, history)
+	if err != nil {
+		return Completion{}, err
+	}
+	out, err = p.client.Complete(ctx, req)
+	out.Provider = p.info.Name
+	return out, err
+}
+
+// GenerateStructured routes a request for JSON output matching schema, using the
+// provider's JSON mode. Providers without it get the request unconstrained, so
+// callers still validate the output.
+func (r *Registry) GenerateStructured(ctx context.Context, provider, model, query, systemPrompt string, history []HistoryMessage, schema map[string]any) (out Completion, err error) {
+	ctx, span := tracer.Start(ctx, "llm.structured", telemetry.Attrs("llm.provider", provider, "llm.model", model))
+	defer func() {
+		recordUsage(span, out.Usage)
+		telemetry.End(span, err)
+	}()
+
+	p, req, err := r.request(provider, model, query, systemPrompt, history)
+	if err != nil {
+		return Completion{}, err
+	}
+	if p.info.Capabilities.JSONMode {
+		req.ResponseSchema = schema
+	}
+	out, err = p.client.Complete(ctx, req)
+	out.Provider = p.info.Name
+	return out, err
+}
+
+// GenerateResponseStream routes a streaming request, calling onDelta for each text chunk.
+// Providers without streaming deliver the whole response as one chunk.
+func (r *Registry) GenerateResponseStream(ctx context.Context, provider, model, query, systemPrompt string, history []HistoryMessage, onDelta StreamHandler) (out Completion, err error) {
+	ctx, span := tracer.Start(ctx, "llm.stream", telemetry.Attrs("llm.provider", provider, "llm.model", model))
+	defer func() {
+		recordUsage(span, out.Usage)
+		telemetry.End(span, err)
+	}()
+
+	p, req, err := r.request(provider, model, query, systemPrompt, history)
+	if err != nil {
+		return Completion{}, err
+	}
+	if p.info.Capabilities.Streaming {
+		out, err = p.client.Stream(ctx, req, onDelta)
+		out.Provider = p.info.Name
+		return out, err
+	}
+	out, err = p.client.Complete(ctx, req)
+	out.Provider = p.info.Name
+	if err == nil && out.Text != "" && onDelta != nil {
+		err = onDelta(out.Text)
+	}
+	return out, err
+}
+
+// GenerateWithFunctions routes a native function-calling request to the appropriate provider
+func (r *Registry) GenerateWithFunctions(ctx context.Context, provider, model, query, systemPrompt string, history []HistoryMessage, functions []FunctionDeclaration) (resp *FunctionResponse, err error) {
+	ctx, span := tracer.Start(ctx, "llm.functions", telemetry.Attrs("llm.provider", provider, "llm.model", model))
+	defer func() {
+		if resp != nil {
+			recordUsage(span, resp.Usage)
+		}
+		telemetry.End(span, err)
+	}()
+
+	p, req, err := r.request(provider, model, query, systemPrompt, history)
+	if err != nil {
+		return nil, err
+	}
+	if !p.info.Capabilities.Tools {
+		return nil, fmt.Errorf("function calling with %s: %w", p.info.Name, ErrUnsupported)
+	}
+	if p.info.ModelQuirks[req.Model].NoTools {
+		return nil, fmt.Errorf("function calling with %s model %s: %w", p.info.Name, req.Model, ErrUnsupported)
+	}
+	return p.client.CallFunctions(ctx, req, functions)
+}
+
+// Summarizer returns a summarizer backed by a provider, the default one when empty
+func (r *Registry) Summarizer(provider, model string) *Summarizer {
+	return &Summarizer{registry: r, provider: provider, model: model}
+}
+
+// Summarizer condenses text with a registered provider; it implements
+// context.LLMSummarizer.
+type Summarizer struct {
+	registry *Registry
+	provider string
+	model    string
+}
+
+// summarizerPrompt keeps summaries factual for later turns
+const summarizerPrompt = "You summarize conversations for an AI assistant's memory. Keep facts, decisions, IDs and open questions; drop pleasantries."
+
+// Summarize implements context.LLMSummarizer
+func (s *Summarizer) Summarize(ctx context.Context, prompt string) (string, error) {
+	out, err := s.registry.GenerateResponse(ctx, s.provider, s.model, prompt, summarizerPrompt, nil)
+	if err != nil {
+		return "", err
+	}
+	return out.Text, nil
+}
+
+// recordUsage adds token counts to an LLM span
+func recordUsage(span trace.Span, u Usage) {
+	if u.TotalTokens == 0 {
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("llm.usage.prompt_tokens", u.PromptTokens),
+		attribute.Int("llm.usage.completion_tokens", u.CompletionTokens),
+		attribute.Int("llm.usage.total_tokens", u.TotalTokens),
+	)
+}
@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agentctx "github.com/antigravity/go-agent-service/internal/context"
+)
+
+var _ agentctx.LLMSummarizer = (*Summarizer)(nil)
+
+// fakeProvider answers every completion with its name and counts the calls.
+type fakeProvider struct {
+	name     string
+	requests []CompletionRequest
+}
+
+func (f *fakeProvider) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	f.requests = append(f.requests, req)
+	return Completion{Text: f.name}, nil
+}
+
+func (f *fakeProvider) Stream(ctx context.Context, req CompletionRequest, onDelta StreamHandler) (Completion, error) {
+	return Completion{}, ErrUnsupported
+}
+
+func (f *fakeProvider) CallFunctions(ctx context.Context, req CompletionRequest, functions []FunctionDeclaration) (*FunctionResponse, error) {
+	return &FunctionResponse{Text: f.name}, nil
+}
+
+func TestRegistryRoutesByName(t *testing.T) {
+	r := NewRegistry()
+	fast := &fakeProvider{name: "fast"}
+	r.Register(ProviderInfo{Name: "fast", Capabilities: Capabilities{Tools: true}}, fast)
+	r.Register(ProviderInfo{Name: "plain"}, &fakeProvider{name: "plain"})
+	if err := r.SetDefault("fast"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := r.GenerateResponse(context.Background(), "", "", "hi", "", nil)
+	if err != nil || out.Text != "fast" {
+		t.Fatalf("expected the default provider, got %q, %v", out.Text, err)
+	}
+	if out, _ = r.GenerateResponse(context.Background(), "plain", "", "hi", "", nil); out.Text != "plain" {
+		t.Fatalf("expected the named provider, got %q", out.Text)
+	}
+	if _, err := r.GenerateResponse(context.Background(), "missing", "", "hi", "", nil); err == nil {
+		t.Fatalf("expected an error for an unknown provider")
+	}
+	if _, err := r.GenerateWithFunctions(context.Background(), "plain", "", "hi", "", nil, nil); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected function calling unsupported without tools, got %v", err)
+	}
+	if !r.SupportsFunctionCalling("") || r.SupportsFunctionCalling("plain") {
+		t.Fatalf("expected function calling support to follow capabilities")
+	}
+
+	if _, _ = r.GenerateStructured(context.Background(), "fast", "", "hi", "", nil, map[string]any{"type": "object"}); fast.requests[1].ResponseSchema != nil {
+		t.Fatalf("expected no schema sent to a provider without JSON mode")
+	}
+	var deltas []string
+	out, err = r.GenerateResponseStream(context.Background(), "plain", "", "hi", "", nil, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil || len(deltas) != 1 || deltas[0] != "plain" {
+		t.Fatalf("expected a single delta from a provider without streaming, got %v, %v", deltas, err)
+	}
+}
+
+func TestRegistryFromSettings(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"from groq"}}]}`))
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "providers.json")
+	doc := `{"providers":[{"name":"groq","apiKeyEnv":"TEST_GROQ_KEY","baseUrl":"` + srv.URL + `"},{"name":"broken","kind":"nope"}]}`
+	if err := os.WriteFile(file, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_GROQ_KEY", "gsk")
+
+	r, errs := NewRegistryFromSettings(RegistrySettings{GroqAPIKey: "unused", ProvidersFile: file})
+	if len(errs) != 1 {
+		t.Fatalf("expected the unknown kind reported, got %v", errs)
+	}
+	if r.HasProvider("openai") || !r.HasProvider("local") {
+		t.Fatalf("expected only keyed providers and local, got %+v", r.Providers())
+	}
+	out, err := r.GenerateResponse(context.Background(), "", "", "hi", "", nil)
+	if err != nil || out.Text != "from groq" {
+		t.Fatalf("expected groq as the default, got %q, %v", out.Text, err)
+	}
+	if auth != "Bearer gsk" {
+		t.Fatalf("expected the file's key, got %q", auth)
+	}
+	if caps, _ := r.Capabilities("groq"); caps.JSONMode || !caps.Tools {
+		t.Fatalf("unexpected groq capabilities %+v", caps)
+	}
+
+	r, _ = NewRegistryFromSettings(RegistrySettings{})
+	if _, err := r.GenerateResponse(context.Background(), "", "", "hi", "", nil); err == nil {
+		t.Fatalf("expected no default without keyed providers")
+	}
+}
+
+func TestOpenAICompatibleProvider(t *testing.T) {
+	var requests []OpenAIRequest
+	var headers []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		requests = append(requests, req)
+		headers = append(headers, r.Header.Clone())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	r := NewRegistry()
+	if err := r.Configure(ProviderConfig{Name: "ollama", Kind: KindOpenAICompatible}); err == nil {
+		t.Fatalf("expected a base URL to be required")
+	}
+	err := r.Configure(ProviderConfig{
+		Name:        "ollama",
+		Kind:        KindOpenAICompatible,
+		BaseURL:     srv.URL + "/v1/",
+		Models:      []string{"llama3.1", "gemma2"},
+		Headers:     map[string]string{"X-Tenant": "acme"},
+		ModelQuirks: map[string]ModelQuirks{"gemma2": {NoSystemRole: true, NoTools: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.GenerateResponse(context.Background(), "ollama", "", "hi", "be brief", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests[0]; got.Model != "llama3.1" || got.Messages[0].Role != "system" {
+		t.Fatalf("expected the first model with a system message, got %+v", got)
+	}
+	if headers[0].Get("Authorization") != "" || headers[0].Get("X-Tenant") != "acme" {
+		t.Fatalf("expected no auth and the header override, got %v", headers[0])
+	}
+
+	history := []HistoryMessage{{Role: "assistant", Content: "hello"}}
+	if _, err := r.GenerateResponse(context.Background(), "ollama", "gemma2", "hi", "be brief", history); err != nil {
+		t.Fatal(err)
+	}
+	messages := requests[1].Messages
+	if len(messages) != 3 || messages[0].Role != "user" || messages[0].Content != "be brief" {
+		t.Fatalf("expected the system prompt folded into a user turn, got %+v", messages)
+	}
+	if _, err := r.GenerateWithFunctions(context.Background(), "ollama", "gemma2", "hi", "", nil, nil); !errors.Is(err, ErrUnsupported) || len(requests) != 2 {
+		t.Fatalf("expected function calling rejected for a model without tools, got %v", err)
+	}
+	if _, err := r.GenerateResponse(context.Background(), "ollama", "mistral", "hi", "", nil); err == nil || len(requests) != 2 {
+		t.Fatalf("expected a model outside the list rejected, got %v", err)
+	}
+}
+
+func TestFoldSystemPrompt(t *testing.T) {
+	req := foldSystemPrompt(CompletionRequest{SystemPrompt: "rules", Query: "hi"})
+	if req.SystemPrompt != "" || req.Query != "rules\n\nhi" {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	history := []HistoryMessage{{Role: "user", Content: "first"}, {Role: "assistant", Content: "reply"}}
+	req = foldSystemPrompt(CompletionRequest{SystemPrompt: "rules", Query: "hi", History: history})
+	if req.History[0].Content != "rules\n\nfirst" || history[0].Content != "first" || req.Query != "hi" {
+		t.Fatalf("expected the first user turn prefixed on a copy, got %+v", req)
+	}
+}
+
+func TestRegistryCostByKindAndPrices(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Configure(ProviderConfig{Name: "openai-eu", Kind: "openai", APIKey: "unused", Model: "gpt-4o"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Configure(ProviderConfig{
+		Name:    "ollama",
+		Kind:    KindOpenAICompatible,
+		BaseURL: "http://ollama:11434/v1",
+		Models:  []string{"llama3.1"},
+		Prices:  map[string]ModelPrice{"llama3.1": {Input: 1, Output: 2}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	usage := Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000}
+
+	if cost, ok := r.Cost("openai-eu", "", usage); !ok || cost != 7.5 {
+		t.Fatalf("expected a renamed provider priced by its kind, got %v, %v", cost, ok)
+	}
+	if cost, ok := r.Cost("ollama", "llama3.1", usage); !ok || cost != 2 {
+		t.Fatalf("expected the configured price, got %v, %v", cost, ok)
+	}
+	if _, ok := r.Cost("ollama", "gemma2", usage); ok {
+		t.Fatalf("expected an unpriced model reported")
+	}
+}
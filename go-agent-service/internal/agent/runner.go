@@ -21,6 +21,7 @@ type Runner struct {
 	geminiClient   *GeminiClient
 	memoryStore    memory.MemoryStore
 	contextBuilder *agentctx.Builder
+	logMask        agentctx.LogMask
 }
 
 // Tool represents a callable tool for the agent
@@ -123,6 +124,13 @@ func (r *Runner) WithMemory(store memory.MemoryStore, config *memory.ContextConf
 	return r
 }
 
+// WithLogMask masks queries before they are logged. Runner requests have no
+// project, so the mask gets an empty project ID.
+func (r *Runner) WithLogMask(mask agentctx.LogMask) *Runner {
+	r.logMask = mask
+	return r
+}
+
 // RegisterTool adds a tool to the agent
 func (r *Runner) RegisterTool(tool Tool) {
 	r.tools = append(r.tools, tool)
@@ -131,8 +139,12 @@ func (r *Runner) RegisterTool(tool Tool) {
 
 // Chat processes a chat request and returns a response
 func (r *Runner) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	query := req.Query
+	if r.logMask != nil {
+		query = r.logMask("", req.Query)
+	}
 	r.logger.Infow("Processing chat request",
-		"query", req.Query,
+		"query", query,
 		"conversation_id", req.ConversationID,
 	)
 
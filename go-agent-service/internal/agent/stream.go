@@ -0,0 +1,50 @@
+// Package agent provides server-sent events parsing for streaming providers
+package agent
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// StreamHandler receives text deltas as they arrive. Returning an error stops the stream.
+type StreamHandler func(delta string) error
+
+// maxSSELineSize bounds a single SSE line (large JSON chunks from Gemini)
+const maxSSELineSize = 1024 * 1024
+
+// readSSE reads an event stream and calls onData with the data payload of each event
+func readSSE(body io.Reader, onData func(data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+
+	var data []string
+	flush := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		payload := strings.Join(data, "\n")
+		data = data[:0]
+		return onData(payload)
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			if err := flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment / keep-alive
+		}
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const geminiSSE = `data: {"candidates": [{"content": {"parts": [{"text": "Three "}],"role": "model"},"index": 0}]}
+
+data: {"candidates": [{"content": {"parts": [{"text": "critical "}],"role": "model"},"index": 0}]}
+
+data: {"candidates": [{"content": {"parts": [{"text": "bugs."}],"role": "model"},"finishReason": "STOP","index": 0}],"usageMetadata": {"promptTokenCount": 12,"candidatesTokenCount": 4,"totalTokenCount": 16}}
+
+`
+
+const openAISSE = `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":"Three "},"finish_reason":null}]}
+
+: keep-alive
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":"critical bugs."},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[],"usage":{"prompt_tokens":20,"completion_tokens":5,"total_tokens":25}}
+
+data: [DONE]
+
+`
+
+func replaySSE(t *testing.T, payload string, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			check(r)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, _ := w.(http.Flusher)
+		for _, event := range strings.SplitAfter(payload, "\n\n") {
+			_, _ = w.Write([]byte(event))
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}))
+}
+
+func TestGeminiStreamChatWithHistory(t *testing.T) {
+	srv := replaySSE(t, geminiSSE, func(r *http.Request) {
+		if !strings.Contains(r.URL.Path, ":streamGenerateContent") || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected request URL: %s", r.URL.String())
+		}
+	})
+	defer srv.Close()
+
+	client := NewGeminiClient("key")
+	client.baseURL = srv.URL
+
+	var deltas []string
+	out, err := client.StreamChatWithHistory(context.Background(), nil, "bugs?", "system", func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamChatWithHistory error: %v", err)
+	}
+	if out.Text != "Three critical bugs." {
+		t.Fatalf("unexpected text: %q", out.Text)
+	}
+	if len(deltas) != 3 {
+		t.Fatalf("expected 3 deltas, got %v", deltas)
+	}
+	if want := (Usage{PromptTokens: 12, CompletionTokens: 4, TotalTokens: 16}); out.Usage != want {
+		t.Fatalf("expected usage %+v, got %+v", want, out.Usage)
+	}
+}
+
+func TestOpenAIStreamChatWithHistory(t *testing.T) {
+	srv := replaySSE(t, openAISSE, func(r *http.Request) {
+		var req OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Stream {
+			t.Errorf("expected stream request, got %+v (err %v)", req, err)
+		}
+		if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			t.Errorf("expected stream usage to be requested")
+		}
+	})
+	defer srv.Close()
+
+	client := NewOpenAIClient("key")
+	client.baseURL = srv.URL
+
+	var deltas []string
+	out, err := client.StreamChatWithHistory(context.Background(), nil, "bugs?", "system", func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamChatWithHistory error: %v", err)
+	}
+	if out.Text != "Three critical bugs." {
+		t.Fatalf("unexpected text: %q", out.Text)
+	}
+	if len(deltas) != 2 {
+		t.Fatalf("expected 2 deltas, got %v", deltas)
+	}
+	if want := (Usage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25}); out.Usage != want {
+		t.Fatalf("expected usage %+v, got %+v", want, out.Usage)
+	}
+}
+
+func TestStreamHandlerErrorStopsStream(t *testing.T) {
+	srv := replaySSE(t, openAISSE, nil)
+	defer srv.Close()
+
+	client := NewOpenAIClient("key")
+	client.baseURL = srv.URL
+
+	stop := errors.New("client disconnected")
+	out, err := client.StreamChatWithHistory(context.Background(), nil, "bugs?", "", func(delta string) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+	if out.Text != "Three " {
+		t.Fatalf("expected partial text, got %q", out.Text)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	usage := Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000, TotalTokens: 1_500_000}
+	if got := EstimateCost("openai", "gpt-4o", usage); got != 7.5 {
+		t.Fatalf("expected gpt-4o cost 7.5, got %v", got)
+	}
+	if got := EstimateCost("", "gpt-4o-mini", usage); got != 0.45 {
+		t.Fatalf("expected gpt-4o-mini cost 0.45 without provider, got %v", got)
+	}
+	if got := EstimateCost("openai", "unknown", usage); got != 0 {
+		t.Fatalf("expected unknown model to be free, got %v", got)
+	}
+}
@@ -0,0 +1,75 @@
+// Package agent provides token usage and pricing for LLM calls
+package agent
+
+// Usage reports the tokens consumed by a single LLM call
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// Completion is the text output of an LLM call together with the model that produced it
+type Completion struct {
+	Text     string
+	Provider string
+	Model    string
+	Usage    Usage
+}
+
+// usage converts Gemini usage metadata, which is absent on intermediate stream chunks
+func (m *UsageMetadata) usage() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	return Usage{
+		PromptTokens:     m.PromptTokenCount,
+		CompletionTokens: m.CandidatesTokenCount,
+		TotalTokens:      m.TotalTokenCount,
+	}
+}
+
+// usage converts OpenAI usage, which is only sent when requested on streams
+func (u *OpenAIUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
+// LookupModel returns the configured model for a provider, matching on model name
+// alone when the provider is empty
+func LookupModel(provider, model string) (ModelConfig, bool) {
+	for _, m := range AvailableModels() {
+		if m.Model == model && (provider == "" || string(m.Provider) == provider) {
+			return m, true
+		}
+	}
+	return ModelConfig{}, false
+}
+
+// Cost returns the USD cost of usage at this model's prices
+func (m ModelConfig) Cost(u Usage) float64 {
+	return (float64(u.PromptTokens)*m.InputPrice + float64(u.CompletionTokens)*m.OutputPrice) / 1_000_000
+}
+
+// EstimateCost prices usage for a provider/model; unknown models cost nothing
+func EstimateCost(provider, model string, u Usage) float64 {
+	m, ok := LookupModel(provider, model)
+	if !ok {
+		return 0
+	}
+	return m.Cost(u)
+}
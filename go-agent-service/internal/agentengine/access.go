@@ -0,0 +1,43 @@
+// Package agentengine classifies tool actions as read or write.
+package agentengine
+
+import "strings"
+
+// AccessKind describes whether a tool action reads or mutates external state.
+type AccessKind string
+
+const (
+	AccessRead  AccessKind = "read"
+	AccessWrite AccessKind = "write"
+)
+
+// readActions are known read-only actions across MCP app tools and internal tools.
+var readActions = map[string]bool{
+	"list_datasets": true,
+	"get_schema":    true,
+	"read_data":     true,
+	"brain_search":  true,
+	"graph_query":   true,
+	"kv_get":        true,
+}
+
+// readPrefixes mark conventional read-only action names.
+var readPrefixes = []string{"get_", "list_", "read_", "search_", "query_", "describe_", "fetch_", "find_"}
+
+// ClassifyAccess returns the access kind of an action. Unknown actions are treated
+// as writes so they fall under the stricter policy.
+func ClassifyAccess(action ToolAction) AccessKind {
+	if action.Access != "" {
+		return action.Access
+	}
+	name := strings.ToLower(action.Name)
+	if readActions[name] {
+		return AccessRead
+	}
+	for _, prefix := range readPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return AccessRead
+		}
+	}
+	return AccessWrite
+}
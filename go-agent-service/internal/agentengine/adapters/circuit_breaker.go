@@ -0,0 +1,194 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// CircuitOpenError is returned for calls to a tool whose circuit is open.
+type CircuitOpenError struct {
+	Tool  string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("tool %s is temporarily unavailable after repeated failures", e.Tool)
+}
+
+// BreakerConfig configures CircuitBreaker.
+type BreakerConfig struct {
+	Threshold int           // Consecutive transient failures that open a tool's circuit
+	Cooldown  time.Duration // How long the circuit stays open before a trial call
+}
+
+// CircuitBreaker tracks transient failures per tool. After Threshold
+// consecutive failures the tool's circuit opens: calls fail fast, the tool is
+// withheld from the planner and reported as degraded. After Cooldown one trial
+// call is let through; its success closes the circuit, its failure reopens it.
+type CircuitBreaker struct {
+	cfg   BreakerConfig
+	clock func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	lastError string
+	probing   bool
+}
+
+// NewCircuitBreaker creates a circuit breaker.
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	return &CircuitBreaker{cfg: cfg, clock: time.Now, circuits: make(map[string]*circuit)}
+}
+
+// Executor wraps next so calls to open tools fail fast and outcomes update the circuits.
+func (b *CircuitBreaker) Executor(next agentengine.ToolExecutor) agentengine.ToolExecutor {
+	return &breakerExecutor{breaker: b, next: next}
+}
+
+// Registry wraps next so open tools are left out of tool listings.
+func (b *CircuitBreaker) Registry(next agentengine.ToolRegistry) agentengine.ToolRegistry {
+	return &breakerRegistry{breaker: b, next: next}
+}
+
+// DegradedTools lists the tools whose circuit is open.
+func (b *CircuitBreaker) DegradedTools() []agentengine.DegradedTool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock()
+	var out []agentengine.DegradedTool
+	for name, c := range b.circuits {
+		if b.isOpen(c, now) {
+			out = append(out, agentengine.DegradedTool{
+				Name:   name,
+				Reason: fmt.Sprintf("%d consecutive failures, last: %s", c.failures, c.lastError),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// isOpen reports whether calls to c should be refused. Once the cooldown has
+// passed the circuit is half-open and one trial call is allowed at a time.
+func (b *CircuitBreaker) isOpen(c *circuit, now time.Time) bool {
+	if c.failures < b.cfg.Threshold {
+		return false
+	}
+	return now.Before(c.openUntil) || c.probing
+}
+
+// allow reserves a call to tool, or returns CircuitOpenError.
+func (b *CircuitBreaker) allow(tool string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[tool]
+	if !ok {
+		return nil
+	}
+	if b.isOpen(c, b.clock()) {
+		return &CircuitOpenError{Tool: tool, Until: c.openUntil}
+	}
+	if c.failures >= b.cfg.Threshold {
+		c.probing = true
+	}
+	return nil
+}
+
+// record updates tool's circuit with a call outcome. Only transient errors
+// count as failures; a tool that answers, even with an error, is healthy.
+func (b *CircuitBreaker) record(tool string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !IsTransient(err) {
+		delete(b.circuits, tool)
+		return
+	}
+	c, ok := b.circuits[tool]
+	if !ok {
+		c = &circuit{}
+		b.circuits[tool] = c
+	}
+	c.failures++
+	c.lastError = err.Error()
+	c.probing = false
+	if c.failures >= b.cfg.Threshold {
+		c.openUntil = b.clock().Add(b.cfg.Cooldown)
+	}
+}
+
+// release clears a pending trial call without recording an outcome.
+func (b *CircuitBreaker) release(tool string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[tool]; ok {
+		c.probing = false
+	}
+}
+
+type breakerExecutor struct {
+	breaker *CircuitBreaker
+	next    agentengine.ToolExecutor
+}
+
+func (e *breakerExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	if err := e.breaker.allow(call.Name); err != nil {
+		return nil, err
+	}
+	result, err := e.next.Execute(ctx, call)
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		// The run was cancelled, which says nothing about the tool.
+		e.breaker.release(call.Name)
+		return result, err
+	}
+	e.breaker.record(call.Name, err)
+	return result, err
+}
+
+type breakerRegistry struct {
+	breaker *CircuitBreaker
+	next    agentengine.ToolRegistry
+}
+
+func (r *breakerRegistry) ListTools(ctx context.Context, userID, projectID string) ([]agentengine.ToolDef, error) {
+	tools, err := r.next.ListTools(ctx, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	degraded := r.breaker.DegradedTools()
+	if len(degraded) == 0 {
+		return tools, nil
+	}
+	skip := make(map[string]bool, len(degraded))
+	for _, tool := range degraded {
+		skip[tool.Name] = true
+	}
+	out := make([]agentengine.ToolDef, 0, len(tools))
+	for _, tool := range tools {
+		if !skip[tool.Name] {
+			out = append(out, tool)
+		}
+	}
+	return out, nil
+}
+
+// DegradedTools implements agentengine.ToolHealth.
+func (r *breakerRegistry) DegradedTools() []agentengine.DegradedTool {
+	return r.breaker.DegradedTools()
+}
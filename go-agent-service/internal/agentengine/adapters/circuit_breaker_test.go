@@ -0,0 +1,68 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+type staticToolList []agentengine.ToolDef
+
+func (s staticToolList) ListTools(ctx context.Context, userID, projectID string) ([]agentengine.ToolDef, error) {
+	return s, nil
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := NewCircuitBreaker(BreakerConfig{Threshold: 2, Cooldown: time.Minute})
+	breaker.clock = func() time.Time { return now }
+
+	inner := &flakyExecutor{errs: []error{context.DeadlineExceeded, context.DeadlineExceeded, context.DeadlineExceeded}}
+	executor := breaker.Executor(inner)
+	registry := breaker.Registry(staticToolList{{Name: "jira"}, {Name: "ucl"}})
+	call := agentengine.ToolCall{Name: "jira", Action: "search"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := executor.Execute(context.Background(), call); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected tool error, got %v", err)
+		}
+	}
+
+	// Open: calls fail fast and the tool is withheld and reported as degraded
+	var open *CircuitOpenError
+	if _, err := executor.Execute(context.Background(), call); !errors.As(err, &open) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	if len(inner.calls) != 2 {
+		t.Fatalf("expected no call while open, got %d", len(inner.calls))
+	}
+	tools, _ := registry.ListTools(context.Background(), "", "")
+	if len(tools) != 1 || tools[0].Name != "ucl" {
+		t.Fatalf("expected jira withheld, got %+v", tools)
+	}
+	degraded := registry.(agentengine.ToolHealth).DegradedTools()
+	if len(degraded) != 1 || degraded[0].Name != "jira" {
+		t.Fatalf("expected jira degraded, got %+v", degraded)
+	}
+
+	// Half-open: a failed trial reopens the circuit
+	now = now.Add(time.Minute)
+	if _, err := executor.Execute(context.Background(), call); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected trial call to reach the tool, got %v", err)
+	}
+	if _, err := executor.Execute(context.Background(), call); !errors.As(err, &open) {
+		t.Fatalf("expected circuit reopened, got %v", err)
+	}
+
+	// A successful trial closes it
+	now = now.Add(time.Minute)
+	if _, err := executor.Execute(context.Background(), call); err != nil {
+		t.Fatalf("expected trial call to succeed, got %v", err)
+	}
+	if degraded := breaker.DegradedTools(); len(degraded) != 0 {
+		t.Fatalf("expected circuit closed, got %+v", degraded)
+	}
+}
@@ -0,0 +1,50 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// DelegateSpec configures a sub-agent in a delegates file.
+type DelegateSpec struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Instructions   string   `json:"instructions,omitempty"`
+	Tools          []string `json:"tools,omitempty"`
+	MaxSteps       int      `json:"maxSteps,omitempty"`
+	Provider       string   `json:"provider,omitempty"`
+	Model          string   `json:"model,omitempty"`
+	TimeoutSeconds int      `json:"timeoutSeconds,omitempty"`
+}
+
+// LoadDelegateSpecs reads a JSON list of sub-agent specs.
+func LoadDelegateSpecs(filename string) ([]DelegateSpec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read delegates file: %w", err)
+	}
+	var specs []DelegateSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("parse delegates file: %w", err)
+	}
+	return specs, nil
+}
+
+// Delegate builds the delegate that runs the spec on engine.
+func (s DelegateSpec) Delegate(engine *agentengine.Engine) agentengine.Delegate {
+	return agentengine.Delegate{
+		Name:         s.Name,
+		Description:  s.Description,
+		Engine:       engine,
+		Instructions: s.Instructions,
+		Tools:        s.Tools,
+		MaxSteps:     s.MaxSteps,
+		Provider:     s.Provider,
+		Model:        s.Model,
+		Timeout:      time.Duration(s.TimeoutSeconds) * time.Second,
+	}
+}
@@ -0,0 +1,84 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// injectionSignals are phrasings of instructions aimed at the agent rather than
+// at people, keyed by the signal they raise.
+var injectionSignals = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"ignore-instructions", regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override|bypass)\s+((all|any|the|your|of)\s+)*((previous|prior|above|earlier|preceding|system|original)\s+)?(instructions?|prompts?|rules|guidelines|directions)\b`)},
+	{"role-override", regexp.MustCompile(`(?i)\b(you are now|from now on,? you|act as (an?|the) |pretend (to be|you are)|new (system )?instructions?\s*:)`)},
+	{"system-prompt", regexp.MustCompile(`(?im)(\bsystem prompt\b|\[/?(system|inst)\]|<\|?/?(system|im_start|im_end)\|?>|^\s*(system|assistant)\s*:)`)},
+	{"conceal-from-user", regexp.MustCompile(`(?i)\b(do not|don't|never)\s+(tell|inform|mention|reveal|notify|show)\b[^.\n]{0,20}\buser\b`)},
+	{"tool-directive", regexp.MustCompile(`(?i)\b(call|use|invoke|run|execute)\s+(the\s+)?[\w./-]+\s+(tool|function)\b`)},
+}
+
+// InjectionDetector flags tool output containing instruction-like phrasing. It
+// is a heuristic: it catches common injection wording, not every attack, which
+// is why flagged output also loses its say over writes.
+type InjectionDetector struct{}
+
+// NewInjectionDetector creates a pattern-based injection detector.
+func NewInjectionDetector() *InjectionDetector {
+	return &InjectionDetector{}
+}
+
+// Detect implements agentengine.InjectionDetector.
+func (d *InjectionDetector) Detect(ctx context.Context, obs agentengine.Observation) []string {
+	_ = ctx
+	text := observationStrings(obs)
+	var signals []string
+	for _, signal := range injectionSignals {
+		for _, s := range text {
+			if signal.pattern.MatchString(s) {
+				signals = append(signals, signal.name)
+				break
+			}
+		}
+	}
+	return signals
+}
+
+// observationStrings returns the strings of an observation: error, message and
+// every string in the result data.
+func observationStrings(obs agentengine.Observation) []string {
+	out := []string{obs.Error}
+	if obs.Result == nil {
+		return out
+	}
+	out = append(out, obs.Result.Message)
+	var data any
+	if raw, err := json.Marshal(obs.Result.Data); err == nil && json.Unmarshal(raw, &data) == nil {
+		out = appendStrings(out, data)
+	}
+	return out
+}
+
+func appendStrings(out []string, value any) []string {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) != "" {
+			out = append(out, v)
+		}
+	case map[string]any:
+		for _, item := range v {
+			out = appendStrings(out, item)
+		}
+	case []any:
+		for _, item := range v {
+			out = appendStrings(out, item)
+		}
+	}
+	return out
+}
+
+var _ agentengine.InjectionDetector = (*InjectionDetector)(nil)
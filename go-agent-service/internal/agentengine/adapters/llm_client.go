@@ -0,0 +1,110 @@
+// Package adapters provides bridge implementations for AgentEngine.
+package adapters
+
+import (
+	"context"
+
+	"github.com/antigravity/go-agent-service/internal/agent"
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// RegistryLLMClient adapts the LLM provider registry to the AgentEngine interface.
+type RegistryLLMClient struct {
+	registry *agent.Registry
+}
+
+// NewRegistryLLMClient creates an adapter for a provider registry.
+func NewRegistryLLMClient(registry *agent.Registry) *RegistryLLMClient {
+	return &RegistryLLMClient{registry: registry}
+}
+
+// Respond implements agentengine.LLMClient.
+func (c *RegistryLLMClient) Respond(ctx context.Context, input agentengine.LLMRequest) (agentengine.LLMResponse, error) {
+	var out agent.Completion
+	var err error
+	if input.ResponseSchema != nil {
+		out, err = c.registry.GenerateStructured(ctx, input.Provider, input.Model, input.Query, input.Prompt, registryHistory(input.History), input.ResponseSchema)
+	} else {
+		out, err = c.registry.GenerateResponse(ctx, input.Provider, input.Model, input.Query, input.Prompt, registryHistory(input.History))
+	}
+	if err != nil {
+		return agentengine.LLMResponse{}, err
+	}
+
+	resp := llmResponse(input, out)
+	resp.Usage = engineUsage(c.registry, resp.Provider, resp.Model, out.Usage)
+	return resp, nil
+}
+
+// RespondStream implements agentengine.StreamingLLMClient.
+// Structured requests are not streamed.
+func (c *RegistryLLMClient) RespondStream(ctx context.Context, input agentengine.LLMRequest, onDelta func(delta string) error) (agentengine.LLMResponse, error) {
+	if input.ResponseSchema != nil {
+		return c.Respond(ctx, input)
+	}
+	out, err := c.registry.GenerateResponseStream(ctx, input.Provider, input.Model, input.Query, input.Prompt, registryHistory(input.History), onDelta)
+	if err != nil {
+		return agentengine.LLMResponse{}, err
+	}
+
+	resp := llmResponse(input, out)
+	resp.Usage = engineUsage(c.registry, resp.Provider, resp.Model, out.Usage)
+	return resp, nil
+}
+
+// llmResponse reports the provider and model that actually answered, which differ
+// from the request when it left them to the registry's defaults.
+func llmResponse(input agentengine.LLMRequest, out agent.Completion) agentengine.LLMResponse {
+	resp := agentengine.LLMResponse{
+		Text:     out.Text,
+		Provider: input.Provider,
+		Model:    input.Model,
+	}
+	if out.Provider != "" {
+		resp.Provider = out.Provider
+	}
+	if out.Model != "" {
+		resp.Model = out.Model
+	}
+	return resp
+}
+
+// usagePricer prices token usage by provider and model; agent.Registry implements it.
+type usagePricer interface {
+	Cost(provider, model string, u agent.Usage) (float64, bool)
+}
+
+// engineUsage converts provider token counts and prices them with pricer, or
+// the built-in model table without one. Tokens of models without a price are
+// marked unpriced.
+func engineUsage(pricer usagePricer, provider, model string, usage agent.Usage) agentengine.Usage {
+	var cost float64
+	var priced bool
+	if pricer != nil {
+		cost, priced = pricer.Cost(provider, model, usage)
+	} else {
+		var m agent.ModelConfig
+		m, priced = agent.LookupModel(provider, model)
+		cost = m.Cost(usage)
+	}
+	return agentengine.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		CostUSD:          cost,
+		Unpriced:         !priced && usage.TotalTokens+usage.PromptTokens+usage.CompletionTokens > 0,
+	}
+}
+
+func registryHistory(history []agentengine.HistoryMessage) []agent.HistoryMessage {
+	out := make([]agent.HistoryMessage, 0, len(history))
+	for _, h := range history {
+		out = append(out, agent.HistoryMessage{
+			Role:    h.Role,
+			Content: h.Content,
+		})
+	}
+	return out
+}
+
+var _ agentengine.StreamingLLMClient = (*RegistryLLMClient)(nil)
@@ -0,0 +1,168 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antigravity/go-agent-service/internal/agent"
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+type stubFunctionCaller struct {
+	gotFunctions []agent.FunctionDeclaration
+	resp         *agent.FunctionResponse
+	err          error
+}
+
+func (s *stubFunctionCaller) GenerateWithFunctions(ctx context.Context, provider, model, query, systemPrompt string, history []agent.HistoryMessage, functions []agent.FunctionDeclaration) (*agent.FunctionResponse, error) {
+	_ = ctx
+	s.gotFunctions = functions
+	return s.resp, s.err
+}
+
+func plannerTestInput() agentengine.PlanInput {
+	return agentengine.PlanInput{
+		Request: agentengine.Request{
+			Query:     "list critical bugs in MOBILE",
+			UserID:    "user-1",
+			ProjectID: "project-1",
+		},
+		Tools: []agentengine.ToolDef{
+			{
+				Name:        "app/jira-1/search_issues",
+				Description: "Jira action search_issues",
+				Actions: []agentengine.ToolAction{
+					{
+						Name:        "search_issues",
+						Description: "Search issues with JQL",
+						InputSchema: `{"type":"object","properties":{"jql":{"type":"string"}},"required":["jql"]}`,
+					},
+				},
+			},
+			{
+				Name:        "store",
+				Description: "Key-value storage",
+				Actions: []agentengine.ToolAction{
+					{Name: "kv_get", Description: "Get value"},
+					{Name: "kv_put", Description: "Put value"},
+				},
+			},
+		},
+	}
+}
+
+func TestLLMPlannerMapsFunctionCalls(t *testing.T) {
+	stub := &stubFunctionCaller{
+		resp: &agent.FunctionResponse{
+			Calls: []agent.FunctionCall{
+				{Name: "app_jira-1_search_issues", Args: map[string]any{"jql": "priority = Critical"}},
+				{Name: "store__kv_get", Args: map[string]any{"key": "last_sync"}},
+			},
+		},
+	}
+	planner := NewLLMPlanner(stub, nil)
+
+	plan, err := planner.Plan(context.Background(), plannerTestInput())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Type != agentengine.PlanToolCalls || len(plan.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %+v", plan)
+	}
+
+	first := plan.ToolCalls[0]
+	if first.Name != "app/jira-1/search_issues" || first.Action != "search_issues" {
+		t.Fatalf("unexpected first call: %+v", first)
+	}
+	if first.Args["jql"] != "priority = Critical" || first.Args["userId"] != "user-1" || first.Args["projectId"] != "project-1" {
+		t.Fatalf("unexpected first call args: %+v", first.Args)
+	}
+	if plan.ToolCalls[1].Name != "store" || plan.ToolCalls[1].Action != "kv_get" {
+		t.Fatalf("unexpected second call: %+v", plan.ToolCalls[1])
+	}
+
+	// 3 tool actions + clarification function
+	if len(stub.gotFunctions) != 4 {
+		t.Fatalf("expected 4 function declarations, got %d", len(stub.gotFunctions))
+	}
+	if stub.gotFunctions[0].Parameters["type"] != "object" {
+		t.Fatalf("expected input schema to be forwarded, got %+v", stub.gotFunctions[0].Parameters)
+	}
+}
+
+func TestLLMPlannerDirectAndClarification(t *testing.T) {
+	stub := &stubFunctionCaller{resp: &agent.FunctionResponse{Text: "Hello!"}}
+	plan, err := NewLLMPlanner(stub, nil).Plan(context.Background(), plannerTestInput())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Type != agentengine.PlanDirect {
+		t.Fatalf("expected direct plan, got %+v", plan)
+	}
+
+	stub.resp = &agent.FunctionResponse{
+		Calls: []agent.FunctionCall{{Name: clarificationFunction, Args: map[string]any{"question": "Which project?"}}},
+	}
+	plan, err = NewLLMPlanner(stub, nil).Plan(context.Background(), plannerTestInput())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Type != agentengine.PlanNeedClarification || plan.Clarification != "Which project?" {
+		t.Fatalf("expected clarification plan, got %+v", plan)
+	}
+}
+
+func TestLLMPlannerFallsBackOnError(t *testing.T) {
+	stub := &stubFunctionCaller{err: errors.New("no LLM provider configured")}
+	input := plannerTestInput()
+	input.Request.Query = "tool:store.kv_get"
+
+	plan, err := NewLLMPlanner(stub, NewHeuristicPlanner()).Plan(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Type != agentengine.PlanToolCalls || plan.ToolCalls[0].Name != "store" {
+		t.Fatalf("expected heuristic fallback plan, got %+v", plan)
+	}
+
+	if _, err := NewLLMPlanner(stub, nil).Plan(context.Background(), input); err == nil {
+		t.Fatalf("expected error without fallback")
+	}
+}
+
+func TestFunctionNameIsProviderSafe(t *testing.T) {
+	name := functionName("app/0f8fad5b-d9cb-469f-a165-70867728950e/create_issue_with_attachments_and_links", "create_issue_with_attachments_and_links", 1)
+	if len(name) > maxFunctionNameLen {
+		t.Fatalf("function name too long: %d", len(name))
+	}
+	if invalidFunctionChars.MatchString(name) {
+		t.Fatalf("function name has invalid characters: %s", name)
+	}
+}
+
+func TestLLMPlannerReportsPricedUsage(t *testing.T) {
+	stub := &stubFunctionCaller{
+		resp: &agent.FunctionResponse{
+			Model: "gemini-2.0-flash",
+			Usage: agent.Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000, TotalTokens: 2_000_000},
+		},
+	}
+	planner := NewLLMPlanner(stub, nil)
+
+	plan, err := planner.Plan(context.Background(), plannerTestInput())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Usage.TotalTokens != 2_000_000 || plan.Usage.CostUSD != 0.5 {
+		t.Fatalf("expected usage priced for the answering model, got %+v", plan.Usage)
+	}
+	if plan.Usage.Unpriced {
+		t.Fatalf("expected a priced model, got %+v", plan.Usage)
+	}
+
+	stub.resp.Model = "llama3.1"
+	if plan, _ = planner.Plan(context.Background(), plannerTestInput()); !plan.Usage.Unpriced || plan.Usage.CostUSD != 0 {
+		t.Fatalf("expected usage of an unknown model marked unpriced, got %+v", plan.Usage)
+	}
+}
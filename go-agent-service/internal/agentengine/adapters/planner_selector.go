@@ -0,0 +1,77 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// Planner modes accepted by PlannerSelector.
+const (
+	PlannerModeAuto      = "auto"
+	PlannerModeLLM       = "llm"
+	PlannerModeHeuristic = "heuristic"
+	PlannerModePlan      = "plan"
+)
+
+// PlannerSelector picks a planner per request based on the requested mode and provider.
+type PlannerSelector struct {
+	mode      string
+	llm       agentengine.Planner
+	heuristic agentengine.Planner
+	steps     agentengine.Planner
+	supports  func(provider string) bool
+}
+
+// NewPlannerSelector creates a selector. In auto mode the LLM planner is used when
+// supports(provider) reports native function calling; otherwise the heuristic planner runs.
+func NewPlannerSelector(mode string, llm, heuristic agentengine.Planner, supports func(provider string) bool) *PlannerSelector {
+	if mode == "" {
+		mode = PlannerModeAuto
+	}
+	return &PlannerSelector{
+		mode:      mode,
+		llm:       llm,
+		heuristic: heuristic,
+		supports:  supports,
+	}
+}
+
+// WithStepPlanner sets the plan-and-execute planner used in plan mode. Without
+// one, plan mode falls back to auto.
+func (s *PlannerSelector) WithStepPlanner(steps agentengine.Planner) *PlannerSelector {
+	s.steps = steps
+	return s
+}
+
+// Plan implements agentengine.Planner.
+func (s *PlannerSelector) Plan(ctx context.Context, input agentengine.PlanInput) (agentengine.Plan, error) {
+	return s.plannerFor(input.Request).Plan(ctx, input)
+}
+
+func (s *PlannerSelector) plannerFor(req agentengine.Request) agentengine.Planner {
+	mode := s.mode
+	if req.Planner != "" {
+		mode = req.Planner
+	}
+
+	switch mode {
+	case PlannerModeHeuristic:
+		return s.heuristic
+	case PlannerModeLLM:
+		if s.llm != nil {
+			return s.llm
+		}
+		return s.heuristic
+	case PlannerModePlan:
+		if s.steps != nil {
+			return s.steps
+		}
+	}
+	if s.llm != nil && (s.supports == nil || s.supports(req.Provider)) {
+		return s.llm
+	}
+	return s.heuristic
+}
+
+var _ agentengine.Planner = (*PlannerSelector)(nil)
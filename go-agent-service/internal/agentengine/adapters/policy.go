@@ -1,6 +1,11 @@
 package adapters
 
-import "github.com/antigravity/go-agent-service/internal/agentengine"
+import (
+	"context"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+	"github.com/antigravity/go-agent-service/internal/policy"
+)
 
 // AllowAllPolicy allows every tool.
 type AllowAllPolicy struct{}
@@ -10,10 +15,47 @@ func NewAllowAllPolicy() *AllowAllPolicy {
 	return &AllowAllPolicy{}
 }
 
-// AllowTool implements agentengine.Policy.
-func (p *AllowAllPolicy) AllowTool(name string) bool {
-	_ = name
-	return true
+// Evaluate implements agentengine.Policy.
+func (p *AllowAllPolicy) Evaluate(ctx context.Context, input agentengine.PolicyContext) agentengine.PolicyDecision {
+	_ = ctx
+	_ = input
+	return agentengine.PolicyDecision{Outcome: agentengine.PolicyAllow}
+}
+
+// RulePolicy adapts the declarative policy evaluator to AgentEngine.
+type RulePolicy struct {
+	evaluator *policy.Evaluator
+}
+
+// NewRulePolicy creates a policy adapter for a rule evaluator.
+func NewRulePolicy(evaluator *policy.Evaluator) *RulePolicy {
+	return &RulePolicy{evaluator: evaluator}
+}
+
+// Evaluate implements agentengine.Policy.
+func (p *RulePolicy) Evaluate(ctx context.Context, input agentengine.PolicyContext) agentengine.PolicyDecision {
+	decision := p.evaluator.Evaluate(ctx, policy.Request{
+		UserID:    input.UserID,
+		ProjectID: input.ProjectID,
+		Tool:      input.Tool,
+		Action:    input.Action,
+		Access:    string(input.Access),
+		CallCount: input.CallCount,
+	})
+
+	outcome := agentengine.PolicyAllow
+	switch decision.Effect {
+	case policy.EffectDeny:
+		outcome = agentengine.PolicyDeny
+	case policy.EffectRequireApproval:
+		outcome = agentengine.PolicyRequireApproval
+	}
+	return agentengine.PolicyDecision{
+		Outcome: outcome,
+		Reason:  decision.Reason,
+		Rule:    decision.RuleID,
+	}
 }
 
 var _ agentengine.Policy = (*AllowAllPolicy)(nil)
+var _ agentengine.Policy = (*RulePolicy)(nil)
@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+	"github.com/antigravity/go-agent-service/internal/mcp"
+)
+
+// RetryPolicy configures RetryingExecutor.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first; 1 disables retries
+	InitialBackoff time.Duration // Wait before the first retry, doubled after each
+	MaxBackoff     time.Duration // Upper bound for a single wait
+}
+
+// RetryingExecutor retries transient tool failures with exponential backoff.
+// The engine gives every write call an idempotency key, which the MCP server
+// uses to apply the change once however often it is retried.
+type RetryingExecutor struct {
+	next   agentengine.ToolExecutor
+	policy RetryPolicy
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryingExecutor wraps next with the retry policy.
+func NewRetryingExecutor(next agentengine.ToolExecutor, policy RetryPolicy) *RetryingExecutor {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 200 * time.Millisecond
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+	return &RetryingExecutor{next: next, policy: policy, sleep: sleepContext}
+}
+
+// Execute implements agentengine.ToolExecutor.
+func (e *RetryingExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	backoff := e.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		// The backend may consume arguments, so each attempt gets its own copy.
+		attemptCall := call
+		attemptCall.Args = cloneArgs(call.Args)
+		result, err := e.next.Execute(ctx, attemptCall)
+		if err == nil || attempt >= e.policy.MaxAttempts || ctx.Err() != nil || !IsTransient(err) {
+			return result, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			return result, err
+		}
+		if sleepErr := e.sleep(ctx, backoff); sleepErr != nil {
+			return result, err
+		}
+		backoff = min(backoff*2, e.policy.MaxBackoff)
+	}
+}
+
+// IsTransient reports whether a tool error is worth retrying: timeouts,
+// 5xx responses from the MCP server, and unavailable gRPC backends.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var statusErr *mcp.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return true
+		}
+	}
+	return false
+}
+
+func cloneArgs(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	return out
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,87 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+	"github.com/antigravity/go-agent-service/internal/mcp"
+)
+
+// flakyExecutor fails with errs in order, then succeeds.
+type flakyExecutor struct {
+	errs  []error
+	calls []agentengine.ToolCall
+}
+
+func (f *flakyExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	recorded := call
+	recorded.Args = cloneArgs(call.Args)
+	f.calls = append(f.calls, recorded)
+	// Mimic the registry, which strips identity arguments
+	delete(call.Args, "userId")
+	if len(f.calls) <= len(f.errs) {
+		return nil, f.errs[len(f.calls)-1]
+	}
+	return &agentengine.ToolResult{Success: true}, nil
+}
+
+func newTestRetryingExecutor(next agentengine.ToolExecutor, waits *[]time.Duration) *RetryingExecutor {
+	executor := NewRetryingExecutor(next, RetryPolicy{MaxAttempts: 4, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 150 * time.Millisecond})
+	executor.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return executor
+}
+
+func TestRetryingExecutorRetriesTransientErrors(t *testing.T) {
+	unavailable := &mcp.StatusError{Op: "execute tool", StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}
+	inner := &flakyExecutor{errs: []error{unavailable, status.Error(codes.Unavailable, "down"), context.DeadlineExceeded}}
+	var waits []time.Duration
+	executor := newTestRetryingExecutor(inner, &waits)
+
+	result, err := executor.Execute(context.Background(), agentengine.ToolCall{Name: "jira", Action: "search", Args: map[string]any{"userId": "u1"}})
+	if err != nil || !result.Success {
+		t.Fatalf("expected success after retries, got %+v, %v", result, err)
+	}
+	if len(inner.calls) != 4 {
+		t.Fatalf("expected 4 attempts, got %d", len(inner.calls))
+	}
+	if inner.calls[3].Args["userId"] != "u1" {
+		t.Fatalf("expected arguments intact on retry, got %+v", inner.calls[3].Args)
+	}
+	want := []time.Duration{100 * time.Millisecond, 150 * time.Millisecond, 150 * time.Millisecond}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Fatalf("expected capped exponential backoff %v, got %v", want, waits)
+		}
+	}
+}
+
+func TestRetryingExecutorLeavesPermanentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		call agentengine.ToolCall
+		err  error
+		want int
+	}{
+		{"client error", agentengine.ToolCall{Name: "jira", Action: "search"}, &mcp.StatusError{StatusCode: http.StatusBadRequest}, 1},
+		{"plain error", agentengine.ToolCall{Name: "jira", Action: "search"}, errors.New("invalid jql"), 1},
+		{"timeout", agentengine.ToolCall{Name: "jira", Action: "create", IdempotencyKey: "k1"}, context.DeadlineExceeded, 2},
+	}
+	for _, tt := range tests {
+		inner := &flakyExecutor{errs: []error{tt.err}}
+		var waits []time.Duration
+		_, _ = newTestRetryingExecutor(inner, &waits).Execute(context.Background(), tt.call)
+		if len(inner.calls) != tt.want {
+			t.Fatalf("%s: expected %d attempts, got %d", tt.name, tt.want, len(inner.calls))
+		}
+	}
+}
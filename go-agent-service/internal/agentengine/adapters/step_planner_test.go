@@ -0,0 +1,69 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// stubLLM replies with fixed text and records the request.
+type stubLLM struct {
+	text    string
+	err     error
+	request agentengine.LLMRequest
+}
+
+func (l *stubLLM) Respond(ctx context.Context, input agentengine.LLMRequest) (agentengine.LLMResponse, error) {
+	l.request = input
+	return agentengine.LLMResponse{Text: l.text, Usage: agentengine.Usage{TotalTokens: 9}}, l.err
+}
+
+func TestStepPlannerParsesDependentSteps(t *testing.T) {
+	llm := &stubLLM{text: "```json\n" + `{"steps": [
+		{"id": "s1", "tool": "jira", "action": "search", "args": {"jql": "priority = Critical"}, "purpose": "find bugs"},
+		{"id": "s2", "tool": "github", "action": "search_prs", "args": {"keys": "{{s1.issues.*.key}}"}, "dependsOn": ["s1"]}
+	]}` + "\n```"}
+	planner := NewStepPlanner(llm, NewHeuristicPlanner())
+
+	plan, err := planner.Plan(context.Background(), agentengine.PlanInput{
+		Request: agentengine.Request{Query: "critical bugs and their PRs", ProjectID: "p1"},
+		Prompt:  "prompt",
+	})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if llm.request.ResponseSchema == nil {
+		t.Fatalf("expected the plan requested as structured output")
+	}
+	if plan.Type != agentengine.PlanSteps || len(plan.Steps) != 2 || plan.Usage.TotalTokens != 9 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	s1, s2 := plan.Steps[0], plan.Steps[1]
+	if s1.ID != "s1" || s1.Call.Name != "jira" || s1.Call.Args["jql"] != "priority = Critical" || s1.Call.Args["projectId"] != "p1" || s1.Purpose != "find bugs" {
+		t.Fatalf("unexpected first step: %+v", s1)
+	}
+	if len(s2.DependsOn) != 1 || s2.DependsOn[0] != "s1" || s2.Call.Args["keys"] != "{{s1.issues.*.key}}" {
+		t.Fatalf("unexpected second step: %+v", s2)
+	}
+}
+
+func TestStepPlannerDirectClarificationAndFallback(t *testing.T) {
+	plan, _ := NewStepPlanner(&stubLLM{text: `{"steps": []}`}, nil).Plan(context.Background(), agentengine.PlanInput{})
+	if plan.Type != agentengine.PlanDirect {
+		t.Fatalf("expected a direct answer for no steps, got %+v", plan)
+	}
+	plan, _ = NewStepPlanner(&stubLLM{text: `{"steps": [], "clarification": "Which project?"}`}, nil).Plan(context.Background(), agentengine.PlanInput{})
+	if plan.Type != agentengine.PlanNeedClarification || plan.Clarification != "Which project?" {
+		t.Fatalf("expected a clarification, got %+v", plan)
+	}
+
+	input := agentengine.PlanInput{Request: agentengine.Request{Query: "hello"}}
+	for _, llm := range []*stubLLM{{err: errors.New("quota")}, {text: "not a plan"}} {
+		plan, err := NewStepPlanner(llm, NewHeuristicPlanner()).Plan(context.Background(), input)
+		if err != nil || plan.Type != agentengine.PlanDirect {
+			t.Fatalf("expected the fallback planner's plan, got %+v, %v", plan, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// CacheConfig configures ToolCache.
+type CacheConfig struct {
+	TTL time.Duration // Lifetime of a cached result; 0 disables caching
+	// ActionTTLs override TTL per action, keyed by "tool.action" or action name; 0 disables caching for it.
+	ActionTTLs map[string]time.Duration
+	MaxEntries int // Oldest results are evicted beyond this (default 1000)
+}
+
+// ToolCache caches successful results of read-only tool calls per session,
+// keyed by tool, action, args, user and project. A write call drops every cached
+// result of its app, in all sessions, since the data it changed may be shared,
+// and reads of the app that overlap it are not cached. Calls without an engine
+// ToolCallScope, or without a session, are not cached.
+type ToolCache struct {
+	cfg   CacheConfig
+	clock func() time.Time
+
+	mu          sync.Mutex
+	entries     map[string]*cacheEntry
+	generations map[string]uint64 // Invalidations per app, so reads that overlap one are not stored
+}
+
+type cacheEntry struct {
+	app     string
+	result  *agentengine.ToolResult
+	stored  time.Time
+	expires time.Time
+}
+
+// NewToolCache creates a tool result cache.
+func NewToolCache(cfg CacheConfig) *ToolCache {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	return &ToolCache{cfg: cfg, clock: time.Now, entries: make(map[string]*cacheEntry), generations: make(map[string]uint64)}
+}
+
+// Executor wraps next so read-only calls are served from the cache and writes invalidate it.
+func (c *ToolCache) Executor(next agentengine.ToolExecutor) agentengine.ToolExecutor {
+	return &cachingExecutor{cache: c, next: next}
+}
+
+// Invalidate drops the cached results of the app a tool belongs to and returns how many were dropped.
+func (c *ToolCache) Invalidate(tool string) int {
+	app := appOf(tool)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[app]++
+	dropped := 0
+	for key, entry := range c.entries {
+		if entry.app == app {
+			delete(c.entries, key)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// generation returns how often an app was invalidated so far.
+func (c *ToolCache) generation(app string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[app]
+}
+
+func (c *ToolCache) ttl(call agentengine.ToolCall) time.Duration {
+	if ttl, ok := c.cfg.ActionTTLs[call.Name+"."+call.Action]; ok {
+		return ttl
+	}
+	if ttl, ok := c.cfg.ActionTTLs[call.Action]; ok {
+		return ttl
+	}
+	return c.cfg.TTL
+}
+
+func (c *ToolCache) get(key string) (*agentengine.ToolResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.clock().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return copyResult(entry.result), true
+}
+
+// put stores a result read at generation gen of its app, unless the app was
+// invalidated since.
+func (c *ToolCache) put(key, app string, gen uint64, result *agentengine.ToolResult, ttl time.Duration) {
+	now := c.clock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[app] != gen {
+		return
+	}
+	c.entries[key] = &cacheEntry{app: app, result: copyResult(result), stored: now, expires: now.Add(ttl)}
+	if len(c.entries) <= c.cfg.MaxEntries {
+		return
+	}
+	// Drop expired results, then the oldest one if still over the limit
+	oldestKey := ""
+	var oldest time.Time
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+			continue
+		}
+		if oldestKey == "" || entry.stored.Before(oldest) {
+			oldestKey, oldest = k, entry.stored
+		}
+	}
+	if len(c.entries) > c.cfg.MaxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+type cachingExecutor struct {
+	cache *ToolCache
+	next  agentengine.ToolExecutor
+}
+
+// Execute implements agentengine.ToolExecutor.
+func (e *cachingExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	scope, ok := agentengine.ToolCallScopeFrom(ctx)
+	if !ok {
+		return e.next.Execute(ctx, call)
+	}
+	if scope.Access == agentengine.AccessWrite {
+		// Invalidate before the write, so no read sees cached data while it
+		// runs, and after it, so reads that overlapped it are not stored
+		dropped := e.cache.Invalidate(call.Name)
+		result, err := e.next.Execute(ctx, call)
+		if dropped += e.cache.Invalidate(call.Name); dropped > 0 {
+			scope.ReportCache(agentengine.CacheInvalidate)
+		}
+		return result, err
+	}
+	if scope.Access != agentengine.AccessRead {
+		return e.next.Execute(ctx, call)
+	}
+
+	ttl := e.cache.ttl(call)
+	if ttl <= 0 || scope.SessionID == "" {
+		return e.next.Execute(ctx, call)
+	}
+	key, err := cacheKey(scope, call)
+	if err != nil {
+		return e.next.Execute(ctx, call)
+	}
+	if result, hit := e.cache.get(key); hit {
+		scope.ReportCache(agentengine.CacheHit)
+		return result, nil
+	}
+
+	scope.ReportCache(agentengine.CacheMiss)
+	app := appOf(call.Name)
+	gen := e.cache.generation(app)
+	result, err := e.next.Execute(ctx, call)
+	if err == nil && result != nil && result.Success {
+		e.cache.put(key, app, gen, result, ttl)
+	}
+	return result, err
+}
+
+// cacheKey identifies a read by session, user, project, tool, action and args.
+// Args are encoded with sorted keys, so their order does not matter.
+func cacheKey(scope agentengine.ToolCallScope, call agentengine.ToolCall) (string, error) {
+	args, err := json.Marshal(call.Args)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{scope.SessionID, scope.UserID, scope.ProjectID, call.Name, call.Action, string(args)}, "\x00"), nil
+}
+
+// appOf names the app a tool belongs to: app tools are scoped per action as
+// app/{appId}/{action}, other tools are their own app.
+func appOf(tool string) string {
+	if rest, ok := strings.CutPrefix(tool, "app/"); ok {
+		if appID, _, found := strings.Cut(rest, "/"); found {
+			return "app/" + appID
+		}
+	}
+	return tool
+}
+
+// copyResult copies a result and all of its data so callers cannot change cached results.
+func copyResult(result *agentengine.ToolResult) *agentengine.ToolResult {
+	out := *result
+	out.Data, _ = cloneData(result.Data).(map[string]any)
+	return &out
+}
+
+// cloneData deep-copies the maps and slices of JSON-like data.
+func cloneData(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = cloneData(item)
+		}
+		return out
+	case []any:
+		if v == nil {
+			return v
+		}
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = cloneData(item)
+		}
+		return out
+	}
+	return value
+}
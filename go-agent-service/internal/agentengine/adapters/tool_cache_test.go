@@ -0,0 +1,153 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// countingExecutor succeeds and counts its calls per action.
+type countingExecutor struct {
+	calls map[string]int
+}
+
+func (c *countingExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	c.calls[call.Action]++
+	return &agentengine.ToolResult{Success: true, Data: map[string]any{"n": c.calls[call.Action]}}, nil
+}
+
+func scoped(session string, access agentengine.AccessKind) context.Context {
+	return agentengine.WithToolCallScope(context.Background(), agentengine.ToolCallScope{SessionID: session, UserID: "u1", Access: access})
+}
+
+func TestToolCacheServesReadsPerSession(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewToolCache(CacheConfig{TTL: time.Minute, ActionTTLs: map[string]time.Duration{"live": 0}})
+	cache.clock = func() time.Time { return now }
+	inner := &countingExecutor{}
+	executor := cache.Executor(inner)
+	read := agentengine.ToolCall{Name: "app/a1/list", Action: "list", Args: map[string]any{"q": "x", "limit": 5}}
+
+	for i := 0; i < 2; i++ {
+		result, err := executor.Execute(scoped("s1", agentengine.AccessRead), read)
+		if err != nil || result.Data["n"] != 1 {
+			t.Fatalf("expected the first result served again, got %+v, %v", result, err)
+		}
+		result.Data["n"] = 99 // Callers cannot change the cached result
+	}
+	if _, _ = executor.Execute(scoped("s2", agentengine.AccessRead), read); inner.calls["list"] != 2 {
+		t.Fatalf("expected another session to miss, got %d calls", inner.calls["list"])
+	}
+	if _, _ = executor.Execute(context.Background(), read); inner.calls["list"] != 3 {
+		t.Fatalf("expected unscoped calls not cached, got %d calls", inner.calls["list"])
+	}
+	live := agentengine.ToolCall{Name: "app/a1/live", Action: "live"}
+	executor.Execute(scoped("s1", agentengine.AccessRead), live)
+	executor.Execute(scoped("s1", agentengine.AccessRead), live)
+	if inner.calls["live"] != 2 {
+		t.Fatalf("expected a zero action TTL to disable caching, got %d calls", inner.calls["live"])
+	}
+
+	now = now.Add(time.Minute)
+	if executor.Execute(scoped("s1", agentengine.AccessRead), read); inner.calls["list"] != 4 {
+		t.Fatalf("expected the result expired, got %d calls", inner.calls["list"])
+	}
+}
+
+func TestToolCacheWriteInvalidatesApp(t *testing.T) {
+	cache := NewToolCache(CacheConfig{TTL: time.Minute})
+	inner := &countingExecutor{}
+	executor := cache.Executor(inner)
+	read := agentengine.ToolCall{Name: "app/a1/list", Action: "list"}
+	other := agentengine.ToolCall{Name: "app/a2/list", Action: "list"}
+	executor.Execute(scoped("s1", agentengine.AccessRead), read)
+	executor.Execute(scoped("s2", agentengine.AccessRead), other)
+
+	executor.Execute(scoped("s3", agentengine.AccessWrite), agentengine.ToolCall{Name: "app/a1/update", Action: "update"})
+	executor.Execute(scoped("s1", agentengine.AccessRead), read)
+	executor.Execute(scoped("s2", agentengine.AccessRead), other)
+	if inner.calls["list"] != 3 {
+		t.Fatalf("expected only the written app's results dropped, got %d calls", inner.calls["list"])
+	}
+}
+
+// overlappingExecutor runs a write of the same app while a read is in flight.
+type overlappingExecutor struct {
+	countingExecutor
+	write func()
+}
+
+func (o *overlappingExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	if call.Action == "list" && o.write != nil {
+		write := o.write
+		o.write = nil
+		write()
+	}
+	return o.countingExecutor.Execute(ctx, call)
+}
+
+func TestToolCacheSkipsReadsOverlappingWrites(t *testing.T) {
+	cache := NewToolCache(CacheConfig{TTL: time.Minute})
+	inner := &overlappingExecutor{}
+	executor := cache.Executor(inner)
+	read := agentengine.ToolCall{Name: "app/a1/list", Action: "list"}
+	inner.write = func() {
+		executor.Execute(scoped("s2", agentengine.AccessWrite), agentengine.ToolCall{Name: "app/a1/update", Action: "update"})
+	}
+
+	executor.Execute(scoped("s1", agentengine.AccessRead), read)
+	executor.Execute(scoped("s1", agentengine.AccessRead), read)
+	if inner.calls["list"] != 2 {
+		t.Fatalf("expected a read overlapping a write not cached, got %d calls", inner.calls["list"])
+	}
+	executor.Execute(scoped("s1", agentengine.AccessRead), read)
+	if inner.calls["list"] != 2 {
+		t.Fatalf("expected later reads cached again, got %d calls", inner.calls["list"])
+	}
+}
+
+// nestedExecutor returns a list of issues.
+type nestedExecutor struct{}
+
+func (nestedExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	return &agentengine.ToolResult{Success: true, Data: map[string]any{
+		"issues": []any{map[string]any{"key": "MOBILE-1"}},
+	}}, nil
+}
+
+func TestToolCacheCopiesNestedData(t *testing.T) {
+	cache := NewToolCache(CacheConfig{TTL: time.Minute})
+	executor := cache.Executor(nestedExecutor{})
+	read := agentengine.ToolCall{Name: "jira", Action: "search"}
+
+	first, _ := executor.Execute(scoped("s1", agentengine.AccessRead), read)
+	first.Data["issues"].([]any)[0].(map[string]any)["key"] = "changed"
+	second, _ := executor.Execute(scoped("s1", agentengine.AccessRead), read)
+	if key := second.Data["issues"].([]any)[0].(map[string]any)["key"]; key != "MOBILE-1" {
+		t.Fatalf("expected nested cached data unchanged, got %v", key)
+	}
+}
+
+func TestToolCacheEvictsOldest(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewToolCache(CacheConfig{TTL: time.Hour, MaxEntries: 2})
+	cache.clock = func() time.Time { return now }
+	executor := cache.Executor(&countingExecutor{})
+	for _, action := range []string{"a", "b", "c"} {
+		now = now.Add(time.Second)
+		executor.Execute(scoped("s1", agentengine.AccessRead), agentengine.ToolCall{Name: "jira", Action: action})
+	}
+	if len(cache.entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(cache.entries))
+	}
+	for _, entry := range cache.entries {
+		if entry.stored.Equal(time.Unix(1, 0)) {
+			t.Fatalf("expected the oldest entry evicted")
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/antigravity/go-agent-service/internal/agentengine"
+	"github.com/antigravity/go-agent-service/internal/mcp"
 	"github.com/antigravity/go-agent-service/internal/tools"
 )
 
@@ -24,6 +25,9 @@ func NewRegistryExecutor(backend ToolExecutorBackend) *RegistryExecutor {
 
 // Execute implements agentengine.ToolExecutor.
 func (e *RegistryExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	if call.IdempotencyKey != "" {
+		ctx = mcp.WithIdempotencyKey(ctx, call.IdempotencyKey)
+	}
 	result, err := e.backend.Execute(ctx, call.Name, call.Action, call.Args)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,207 @@
+// Package agentengine defines the human approval gate for mutating tool calls.
+package agentengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRunNotFound is returned when a paused run does not exist.
+var ErrRunNotFound = errors.New("agent run not found")
+
+// RunStatus describes how a run ended.
+type RunStatus string
+
+const (
+	RunCompleted        RunStatus = "completed"
+	RunAwaitingApproval RunStatus = "awaiting_approval"
+	// Statuses reported while durable runs are in progress or after they stop
+	RunRunning  RunStatus = "running"
+	RunFailed   RunStatus = "failed"
+	RunCanceled RunStatus = "canceled"
+)
+
+// ApprovalStatus tracks the decision for a pending tool call.
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
+// PendingCall is a tool call waiting for a human decision.
+type PendingCall struct {
+	ID     string // {runID}:{index}
+	Call   ToolCall
+	Access AccessKind
+	Reason string
+	Status ApprovalStatus
+	Note   string // Reviewer comment
+}
+
+// PendingRun is a paused run persisted until every pending call is decided.
+type PendingRun struct {
+	ID           string
+	Request      Request
+	Prompt       string
+	Observations []Observation
+	Step         int
+	Calls        []PendingCall
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	// PromptVersion is the template version of Prompt, kept for the resumed run's trace.
+	PromptVersion string
+	// Placeholders are the values redacted in Prompt, restored in the resumed run's answer.
+	Placeholders map[string]string
+	// CallCounts are the calls made so far per tool action, so per-run call limits
+	// carry over to the resumed run.
+	CallCounts map[string]int
+}
+
+// RunOwner is the user and project a run was started for. Only they may decide
+// its pending calls.
+type RunOwner struct {
+	UserID    string
+	ProjectID string
+}
+
+// OwnedBy reports whether the run was started for owner.
+func (r *PendingRun) OwnedBy(owner RunOwner) bool {
+	return r.Request.UserID == owner.UserID && r.Request.ProjectID == owner.ProjectID
+}
+
+// ApprovalDecision approves or rejects a pending call.
+type ApprovalDecision struct {
+	CallID   string
+	Approved bool
+	Reason   string
+}
+
+// RunStore persists paused runs.
+type RunStore interface {
+	SaveRun(ctx context.Context, run *PendingRun) error
+	GetRun(ctx context.Context, id string) (*PendingRun, error)
+	// DeleteRun claims a run for resumption. It returns ErrRunNotFound when the run
+	// is already gone, so of concurrent deciders only one resumes it.
+	DeleteRun(ctx context.Context, id string) error
+}
+
+// PendingCallID builds the ID of the index-th pending call in a run.
+func PendingCallID(runID string, index int) string {
+	return runID + ":" + strconv.Itoa(index)
+}
+
+// ParsePendingCallID splits a pending call ID into its run ID.
+func ParsePendingCallID(id string) (string, error) {
+	i := strings.LastIndex(id, ":")
+	if i <= 0 {
+		return "", fmt.Errorf("invalid pending action id: %s", id)
+	}
+	if _, err := strconv.Atoi(id[i+1:]); err != nil {
+		return "", fmt.Errorf("invalid pending action id: %s", id)
+	}
+	return id[:i], nil
+}
+
+// Pending returns the calls still awaiting a decision.
+func (r *PendingRun) Pending() []PendingCall {
+	out := make([]PendingCall, 0, len(r.Calls))
+	for _, call := range r.Calls {
+		if call.Status == ApprovalPending {
+			out = append(out, call)
+		}
+	}
+	return out
+}
+
+// ApplyDecisions records decisions on the run's pending calls. It fails on calls
+// that are unknown or already decided, possibly after recording earlier decisions.
+func (r *PendingRun) ApplyDecisions(decisions []ApprovalDecision) error {
+	for _, decision := range decisions {
+		found := false
+		for i := range r.Calls {
+			if r.Calls[i].ID != decision.CallID {
+				continue
+			}
+			found = true
+			if r.Calls[i].Status != ApprovalPending {
+				return fmt.Errorf("action %s already %s", decision.CallID, r.Calls[i].Status)
+			}
+			r.Calls[i].Status = ApprovalRejected
+			if decision.Approved {
+				r.Calls[i].Status = ApprovalApproved
+			}
+			r.Calls[i].Note = decision.Reason
+		}
+		if !found {
+			return fmt.Errorf("unknown action %s for run %s", decision.CallID, r.ID)
+		}
+	}
+	return nil
+}
+
+// ApprovalMessage tells the user which calls await their decision.
+func ApprovalMessage(calls []PendingCall) string {
+	labels := make([]string, 0, len(calls))
+	for _, call := range calls {
+		labels = append(labels, call.Call.Name+"."+call.Call.Action)
+	}
+	return fmt.Sprintf("I need your approval before running: %s. Approve or reject the proposed action(s) to continue.", strings.Join(labels, ", "))
+}
+
+// MemoryRunStore keeps paused runs in process memory.
+type MemoryRunStore struct {
+	mu   sync.Mutex
+	runs map[string]*PendingRun
+}
+
+// NewMemoryRunStore creates an in-memory run store.
+func NewMemoryRunStore() *MemoryRunStore {
+	return &MemoryRunStore{runs: make(map[string]*PendingRun)}
+}
+
+// SaveRun implements RunStore.
+func (s *MemoryRunStore) SaveRun(ctx context.Context, run *PendingRun) error {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *run
+	copied.Calls = append([]PendingCall(nil), run.Calls...)
+	s.runs[run.ID] = &copied
+	return nil
+}
+
+// GetRun implements RunStore.
+func (s *MemoryRunStore) GetRun(ctx context.Context, id string) (*PendingRun, error) {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, ErrRunNotFound
+	}
+	copied := *run
+	copied.Calls = append([]PendingCall(nil), run.Calls...)
+	return &copied, nil
+}
+
+// DeleteRun implements RunStore.
+func (s *MemoryRunStore) DeleteRun(ctx context.Context, id string) error {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.runs[id]; !ok {
+		return ErrRunNotFound
+	}
+	delete(s.runs, id)
+	return nil
+}
+
+var _ RunStore = (*MemoryRunStore)(nil)
@@ -0,0 +1,96 @@
+// Package cassette records LLM and tool exchanges of agent runs to a JSONL file
+// and replays them deterministically, turning real conversations into offline tests.
+package cassette
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// ErrUnmatched is returned when a replayed request has no recorded exchange left.
+var ErrUnmatched = errors.New("cassette: unmatched request")
+
+// Kind identifies the interface an entry was recorded from.
+type Kind string
+
+const (
+	KindLLM  Kind = "llm"
+	KindTool Kind = "tool"
+)
+
+// Entry is one recorded exchange, stored as a single JSONL line.
+type Entry struct {
+	Seq        int                      `json:"seq"`
+	Kind       Kind                     `json:"kind"`
+	Key        string                   `json:"key"`
+	Request    *agentengine.LLMRequest  `json:"request,omitempty"`
+	Response   *agentengine.LLMResponse `json:"response,omitempty"`
+	Deltas     []string                 `json:"deltas,omitempty"` // Streamed responses only
+	Call       *agentengine.ToolCall    `json:"call,omitempty"`
+	Result     *agentengine.ToolResult  `json:"result,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+	RecordedAt time.Time                `json:"recordedAt"`
+}
+
+// datePattern matches the YYYY-MM-DD dates system prompts carry for today.
+var datePattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+
+// LLMKey identifies an LLM request by its full content. Dates in the prompt
+// are ignored, so a cassette recorded on one day replays on the next.
+func LLMKey(req agentengine.LLMRequest) string {
+	req.Prompt = datePattern.ReplaceAllString(req.Prompt, "YYYY-MM-DD")
+	return hashKey(KindLLM, req)
+}
+
+// ToolKey identifies a tool call by tool, action and arguments. Idempotency
+// keys are random per run, so they are ignored.
+func ToolKey(call agentengine.ToolCall) string {
+	call.IdempotencyKey = ""
+	return hashKey(KindTool, call)
+}
+
+// hashKey hashes the JSON encoding of v; map keys are sorted, so equal values hash equally.
+func hashKey(kind Kind, v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%#v", v))
+	}
+	sum := sha256.Sum256(append([]byte(kind+":"), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadFile loads the entries of a cassette file in recorded order.
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open cassette: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("cassette %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	return entries, nil
+}
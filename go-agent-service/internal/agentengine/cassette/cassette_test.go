@@ -0,0 +1,176 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+type toolPlanner struct{}
+
+func (toolPlanner) Plan(ctx context.Context, input agentengine.PlanInput) (agentengine.Plan, error) {
+	_ = ctx
+	if input.Step > 1 {
+		return agentengine.Plan{Type: agentengine.PlanDirect}, nil
+	}
+	return agentengine.Plan{Type: agentengine.PlanToolCalls, ToolCalls: []agentengine.ToolCall{
+		{Name: "jira", Action: "search", Args: map[string]any{"jql": "priority = Critical", "limit": 5}},
+		{Name: "jira", Action: "get", Args: map[string]any{"key": "MOBILE-1"}},
+	}}, nil
+}
+
+type liveLLM struct{ calls int }
+
+func (l *liveLLM) Respond(ctx context.Context, input agentengine.LLMRequest) (agentengine.LLMResponse, error) {
+	_ = ctx
+	l.calls++
+	return agentengine.LLMResponse{Text: "2 critical bugs", Model: "gpt-4o-mini", Usage: agentengine.Usage{TotalTokens: 42}}, nil
+}
+
+func (l *liveLLM) RespondStream(ctx context.Context, input agentengine.LLMRequest, onDelta func(delta string) error) (agentengine.LLMResponse, error) {
+	resp, err := l.Respond(ctx, input)
+	for _, delta := range []string{"2 critical ", "bugs"} {
+		if err := onDelta(delta); err != nil {
+			return agentengine.LLMResponse{}, err
+		}
+	}
+	return resp, err
+}
+
+type liveExecutor struct{}
+
+func (liveExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	_ = ctx
+	if call.Action == "get" {
+		return nil, errors.New("issue not found")
+	}
+	return &agentengine.ToolResult{Success: true, Data: map[string]any{"total": 2, "keys": []any{"MOBILE-1", "MOBILE-2"}}}, nil
+}
+
+type staticTools struct{}
+
+func (staticTools) ListTools(ctx context.Context, userID, projectID string) ([]agentengine.ToolDef, error) {
+	return []agentengine.ToolDef{{Name: "jira", Actions: []agentengine.ToolAction{{Name: "search"}, {Name: "get"}}}}, nil
+}
+
+type plainAssembler struct{}
+
+func (plainAssembler) Build(ctx context.Context, req agentengine.Request, tools []agentengine.ToolDef) (string, error) {
+	return req.Query, nil
+}
+
+func (plainAssembler) AppendObservations(prompt string, observations []agentengine.Observation) (string, error) {
+	return prompt + "\n" + strings.Repeat("obs;", len(observations)), nil
+}
+
+func newEngine(t *testing.T, llm agentengine.LLMClient, executor agentengine.ToolExecutor) *agentengine.Engine {
+	t.Helper()
+	engine, err := agentengine.NewEngine(agentengine.Config{
+		Planner:          toolPlanner{},
+		LLM:              llm,
+		Tools:            staticTools{},
+		Executor:         executor,
+		Context:          plainAssembler{},
+		MaxParallelTools: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func streamText(t *testing.T, engine *agentengine.Engine, query string) (*agentengine.Response, string, error) {
+	t.Helper()
+	var deltas []string
+	resp, err := engine.RunStream(context.Background(), agentengine.Request{Query: query, UserID: "u1"}, func(ev agentengine.Event) error {
+		if ev.Type == agentengine.EventToken {
+			deltas = append(deltas, ev.Delta)
+		}
+		return nil
+	})
+	return resp, strings.Join(deltas, "|"), err
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	live := &liveLLM{}
+	recorded, recordedDeltas, err := streamText(t, newEngine(t, recorder.LLM(live), recorder.Executor(liveExecutor{})), "critical bugs?")
+	if err != nil {
+		t.Fatalf("record run: %v", err)
+	}
+	if err := recorder.Close(); err != nil || recorder.Err() != nil {
+		t.Fatalf("close recorder: %v %v", err, recorder.Err())
+	}
+
+	entries, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 2 tool and 1 llm entries, got %d", len(entries))
+	}
+
+	player, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	replayed, replayedDeltas, err := streamText(t, newEngine(t, player.LLM(), player.Executor()), "critical bugs?")
+	if err != nil {
+		t.Fatalf("replay run: %v", err)
+	}
+	if err := player.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if live.calls != 1 {
+		t.Fatalf("replay must not call the live LLM, got %d calls", live.calls)
+	}
+	if replayed.Text != recorded.Text || replayedDeltas != recordedDeltas || replayed.Usage != recorded.Usage {
+		t.Fatalf("replay differs: %q %q %+v vs %q %q %+v", replayed.Text, replayedDeltas, replayed.Usage, recorded.Text, recordedDeltas, recorded.Usage)
+	}
+	if len(replayed.Observations) != 2 || replayed.Observations[1].Error != "issue not found" {
+		t.Fatalf("expected recorded tool error to replay, got %+v", replayed.Observations)
+	}
+}
+
+func TestReplayFailsOnUnmatchedRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	if _, _, err := streamText(t, newEngine(t, recorder.LLM(&liveLLM{}), recorder.Executor(liveExecutor{})), "critical bugs?"); err != nil {
+		t.Fatalf("record run: %v", err)
+	}
+	recorder.Close()
+
+	player, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	_, _, err = streamText(t, newEngine(t, player.LLM(), player.Executor()), "different question")
+	if !errors.Is(err, ErrUnmatched) {
+		t.Fatalf("expected unmatched LLM request to fail the run, got %v", err)
+	}
+	if err := player.Verify(); !errors.Is(err, ErrUnmatched) || !strings.Contains(err.Error(), "not replayed") {
+		t.Fatalf("expected Verify to report the miss and the unused entry, got %v", err)
+	}
+}
+
+func TestLLMKeyIgnoresPromptDates(t *testing.T) {
+	today := agentengine.LLMRequest{Query: "bugs?", Prompt: "Today is 2026-10-16."}
+	tomorrow := agentengine.LLMRequest{Query: "bugs?", Prompt: "Today is 2026-10-17."}
+	if LLMKey(today) != LLMKey(tomorrow) {
+		t.Fatalf("expected prompts differing only in the date to match")
+	}
+	if LLMKey(today) == LLMKey(agentengine.LLMRequest{Query: "bugs?", Prompt: "Today is Friday."}) {
+		t.Fatalf("expected other prompt changes to matter")
+	}
+}
@@ -0,0 +1,188 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// Player serves recorded exchanges back. Requests are matched on their full
+// content; identical requests are served in recorded order, so parallel tool
+// calls replay deterministically regardless of completion order.
+type Player struct {
+	mu     sync.Mutex
+	queues map[string][]int
+	used   []bool
+	misses []string
+
+	entries []Entry
+}
+
+// Load reads a cassette file for replay.
+func Load(path string) (*Player, error) {
+	entries, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlayer(entries), nil
+}
+
+// NewPlayer replays the given entries.
+func NewPlayer(entries []Entry) *Player {
+	p := &Player{
+		queues:  make(map[string][]int),
+		used:    make([]bool, len(entries)),
+		entries: entries,
+	}
+	for i, entry := range entries {
+		p.queues[entry.Key] = append(p.queues[entry.Key], i)
+	}
+	return p
+}
+
+// LLM returns a streaming LLM client that replays recorded responses.
+func (p *Player) LLM() agentengine.StreamingLLMClient {
+	return &replayLLM{p: p}
+}
+
+// Executor returns a tool executor that replays recorded results.
+func (p *Player) Executor() agentengine.ToolExecutor {
+	return &replayExecutor{p: p}
+}
+
+// Verify reports unmatched requests and recorded exchanges that were never
+// replayed. The engine turns tool errors into observations, so tests must call
+// Verify to catch unmatched tool calls.
+func (p *Player) Verify() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for _, miss := range p.misses {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrUnmatched, miss))
+	}
+	for i, used := range p.used {
+		if !used {
+			errs = append(errs, fmt.Errorf("cassette: entry %d (%s) not replayed: %s", p.entries[i].Seq, p.entries[i].Kind, describe(p.entries[i])))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// next pops the first unused entry recorded for key, recording a miss when none is left.
+func (p *Player) next(key, desc string) (Entry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	queue := p.queues[key]
+	if len(queue) == 0 {
+		p.misses = append(p.misses, desc)
+		return Entry{}, fmt.Errorf("%w: %s", ErrUnmatched, desc)
+	}
+	p.queues[key] = queue[1:]
+	p.used[queue[0]] = true
+	return p.entries[queue[0]], nil
+}
+
+type replayLLM struct {
+	p *Player
+}
+
+func (l *replayLLM) Respond(ctx context.Context, input agentengine.LLMRequest) (agentengine.LLMResponse, error) {
+	_ = ctx
+	entry, err := l.replay(input)
+	if err != nil {
+		return agentengine.LLMResponse{}, err
+	}
+	return *entry.Response, nil
+}
+
+// RespondStream replays recorded deltas, or the whole response as one delta
+// when it was recorded without streaming.
+func (l *replayLLM) RespondStream(ctx context.Context, input agentengine.LLMRequest, onDelta func(delta string) error) (agentengine.LLMResponse, error) {
+	_ = ctx
+	entry, err := l.replay(input)
+	if err != nil {
+		return agentengine.LLMResponse{}, err
+	}
+	deltas := entry.Deltas
+	if len(deltas) == 0 {
+		deltas = []string{entry.Response.Text}
+	}
+	for _, delta := range deltas {
+		if err := onDelta(delta); err != nil {
+			return agentengine.LLMResponse{}, err
+		}
+	}
+	return *entry.Response, nil
+}
+
+// replay returns the next recorded response for input, or the recorded error.
+func (l *replayLLM) replay(input agentengine.LLMRequest) (Entry, error) {
+	entry, err := l.p.next(LLMKey(input), describeLLM(input))
+	if err != nil {
+		return Entry{}, err
+	}
+	if entry.Error != "" {
+		return Entry{}, errors.New(entry.Error)
+	}
+	if entry.Response == nil {
+		return Entry{}, fmt.Errorf("cassette: entry %d has no response", entry.Seq)
+	}
+	return entry, nil
+}
+
+type replayExecutor struct {
+	p *Player
+}
+
+func (e *replayExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	_ = ctx
+	entry, err := e.p.next(ToolKey(call), describeTool(call))
+	if err != nil {
+		return nil, err
+	}
+	if entry.Error != "" {
+		return entry.Result, errors.New(entry.Error)
+	}
+	return entry.Result, nil
+}
+
+func describe(entry Entry) string {
+	switch {
+	case entry.Request != nil:
+		return describeLLM(*entry.Request)
+	case entry.Call != nil:
+		return describeTool(*entry.Call)
+	}
+	return entry.Key
+}
+
+func describeLLM(req agentengine.LLMRequest) string {
+	return fmt.Sprintf("llm request %q (provider %q, model %q, %d prompt chars, %d observations)",
+		truncate(req.Query, 60), req.Provider, req.Model, len(req.Prompt), len(req.Observations))
+}
+
+func describeTool(call agentengine.ToolCall) string {
+	keys := make([]string, 0, len(call.Args))
+	for k := range call.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("tool call %s.%s (args %s)", call.Name, call.Action, strings.Join(keys, ","))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+var (
+	_ agentengine.StreamingLLMClient = (*replayLLM)(nil)
+	_ agentengine.ToolExecutor       = (*replayExecutor)(nil)
+)
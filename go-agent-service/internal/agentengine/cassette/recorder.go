@@ -0,0 +1,125 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// Recorder appends every exchange of the wrapped clients to a cassette file.
+// It is safe for concurrent use by parallel tool calls.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+	seq  int
+	err  error
+}
+
+// NewRecorder opens path for appending, creating it if needed.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open cassette: %w", err)
+	}
+	return &Recorder{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// LLM wraps an LLM client. Streaming clients stay streaming and have their deltas recorded.
+func (r *Recorder) LLM(inner agentengine.LLMClient) agentengine.LLMClient {
+	if streamer, ok := inner.(agentengine.StreamingLLMClient); ok {
+		return &recordingStreamingLLM{recordingLLM{r: r, inner: inner}, streamer}
+	}
+	return &recordingLLM{r: r, inner: inner}
+}
+
+// Executor wraps a tool executor.
+func (r *Recorder) Executor(inner agentengine.ToolExecutor) agentengine.ToolExecutor {
+	return &recordingExecutor{r: r, inner: inner}
+}
+
+// Err returns the first write error, if any. Write errors never fail the wrapped calls.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close closes the cassette file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+func (r *Recorder) write(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	entry.Seq = r.seq
+	entry.RecordedAt = time.Now()
+	if err := r.enc.Encode(entry); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+type recordingLLM struct {
+	r     *Recorder
+	inner agentengine.LLMClient
+}
+
+func (l *recordingLLM) Respond(ctx context.Context, input agentengine.LLMRequest) (agentengine.LLMResponse, error) {
+	resp, err := l.inner.Respond(ctx, input)
+	l.r.write(llmEntry(input, resp, nil, err))
+	return resp, err
+}
+
+type recordingStreamingLLM struct {
+	recordingLLM
+	streamer agentengine.StreamingLLMClient
+}
+
+func (l *recordingStreamingLLM) RespondStream(ctx context.Context, input agentengine.LLMRequest, onDelta func(delta string) error) (agentengine.LLMResponse, error) {
+	var deltas []string
+	resp, err := l.streamer.RespondStream(ctx, input, func(delta string) error {
+		deltas = append(deltas, delta)
+		return onDelta(delta)
+	})
+	l.r.write(llmEntry(input, resp, deltas, err))
+	return resp, err
+}
+
+func llmEntry(input agentengine.LLMRequest, resp agentengine.LLMResponse, deltas []string, err error) Entry {
+	entry := Entry{Kind: KindLLM, Key: LLMKey(input), Request: &input, Deltas: deltas}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Response = &resp
+	}
+	return entry
+}
+
+type recordingExecutor struct {
+	r     *Recorder
+	inner agentengine.ToolExecutor
+}
+
+func (e *recordingExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	result, err := e.inner.Execute(ctx, call)
+	entry := Entry{Kind: KindTool, Key: ToolKey(call), Call: &call, Result: result}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	e.r.write(entry)
+	return result, err
+}
+
+var (
+	_ agentengine.StreamingLLMClient = (*recordingStreamingLLM)(nil)
+	_ agentengine.ToolExecutor       = (*recordingExecutor)(nil)
+)
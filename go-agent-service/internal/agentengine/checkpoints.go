@@ -0,0 +1,126 @@
+package agentengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrCheckpointNotFound is returned for checkpoint versions a store does not hold.
+var ErrCheckpointNotFound = errors.New("run checkpoint not found")
+
+// CheckpointState is the part of a RunCheckpoint that grows with every step.
+type CheckpointState struct {
+	Prompt       string
+	Tools        []ToolDef
+	Observations []Observation
+	Shaped       map[string]Observation
+	Events       []TraceEvent
+}
+
+// CheckpointStore keeps the growing state of durable runs outside the runner,
+// so checkpoints passed between workflow activities stay small. Every step
+// saves a new version; a retried step reads the version it started from again.
+type CheckpointStore interface {
+	// SaveCheckpoint stores a version of a run's state and drops the versions
+	// before the one it replaces.
+	SaveCheckpoint(ctx context.Context, runID string, version int, state CheckpointState) error
+	// LoadCheckpoint returns ErrCheckpointNotFound for unknown versions.
+	LoadCheckpoint(ctx context.Context, runID string, version int) (CheckpointState, error)
+	// DeleteCheckpoints drops every version of a finished run.
+	DeleteCheckpoints(ctx context.Context, runID string) error
+}
+
+// MemoryCheckpointStore is an in-memory CheckpointStore. It only serves runs
+// whose activities execute in this process.
+type MemoryCheckpointStore struct {
+	mu   sync.Mutex
+	runs map[string]map[int]CheckpointState
+}
+
+// NewMemoryCheckpointStore creates an in-memory checkpoint store.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{runs: make(map[string]map[int]CheckpointState)}
+}
+
+func (s *MemoryCheckpointStore) SaveCheckpoint(ctx context.Context, runID string, version int, state CheckpointState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.runs[runID]
+	if versions == nil {
+		versions = make(map[int]CheckpointState)
+		s.runs[runID] = versions
+	}
+	versions[version] = state
+	for v := range versions {
+		if v < version-1 {
+			delete(versions, v)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryCheckpointStore) LoadCheckpoint(ctx context.Context, runID string, version int) (CheckpointState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.runs[runID][version]
+	if !ok {
+		return CheckpointState{}, ErrCheckpointNotFound
+	}
+	return state, nil
+}
+
+func (s *MemoryCheckpointStore) DeleteCheckpoints(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runs, runID)
+	return nil
+}
+
+// loadCheckpoint fills in the state of a checkpoint kept in the checkpoint store.
+func (e *Engine) loadCheckpoint(ctx context.Context, cp *RunCheckpoint) error {
+	if e.checkpoints == nil || cp.Version == 0 {
+		return nil
+	}
+	state, err := e.checkpoints.LoadCheckpoint(ctx, cp.RunID, cp.Version)
+	if err != nil {
+		return fmt.Errorf("load checkpoint %s version %d: %w", cp.RunID, cp.Version, err)
+	}
+	cp.Prompt = state.Prompt
+	cp.Tools = state.Tools
+	cp.Observations = state.Observations
+	cp.Shaped = state.Shaped
+	cp.Events = state.Events
+	return nil
+}
+
+// storeCheckpoint saves the state of a checkpoint as its next version and
+// leaves the checkpoint referring to it. Without a store the state stays inline.
+func (e *Engine) storeCheckpoint(ctx context.Context, cp *RunCheckpoint) error {
+	if e.checkpoints == nil {
+		return nil
+	}
+	version := cp.Version + 1
+	err := e.checkpoints.SaveCheckpoint(ctx, cp.RunID, version, CheckpointState{
+		Prompt:       cp.Prompt,
+		Tools:        cp.Tools,
+		Observations: cp.Observations,
+		Shaped:       cp.Shaped,
+		Events:       cp.Events,
+	})
+	if err != nil {
+		return fmt.Errorf("save checkpoint %s version %d: %w", cp.RunID, version, err)
+	}
+	cp.Version = version
+	cp.Prompt, cp.Tools, cp.Observations, cp.Shaped, cp.Events = "", nil, nil, nil, nil
+	return nil
+}
+
+// dropCheckpoints deletes the stored state of a finished run.
+func (e *Engine) dropCheckpoints(ctx context.Context, cp *RunCheckpoint) {
+	if e.checkpoints == nil || cp.Version == 0 {
+		return
+	}
+	_ = e.checkpoints.DeleteCheckpoints(ctx, cp.RunID)
+}
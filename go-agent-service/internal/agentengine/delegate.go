@@ -0,0 +1,174 @@
+package agentengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/telemetry"
+)
+
+// DelegateAction is the single action of a delegate tool.
+const DelegateAction = "run"
+
+const delegateSchema = `{
+	"type": "object",
+	"properties": {
+		"task": {"type": "string", "description": "Self-contained task for the sub-agent, including any context it needs"}
+	},
+	"required": ["task"]
+}`
+
+// Delegate exposes another engine to the planner as a tool. The sub-agent runs
+// the task it is given under its own instructions, tool subset, step limit and
+// model, with a child trace, and its final answer becomes the observation.
+// Sub-agents cannot pause for approval: calls that need it are rejected.
+type Delegate struct {
+	Name         string // Tool name shown to the planner
+	Description  string
+	Engine       *Engine
+	Instructions string   // Prepended to the sub-agent's prompt
+	Tools        []string // Tools the sub-agent may use; empty allows all of its engine's tools
+	MaxSteps     int      // Defaults to the sub-agent engine's limit
+	Provider     string   // Provider and model default to the delegating request's
+	Model        string
+	Timeout      time.Duration // Bounds the whole sub-agent run; optional
+}
+
+// delegation links a sub-agent run to the run that delegated to it.
+type delegation struct {
+	depth         int
+	parentTraceID string
+}
+
+type delegationKey struct{}
+
+func delegationFrom(ctx context.Context) delegation {
+	link, _ := ctx.Value(delegationKey{}).(delegation)
+	return link
+}
+
+func validateDelegates(delegates []Delegate) error {
+	seen := make(map[string]bool, len(delegates))
+	for _, d := range delegates {
+		switch {
+		case d.Name == "":
+			return errors.New("delegate name is required")
+		case d.Engine == nil:
+			return fmt.Errorf("delegate %s has no engine", d.Name)
+		case seen[d.Name]:
+			return fmt.Errorf("duplicate delegate %s", d.Name)
+		}
+		seen[d.Name] = true
+	}
+	return nil
+}
+
+// delegateTools describes the delegates as tools, or none once depth reaches the limit.
+func (e *Engine) delegateTools(depth int) []ToolDef {
+	if depth >= e.maxDepth {
+		return nil
+	}
+	tools := make([]ToolDef, 0, len(e.delegates))
+	for _, d := range e.delegates {
+		tools = append(tools, ToolDef{
+			Name:        d.Name,
+			Description: d.Description,
+			Actions: []ToolAction{{
+				Name:        DelegateAction,
+				Description: "Delegate a task to the " + d.Name + " agent and return its answer",
+				InputSchema: delegateSchema,
+				// The sub-agent's own tool calls are authorized individually.
+				Access: AccessRead,
+			}},
+		})
+	}
+	return tools
+}
+
+func (e *Engine) findDelegate(name string) (Delegate, bool) {
+	for _, d := range e.delegates {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Delegate{}, false
+}
+
+// runDelegate runs a sub-agent for a delegate call and returns its answer as an observation.
+func (e *Engine) runDelegate(ctx context.Context, state *runState, step int, d Delegate, call ToolCall) Observation {
+	link := delegation{depth: state.depth + 1, parentTraceID: state.trace.ID}
+	ctx = context.WithValue(ctx, delegationKey{}, link)
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+	ctx, span := tracer.Start(ctx, "agent.delegate", telemetry.Attrs("agent.delegate", d.Name))
+
+	task, _ := call.Args["task"].(string)
+	req := Request{
+		Query:     strings.TrimSpace(task),
+		SessionID: state.req.SessionID,
+		UserID:    state.req.UserID,
+		ProjectID: state.req.ProjectID,
+		Provider:  state.req.Provider,
+		Model:     state.req.Model,
+	}
+	if d.Provider != "" || d.Model != "" {
+		req.Provider, req.Model = d.Provider, d.Model
+	}
+
+	childID := newRunID()
+	started := e.clock()
+	resp, err := d.Engine.run(ctx, req, nil, runOptions{
+		traceID:      childID,
+		instructions: d.Instructions,
+		tools:        d.Tools,
+		maxSteps:     d.MaxSteps,
+	})
+	telemetry.End(span, err)
+
+	attrs := map[string]any{"agent": d.Name, "depth": link.depth, "childTraceId": childID}
+	ev := TraceEvent{Name: "delegate", Step: step, Duration: e.clock().Sub(started), Attrs: attrs}
+	if err != nil {
+		ev.Detail = err.Error()
+		state.trace.Record(ev)
+		return Observation{ToolName: d.Name, Error: fmt.Sprintf("%s agent failed: %s", d.Name, err.Error())}
+	}
+	addUsageAttrs(attrs, resp.Usage)
+	state.addDelegatedUsage(resp.Usage)
+	state.trace.Record(ev)
+	// The sub-agent's citations stay citable by the parent's answer
+	sources := make([]Source, 0, len(resp.Citations))
+	for _, citation := range resp.Citations {
+		if citation.Kind != "observation" {
+			sources = append(sources, Source{ID: citation.ID, Kind: citation.Kind, Title: citation.Title, URL: citation.URL})
+		}
+	}
+	return Observation{ToolName: d.Name, Sources: sources, Result: &ToolResult{
+		Success: true,
+		Message: resp.Text,
+		Data:    map[string]any{"answer": resp.Text, "traceId": childID},
+	}}
+}
+
+// filterTools keeps the named tools; an empty list keeps all.
+func filterTools(tools []ToolDef, names []string) []ToolDef {
+	if len(names) == 0 {
+		return tools
+	}
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	out := make([]ToolDef, 0, len(names))
+	for _, tool := range tools {
+		if allowed[tool.Name] {
+			out = append(out, tool)
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package agentengine
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// watchingPlanner follows scripted plans and records the prompt and tools of each step.
+type watchingPlanner struct {
+	scriptedPlanner
+
+	mu      sync.Mutex
+	prompts []string
+	tools   [][]string
+}
+
+func (p *watchingPlanner) Plan(ctx context.Context, input PlanInput) (Plan, error) {
+	names := make([]string, 0, len(input.Tools))
+	for _, tool := range input.Tools {
+		names = append(names, tool.Name)
+	}
+	p.mu.Lock()
+	p.prompts = append(p.prompts, input.Prompt)
+	p.tools = append(p.tools, names)
+	p.mu.Unlock()
+	return p.scriptedPlanner.Plan(ctx, input)
+}
+
+func delegateTestEngine(t *testing.T, planner Planner, text string, cfg Config) *Engine {
+	t.Helper()
+	cfg.Planner = planner
+	cfg.LLM = &staticLLM{text: text, usage: Usage{TotalTokens: 10}}
+	cfg.Tools = &staticTools{tools: []ToolDef{
+		{Name: "jira", Actions: []ToolAction{{Name: "search"}, {Name: "create"}}},
+		{Name: "ucl", Actions: []ToolAction{{Name: "query"}}},
+	}}
+	cfg.Executor = echoExecutor{}
+	cfg.Context = plainAssembler{}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestDelegateRunsSubAgentUnderChildTrace(t *testing.T) {
+	traces := NewMemoryTraceStore(0)
+	usage := NewMemoryUsageStore()
+	childPlanner := &watchingPlanner{scriptedPlanner: scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "jira", Action: "search"}, {Name: "jira", Action: "create"}}},
+	}}}
+	child := delegateTestEngine(t, childPlanner, "2 critical bugs", Config{
+		Traces:        traces,
+		Usage:         usage,
+		Runs:          NewMemoryRunStore(),
+		ApproveWrites: true,
+		Delegates:     []Delegate{{Name: "nested", Engine: newTestEngine(t, &scriptedPlanner{})}},
+	})
+
+	parentPlanner := &scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "triage", Action: DelegateAction, Args: map[string]any{"task": "find critical bugs"}}}},
+	}}
+	parent := delegateTestEngine(t, parentPlanner, "done", Config{
+		Traces: traces,
+		Usage:  usage,
+		Delegates: []Delegate{{
+			Name:         "triage",
+			Engine:       child,
+			Instructions: "You triage Jira tickets.",
+			Tools:        []string{"jira"},
+			MaxSteps:     2,
+		}},
+	})
+
+	resp, err := parent.Run(context.Background(), Request{Query: "what is broken?", SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	obs := resp.Observations[0]
+	if obs.ToolName != "triage" || obs.Result == nil || obs.Result.Message != "2 critical bugs" {
+		t.Fatalf("expected sub-agent answer as observation, got %+v", obs)
+	}
+
+	// The sub-agent sees its instructions, its tool subset and no nested delegates at depth 1
+	if !strings.HasPrefix(childPlanner.prompts[0], "You triage Jira tickets.\n\nfind critical bugs") {
+		t.Fatalf("unexpected sub-agent prompt: %q", childPlanner.prompts[0])
+	}
+	if got := strings.Join(childPlanner.tools[0], ","); got != "jira" {
+		t.Fatalf("expected only jira for the sub-agent, got %s", got)
+	}
+	// Writes that would need approval are rejected instead of pausing the sub-agent
+	if !strings.Contains(childPlanner.prompts[1], "jira,jira") {
+		t.Fatalf("expected both sub-agent calls observed, got %q", childPlanner.prompts[1])
+	}
+
+	childID, _ := obs.Result.Data["traceId"].(string)
+	childTrace, err := traces.GetTrace(context.Background(), childID)
+	if err != nil {
+		t.Fatalf("GetTrace child: %v", err)
+	}
+	if childTrace.ParentID != resp.Trace.ID || childTrace.Status != string(RunCompleted) {
+		t.Fatalf("expected child trace linked to %s, got %+v", resp.Trace.ID, childTrace)
+	}
+	var created *TraceEvent
+	for i, ev := range childTrace.Events {
+		if ev.Name == "tool.call" && ev.Attrs["action"] == "create" {
+			created = &childTrace.Events[i]
+		}
+	}
+	if created == nil || created.Attrs["outcome"] != "rejected" {
+		t.Fatalf("expected rejected write in child trace, got %+v", childTrace.Events)
+	}
+
+	// Sub-agent usage counts toward the parent run but is recorded once
+	if resp.Usage.TotalTokens != 20 {
+		t.Fatalf("expected parent usage to include the sub-agent, got %+v", resp.Usage)
+	}
+	total, _ := usage.SumUsage(context.Background(), UsageFilter{SessionID: "s1"})
+	if total.TotalTokens != 20 {
+		t.Fatalf("expected 20 recorded tokens, got %+v", total)
+	}
+}
+
+func TestDelegationDepthIsLimited(t *testing.T) {
+	childPlanner := &watchingPlanner{}
+	leaf := newTestEngine(t, &scriptedPlanner{})
+	for _, depth := range []int{1, 2} {
+		childPlanner.tools = nil
+		child := delegateTestEngine(t, childPlanner, "ok", Config{
+			MaxDelegationDepth: depth,
+			Delegates:          []Delegate{{Name: "leaf", Engine: leaf}},
+		})
+		parent := delegateTestEngine(t, &scriptedPlanner{plans: []Plan{
+			{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "child", Action: DelegateAction, Args: map[string]any{"task": "go"}}}},
+		}}, "done", Config{Delegates: []Delegate{{Name: "child", Engine: child}}})
+
+		if _, err := parent.Run(context.Background(), Request{Query: "q"}); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		sawLeaf := strings.Contains(strings.Join(childPlanner.tools[0], ","), "leaf")
+		if sawLeaf != (depth > 1) {
+			t.Fatalf("max depth %d: sub-agent saw delegates %v", depth, childPlanner.tools[0])
+		}
+	}
+
+	if _, err := NewEngine(Config{
+		Planner: &scriptedPlanner{}, LLM: &staticLLM{}, Tools: &staticTools{}, Executor: echoExecutor{}, Context: plainAssembler{},
+		Delegates: []Delegate{{Name: "a", Engine: leaf}, {Name: "a", Engine: leaf}},
+	}); err == nil {
+		t.Fatalf("expected duplicate delegates to be rejected")
+	}
+}
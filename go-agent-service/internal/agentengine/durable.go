@@ -0,0 +1,310 @@
+// Package agentengine exposes the steps of a run for durable runners.
+package agentengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RunCheckpoint is the serializable state of a run between steps. Durable
+// runners, such as the Temporal agent workflow, advance a run with BeginRun,
+// PlanStep, AuthorizeCalls, ExecuteCall, ObserveCalls, AnswerStep and
+// CompleteRun or FailRun, each of which may run in a different process. With a
+// CheckpointStore, the prompt, tools, observations and trace events are kept in
+// the store and the checkpoint names their version.
+type RunCheckpoint struct {
+	RunID        string
+	TraceID      string
+	Request      Request
+	Prompt       string
+	Tools        []ToolDef
+	Observations []Observation // Raw observations in run order
+	Observed     int           // Number of observations, also when they are stored
+	Step         int
+	Paused       int // Calls that waited for approval so far, numbering pending call IDs
+	Usage        Usage
+	Delegated    Usage // Usage of sub-agent runs
+	CallCounts   map[string]int
+	Shaped       map[string]Observation // Prompt copies of shortened observations by ID
+	Structured   any
+	Events       []TraceEvent
+	Started      time.Time
+
+	// PromptVersion is the template version of Prompt, kept on the run's trace.
+	PromptVersion string
+	// Placeholders are the values redacted so far, restored in tool args and the answer.
+	Placeholders map[string]string
+	// Version names the state saved in the CheckpointStore; zero when it is inline.
+	Version int
+}
+
+// CallReview is the verdict on a call a durable run executes.
+type CallReview struct {
+	Call     ToolCall
+	Access   AccessKind
+	Rejected *Observation   // Set when the call must not run
+	Status   ApprovalStatus // Set for calls that waited for a decision
+}
+
+// CallOutcome is the result of ExecuteCall: the observation and the trace
+// events and sub-agent usage it produced.
+type CallOutcome struct {
+	Observation Observation
+	Events      []TraceEvent
+	Delegated   Usage
+}
+
+// Review turns a decided call into the review ExecuteCall runs: approved calls
+// execute, rejected calls become an observation of the rejection.
+func (c PendingCall) Review() CallReview {
+	review := CallReview{Call: c.Call, Access: c.Access, Status: c.Status}
+	if c.Status != ApprovalApproved {
+		review.Rejected = &Observation{ToolName: c.Call.Name, Error: rejectionMessage(c)}
+	}
+	return review
+}
+
+// BeginRun discovers tools and builds the prompt of a durable run. runID names
+// the run on its trace and in pending call IDs.
+func (e *Engine) BeginRun(ctx context.Context, runID string, req Request) (*RunCheckpoint, error) {
+	if req.Query == "" {
+		return nil, errors.New("query is required")
+	}
+	trace := newTrace(req)
+	trace.RunID = runID
+	redactor := e.redactor(ctx, req, nil)
+	_, tools, prompt, err := e.prepare(ctx, req, trace, runOptions{}, redactor)
+	if err != nil {
+		e.finishTrace(ctx, trace, nil, err)
+		return nil, err
+	}
+	cp := &RunCheckpoint{
+		RunID:   runID,
+		TraceID: trace.ID,
+		Request: req,
+		Prompt:  prompt,
+		Started: trace.Started,
+
+		PromptVersion: trace.PromptVersion,
+	}
+	state := newRunState(req, tools, trace, false)
+	state.redactor = redactor
+	cp.save(state)
+	if err := e.storeCheckpoint(ctx, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// PlanStep advances the run to its next step and asks the planner what to do.
+func (e *Engine) PlanStep(ctx context.Context, cp *RunCheckpoint) (Plan, error) {
+	if cp.Step >= e.maxSteps {
+		return Plan{}, fmt.Errorf("%w (%d)", ErrMaxStepsExceeded, e.maxSteps)
+	}
+	if err := e.loadCheckpoint(ctx, cp); err != nil {
+		return Plan{}, err
+	}
+	state := e.restore(ctx, cp)
+	prior, err := e.loadPriorUsage(ctx, cp.Request)
+	if err != nil {
+		return Plan{}, err
+	}
+	state.prior = prior
+
+	cp.Step++
+	plan, err := e.plan(ctx, state, cp.Prompt, cp.Observations, cp.Step)
+	if err == nil && plan.Type == PlanSteps {
+		// Durable runs execute one round of calls per step, so the rest of the plan is re-planned
+		plan = readySteps(plan)
+		state.trace.Record(TraceEvent{Name: "plan.reduced", Step: cp.Step, Attrs: map[string]any{"calls": len(plan.ToolCalls)}})
+	}
+	cp.save(state)
+	if err != nil {
+		return plan, err
+	}
+	return plan, e.storeCheckpoint(ctx, cp)
+}
+
+// AuthorizeCalls validates a step's calls and applies policy and the approval
+// gate. It returns the calls to execute now, including rejected ones, and the
+// calls awaiting a decision.
+func (e *Engine) AuthorizeCalls(ctx context.Context, cp *RunCheckpoint, calls []ToolCall) ([]CallReview, []PendingCall, error) {
+	if err := e.loadCheckpoint(ctx, cp); err != nil {
+		return nil, nil, err
+	}
+	state := e.restore(ctx, cp)
+	var reviews []CallReview
+	var pending []PendingCall
+	for _, call := range calls {
+		auth := e.authorize(ctx, state, call, nil)
+		if auth.approval {
+			pending = append(pending, PendingCall{
+				ID:     PendingCallID(cp.RunID, cp.Paused),
+				Call:   auth.call,
+				Access: auth.access,
+				Reason: auth.reason,
+				Status: ApprovalPending,
+			})
+			cp.Paused++
+			continue
+		}
+		reviews = append(reviews, CallReview{Call: auth.call, Access: auth.access, Rejected: auth.rejection})
+	}
+	recordPending(state.trace, cp.Step, pending)
+	cp.save(state)
+	if err := e.storeCheckpoint(ctx, cp); err != nil {
+		return nil, nil, err
+	}
+	return reviews, pending, nil
+}
+
+// ExecuteCall runs one reviewed call of the current step. It leaves the
+// checkpoint unchanged so the calls of a step can run concurrently; ObserveCalls
+// adds their outcomes.
+func (e *Engine) ExecuteCall(ctx context.Context, cp *RunCheckpoint, review CallReview) (CallOutcome, error) {
+	if err := e.loadCheckpoint(ctx, cp); err != nil {
+		return CallOutcome{}, err
+	}
+	state := e.restore(ctx, cp)
+	state.delegated = Usage{}
+	started := e.clock()
+	var obs Observation
+	outcome := "rejected"
+	if review.Rejected != nil {
+		obs = *review.Rejected
+	} else {
+		obs = e.runCall(ctx, state, cp.Step, review.Call)
+		outcome = "executed"
+	}
+	if review.Status != "" {
+		outcome = string(review.Status)
+	}
+	state.trace.Record(toolCallEvent(cp.Step, review.Call, review.Access, obs, e.clock().Sub(started), outcome))
+	return CallOutcome{
+		Observation: obs,
+		Events:      state.trace.Snapshot().Events[len(cp.Events):],
+		Delegated:   state.delegated,
+	}, nil
+}
+
+// ObserveCalls adds the outcomes of a step's calls, in call order, and appends
+// them to the prompt.
+func (e *Engine) ObserveCalls(ctx context.Context, cp *RunCheckpoint, outcomes []CallOutcome) error {
+	if err := e.loadCheckpoint(ctx, cp); err != nil {
+		return err
+	}
+	state := e.restore(ctx, cp)
+	for _, outcome := range outcomes {
+		obs := outcome.Observation
+		obs.ID = state.nextObservationID()
+		cp.Observations = append(cp.Observations, obs)
+		state.remember([]Observation{obs})
+		state.addDelegatedUsage(outcome.Delegated)
+		for _, ev := range outcome.Events {
+			state.trace.Record(ev)
+		}
+	}
+	prompt, err := e.appendObservations(state, cp.Prompt, e.promptObservations(ctx, state, cp.Step, cp.Observations))
+	if err != nil {
+		return err
+	}
+	cp.Prompt = prompt
+	state.trace.Record(TraceEvent{Name: "prompt.updated", Step: cp.Step, Attrs: map[string]any{"chars": len(prompt)}})
+	cp.save(state)
+	return e.storeCheckpoint(ctx, cp)
+}
+
+// AnswerStep generates the final answer of the run.
+func (e *Engine) AnswerStep(ctx context.Context, cp *RunCheckpoint) (LLMResponse, error) {
+	if err := e.loadCheckpoint(ctx, cp); err != nil {
+		return LLMResponse{}, err
+	}
+	state := e.restore(ctx, cp)
+	prior, err := e.loadPriorUsage(ctx, cp.Request)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	state.prior = prior
+
+	reply, err := e.finalAnswer(ctx, state, cp.Prompt, cp.Observations, cp.Step, func(Event) error { return nil })
+	cp.save(state)
+	if err != nil {
+		return reply, err
+	}
+	return reply, e.storeCheckpoint(ctx, cp)
+}
+
+// CompleteRun records the answer in memory, records usage, persists the trace
+// and drops the run's stored checkpoints.
+func (e *Engine) CompleteRun(ctx context.Context, cp *RunCheckpoint, reply LLMResponse) (*Response, error) {
+	if err := e.loadCheckpoint(ctx, cp); err != nil {
+		return nil, err
+	}
+	state := e.restore(ctx, cp)
+	resp := e.finalize(ctx, state, reply, cp.Observations)
+	resp.Status = RunCompleted
+	resp.RunID = cp.RunID
+	resp.Usage = state.runUsage()
+	e.recordUsage(ctx, state, resp)
+	e.finishTrace(ctx, state.trace, resp, nil)
+	e.dropCheckpoints(ctx, cp)
+	return resp, nil
+}
+
+// FailRun records the usage of a run that stopped with cause, persists its
+// trace and drops the run's stored checkpoints.
+func (e *Engine) FailRun(ctx context.Context, cp *RunCheckpoint, cause string) error {
+	if err := e.loadCheckpoint(ctx, cp); err != nil {
+		return err
+	}
+	state := e.restore(ctx, cp)
+	e.recordUsage(ctx, state, nil)
+	e.finishTrace(ctx, state.trace, nil, errors.New(cause))
+	e.dropCheckpoints(ctx, cp)
+	return nil
+}
+
+// restore rebuilds the run state of a checkpoint.
+func (e *Engine) restore(ctx context.Context, cp *RunCheckpoint) *runState {
+	trace := newTrace(cp.Request)
+	trace.ID = cp.TraceID
+	trace.RunID = cp.RunID
+	trace.PromptVersion = cp.PromptVersion
+	trace.Started = cp.Started
+	trace.Events = append(trace.Events, cp.Events...)
+
+	state := newRunState(cp.Request, cp.Tools, trace, false)
+	state.durable = true
+	state.redactor = e.redactor(ctx, cp.Request, cp.Placeholders)
+	trace.Query = state.redact(trace.Query)
+	state.usage = cp.Usage
+	state.delegated = cp.Delegated
+	state.structured = cp.Structured
+	state.observed = len(cp.Observations)
+	for key, count := range cp.CallCounts {
+		state.callCounts[key] = count
+	}
+	for id, obs := range cp.Shaped {
+		state.shaped[id] = obs
+	}
+	state.remember(cp.Observations)
+	return state
+}
+
+// save copies what a step changed in the run state back into the checkpoint.
+func (cp *RunCheckpoint) save(state *runState) {
+	events := state.trace.Snapshot().Events
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	cp.Tools = state.tools
+	cp.Observed = len(cp.Observations)
+	cp.Usage = state.usage
+	cp.Delegated = state.delegated
+	cp.CallCounts = state.callCounts
+	cp.Shaped = state.shaped
+	cp.Structured = state.structured
+	cp.Events = events
+	cp.Placeholders = state.placeholders()
+}
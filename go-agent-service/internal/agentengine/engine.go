@@ -3,13 +3,26 @@ package agentengine
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 )
 
+var tracer = telemetry.Tracer("github.com/antigravity/go-agent-service/internal/agentengine")
+
+// ErrMaxStepsExceeded is returned when a run reaches its step limit without an answer.
+var ErrMaxStepsExceeded = errors.New("max steps exceeded")
+
 // Engine orchestrates the ReAct loop.
 type Engine struct {
 	planner     Planner
@@ -22,6 +35,24 @@ type Engine struct {
 	clock       func() time.Time
 	toolTimeout time.Duration
 	maxSteps    int
+	maxParallel int
+
+	runs          RunStore
+	checkpoints   CheckpointStore
+	approveWrites bool
+	decideMu      sync.Mutex
+	traces        TraceStore
+	usage         UsageStore
+	budget        Budget
+	delegates     []Delegate
+	maxDepth      int
+
+	observationTokens     int
+	observationItems      int
+	summarizeObservations bool
+
+	redaction Redaction
+	injection InjectionDetector
 }
 
 // Config wires engine dependencies.
@@ -35,7 +66,37 @@ type Config struct {
 	Policy      Policy
 	ToolTimeout time.Duration
 	MaxSteps    int
-	Clock       func() time.Time
+	// MaxParallelTools bounds concurrent tool calls within a plan step (default 1, sequential).
+	MaxParallelTools int
+	// Runs persists runs paused for approval. Without it, approval outcomes become error observations.
+	Runs RunStore
+	// Checkpoints keeps the prompt, observations and trace events of durable runs
+	// between steps, so checkpoints carry a reference instead. Optional.
+	Checkpoints CheckpointStore
+	// ApproveWrites pauses on write actions that no policy rule explicitly allows (requires Runs).
+	ApproveWrites bool
+	// Traces persists the trace of every run. Optional.
+	Traces TraceStore
+	// Usage records the token usage of every run. Session and project budgets require it.
+	Usage UsageStore
+	// Budget caps token and cost usage per run, session and project.
+	Budget Budget
+	// Delegates expose other engines to the planner as tools. Optional.
+	Delegates []Delegate
+	// MaxDelegationDepth bounds nested delegation (default 1: sub-agents do not delegate further).
+	MaxDelegationDepth int
+	// ObservationTokens is the prompt budget per tool result (default 1000, negative disables shaping).
+	// Larger results are truncated, or summarized with SummarizeObservations, and stay readable by ID.
+	ObservationTokens int
+	// ObservationItems is the number of array items kept when truncating (default 20).
+	ObservationItems      int
+	SummarizeObservations bool
+	Clock                 func() time.Time
+	// Redaction masks sensitive values before prompts leave for the LLM. Optional.
+	Redaction Redaction
+	// Injection flags instruction-like content in tool output. Writes whose args
+	// come from flagged output need approval. Optional.
+	Injection InjectionDetector
 }
 
 // NewEngine creates an engine with the provided config.
@@ -64,6 +125,21 @@ func NewEngine(cfg Config) (*Engine, error) {
 	if cfg.ToolTimeout <= 0 {
 		cfg.ToolTimeout = 20 * time.Second
 	}
+	if cfg.MaxParallelTools <= 0 {
+		cfg.MaxParallelTools = 1
+	}
+	if cfg.MaxDelegationDepth <= 0 {
+		cfg.MaxDelegationDepth = 1
+	}
+	if cfg.ObservationTokens == 0 {
+		cfg.ObservationTokens = defaultObservationTokens
+	}
+	if cfg.ObservationItems <= 0 {
+		cfg.ObservationItems = defaultObservationItems
+	}
+	if err := validateDelegates(cfg.Delegates); err != nil {
+		return nil, err
+	}
 
 	return &Engine{
 		planner:     cfg.Planner,
@@ -76,121 +152,782 @@ func NewEngine(cfg Config) (*Engine, error) {
 		clock:       cfg.Clock,
 		toolTimeout: cfg.ToolTimeout,
 		maxSteps:    cfg.MaxSteps,
+		maxParallel: cfg.MaxParallelTools,
+
+		runs:          cfg.Runs,
+		checkpoints:   cfg.Checkpoints,
+		approveWrites: cfg.ApproveWrites,
+		traces:        cfg.Traces,
+		usage:         cfg.Usage,
+		budget:        cfg.Budget,
+		delegates:     cfg.Delegates,
+		maxDepth:      cfg.MaxDelegationDepth,
+
+		observationTokens:     cfg.ObservationTokens,
+		observationItems:      cfg.ObservationItems,
+		summarizeObservations: cfg.SummarizeObservations,
+
+		redaction: cfg.Redaction,
+		injection: cfg.Injection,
 	}, nil
 }
 
 // Run executes the ReAct loop and returns a response.
 func (e *Engine) Run(ctx context.Context, req Request) (*Response, error) {
+	return e.RunStream(ctx, req, nil)
+}
+
+// RunStream executes the ReAct loop and reports progress to handler.
+// A nil handler behaves like Run.
+func (e *Engine) RunStream(ctx context.Context, req Request, handler EventHandler) (*Response, error) {
+	return e.run(ctx, req, handler, runOptions{})
+}
+
+// runOptions adjust a run on behalf of a delegating parent.
+type runOptions struct {
+	traceID      string   // Preassigned so the parent can link a failed run
+	instructions string   // Prepended to the prompt
+	tools        []string // Tool subset; empty allows all
+	maxSteps     int      // Overrides the engine's step limit
+}
+
+func (e *Engine) run(ctx context.Context, req Request, handler EventHandler, opts runOptions) (*Response, error) {
 	if req.Query == "" {
 		return nil, errors.New("query is required")
 	}
 
-	trace := NewTrace(req.SessionID)
-	tools, err := e.tools.ListTools(ctx, req.UserID, req.ProjectID)
-	toolWarning := ""
-	if err != nil {
-		trace.AddEvent("tools.list.failed", err.Error())
-		toolWarning = fmt.Sprintf("Tool discovery failed; proceeding without tools: %s", err.Error())
+	link := delegationFrom(ctx)
+	trace := newTrace(req)
+	if opts.traceID != "" {
+		trace.ID = opts.traceID
 	}
+	trace.ParentID = link.parentTraceID
+	ctx, span := tracer.Start(ctx, "agent.run", telemetry.Attrs(
+		"agent.trace_id", trace.ID,
+		"agent.session_id", req.SessionID,
+		"agent.user_id", req.UserID,
+		"agent.project_id", req.ProjectID,
+	))
 
-	prompt, err := e.context.Build(ctx, req, tools)
+	redactor := e.redactor(ctx, req, nil)
+	prior, tools, prompt, err := e.prepare(ctx, req, trace, opts, redactor)
 	if err != nil {
+		e.finishTrace(ctx, trace, nil, err)
+		telemetry.End(span, err)
 		return nil, err
 	}
+
+	state := newRunState(req, tools, trace, handler != nil)
+	state.prior = prior
+	state.redactor = redactor
+	state.depth = link.depth
+	state.maxSteps = opts.maxSteps
+	resp, err := e.loop(ctx, state, prompt, nil, 0, e.emitter(handler))
+	e.recordUsage(ctx, state, resp)
+	e.finishTrace(ctx, trace, resp, err)
+	telemetry.End(span, err)
+	return resp, err
+}
+
+// prepare loads the usage counting toward budgets, discovers tools and builds
+// the initial prompt of a run. With a redactor, the prompt and traced query are redacted.
+func (e *Engine) prepare(ctx context.Context, req Request, trace *Trace, opts runOptions, redactor Redactor) (priorUsage, []ToolDef, string, error) {
+	if redactor != nil {
+		trace.Query = redactor.Redact(trace.Query)
+	}
+	prior, err := e.loadPriorUsage(ctx, req)
+	if err != nil {
+		return prior, nil, "", err
+	}
+
+	tools, toolErr := e.listTools(ctx, req, trace)
+	tools = filterTools(tools, opts.tools)
+	toolWarning := ""
+	if toolErr != nil {
+		toolWarning = fmt.Sprintf("Tool discovery failed; proceeding without tools: %s", toolErr.Error())
+	} else {
+		toolWarning = e.degradedNote(trace)
+	}
+
+	started := e.clock()
+	buildCtx, version := withPromptVersion(ctx)
+	prompt, err := e.context.Build(buildCtx, req, tools)
+	if err != nil {
+		return prior, nil, "", err
+	}
+	trace.PromptVersion = *version
 	if toolWarning != "" {
 		prompt = prompt + "\n\n## System Notes\n" + toolWarning
 	}
+	if opts.instructions != "" {
+		prompt = opts.instructions + "\n\n" + prompt
+	}
+	if redactor != nil {
+		prompt = redactor.Redact(prompt)
+	}
+	attrs := map[string]any{"chars": len(prompt), "tools": len(tools)}
+	if trace.PromptVersion != "" {
+		attrs["template"] = trace.PromptVersion
+	}
+	trace.Record(TraceEvent{
+		Name:     "prompt.built",
+		Duration: e.clock().Sub(started),
+		Attrs:    attrs,
+	})
+	return prior, tools, prompt, nil
+}
 
-	step := 0
-	var observations []Observation
-	for step < e.maxSteps {
-		step++
+// Decide records approval decisions for a paused run. Once every pending call is
+// decided, approved calls execute, rejected calls become observations and the run resumes.
+// Runs owned by someone else than owner are reported as ErrRunNotFound.
+func (e *Engine) Decide(ctx context.Context, owner RunOwner, runID string, decisions []ApprovalDecision) (*Response, error) {
+	return e.DecideStream(ctx, owner, runID, decisions, nil)
+}
 
-		plan, err := e.planner.Plan(ctx, PlanInput{
-			Request:      req,
-			Prompt:       prompt,
-			Tools:        tools,
-			Observations: observations,
-			Step:         step,
-		})
+// DecideStream is Decide with progress events for the resumed run.
+func (e *Engine) DecideStream(ctx context.Context, owner RunOwner, runID string, decisions []ApprovalDecision, handler EventHandler) (*Response, error) {
+	if e.runs == nil {
+		return nil, errors.New("approval gate not configured")
+	}
+
+	e.decideMu.Lock()
+	run, err := e.runs.GetRun(ctx, runID)
+	if err != nil {
+		e.decideMu.Unlock()
+		return nil, err
+	}
+	if !run.OwnedBy(owner) {
+		e.decideMu.Unlock()
+		return nil, ErrRunNotFound
+	}
+	if err := run.ApplyDecisions(decisions); err != nil {
+		e.decideMu.Unlock()
+		return nil, err
+	}
+	run.UpdatedAt = e.clock()
+
+	if pending := run.Pending(); len(pending) > 0 {
+		err := e.runs.SaveRun(ctx, run)
+		e.decideMu.Unlock()
 		if err != nil {
 			return nil, err
 		}
+		return &Response{
+			Text:         ApprovalMessage(pending),
+			Status:       RunAwaitingApproval,
+			RunID:        run.ID,
+			PendingCalls: pending,
+			Observations: run.Observations,
+		}, nil
+	}
+
+	// Check budgets before consuming the run so an exhausted budget leaves it pending.
+	prior, err := e.loadPriorUsage(ctx, run.Request)
+	if err != nil {
+		e.decideMu.Unlock()
+		return nil, err
+	}
+	// decideMu only serializes this process; deleting the run claims it across
+	// replicas, so approved calls run only for the decider whose delete succeeds.
+	err = e.runs.DeleteRun(ctx, run.ID)
+	e.decideMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	req := run.Request
+	trace := newTrace(req)
+	trace.RunID = run.ID
+	trace.PromptVersion = run.PromptVersion
+	ctx, span := tracer.Start(ctx, "agent.resume", telemetry.Attrs(
+		"agent.trace_id", trace.ID,
+		"agent.run_id", run.ID,
+		"agent.session_id", req.SessionID,
+	))
+
+	tools, _ := e.listTools(ctx, req, trace)
+	state := newRunState(req, tools, trace, handler != nil)
+	state.prior = prior
+	state.redactor = e.redactor(ctx, req, run.Placeholders)
+	maps.Copy(state.callCounts, run.CallCounts)
+	trace.Query = state.redact(trace.Query)
+	resp, err := e.resume(ctx, state, run, e.emitter(handler))
+	e.recordUsage(ctx, state, resp)
+	e.finishTrace(ctx, trace, resp, err)
+	telemetry.End(span, err)
+	return resp, err
+}
+
+// resume executes the decided calls of a paused run and continues its loop.
+func (e *Engine) resume(ctx context.Context, state *runState, run *PendingRun, emit func(Event) error) (*Response, error) {
+	observations := append([]Observation(nil), run.Observations...)
+	state.observed = len(observations)
+	state.remember(observations)
+	for i := range run.Calls {
+		pending := run.Calls[i]
+		call := pending.Call
+		if err := emit(Event{Type: EventToolCallStarted, Step: run.Step, ToolCall: &call}); err != nil {
+			return nil, err
+		}
+		started := e.clock()
+		var obs Observation
+		if pending.Status == ApprovalApproved {
+			obs = e.runCall(ctx, state, run.Step, call)
+		} else {
+			obs = Observation{ToolName: call.Name, Error: rejectionMessage(pending)}
+		}
+		obs.ID = state.nextObservationID()
+		observations = append(observations, obs)
+		state.remember([]Observation{obs})
+		duration := e.clock().Sub(started)
+		state.trace.Record(toolCallEvent(run.Step, call, pending.Access, obs, duration, string(pending.Status)))
+		if err := emit(Event{
+			Type:        EventToolCallFinished,
+			Step:        run.Step,
+			ToolCall:    &call,
+			Observation: &obs,
+			Duration:    duration,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	prompt, err := e.appendObservations(state, run.Prompt, e.promptObservations(ctx, state, run.Step, observations))
+	if err != nil {
+		return nil, err
+	}
+	state.trace.Record(TraceEvent{Name: "prompt.updated", Step: run.Step, Attrs: map[string]any{"chars": len(prompt)}})
+	return e.loop(ctx, state, prompt, observations, run.Step, emit)
+}
+
+// loop runs plan/act/observe steps after step until the run completes or pauses.
+func (e *Engine) loop(ctx context.Context, state *runState, prompt string, observations []Observation, step int, emit func(Event) error) (*Response, error) {
+	maxSteps := e.maxSteps
+	if state.maxSteps > 0 {
+		maxSteps = state.maxSteps
+	}
+	for step < maxSteps {
+		step++
+		plan, err := e.plan(ctx, state, prompt, observations, step)
+		if err != nil {
+			return nil, err
+		}
+		if err := emit(Event{Type: EventPlan, Step: step, Plan: &plan}); err != nil {
+			return nil, err
+		}
 
 		if plan.Type == PlanDirect {
-			reply, err := e.llm.Respond(ctx, LLMRequest{
-				Query:        req.Query,
-				Prompt:       prompt,
-				Observations: observations,
-				History:      req.History,
-				Provider:     req.Provider,
-				Model:        req.Model,
-			})
+			reply, err := e.finalAnswer(ctx, state, prompt, observations, step, emit)
 			if err != nil {
 				return nil, err
 			}
-			return e.finalize(ctx, req, reply, observations, trace), nil
+			return e.complete(ctx, state, step, reply, observations, emit)
 		}
 
 		if plan.Type == PlanNeedClarification {
-			return e.finalize(ctx, req, LLMResponse{
+			if err := emit(Event{Type: EventToken, Step: step, Delta: plan.Clarification}); err != nil {
+				return nil, err
+			}
+			return e.complete(ctx, state, step, LLMResponse{
 				Text: plan.Clarification,
-			}, observations, trace), nil
+			}, observations, emit)
+		}
+
+		if plan.Type == PlanSteps {
+			executed, err := e.executePlan(ctx, state, step, plan.Steps, emit)
+			if err != nil {
+				return nil, err
+			}
+			observations = append(observations, executed.observations...)
+			if len(executed.pending) > 0 {
+				return e.pause(ctx, state, step, prompt, observations, executed.pending, emit)
+			}
+			prompt, err = e.appendObservations(state, prompt, e.promptObservations(ctx, state, step, observations))
+			if err != nil {
+				return nil, err
+			}
+			state.trace.Record(TraceEvent{Name: "prompt.updated", Step: step, Attrs: map[string]any{"chars": len(prompt)}})
+			if executed.replan != "" {
+				continue
+			}
+			reply, err := e.finalAnswer(ctx, state, prompt, observations, step, emit)
+			if err != nil {
+				return nil, err
+			}
+			return e.complete(ctx, state, step, reply, observations, emit)
 		}
 
 		if len(plan.ToolCalls) == 0 {
 			return nil, fmt.Errorf("planner returned tool plan with no calls")
 		}
 
-		for _, call := range plan.ToolCalls {
-			if validationErr := validateToolCall(call, tools); validationErr != "" {
-				observations = append(observations, Observation{
-					ToolName: call.Name,
-					Error:    validationErr,
-				})
-				continue
-			}
-			if e.policy != nil && !e.policy.AllowTool(call.Name) {
-				observations = append(observations, Observation{
-					ToolName: call.Name,
-					Error:    "tool blocked by policy",
-				})
-				continue
-			}
+		stepObservations, pending, err := e.executeCalls(ctx, state, step, plan.ToolCalls, nil, emit)
+		if err != nil {
+			return nil, err
+		}
+		observations = append(observations, stepObservations...)
+
+		if len(pending) > 0 {
+			return e.pause(ctx, state, step, prompt, observations, pending, emit)
+		}
+
+		prompt, err = e.appendObservations(state, prompt, e.promptObservations(ctx, state, step, observations))
+		if err != nil {
+			return nil, err
+		}
+		state.trace.Record(TraceEvent{Name: "prompt.updated", Step: step, Attrs: map[string]any{"chars": len(prompt)}})
+	}
+
+	return nil, fmt.Errorf("%w (%d)", ErrMaxStepsExceeded, maxSteps)
+}
+
+// plan checks budgets and asks the planner for the next step.
+func (e *Engine) plan(ctx context.Context, state *runState, prompt string, observations []Observation, step int) (Plan, error) {
+	if err := e.checkBudget(state); err != nil {
+		state.trace.Record(TraceEvent{Name: "budget.exceeded", Step: step, Detail: err.Error()})
+		return Plan{}, err
+	}
+
+	started := e.clock()
+	planCtx, span := tracer.Start(ctx, "agent.plan")
+	plan, err := e.planner.Plan(planCtx, PlanInput{
+		Request:      state.outbound(),
+		Prompt:       prompt,
+		Tools:        state.tools,
+		Observations: state.redactObservations(e.promptObservations(ctx, state, step, observations)),
+		Step:         step,
+	})
+	span.SetAttributes(attribute.Int("agent.step", step), attribute.String("agent.plan_type", string(plan.Type)))
+	telemetry.End(span, err)
+	if err != nil {
+		state.trace.Record(TraceEvent{Name: "plan.failed", Step: step, Detail: err.Error(), Duration: e.clock().Sub(started)})
+		return Plan{}, err
+	}
+	plan = state.restorePlan(plan)
+	state.addUsage(plan.Usage)
+	state.trace.Record(planEvent(step, plan, len(prompt), e.clock().Sub(started)))
+	state.noteUnpriced(step, state.req.Provider, state.req.Model, plan.Usage)
+	return plan, nil
+}
+
+// finalAnswer checks budgets and answers the request from the prompt and observations.
+func (e *Engine) finalAnswer(ctx context.Context, state *runState, prompt string, observations []Observation, step int, emit func(Event) error) (LLMResponse, error) {
+	if err := e.checkBudget(state); err != nil {
+		state.trace.Record(TraceEvent{Name: "budget.exceeded", Step: step, Detail: err.Error()})
+		return LLMResponse{}, err
+	}
+	req := state.outbound()
+	return e.answer(ctx, state, LLMRequest{
+		Query:          req.Query,
+		Prompt:         citationPrompt(prompt, observations, req.ResponseSchema != nil),
+		Observations:   state.redactObservations(e.promptObservations(ctx, state, step, observations)),
+		History:        req.History,
+		Provider:       req.Provider,
+		Model:          req.Model,
+		ResponseSchema: req.ResponseSchema,
+	}, step, emit)
+}
+
+// pause persists the run and returns the calls awaiting approval.
+func (e *Engine) pause(ctx context.Context, state *runState, step int, prompt string, observations []Observation, pending []PendingCall, emit func(Event) error) (*Response, error) {
+	runID := newRunID()
+	for i := range pending {
+		pending[i].ID = PendingCallID(runID, i)
+	}
+	now := e.clock()
+	run := &PendingRun{
+		ID:           runID,
+		Request:      state.req,
+		Prompt:       prompt,
+		Observations: observations,
+		Step:         step,
+		Calls:        pending,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+
+		PromptVersion: state.trace.PromptVersion,
+		Placeholders:  state.placeholders(),
+		CallCounts:    state.callCountsSnapshot(),
+	}
+	if err := e.runs.SaveRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("save pending run: %w", err)
+	}
+	state.trace.RunID = runID
+	recordPending(state.trace, step, pending)
+
+	text := ApprovalMessage(pending)
+	if err := emit(Event{Type: EventApprovalRequired, Step: step, PendingCalls: pending}); err != nil {
+		return nil, err
+	}
+	if err := emit(Event{Type: EventToken, Step: step, Delta: text}); err != nil {
+		return nil, err
+	}
+	resp := &Response{
+		Text:         text,
+		Provider:     state.req.Provider,
+		Model:        state.req.Model,
+		Observations: observations,
+		Trace:        state.trace,
+		Status:       RunAwaitingApproval,
+		RunID:        runID,
+		PendingCalls: pending,
+		Usage:        state.runUsage(),
+	}
+	if err := emit(Event{Type: EventFinal, Step: step, Response: resp}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (e *Engine) emitter(handler EventHandler) func(Event) error {
+	return func(ev Event) error {
+		if handler == nil {
+			return nil
+		}
+		ev.At = e.clock()
+		return handler(ev)
+	}
+}
 
-			execCtx := ctx
-			var cancel context.CancelFunc
-			if e.toolTimeout > 0 {
-				execCtx, cancel = context.WithTimeout(ctx, e.toolTimeout)
+// respond calls the LLM, streaming token deltas when the LLM client supports it.
+// Structured answers are neither streamed nor emitted until they validate.
+func (e *Engine) respond(ctx context.Context, state *runState, input LLMRequest, step int, emit func(Event) error) (LLMResponse, error) {
+	ctx, span := tracer.Start(ctx, "agent.llm", telemetry.Attrs("llm.provider", input.Provider, "llm.model", input.Model))
+	started := e.clock()
+	streamer, streamed := e.llm.(StreamingLLMClient)
+	structured := input.ResponseSchema != nil
+	streamed = streamed && state.stream && !structured
+
+	var reply LLMResponse
+	var err error
+	if streamed && state.redactor != nil {
+		restorer := &restoreStream{redactor: state.redactor}
+		reply, err = streamer.RespondStream(ctx, input, func(delta string) error {
+			if delta = restorer.next(delta); delta == "" {
+				return nil
 			}
-			result, err := e.executor.Execute(execCtx, call)
-			if cancel != nil {
-				cancel()
+			return emit(Event{Type: EventToken, Step: step, Delta: delta})
+		})
+		if rest := restorer.flush(); err == nil && rest != "" {
+			err = emit(Event{Type: EventToken, Step: step, Delta: rest})
+		}
+	} else if streamed {
+		reply, err = streamer.RespondStream(ctx, input, func(delta string) error {
+			return emit(Event{Type: EventToken, Step: step, Delta: delta})
+		})
+	} else {
+		reply, err = e.llm.Respond(ctx, input)
+	}
+
+	attrs := map[string]any{
+		"provider":     input.Provider,
+		"model":        input.Model,
+		"promptChars":  len(input.Prompt),
+		"observations": len(input.Observations),
+		"streamed":     streamed,
+	}
+	ev := TraceEvent{Name: "llm.call", Step: step, Duration: e.clock().Sub(started), Attrs: attrs}
+	telemetry.End(span, err)
+	if err != nil {
+		ev.Detail = err.Error()
+		state.trace.Record(ev)
+		return LLMResponse{}, err
+	}
+	reply.Text = state.restore(reply.Text)
+	if reply.Provider != "" {
+		attrs["provider"] = reply.Provider
+	}
+	if reply.Model != "" {
+		attrs["model"] = reply.Model
+	}
+	attrs["responseChars"] = len(reply.Text)
+	addUsageAttrs(attrs, reply.Usage)
+	state.addUsage(reply.Usage)
+	state.trace.Record(ev)
+	state.noteUnpriced(step, reply.Provider, reply.Model, reply.Usage)
+
+	if !streamed && !structured {
+		if err := emit(Event{Type: EventToken, Step: step, Delta: reply.Text}); err != nil {
+			return LLMResponse{}, err
+		}
+	}
+	return reply, nil
+}
+
+// executeCalls authorizes a step's tool calls in order, then runs the allowed ones with
+// bounded concurrency. Observations are returned in call order regardless of completion
+// order; events are serialized. Calls that need approval are returned as pending when
+// a run store is configured. tainted optionally lists, per call, the flagged
+// observations its args were built from.
+func (e *Engine) executeCalls(ctx context.Context, state *runState, step int, calls []ToolCall, tainted [][]string, emit func(Event) error) ([]Observation, []PendingCall, error) {
+	authorized := make([]authorization, len(calls))
+	var pending []PendingCall
+	for i := range calls {
+		var derived []string
+		if i < len(tainted) {
+			derived = tainted[i]
+		}
+		authorized[i] = e.authorize(ctx, state, calls[i], derived)
+		if authorized[i].approval {
+			pending = append(pending, PendingCall{
+				Call:   authorized[i].call,
+				Access: authorized[i].access,
+				Reason: authorized[i].reason,
+				Status: ApprovalPending,
+			})
+		}
+	}
+
+	stepCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		emitErr error
+		wg      sync.WaitGroup
+	)
+	safeEmit := func(ev Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if emitErr != nil {
+			return emitErr
+		}
+		if err := emit(ev); err != nil {
+			emitErr = err
+			cancel()
+		}
+		return emitErr
+	}
+
+	observations := make([]Observation, len(calls))
+	ids := make([]string, len(calls))
+	for i := range authorized {
+		if !authorized[i].approval {
+			ids[i] = state.nextObservationID()
+		}
+	}
+	sem := make(chan struct{}, e.maxParallel)
+	for i := range authorized {
+		auth := authorized[i]
+		if auth.approval {
+			continue
+		}
+		call := auth.call
+		select {
+		case sem <- struct{}{}:
+		case <-stepCtx.Done():
+			observations[i] = Observation{ID: ids[i], ToolName: call.Name, Error: stepCtx.Err().Error()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := safeEmit(Event{Type: EventToolCallStarted, Step: step, ToolCall: &call}); err != nil {
+				observations[i] = Observation{ID: ids[i], ToolName: call.Name, Error: err.Error()}
+				return
 			}
-			if err != nil {
-				observations = append(observations, Observation{
-					ToolName: call.Name,
-					Error:    err.Error(),
-				})
-				continue
+			started := e.clock()
+			var obs Observation
+			outcome := "rejected"
+			if auth.rejection != nil {
+				obs = *auth.rejection
+			} else {
+				obs = e.runCall(stepCtx, state, step, call)
+				outcome = "executed"
 			}
-			observations = append(observations, Observation{
-				ToolName: call.Name,
-				Result:   result,
+			obs.ID = ids[i]
+			observations[i] = obs
+			duration := e.clock().Sub(started)
+			state.trace.Record(toolCallEvent(step, call, auth.access, obs, duration, outcome))
+			_ = safeEmit(Event{
+				Type:        EventToolCallFinished,
+				Step:        step,
+				ToolCall:    &call,
+				Observation: &obs,
+				Duration:    duration,
 			})
+		}(i)
+	}
+	wg.Wait()
+
+	if emitErr != nil {
+		return nil, nil, emitErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]Observation, 0, len(calls))
+	for i := range authorized {
+		if !authorized[i].approval {
+			out = append(out, observations[i])
 		}
+	}
+	state.remember(out)
+	return out, pending, nil
+}
 
-		prompt, err = e.context.AppendObservations(prompt, observations)
-		if err != nil {
-			return nil, err
+// authorization is the pre-execution verdict for a tool call.
+type authorization struct {
+	call      ToolCall     // Call with coerced args
+	rejection *Observation // Set when the call must not run
+	approval  bool         // Call waits for a human decision
+	access    AccessKind
+	reason    string
+}
+
+// authorize validates a call and applies policy and the write approval gate.
+// tainted lists flagged observations the call is known to derive from.
+func (e *Engine) authorize(ctx context.Context, state *runState, call ToolCall, tainted []string) authorization {
+	call, validationErr := validateToolCall(call, state.tools)
+	if validationErr != "" {
+		return authorization{call: call, rejection: &Observation{
+			ToolName: call.Name,
+			Error:    validationErr,
+		}}
+	}
+
+	action, _ := findAction(state.tools, call.Name, call.Action)
+	access := ClassifyAccess(action)
+	if access == AccessWrite && call.IdempotencyKey == "" {
+		call.IdempotencyKey = newRunID()
+	}
+	decision := PolicyDecision{Outcome: PolicyAllow}
+	if e.policy != nil {
+		decision = e.policy.Evaluate(ctx, PolicyContext{
+			SessionID: state.req.SessionID,
+			UserID:    state.req.UserID,
+			ProjectID: state.req.ProjectID,
+			Tool:      call.Name,
+			Action:    call.Action,
+			Args:      call.Args,
+			Access:    access,
+			CallCount: state.countCall(call),
+		})
+	}
+
+	// A delegate's task becomes the sub-agent's query, so it is checked like a write.
+	_, delegated := e.findDelegate(call.Name)
+	if decision.Outcome != PolicyDeny && (access == AccessWrite || delegated) {
+		if ids := mergeIDs(state.taintedBy(call), tainted); len(ids) > 0 {
+			return e.authorizeTainted(state, call, access, ids)
+		}
+	}
+
+	switch decision.Outcome {
+	case PolicyDeny:
+		return authorization{call: call, access: access, rejection: &Observation{
+			ToolName: call.Name,
+			Error:    policyMessage("tool blocked by policy", decision),
+		}}
+	case PolicyRequireApproval:
+		if !e.gated(state) || state.depth > 0 {
+			return authorization{call: call, access: access, rejection: &Observation{
+				ToolName: call.Name,
+				Error:    policyMessage("tool call requires approval", decision),
+			}}
+		}
+		return authorization{call: call, access: access, approval: true, reason: policyMessage("required by policy", decision)}
+	}
+
+	// Writes allowed by an explicit rule skip the gate; default-allowed writes do not.
+	// Sub-agents cannot pause, so gated writes are rejected there.
+	if e.gated(state) && e.approveWrites && access == AccessWrite && decision.Rule == "" {
+		if state.depth > 0 {
+			return authorization{call: call, access: access, rejection: &Observation{
+				ToolName: call.Name,
+				Error:    "mutating action requires approval, which sub-agents cannot request",
+			}}
+		}
+		return authorization{call: call, access: access, approval: true, reason: "mutating action"}
+	}
+	return authorization{call: call, access: access}
+}
+
+// gated reports whether calls of the run can wait for approval: paused runs are
+// persisted in the run store, durable runs wait in their workflow.
+func (e *Engine) gated(state *runState) bool {
+	return e.runs != nil || state.durable
+}
+
+// runCall executes an authorized call, delegating to a sub-agent when the call
+// names one, and screens the outcome for prompt injection.
+func (e *Engine) runCall(ctx context.Context, state *runState, step int, call ToolCall) Observation {
+	var obs Observation
+	switch d, ok := e.findDelegate(call.Name); {
+	case ok:
+		obs = e.runDelegate(ctx, state, step, d, call)
+	case call.Name == ObservationTool && call.Action == ObservationAction:
+		obs = e.readObservation(state, call)
+	default:
+		obs = e.runTool(ctx, state, step, call)
+	}
+	return e.screen(ctx, state, step, obs)
+}
+
+// runTool executes an authorized call with the tool timeout and traces how a
+// caching executor served it.
+func (e *Engine) runTool(ctx context.Context, state *runState, step int, call ToolCall) Observation {
+	ctx, span := tracer.Start(ctx, "agent.tool", telemetry.Attrs("tool.name", call.Name, "tool.action", call.Action))
+	action, _ := findAction(state.tools, call.Name, call.Action)
+	access := ClassifyAccess(action)
+	execCtx, cacheStatus := withToolCallScope(ctx, state, access)
+	var cancel context.CancelFunc
+	if e.toolTimeout > 0 {
+		execCtx, cancel = context.WithTimeout(execCtx, e.toolTimeout)
+	}
+	result, err := e.executor.Execute(execCtx, call)
+	if cancel != nil {
+		cancel()
+	}
+	telemetry.End(span, err)
+	if *cacheStatus != "" {
+		state.trace.Record(TraceEvent{Name: "tool.cache", Step: step, Attrs: map[string]any{
+			"tool":   call.Name,
+			"action": call.Action,
+			"access": string(access),
+			"status": string(*cacheStatus),
+		}})
+	}
+	if err != nil {
+		return Observation{
+			ToolName: call.Name,
+			Error:    err.Error(),
 		}
 	}
+	return Observation{
+		ToolName: call.Name,
+		Result:   result,
+		Sources:  observationSources(call, result),
+	}
+}
 
-	return nil, fmt.Errorf("max steps exceeded (%d)", e.maxSteps)
+func (e *Engine) complete(ctx context.Context, state *runState, step int, reply LLMResponse, observations []Observation, emit func(Event) error) (*Response, error) {
+	resp := e.finalize(ctx, state, reply, observations)
+	resp.Status = RunCompleted
+	resp.Usage = state.runUsage()
+	if err := emit(Event{Type: EventFinal, Step: step, Response: resp}); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
-func (e *Engine) finalize(ctx context.Context, req Request, reply LLMResponse, observations []Observation, trace *Trace) *Response {
-	if e.memory != nil {
+// finalize records the turn in memory and builds the response. Sub-agent runs
+// leave memory alone; their answer reaches the session through the parent.
+func (e *Engine) finalize(ctx context.Context, state *runState, reply LLMResponse, observations []Observation) *Response {
+	req := state.req
+	var citations []Citation
+	if req.ResponseSchema == nil {
+		var unknown []string
+		citations, unknown, reply.Text = resolveCitations(reply.Text, observations)
+		if len(unknown) > 0 {
+			state.trace.Record(TraceEvent{Name: "citations.unknown", Detail: "answer cites IDs no observation provided", Attrs: map[string]any{"ids": unknown}})
+		}
+	}
+	if e.memory != nil && state.depth == 0 {
 		_ = e.memory.AddTurn(ctx, req.SessionID, req.Query, "user", e.clock())
 		_ = e.memory.AddTurn(ctx, req.SessionID, reply.Text, "assistant", e.clock())
 		if len(observations) > 0 {
@@ -202,76 +939,268 @@ func (e *Engine) finalize(ctx context.Context, req Request, reply LLMResponse, o
 		}
 	}
 
-	return &Response{
+	resp := &Response{
 		Text:         reply.Text,
 		Provider:     reply.Provider,
 		Model:        reply.Model,
 		Observations: observations,
-		Trace:        trace,
+		Trace:        state.trace,
+		Structured:   state.structured,
+		Citations:    citations,
 	}
+	return resp
 }
 
-func validateToolCall(call ToolCall, tools []ToolDef) string {
+// validateToolCall checks the call against the tool catalog and action schema,
+// returning the call with coerced args or an LLM-readable error.
+func validateToolCall(call ToolCall, tools []ToolDef) (ToolCall, string) {
 	if call.Name == "" {
-		return "missing tool name"
+		return call, "missing tool name"
 	}
-	var tool *ToolDef
-	for i := range tools {
-		if tools[i].Name == call.Name {
-			tool = &tools[i]
-			break
+	if call.Action == "" {
+		if _, ok := findTool(tools, call.Name); !ok {
+			return call, fmt.Sprintf("unknown tool: %s", call.Name)
 		}
+		return call, "missing tool action"
 	}
-	if tool == nil {
-		return fmt.Sprintf("unknown tool: %s", call.Name)
+	action, lookupErr := findAction(tools, call.Name, call.Action)
+	if lookupErr != "" {
+		return call, lookupErr
 	}
-	if call.Action == "" {
-		return "missing tool action"
+	if action.InputSchema == "" {
+		return call, ""
+	}
+
+	args, violations, err := ValidateArgs(action.InputSchema, call.Args)
+	if err != nil {
+		return call, fmt.Sprintf("cannot validate arguments for %s.%s: %s", call.Name, call.Action, err.Error())
+	}
+	if len(violations) > 0 {
+		return call, fmt.Sprintf("invalid arguments for %s.%s: %s", call.Name, call.Action, strings.Join(violations, "; "))
 	}
-	var action *ToolAction
-	for i := range tool.Actions {
-		if tool.Actions[i].Name == call.Action {
-			action = &tool.Actions[i]
-			break
+	call.Args = args
+	return call, ""
+}
+
+func findTool(tools []ToolDef, name string) (ToolDef, bool) {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return tool, true
 		}
 	}
-	if action == nil {
-		return fmt.Sprintf("unknown action: %s", call.Action)
+	return ToolDef{}, false
+}
+
+func findAction(tools []ToolDef, toolName, actionName string) (ToolAction, string) {
+	tool, ok := findTool(tools, toolName)
+	if !ok {
+		return ToolAction{}, fmt.Sprintf("unknown tool: %s", toolName)
 	}
-	if action.InputSchema == "" {
-		return ""
+	for _, action := range tool.Actions {
+		if action.Name == actionName {
+			return action, ""
+		}
 	}
-	return validateRequiredFields(action.InputSchema, call.Args)
+	return ToolAction{}, fmt.Sprintf("unknown action: %s", actionName)
 }
 
-func validateRequiredFields(schema string, args map[string]any) string {
-	if args == nil {
-		args = map[string]any{}
+func policyMessage(prefix string, decision PolicyDecision) string {
+	msg := prefix
+	if decision.Rule != "" {
+		msg += " (rule " + decision.Rule + ")"
 	}
-	var payload map[string]any
-	if err := json.Unmarshal([]byte(schema), &payload); err != nil {
-		return ""
+	if decision.Reason != "" {
+		msg += ": " + decision.Reason
+	}
+	return msg
+}
+
+// runState holds per-run data shared by concurrent tool calls.
+type runState struct {
+	req    Request
+	tools  []ToolDef
+	trace  *Trace
+	stream bool
+	prior  priorUsage
+
+	depth    int  // Delegation depth; 0 for runs started by a caller
+	maxSteps int  // Step limit override for sub-agent runs
+	durable  bool // Run advanced step by step from a RunCheckpoint
+
+	redactor Redactor // Masks text sent to the LLM; nil when the run does not redact
+
+	mu         sync.Mutex
+	callCounts map[string]int
+	usage      Usage
+	delegated  Usage // Usage of sub-agent runs
+	observed   int   // Observations numbered so far
+	structured any   // Validated answer for requests with a response schema
+	raw        map[string]Observation
+	shaped     map[string]Observation
+}
+
+func newRunState(req Request, tools []ToolDef, trace *Trace, stream bool) *runState {
+	return &runState{
+		req:        req,
+		tools:      tools,
+		trace:      trace,
+		stream:     stream,
+		callCounts: make(map[string]int),
+		raw:        make(map[string]Observation),
+		shaped:     make(map[string]Observation),
 	}
-	requiredRaw, ok := payload["required"]
+}
+
+// countCall returns how many times the call's tool action ran before and records this call.
+func (s *runState) countCall(call ToolCall) int {
+	key := call.Name + "/" + call.Action
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := s.callCounts[key]
+	s.callCounts[key] = count + 1
+	return count
+}
+
+// callCountsSnapshot copies the calls made so far per tool action.
+func (s *runState) callCountsSnapshot() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.callCounts)
+}
+
+// recordPending traces the calls a run waits on.
+func recordPending(trace *Trace, step int, pending []PendingCall) {
+	for _, call := range pending {
+		trace.Record(TraceEvent{
+			Name:   "tool.pending",
+			Step:   step,
+			Detail: call.Reason,
+			Attrs:  map[string]any{"id": call.ID, "tool": call.Call.Name, "action": call.Call.Action, "args": call.Call.Args, "access": string(call.Access)},
+		})
+	}
+}
+
+func rejectionMessage(call PendingCall) string {
+	if call.Note != "" {
+		return "rejected by user: " + call.Note
+	}
+	return "rejected by user"
+}
+
+// listTools discovers the tools available to the request and traces the outcome.
+func (e *Engine) listTools(ctx context.Context, req Request, trace *Trace) ([]ToolDef, error) {
+	started := e.clock()
+	tools, err := e.tools.ListTools(ctx, req.UserID, req.ProjectID)
+	tools = append(tools[:len(tools):len(tools)], e.delegateTools(delegationFrom(ctx).depth)...)
+	if err != nil {
+		trace.Record(TraceEvent{Name: "tools.list.failed", Detail: err.Error(), Duration: e.clock().Sub(started)})
+		return tools, err
+	}
+	trace.Record(TraceEvent{Name: "tools.listed", Duration: e.clock().Sub(started), Attrs: map[string]any{"count": len(tools)}})
+	return tools, nil
+}
+
+// degradedNote tells the planner which tools the registry withholds, if any.
+func (e *Engine) degradedNote(trace *Trace) string {
+	health, ok := e.tools.(ToolHealth)
 	if !ok {
 		return ""
 	}
-	reqSlice, ok := requiredRaw.([]any)
-	if !ok || len(reqSlice) == 0 {
+	degraded := health.DegradedTools()
+	if len(degraded) == 0 {
 		return ""
 	}
-	missing := make([]string, 0)
-	for _, item := range reqSlice {
-		name, ok := item.(string)
-		if !ok {
-			continue
+	lines := make([]string, 0, len(degraded))
+	names := make([]string, 0, len(degraded))
+	for _, tool := range degraded {
+		lines = append(lines, fmt.Sprintf("- %s: %s", tool.Name, tool.Reason))
+		names = append(names, tool.Name)
+	}
+	trace.Record(TraceEvent{Name: "tools.degraded", Attrs: map[string]any{"tools": names}})
+	return "These tools are temporarily unavailable; do not plan on them:\n" + strings.Join(lines, "\n")
+}
+
+// finishTrace marks the trace with the run outcome and persists it. Store errors
+// are ignored so tracing never fails a run.
+func (e *Engine) finishTrace(ctx context.Context, trace *Trace, resp *Response, err error) {
+	switch {
+	case err != nil:
+		trace.finish("failed", err.Error())
+	case resp.Status == "":
+		trace.finish(string(RunCompleted), "")
+	default:
+		trace.finish(string(resp.Status), "")
+	}
+	if e.traces != nil {
+		_ = e.traces.SaveTrace(context.WithoutCancel(ctx), trace.Snapshot())
+	}
+}
+
+func newTrace(req Request) *Trace {
+	trace := NewTrace(newRunID())
+	trace.SessionID = req.SessionID
+	trace.UserID = req.UserID
+	trace.ProjectID = req.ProjectID
+	trace.Query = req.Query
+	return trace
+}
+
+func planEvent(step int, plan Plan, promptChars int, duration time.Duration) TraceEvent {
+	attrs := map[string]any{"type": string(plan.Type), "promptChars": promptChars}
+	addUsageAttrs(attrs, plan.Usage)
+	if len(plan.ToolCalls) > 0 {
+		calls := make([]string, 0, len(plan.ToolCalls))
+		for _, call := range plan.ToolCalls {
+			calls = append(calls, call.Name+"."+call.Action)
 		}
-		if _, exists := args[name]; !exists {
-			missing = append(missing, name)
+		attrs["calls"] = calls
+	}
+	if len(plan.Steps) > 0 {
+		steps := make([]map[string]any, 0, len(plan.Steps))
+		for _, planStep := range plan.Steps {
+			steps = append(steps, map[string]any{
+				"id":        planStep.ID,
+				"call":      planStep.Call.Name + "." + planStep.Call.Action,
+				"args":      planStep.Call.Args,
+				"dependsOn": planStep.DependsOn,
+				"purpose":   planStep.Purpose,
+			})
 		}
+		attrs["steps"] = steps
 	}
-	if len(missing) == 0 {
-		return ""
+	return TraceEvent{Name: "plan", Step: step, Detail: plan.Clarification, Duration: duration, Attrs: attrs}
+}
+
+// toolCallEvent records a finished tool call. outcome is executed, rejected
+// (validation or policy) or the approval status of a resumed call.
+func toolCallEvent(step int, call ToolCall, access AccessKind, obs Observation, duration time.Duration, outcome string) TraceEvent {
+	attrs := map[string]any{
+		"tool":    call.Name,
+		"action":  call.Action,
+		"args":    call.Args,
+		"outcome": outcome,
+	}
+	if access != "" {
+		attrs["access"] = string(access)
+	}
+	if obs.Result != nil {
+		if data, err := json.Marshal(obs.Result); err == nil {
+			attrs["resultBytes"] = len(data)
+		}
+		attrs["success"] = obs.Result.Success
 	}
-	return "missing required params: " + strings.Join(missing, ", ")
+	if len(obs.Sources) > 0 {
+		ids := make([]string, 0, len(obs.Sources))
+		for _, source := range obs.Sources {
+			ids = append(ids, source.ID)
+		}
+		attrs["sources"] = ids
+	}
+	return TraceEvent{Name: "tool.call", Step: step, Detail: obs.Error, Duration: duration, Attrs: attrs}
+}
+
+func newRunID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
 }
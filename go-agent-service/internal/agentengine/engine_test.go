@@ -0,0 +1,858 @@
+package agentengine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type scriptedPlanner struct {
+	plans []Plan
+}
+
+func (p *scriptedPlanner) Plan(ctx context.Context, input PlanInput) (Plan, error) {
+	_ = ctx
+	if input.Step > len(p.plans) {
+		return Plan{Type: PlanDirect}, nil
+	}
+	return p.plans[input.Step-1], nil
+}
+
+type staticLLM struct {
+	text  string
+	usage Usage
+}
+
+func (l *staticLLM) Respond(ctx context.Context, input LLMRequest) (LLMResponse, error) {
+	_ = ctx
+	_ = input
+	return LLMResponse{Text: l.text, Usage: l.usage}, nil
+}
+
+type staticTools struct {
+	tools []ToolDef
+}
+
+func (t *staticTools) ListTools(ctx context.Context, userID, projectID string) ([]ToolDef, error) {
+	_ = ctx
+	_ = userID
+	_ = projectID
+	return t.tools, nil
+}
+
+type echoExecutor struct{}
+
+func (echoExecutor) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	_ = ctx
+	if call.Action == "fail" {
+		return nil, errors.New("boom")
+	}
+	return &ToolResult{Success: true, Message: call.Name + "." + call.Action}, nil
+}
+
+type plainAssembler struct{}
+
+func (plainAssembler) Build(ctx context.Context, req Request, tools []ToolDef) (string, error) {
+	_ = ctx
+	_ = tools
+	return req.Query, nil
+}
+
+func (plainAssembler) AppendObservations(prompt string, observations []Observation) (string, error) {
+	return prompt + "\n" + observationNames(observations), nil
+}
+
+func observationNames(observations []Observation) string {
+	parts := make([]string, 0, len(observations))
+	for _, obs := range observations {
+		parts = append(parts, obs.ToolName)
+	}
+	return strings.Join(parts, ",")
+}
+
+func newTestEngine(t *testing.T, planner Planner) *Engine {
+	t.Helper()
+	engine, err := NewEngine(Config{
+		Planner: planner,
+		LLM:     &staticLLM{text: "done"},
+		Tools: &staticTools{tools: []ToolDef{
+			{Name: "jira", Actions: []ToolAction{{Name: "search"}, {Name: "fail"}}},
+		}},
+		Executor: echoExecutor{},
+		Context:  plainAssembler{},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestRunStreamEmitsEventsInOrder(t *testing.T) {
+	planner := &scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{
+			{Name: "jira", Action: "search"},
+			{Name: "jira", Action: "fail"},
+		}},
+	}}
+	engine := newTestEngine(t, planner)
+
+	var events []Event
+	resp, err := engine.RunStream(context.Background(), Request{Query: "bugs"}, func(ev Event) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunStream: %v", err)
+	}
+	if resp.Text != "done" || len(resp.Observations) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	want := []EventType{
+		EventPlan,
+		EventToolCallStarted, EventToolCallFinished,
+		EventToolCallStarted, EventToolCallFinished,
+		EventPlan,
+		EventToken,
+		EventFinal,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d: %+v", len(want), len(events), events)
+	}
+	for i, ev := range events {
+		if ev.Type != want[i] {
+			t.Fatalf("event %d: expected %s, got %s", i, want[i], ev.Type)
+		}
+	}
+	if events[2].Observation == nil || events[2].Observation.Result == nil {
+		t.Fatalf("expected observation on tool_call_finished, got %+v", events[2])
+	}
+	if events[4].Observation == nil || events[4].Observation.Error != "boom" {
+		t.Fatalf("expected failed observation, got %+v", events[4])
+	}
+	if events[6].Delta != "done" || events[7].Response != resp {
+		t.Fatalf("unexpected final events: %+v %+v", events[6], events[7])
+	}
+}
+
+func TestRunStreamHandlerErrorAborts(t *testing.T) {
+	engine := newTestEngine(t, &scriptedPlanner{})
+	stop := errors.New("client gone")
+
+	_, err := engine.RunStream(context.Background(), Request{Query: "hi"}, func(ev Event) error {
+		if ev.Type == EventToken {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+}
+
+func TestRunPersistsStructuredTrace(t *testing.T) {
+	planner := &scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{
+			{Name: "jira", Action: "search", Args: map[string]any{"q": "bugs"}},
+			{Name: "jira", Action: "fail"},
+		}},
+	}}
+	traces := NewMemoryTraceStore(0)
+	engine := newTestEngine(t, planner)
+	engine.traces = traces
+
+	resp, err := engine.Run(context.Background(), Request{Query: "bugs", SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	stored, err := traces.GetTrace(context.Background(), resp.Trace.ID)
+	if err != nil {
+		t.Fatalf("GetTrace: %v", err)
+	}
+	if stored.SessionID != "s1" || stored.Status != string(RunCompleted) || stored.Finished.IsZero() {
+		t.Fatalf("unexpected trace: %+v", stored)
+	}
+
+	var names []string
+	for _, ev := range stored.Events {
+		names = append(names, ev.Name)
+	}
+	want := "tools.listed,prompt.built,plan,tool.call,tool.call,prompt.updated,plan,llm.call,run.completed"
+	if got := strings.Join(names, ","); got != want {
+		t.Fatalf("expected events %s, got %s", want, got)
+	}
+
+	search, failed := stored.Events[3], stored.Events[4]
+	if search.Attrs["action"] != "search" || search.Attrs["args"].(map[string]any)["q"] != "bugs" || search.Attrs["resultBytes"].(int) == 0 {
+		t.Fatalf("unexpected tool event: %+v", search)
+	}
+	if failed.Detail != "boom" {
+		t.Fatalf("expected failed tool error in trace, got %+v", failed)
+	}
+	if stored.Events[7].Attrs["responseChars"] != len("done") {
+		t.Fatalf("unexpected llm event: %+v", stored.Events[7])
+	}
+
+	list, err := traces.ListTraces(context.Background(), "s1", 10)
+	if err != nil || len(list) != 1 || list[0].ID != stored.ID {
+		t.Fatalf("unexpected session traces: %v %+v", err, list)
+	}
+}
+
+type streamingLLM struct {
+	staticLLM
+	deltas []string
+}
+
+func (l *streamingLLM) RespondStream(ctx context.Context, input LLMRequest, onDelta func(delta string) error) (LLMResponse, error) {
+	_ = ctx
+	_ = input
+	for _, delta := range l.deltas {
+		if err := onDelta(delta); err != nil {
+			return LLMResponse{}, err
+		}
+	}
+	return LLMResponse{Text: strings.Join(l.deltas, "")}, nil
+}
+
+func TestRunStreamUsesStreamingLLM(t *testing.T) {
+	engine, err := NewEngine(Config{
+		Planner:  &scriptedPlanner{},
+		LLM:      &streamingLLM{deltas: []string{"Hel", "lo"}},
+		Tools:    &staticTools{},
+		Executor: echoExecutor{},
+		Context:  plainAssembler{},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	var deltas []string
+	resp, err := engine.RunStream(context.Background(), Request{Query: "hi"}, func(ev Event) error {
+		if ev.Type == EventToken {
+			deltas = append(deltas, ev.Delta)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunStream: %v", err)
+	}
+	if resp.Text != "Hello" || strings.Join(deltas, "|") != "Hel|lo" {
+		t.Fatalf("unexpected stream: text=%q deltas=%v", resp.Text, deltas)
+	}
+}
+
+type slowExecutor struct {
+	mu      sync.Mutex
+	active  int
+	peak    int
+	delay   map[string]time.Duration
+	started chan struct{}
+}
+
+func (s *slowExecutor) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	s.mu.Lock()
+	s.active++
+	if s.active > s.peak {
+		s.peak = s.active
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+	if s.started != nil {
+		s.started <- struct{}{}
+	}
+
+	select {
+	case <-time.After(s.delay[call.Action]):
+		return &ToolResult{Success: true, Message: call.Action}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func parallelTestEngine(t *testing.T, executor ToolExecutor, maxParallel int) *Engine {
+	t.Helper()
+	engine, err := NewEngine(Config{
+		Planner: &scriptedPlanner{plans: []Plan{
+			{Type: PlanToolCalls, ToolCalls: []ToolCall{
+				{Name: "ops", Action: "jira"},
+				{Name: "ops", Action: "github"},
+				{Name: "ops", Action: "pagerduty"},
+			}},
+		}},
+		LLM: &staticLLM{text: "done"},
+		Tools: &staticTools{tools: []ToolDef{
+			{Name: "ops", Actions: []ToolAction{{Name: "jira"}, {Name: "github"}, {Name: "pagerduty"}}},
+		}},
+		Executor:         executor,
+		Context:          plainAssembler{},
+		MaxParallelTools: maxParallel,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestRunExecutesToolCallsInParallelInCallOrder(t *testing.T) {
+	executor := &slowExecutor{delay: map[string]time.Duration{
+		"jira":      60 * time.Millisecond,
+		"github":    10 * time.Millisecond,
+		"pagerduty": 30 * time.Millisecond,
+	}}
+	engine := parallelTestEngine(t, executor, 2)
+
+	resp, err := engine.Run(context.Background(), Request{Query: "status"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if executor.peak != 2 {
+		t.Fatalf("expected peak concurrency 2, got %d", executor.peak)
+	}
+
+	got := make([]string, 0, len(resp.Observations))
+	for _, obs := range resp.Observations {
+		if obs.Result == nil {
+			t.Fatalf("unexpected failed observation: %+v", obs)
+		}
+		got = append(got, obs.Result.Message)
+	}
+	if strings.Join(got, ",") != "jira,github,pagerduty" {
+		t.Fatalf("observations out of call order: %v", got)
+	}
+}
+
+func TestRunCancelsToolCallsWithParentContext(t *testing.T) {
+	executor := &slowExecutor{
+		delay:   map[string]time.Duration{"jira": time.Minute, "github": time.Minute, "pagerduty": time.Minute},
+		started: make(chan struct{}, 3),
+	}
+	engine := parallelTestEngine(t, executor, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for i := 0; i < 3; i++ {
+			<-executor.started
+		}
+		cancel()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := engine.Run(ctx, Request{Query: "status"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("run did not stop after cancellation")
+	}
+}
+
+type recordingPolicy struct {
+	mu     sync.Mutex
+	inputs []PolicyContext
+}
+
+func (p *recordingPolicy) Evaluate(ctx context.Context, input PolicyContext) PolicyDecision {
+	_ = ctx
+	p.mu.Lock()
+	p.inputs = append(p.inputs, input)
+	p.mu.Unlock()
+	if input.Access == AccessWrite {
+		return PolicyDecision{Outcome: PolicyRequireApproval, Reason: "writes are reviewed", Rule: "writes"}
+	}
+	if input.CallCount >= 1 {
+		return PolicyDecision{Outcome: PolicyDeny, Reason: "budget exhausted"}
+	}
+	return PolicyDecision{Outcome: PolicyAllow}
+}
+
+func TestRunAppliesPolicyDecisions(t *testing.T) {
+	policy := &recordingPolicy{}
+	engine, err := NewEngine(Config{
+		Planner: &scriptedPlanner{plans: []Plan{
+			{Type: PlanToolCalls, ToolCalls: []ToolCall{
+				{Name: "jira", Action: "search_issues"},
+				{Name: "jira", Action: "search_issues"},
+				{Name: "jira", Action: "create_issue"},
+			}},
+		}},
+		LLM: &staticLLM{text: "done"},
+		Tools: &staticTools{tools: []ToolDef{
+			{Name: "jira", Actions: []ToolAction{{Name: "search_issues"}, {Name: "create_issue"}}},
+		}},
+		Executor: echoExecutor{},
+		Context:  plainAssembler{},
+		Policy:   policy,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	resp, err := engine.Run(context.Background(), Request{Query: "file a bug", UserID: "u1", ProjectID: "p1"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	obs := resp.Observations
+	if len(obs) != 3 || obs[0].Result == nil {
+		t.Fatalf("expected first search to run, got %+v", obs)
+	}
+	if obs[1].Error != "tool blocked by policy: budget exhausted" {
+		t.Fatalf("unexpected budget observation: %q", obs[1].Error)
+	}
+	if obs[2].Error != "tool call requires approval (rule writes): writes are reviewed" {
+		t.Fatalf("unexpected approval observation: %q", obs[2].Error)
+	}
+	if policy.inputs[2].UserID != "u1" || policy.inputs[2].ProjectID != "p1" || policy.inputs[2].Access != AccessWrite {
+		t.Fatalf("unexpected policy context: %+v", policy.inputs[2])
+	}
+}
+
+type countingExecutor struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *countingExecutor) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	_ = ctx
+	c.mu.Lock()
+	c.calls = append(c.calls, call.Action)
+	c.mu.Unlock()
+	return &ToolResult{Success: true, Message: call.Action}, nil
+}
+
+func approvalTestEngine(t *testing.T, executor ToolExecutor) *Engine {
+	t.Helper()
+	return approvalTestEngineWithRuns(t, executor, NewMemoryRunStore())
+}
+
+func approvalTestEngineWithRuns(t *testing.T, executor ToolExecutor, runs RunStore) *Engine {
+	t.Helper()
+	engine, err := NewEngine(Config{
+		Planner: &scriptedPlanner{plans: []Plan{
+			{Type: PlanToolCalls, ToolCalls: []ToolCall{
+				{Name: "jira", Action: "search_issues"},
+				{Name: "jira", Action: "create_issue", Args: map[string]any{"summary": "Crash on login"}},
+			}},
+		}},
+		LLM: &staticLLM{text: "done"},
+		Tools: &staticTools{tools: []ToolDef{
+			{Name: "jira", Actions: []ToolAction{{Name: "search_issues"}, {Name: "create_issue"}}},
+		}},
+		Executor:      executor,
+		Context:       plainAssembler{},
+		Runs:          runs,
+		ApproveWrites: true,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+// barrierRunStore holds GetRun until n callers have loaded the run, as two
+// replicas deciding the same run at once would.
+type barrierRunStore struct {
+	*MemoryRunStore
+	loaded sync.WaitGroup
+}
+
+func (s *barrierRunStore) GetRun(ctx context.Context, id string) (*PendingRun, error) {
+	run, err := s.MemoryRunStore.GetRun(ctx, id)
+	s.loaded.Done()
+	s.loaded.Wait()
+	return run, err
+}
+
+func TestConcurrentDecisionsRunApprovedCallsOnce(t *testing.T) {
+	executor := &countingExecutor{}
+	runs := &barrierRunStore{MemoryRunStore: NewMemoryRunStore()}
+	replicas := []*Engine{approvalTestEngineWithRuns(t, executor, runs), approvalTestEngineWithRuns(t, executor, runs)}
+	ctx := context.Background()
+
+	resp, err := replicas[0].Run(ctx, Request{Query: "file a bug"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	decision := []ApprovalDecision{{CallID: resp.PendingCalls[0].ID, Approved: true}}
+	runs.loaded.Add(len(replicas))
+	errs := make([]error, len(replicas))
+	var wg sync.WaitGroup
+	for i, engine := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = engine.Decide(ctx, RunOwner{}, resp.RunID, decision)
+		}()
+	}
+	wg.Wait()
+
+	if (errs[0] == nil) == (errs[1] == nil) {
+		t.Fatalf("expected exactly one decider to claim the run, got %v", errs)
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrRunNotFound) {
+			t.Fatalf("expected the losing decider to find the run gone, got %v", err)
+		}
+	}
+	if strings.Join(executor.calls, ",") != "search_issues,create_issue" {
+		t.Fatalf("expected the approved write to run once, got %v", executor.calls)
+	}
+}
+
+func TestRunPausesOnWritesAndResumesAfterApproval(t *testing.T) {
+	executor := &countingExecutor{}
+	engine := approvalTestEngine(t, executor)
+	ctx := context.Background()
+
+	resp, err := engine.Run(ctx, Request{Query: "file a bug"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if resp.Status != RunAwaitingApproval || resp.RunID == "" || len(resp.PendingCalls) != 1 {
+		t.Fatalf("expected paused run, got %+v", resp)
+	}
+	pending := resp.PendingCalls[0]
+	if pending.Call.Action != "create_issue" || pending.ID != PendingCallID(resp.RunID, 0) {
+		t.Fatalf("unexpected pending call: %+v", pending)
+	}
+	if strings.Join(executor.calls, ",") != "search_issues" {
+		t.Fatalf("expected only the read to run before approval, got %v", executor.calls)
+	}
+
+	resumed, err := engine.Decide(ctx, RunOwner{}, resp.RunID, []ApprovalDecision{{CallID: pending.ID, Approved: true}})
+	if err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+	if resumed.Status != RunCompleted || resumed.Text != "done" || len(resumed.Observations) != 2 {
+		t.Fatalf("expected completed run, got %+v", resumed)
+	}
+	if strings.Join(executor.calls, ",") != "search_issues,create_issue" {
+		t.Fatalf("expected approved write to run, got %v", executor.calls)
+	}
+
+	if _, err := engine.Decide(ctx, RunOwner{}, resp.RunID, []ApprovalDecision{{CallID: pending.ID, Approved: true}}); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected run to be consumed, got %v", err)
+	}
+}
+
+func TestDecideRejectsOtherOwners(t *testing.T) {
+	executor := &countingExecutor{}
+	engine := approvalTestEngine(t, executor)
+	ctx := context.Background()
+
+	resp, err := engine.Run(ctx, Request{Query: "file a bug", UserID: "u1", ProjectID: "p1"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	decision := []ApprovalDecision{{CallID: resp.PendingCalls[0].ID, Approved: true}}
+	for _, other := range []RunOwner{{UserID: "u2", ProjectID: "p1"}, {UserID: "u1", ProjectID: "p2"}, {}} {
+		if _, err := engine.Decide(ctx, other, resp.RunID, decision); !errors.Is(err, ErrRunNotFound) {
+			t.Fatalf("expected %+v to be refused as not found, got %v", other, err)
+		}
+	}
+	if strings.Join(executor.calls, ",") != "search_issues" {
+		t.Fatalf("expected the write held, got %v", executor.calls)
+	}
+	if _, err := engine.Decide(ctx, RunOwner{UserID: "u1", ProjectID: "p1"}, resp.RunID, decision); err != nil {
+		t.Fatalf("expected the owner to decide, got %v", err)
+	}
+}
+
+func TestRejectedCallBecomesObservation(t *testing.T) {
+	executor := &countingExecutor{}
+	engine := approvalTestEngine(t, executor)
+	ctx := context.Background()
+
+	resp, err := engine.Run(ctx, Request{Query: "file a bug"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	resumed, err := engine.Decide(ctx, RunOwner{}, resp.RunID, []ApprovalDecision{{CallID: resp.PendingCalls[0].ID, Reason: "duplicate"}})
+	if err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+	last := resumed.Observations[len(resumed.Observations)-1]
+	if last.Error != "rejected by user: duplicate" {
+		t.Fatalf("unexpected rejection observation: %+v", last)
+	}
+	if strings.Join(executor.calls, ",") != "search_issues" {
+		t.Fatalf("rejected write must not run, got %v", executor.calls)
+	}
+}
+
+func usageTestEngine(t *testing.T, planner Planner, store UsageStore, budget Budget) *Engine {
+	t.Helper()
+	engine, err := NewEngine(Config{
+		Planner: planner,
+		LLM:     &staticLLM{text: "done", usage: Usage{PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40, CostUSD: 0.004}},
+		Tools: &staticTools{tools: []ToolDef{
+			{Name: "jira", Actions: []ToolAction{{Name: "search"}}},
+		}},
+		Executor: echoExecutor{},
+		Context:  plainAssembler{},
+		Usage:    store,
+		Budget:   budget,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestRunAccumulatesAndRecordsUsage(t *testing.T) {
+	planUsage := Usage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100, CostUSD: 0.01}
+	planner := &scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "jira", Action: "search"}}, Usage: planUsage},
+		{Type: PlanDirect, Usage: planUsage},
+	}}
+	store := NewMemoryUsageStore()
+	engine := usageTestEngine(t, planner, store, Budget{})
+
+	resp, err := engine.Run(context.Background(), Request{Query: "bugs", SessionID: "s1", UserID: "u1", ProjectID: "p1"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if resp.Usage.TotalTokens != 240 || resp.Usage.PromptTokens != 190 {
+		t.Fatalf("expected planner and LLM usage summed, got %+v", resp.Usage)
+	}
+
+	summaries, err := store.ReportUsage(context.Background(), UsageFilter{UserID: "u1"}, UsageByProject)
+	if err != nil {
+		t.Fatalf("ReportUsage: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].Key != "p1" || summaries[0].Runs != 1 || summaries[0].Usage.TotalTokens != 240 {
+		t.Fatalf("unexpected usage report: %+v", summaries)
+	}
+}
+
+func TestRunRecordsUnpricedUsage(t *testing.T) {
+	planner := &scriptedPlanner{plans: []Plan{
+		{Type: PlanDirect, Usage: Usage{TotalTokens: 100, Unpriced: true}},
+	}}
+	engine := usageTestEngine(t, planner, nil, Budget{})
+
+	resp, err := engine.Run(context.Background(), Request{Query: "bugs", Provider: "ollama", Model: "llama3.1"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	unknown := traceEvents(resp.Trace, "cost.unknown")
+	if len(unknown) != 1 || unknown[0].Attrs["model"] != "llama3.1" || !resp.Usage.Unpriced {
+		t.Fatalf("expected the unpriced planner call traced, got %+v (%+v)", unknown, resp.Usage)
+	}
+}
+
+func TestRunEnforcesBudgets(t *testing.T) {
+	planUsage := Usage{TotalTokens: 100}
+	planner := &scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "jira", Action: "search"}}, Usage: planUsage},
+		{Type: PlanDirect, Usage: planUsage},
+	}}
+	store := NewMemoryUsageStore()
+	engine := usageTestEngine(t, planner, store, Budget{RunTokens: 150, SessionTokens: 1000})
+
+	_, err := engine.Run(context.Background(), Request{Query: "bugs", SessionID: "s1"})
+	var budgetErr *BudgetError
+	if !errors.Is(err, ErrBudgetExceeded) || !errors.As(err, &budgetErr) || budgetErr.Scope != "run" {
+		t.Fatalf("expected run budget error before the LLM call, got %v", err)
+	}
+	used, _ := store.SumUsage(context.Background(), UsageFilter{SessionID: "s1"})
+	if used.TotalTokens != 200 {
+		t.Fatalf("expected usage of the failed run to be recorded, got %+v", used)
+	}
+
+	_ = store.RecordUsage(context.Background(), UsageRecord{SessionID: "s1", Usage: Usage{TotalTokens: 900}})
+	_, err = engine.Run(context.Background(), Request{Query: "bugs", SessionID: "s1"})
+	if !errors.As(err, &budgetErr) || budgetErr.Scope != "session" {
+		t.Fatalf("expected session budget error, got %v", err)
+	}
+	if _, err := engine.Run(context.Background(), Request{Query: "bugs", SessionID: "s2"}); !errors.As(err, &budgetErr) || budgetErr.Scope != "run" {
+		t.Fatalf("expected other sessions to keep their budget, got %v", err)
+	}
+}
+
+type degradedTools struct {
+	staticTools
+	degraded []DegradedTool
+}
+
+func (t *degradedTools) DegradedTools() []DegradedTool {
+	return t.degraded
+}
+
+type keyRecordingExecutor struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+func (e *keyRecordingExecutor) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	e.mu.Lock()
+	e.keys[call.Action] = call.IdempotencyKey
+	e.mu.Unlock()
+	return &ToolResult{Success: true}, nil
+}
+
+func TestRunKeysWritesAndReportsDegradedTools(t *testing.T) {
+	planner := &watchingPlanner{scriptedPlanner: scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "jira", Action: "search_issues"}, {Name: "jira", Action: "create_issue"}}},
+	}}}
+	executor := &keyRecordingExecutor{keys: map[string]string{}}
+	traces := NewMemoryTraceStore(0)
+	engine, err := NewEngine(Config{
+		Planner: planner,
+		LLM:     &staticLLM{text: "done"},
+		Tools: &degradedTools{
+			staticTools: staticTools{tools: []ToolDef{{Name: "jira", Actions: []ToolAction{{Name: "search_issues"}, {Name: "create_issue"}}}}},
+			degraded:    []DegradedTool{{Name: "pagerduty", Reason: "5 consecutive failures"}},
+		},
+		Executor: executor,
+		Context:  plainAssembler{},
+		Traces:   traces,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	resp, err := engine.Run(context.Background(), Request{Query: "file a bug"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if executor.keys["search_issues"] != "" || executor.keys["create_issue"] == "" {
+		t.Fatalf("expected only the write keyed, got %+v", executor.keys)
+	}
+	if !strings.Contains(planner.prompts[0], "- pagerduty: 5 consecutive failures") {
+		t.Fatalf("expected degraded tools in the prompt, got %q", planner.prompts[0])
+	}
+	trace, _ := traces.GetTrace(context.Background(), resp.Trace.ID)
+	found := false
+	for _, ev := range trace.Events {
+		found = found || ev.Name == "tools.degraded"
+	}
+	if !found {
+		t.Fatalf("expected tools.degraded trace event, got %+v", trace.Events)
+	}
+}
+
+// scopeExecutor records the scope of each call and reports a cache hit.
+type scopeExecutor struct {
+	scopes []ToolCallScope
+}
+
+func (s *scopeExecutor) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	scope, _ := ToolCallScopeFrom(ctx)
+	s.scopes = append(s.scopes, scope)
+	scope.ReportCache(CacheHit)
+	return &ToolResult{Success: true}, nil
+}
+
+func TestRunScopesToolCallsAndTracesCacheUse(t *testing.T) {
+	executor := &scopeExecutor{}
+	engine, err := NewEngine(Config{
+		Planner: &scriptedPlanner{plans: []Plan{{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "jira", Action: "search"}}}}},
+		LLM:     &staticLLM{text: "done"},
+		Tools: &staticTools{tools: []ToolDef{
+			{Name: "jira", Actions: []ToolAction{{Name: "search", Access: AccessRead}}},
+		}},
+		Executor: executor,
+		Context:  plainAssembler{},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	resp, err := engine.Run(context.Background(), Request{Query: "bugs", SessionID: "s1", UserID: "u1", ProjectID: "p1"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(executor.scopes) != 1 {
+		t.Fatalf("expected one call, got %d", len(executor.scopes))
+	}
+	if scope := executor.scopes[0]; scope.SessionID != "s1" || scope.UserID != "u1" || scope.ProjectID != "p1" || scope.Access != AccessRead {
+		t.Fatalf("unexpected scope: %+v", scope)
+	}
+	cache := traceEvents(resp.Trace, "tool.cache")
+	if len(cache) != 1 || cache[0].Attrs["status"] != "hit" || cache[0].Attrs["tool"] != "jira" {
+		t.Fatalf("unexpected tool.cache events: %+v", cache)
+	}
+}
+
+// versionedAssembler reports the prompt template version it rendered.
+type versionedAssembler struct {
+	plainAssembler
+}
+
+func (versionedAssembler) Build(ctx context.Context, req Request, tools []ToolDef) (string, error) {
+	ReportPromptVersion(ctx, "p1@v2")
+	return req.Query, nil
+}
+
+func TestRunRecordsPromptVersion(t *testing.T) {
+	engine, err := NewEngine(Config{
+		Planner:  &scriptedPlanner{},
+		LLM:      &staticLLM{text: "done"},
+		Tools:    &staticTools{},
+		Executor: echoExecutor{},
+		Context:  versionedAssembler{},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	resp, err := engine.Run(context.Background(), Request{Query: "hello"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if resp.Trace.PromptVersion != "p1@v2" {
+		t.Fatalf("expected the prompt version on the trace, got %q", resp.Trace.PromptVersion)
+	}
+	if built := traceEvents(resp.Trace, "prompt.built"); len(built) != 1 || built[0].Attrs["template"] != "p1@v2" {
+		t.Fatalf("unexpected prompt.built events: %+v", built)
+	}
+}
+
+func TestResumedRunKeepsCallCounts(t *testing.T) {
+	policy := &recordingPolicy{}
+	engine, err := NewEngine(Config{
+		Planner: &scriptedPlanner{plans: []Plan{
+			{Type: PlanToolCalls, ToolCalls: []ToolCall{
+				{Name: "jira", Action: "search_issues"},
+				{Name: "jira", Action: "create_issue"},
+			}},
+			{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "jira", Action: "search_issues"}}},
+		}},
+		LLM: &staticLLM{text: "done"},
+		Tools: &staticTools{tools: []ToolDef{
+			{Name: "jira", Actions: []ToolAction{{Name: "search_issues"}, {Name: "create_issue"}}},
+		}},
+		Executor: echoExecutor{},
+		Context:  plainAssembler{},
+		Policy:   policy,
+		Runs:     NewMemoryRunStore(),
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	resp, err := engine.Run(context.Background(), Request{Query: "file a bug"})
+	if err != nil || resp.Status != RunAwaitingApproval {
+		t.Fatalf("expected the create awaiting approval, got %+v, %v", resp, err)
+	}
+	resumed, err := engine.Decide(context.Background(), RunOwner{}, resp.RunID, []ApprovalDecision{{CallID: resp.PendingCalls[0].ID, Approved: true}})
+	if err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+	if last := resumed.Observations[len(resumed.Observations)-1]; last.Error != "tool blocked by policy: budget exhausted" {
+		t.Fatalf("expected the second search counted against the run, got %+v", last)
+	}
+}
@@ -0,0 +1,33 @@
+// Package agentengine defines run events emitted while the agent loop progresses.
+package agentengine
+
+import "time"
+
+// EventType identifies a run event.
+type EventType string
+
+const (
+	EventPlan             EventType = "plan"
+	EventToolCallStarted  EventType = "tool_call_started"
+	EventToolCallFinished EventType = "tool_call_finished"
+	EventToken            EventType = "token"
+	EventApprovalRequired EventType = "approval_required"
+	EventFinal            EventType = "final"
+)
+
+// Event is emitted by Engine.RunStream as the run progresses.
+type Event struct {
+	Type         EventType
+	Step         int
+	Plan         *Plan         // EventPlan
+	ToolCall     *ToolCall     // EventToolCallStarted, EventToolCallFinished
+	Observation  *Observation  // EventToolCallFinished
+	Delta        string        // EventToken
+	PendingCalls []PendingCall // EventApprovalRequired
+	Response     *Response     // EventFinal
+	Duration     time.Duration
+	At           time.Time
+}
+
+// EventHandler receives run events. Returning an error aborts the run.
+type EventHandler func(Event) error
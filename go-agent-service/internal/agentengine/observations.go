@@ -0,0 +1,320 @@
+package agentengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ObservationTool lets the planner read slices of results that were shortened
+// to fit the prompt. It is offered once a run has shortened an observation.
+const (
+	ObservationTool   = "observation"
+	ObservationAction = "read_slice"
+)
+
+const observationSchema = `{
+	"type": "object",
+	"properties": {
+		"id": {"type": "string", "description": "Observation ID, e.g. obs2"},
+		"path": {"type": "string", "description": "Dot-separated path into the result data, e.g. records or records.3.fields; empty for the whole result"},
+		"offset": {"type": "integer", "description": "First array item to return"},
+		"limit": {"type": "integer", "description": "Number of array items to return"}
+	},
+	"required": ["id"]
+}`
+
+const (
+	defaultObservationTokens = 1000
+	defaultObservationItems  = 20
+	maxObservationString     = 2000
+	minObservationString     = 80
+	// summaryInputFactor bounds the raw result sent for summarization, in multiples of the budget.
+	summaryInputFactor = 8
+)
+
+// estimateTokens approximates the token count of text at four characters per token.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// observationTool describes the slice reader.
+func observationTool() ToolDef {
+	return ToolDef{
+		Name:        ObservationTool,
+		Description: "Read part of an earlier tool result that was shortened in the prompt",
+		Actions: []ToolAction{{
+			Name:        ObservationAction,
+			Description: "Return the data at a path of an observation, optionally a range of array items",
+			InputSchema: observationSchema,
+			Access:      AccessRead,
+		}},
+	}
+}
+
+// nextObservationID numbers observations in the order the run produced them.
+func (s *runState) nextObservationID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observed++
+	return "obs" + strconv.Itoa(s.observed)
+}
+
+// remember keeps raw observations addressable by ID for the rest of the run, for
+// the slice reader and for tracing args back to flagged output. Observations are
+// remembered as they are produced, whether or not the prompt shapes them.
+func (s *runState) remember(observations []Observation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, obs := range observations {
+		if obs.ID != "" {
+			s.raw[obs.ID] = obs
+		}
+	}
+}
+
+// promptObservations returns the observations as the planner and LLM see them:
+// results over the token budget are truncated or summarized, and the slice
+// reader is offered to the planner. Raw observations are left untouched.
+func (e *Engine) promptObservations(ctx context.Context, state *runState, step int, observations []Observation) []Observation {
+	if e.observationTokens < 0 || len(observations) == 0 {
+		return observations
+	}
+	out := make([]Observation, len(observations))
+	for i, obs := range observations {
+		state.mu.Lock()
+		shaped, ok := state.shaped[obs.ID]
+		state.mu.Unlock()
+		if !ok {
+			shaped = e.shapeObservation(ctx, state, step, obs)
+			if obs.ID != "" {
+				state.mu.Lock()
+				state.shaped[obs.ID] = shaped
+				state.mu.Unlock()
+			}
+		}
+		out[i] = shaped
+		if shaped.Note != "" && obs.ID != "" {
+			state.offerObservationTool()
+		}
+	}
+	return out
+}
+
+// offerObservationTool adds the slice reader to the run's tools once.
+func (s *runState) offerObservationTool() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := findTool(s.tools, ObservationTool); ok {
+		return
+	}
+	s.tools = append(s.tools[:len(s.tools):len(s.tools)], observationTool())
+}
+
+// shapeObservation fits one observation into the token budget.
+func (e *Engine) shapeObservation(ctx context.Context, state *runState, step int, obs Observation) Observation {
+	if obs.Result == nil {
+		return obs
+	}
+	data := normalizeJSON(obs.Result.Data)
+	raw, _ := json.Marshal(data)
+	budget := e.observationTokens
+	message := truncateString(obs.Result.Message, budget*4)
+	if estimateTokens(string(raw)) <= budget && message == obs.Result.Message {
+		return obs
+	}
+
+	result := *obs.Result
+	result.Message = message
+	shaped := obs
+	shaped.Result = &result
+	ref := "the " + ObservationTool + "." + ObservationAction + " tool"
+	if obs.ID != "" {
+		ref = fmt.Sprintf("%s with id %q", ref, obs.ID)
+	}
+
+	if e.summarizeObservations {
+		if summary, ok := e.summarizeObservation(ctx, state, step, obs, raw); ok {
+			result.Data = map[string]any{"summary": summary}
+			shaped.Note = fmt.Sprintf("Result of %d tokens summarized; read exact values with %s.", estimateTokens(string(raw)), ref)
+			return shaped
+		}
+	}
+
+	fitted, tokens := fitData(data, budget, e.observationItems)
+	if m, ok := fitted.(map[string]any); ok {
+		result.Data = m
+	} else {
+		result.Data = map[string]any{"value": fitted}
+	}
+	shaped.Note = fmt.Sprintf("Result truncated from %d to %d tokens; read omitted items with %s.", estimateTokens(string(raw)), tokens, ref)
+	state.trace.Record(TraceEvent{Name: "observation.truncated", Step: step, Attrs: map[string]any{
+		"id": obs.ID, "tool": obs.ToolName, "tokens": estimateTokens(string(raw)), "shapedTokens": tokens,
+	}})
+	return shaped
+}
+
+// summarizeObservation asks the LLM to condense a raw result.
+func (e *Engine) summarizeObservation(ctx context.Context, state *runState, step int, obs Observation, raw []byte) (string, bool) {
+	input := truncateString(string(raw), e.observationTokens*4*summaryInputFactor)
+	started := e.clock()
+	reply, err := e.llm.Respond(ctx, LLMRequest{
+		Query: "Summarize a tool result",
+		Prompt: state.redact(fmt.Sprintf("Summarize this result of the %s tool in at most %d words for an agent answering %q. "+
+			"Keep identifiers, counts, statuses and any values relevant to the question.\n\n%s",
+			obs.ToolName, e.observationTokens/2, state.req.Query, input)),
+		Provider: state.req.Provider,
+		Model:    state.req.Model,
+	})
+	attrs := map[string]any{"id": obs.ID, "tool": obs.ToolName, "tokens": estimateTokens(string(raw))}
+	ev := TraceEvent{Name: "observation.summarized", Step: step, Duration: e.clock().Sub(started), Attrs: attrs}
+	if err != nil {
+		ev.Detail = err.Error()
+		state.trace.Record(ev)
+		return "", false
+	}
+	addUsageAttrs(attrs, reply.Usage)
+	state.addUsage(reply.Usage)
+	state.trace.Record(ev)
+	state.noteUnpriced(step, reply.Provider, reply.Model, reply.Usage)
+	summary := strings.TrimSpace(reply.Text)
+	return summary, summary != ""
+}
+
+// readObservation serves a slice of a remembered raw observation.
+func (e *Engine) readObservation(state *runState, call ToolCall) Observation {
+	id, _ := call.Args["id"].(string)
+	state.mu.Lock()
+	obs, ok := state.raw[id]
+	state.mu.Unlock()
+	if !ok {
+		return Observation{ToolName: ObservationTool, Error: fmt.Sprintf("unknown observation %q", id)}
+	}
+	if obs.Result == nil {
+		return Observation{ToolName: ObservationTool, Error: fmt.Sprintf("observation %s has no result data", id)}
+	}
+
+	path, _ := call.Args["path"].(string)
+	value, err := lookupPath(normalizeJSON(obs.Result.Data), path)
+	if err != nil {
+		return Observation{ToolName: ObservationTool, Error: err.Error()}
+	}
+	data := map[string]any{"id": id, "path": path}
+	if items, ok := value.([]any); ok {
+		offset := max(intArg(call.Args["offset"]), 0)
+		limit := intArg(call.Args["limit"])
+		if limit <= 0 {
+			limit = e.observationItems
+		}
+		offset = min(offset, len(items))
+		limit = min(limit, len(items)-offset)
+		data["total"] = len(items)
+		data["offset"] = offset
+		value = items[offset : offset+limit]
+	}
+	data["value"] = value
+	return Observation{ToolName: ObservationTool, Result: &ToolResult{Success: true, Data: data}}
+}
+
+// fitData shrinks arrays and strings until the data fits the token budget or
+// cannot shrink further. It returns the data and its estimated tokens.
+func fitData(data any, budget, items int) (any, int) {
+	chars := maxObservationString
+	for {
+		shaped := truncateData(data, items, chars)
+		raw, _ := json.Marshal(shaped)
+		tokens := estimateTokens(string(raw))
+		if tokens <= budget || (items <= 1 && chars <= minObservationString) {
+			return shaped, tokens
+		}
+		items = max(items/2, 1)
+		chars = max(chars/2, minObservationString)
+	}
+}
+
+// truncateData keeps the first items of each array, noting how many were left
+// out, and cuts strings longer than chars.
+func truncateData(data any, items, chars int) any {
+	switch v := data.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			out[key] = truncateData(value, items, chars)
+		}
+		return out
+	case []any:
+		n := min(len(v), items)
+		out := make([]any, 0, n+1)
+		for _, value := range v[:n] {
+			out = append(out, truncateData(value, items, chars))
+		}
+		if len(v) > n {
+			out = append(out, fmt.Sprintf("... %d more items", len(v)-n))
+		}
+		return out
+	case string:
+		return truncateString(v, chars)
+	default:
+		return v
+	}
+}
+
+// truncateString cuts s to at most limit bytes on a rune boundary, noting the cut.
+func truncateString(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... (%d more chars)", s[:cut], len(s)-cut)
+}
+
+// lookupPath follows a dot-separated path of keys and array indexes.
+func lookupPath(data any, path string) (any, error) {
+	if path == "" {
+		return data, nil
+	}
+	current := data
+	for _, part := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]any:
+			next, ok := v[part]
+			if !ok {
+				return nil, fmt.Errorf("path %s: no key %q", path, part)
+			}
+			current = next
+		case []any:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("path %s: index %q out of range (%d items)", path, part, len(v))
+			}
+			current = v[index]
+		default:
+			return nil, fmt.Errorf("path %s: %q is not an object or array", path, part)
+		}
+	}
+	return current, nil
+}
+
+func intArg(value any) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return int(n)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,126 @@
+package agentengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// recordsExecutor returns n records for any call.
+type recordsExecutor struct {
+	n int
+}
+
+func (e recordsExecutor) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	records := make([]map[string]any, e.n)
+	for i := range records {
+		records[i] = map[string]any{"id": fmt.Sprintf("REC-%d", i), "body": strings.Repeat("x", 200)}
+	}
+	return &ToolResult{Success: true, Data: map[string]any{"records": records}}, nil
+}
+
+// observationPlanner records the observations and tools the planner sees.
+type observationPlanner struct {
+	scriptedPlanner
+	seen  [][]Observation
+	tools [][]ToolDef
+}
+
+func (p *observationPlanner) Plan(ctx context.Context, input PlanInput) (Plan, error) {
+	p.seen = append(p.seen, input.Observations)
+	p.tools = append(p.tools, input.Tools)
+	return p.scriptedPlanner.Plan(ctx, input)
+}
+
+func observationTestEngine(t *testing.T, planner Planner, llm LLMClient, summarize bool) *Engine {
+	t.Helper()
+	engine, err := NewEngine(Config{
+		Planner:               planner,
+		LLM:                   llm,
+		Tools:                 &staticTools{tools: []ToolDef{{Name: "ucl", Actions: []ToolAction{{Name: "read_data"}}}}},
+		Executor:              recordsExecutor{n: 50},
+		Context:               plainAssembler{},
+		ObservationTokens:     300,
+		ObservationItems:      10,
+		SummarizeObservations: summarize,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestOversizedObservationsAreTruncatedAndSliceable(t *testing.T) {
+	planner := &observationPlanner{scriptedPlanner: scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "ucl", Action: "read_data"}}},
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: ObservationTool, Action: ObservationAction, Args: map[string]any{
+			"id": "obs1", "path": "records", "offset": 40, "limit": 2,
+		}}}},
+	}}}
+	engine := observationTestEngine(t, planner, &staticLLM{text: "done"}, false)
+
+	resp, err := engine.Run(context.Background(), Request{Query: "read the records"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	shaped := planner.seen[1][0]
+	payload, _ := json.Marshal(shaped.Result.Data)
+	if shaped.ID != "obs1" || shaped.Note == "" || estimateTokens(string(payload)) > 300 {
+		t.Fatalf("expected obs1 truncated to the budget, got %s (%s)", payload, shaped.Note)
+	}
+	records := shaped.Result.Data["records"].([]any)
+	if more, _ := records[len(records)-1].(string); !strings.HasSuffix(more, "more items") {
+		t.Fatalf("expected a note about omitted items, got %v", records[len(records)-1])
+	}
+	if _, ok := findTool(planner.tools[1], ObservationTool); !ok {
+		t.Fatalf("expected the slice reader to be offered after truncation")
+	}
+
+	// The raw payload stays intact and the slice reader serves the omitted items
+	if raw := resp.Observations[0].Result.Data["records"].([]map[string]any); len(raw) != 50 {
+		t.Fatalf("expected raw observation kept, got %d records", len(raw))
+	}
+	slice := resp.Observations[1]
+	value, _ := slice.Result.Data["value"].([]any)
+	if slice.ID != "obs2" || len(value) != 2 || value[0].(map[string]any)["id"] != "REC-40" || slice.Result.Data["total"] != 50 {
+		t.Fatalf("unexpected slice: %+v", slice.Result)
+	}
+}
+
+func TestOversizedObservationsCanBeSummarized(t *testing.T) {
+	planner := &observationPlanner{scriptedPlanner: scriptedPlanner{plans: []Plan{
+		{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "ucl", Action: "read_data"}}},
+	}}}
+	llm := &staticLLM{text: "50 records REC-0 to REC-49", usage: Usage{TotalTokens: 7}}
+	engine := observationTestEngine(t, planner, llm, true)
+
+	resp, err := engine.Run(context.Background(), Request{Query: "read the records"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	shaped := planner.seen[1][0]
+	if shaped.Result.Data["summary"] != "50 records REC-0 to REC-49" || !strings.Contains(shaped.Note, "obs1") {
+		t.Fatalf("expected summarized observation, got %+v (%s)", shaped.Result.Data, shaped.Note)
+	}
+	if resp.Usage.TotalTokens != 14 {
+		t.Fatalf("expected summary usage counted, got %+v", resp.Usage)
+	}
+}
+
+func TestObservationSliceClampsHugeLimits(t *testing.T) {
+	engine := observationTestEngine(t, &scriptedPlanner{}, &staticLLM{text: "done"}, false)
+	state := newRunState(Request{}, nil, newTrace(Request{}), false)
+	result, _ := recordsExecutor{n: 50}.Execute(context.Background(), ToolCall{})
+	state.remember([]Observation{{ID: "obs1", Result: result}})
+
+	slice := engine.readObservation(state, ToolCall{Args: map[string]any{
+		"id": "obs1", "path": "records", "offset": 45, "limit": math.MaxInt,
+	}})
+	if value, _ := slice.Result.Data["value"].([]any); len(value) != 5 {
+		t.Fatalf("expected the last 5 records, got %+v", slice)
+	}
+}
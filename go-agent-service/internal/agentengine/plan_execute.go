@@ -0,0 +1,387 @@
+package agentengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// stepRefPattern matches references to earlier step results in plan step args.
+var stepRefPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)(?:\.([^{}\s]+))?\s*\}\}`)
+
+// planStepOutcome is how a plan step ended, as traced on plan.step events.
+type planStepOutcome string
+
+const (
+	stepExecuted planStepOutcome = "executed"
+	stepFailed   planStepOutcome = "failed"
+	stepSkipped  planStepOutcome = "skipped"
+	stepPending  planStepOutcome = "pending"
+)
+
+// planResult is what executing a multi-step plan produced.
+type planResult struct {
+	observations []Observation
+	pending      []PendingCall
+	// replan explains why the planner must look again; empty when every step succeeded.
+	replan string
+}
+
+// executePlan runs the steps of a multi-step plan in dependency order. Steps
+// whose dependencies are done run together, as the calls of one ReAct step
+// would. A failed step, or an argument that references data its dependency did
+// not return, stops the plan for re-planning; steps that were not run are traced
+// as skipped. A step that needs approval pauses the run, and the planner plans
+// the remaining work after it resumes.
+func (e *Engine) executePlan(ctx context.Context, state *runState, step int, steps []PlanStep, emit func(Event) error) (planResult, error) {
+	var result planResult
+	if err := validatePlanSteps(steps); err != nil {
+		state.trace.Record(TraceEvent{Name: "plan.invalid", Step: step, Detail: err.Error()})
+		result.observations = []Observation{{ID: state.nextObservationID(), ToolName: "plan", Error: "invalid plan: " + err.Error()}}
+		result.replan = err.Error()
+		state.remember(result.observations)
+		return result, nil
+	}
+
+	outputs := make(map[string]any, len(steps))
+	done := make(map[string]bool, len(steps))
+	// flagged maps steps whose output was flagged as possible prompt injection to
+	// its observation; steps referencing them are tainted however the data is used.
+	flagged := make(map[string]string, len(steps))
+	for len(done) < len(steps) && result.replan == "" {
+		var wave []PlanStep
+		var calls []ToolCall
+		var tainted [][]string
+		for _, planStep := range steps {
+			if done[planStep.ID] || !dependenciesDone(planStep, done) {
+				continue
+			}
+			call := planStep.Call
+			args, err := resolveStepArgs(call.Args, outputs)
+			if err != nil {
+				done[planStep.ID] = true
+				e.recordPlanStep(state, step, planStep, stepFailed, "", err.Error())
+				result.replan = fmt.Sprintf("step %s: %s", planStep.ID, err.Error())
+				continue
+			}
+			var ids []string
+			for _, ref := range stepRefs(planStep.Call.Args) {
+				if id, ok := flagged[ref]; ok && !slices.Contains(ids, id) {
+					ids = append(ids, id)
+				}
+			}
+			call.Args = args
+			wave = append(wave, planStep)
+			calls = append(calls, call)
+			tainted = append(tainted, ids)
+		}
+		if len(calls) == 0 {
+			break
+		}
+
+		observations, pending, err := e.executeCalls(ctx, state, step, calls, tainted, emit)
+		if err != nil {
+			return planResult{}, err
+		}
+		result.observations = append(result.observations, observations...)
+		if len(pending) > 0 {
+			for _, planStep := range wave {
+				done[planStep.ID] = true
+				e.recordPlanStep(state, step, planStep, stepPending, "", "")
+			}
+			result.pending = pending
+			break
+		}
+
+		for i, planStep := range wave {
+			done[planStep.ID] = true
+			obs := observations[i]
+			if len(obs.Injection) > 0 {
+				flagged[planStep.ID] = obs.ID
+			}
+			if failure := stepFailure(obs); failure != "" {
+				e.recordPlanStep(state, step, planStep, stepFailed, obs.ID, failure)
+				result.replan = fmt.Sprintf("step %s (%s) failed: %s", planStep.ID, planStep.Call.Name, failure)
+				continue
+			}
+			outputs[planStep.ID] = normalizeJSON(obs.Result.Data)
+			e.recordPlanStep(state, step, planStep, stepExecuted, obs.ID, "")
+		}
+	}
+
+	for _, planStep := range steps {
+		if !done[planStep.ID] {
+			e.recordPlanStep(state, step, planStep, stepSkipped, "", "")
+		}
+	}
+	if result.replan != "" {
+		state.trace.Record(TraceEvent{Name: "plan.replan", Step: step, Detail: result.replan})
+	}
+	return result, nil
+}
+
+func (e *Engine) recordPlanStep(state *runState, step int, planStep PlanStep, outcome planStepOutcome, observationID, detail string) {
+	attrs := map[string]any{
+		"id":      planStep.ID,
+		"tool":    planStep.Call.Name,
+		"action":  planStep.Call.Action,
+		"outcome": string(outcome),
+	}
+	if len(planStep.DependsOn) > 0 {
+		attrs["dependsOn"] = planStep.DependsOn
+	}
+	if observationID != "" {
+		attrs["observation"] = observationID
+	}
+	state.trace.Record(TraceEvent{Name: "plan.step", Step: step, Detail: detail, Attrs: attrs})
+}
+
+// stepFailure describes why a step's observation does not count as a result, if it does not.
+func stepFailure(obs Observation) string {
+	switch {
+	case obs.Error != "":
+		return obs.Error
+	case obs.Result == nil:
+		return "no result"
+	case !obs.Result.Success:
+		if obs.Result.Message != "" {
+			return obs.Result.Message
+		}
+		return "tool reported failure"
+	}
+	return ""
+}
+
+func dependenciesDone(planStep PlanStep, done map[string]bool) bool {
+	for _, dep := range planStep.DependsOn {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// validatePlanSteps checks that step IDs are unique, dependencies name other
+// steps, references name dependencies, and the steps have no cycle.
+func validatePlanSteps(steps []PlanStep) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("plan has no steps")
+	}
+	byID := make(map[string]PlanStep, len(steps))
+	for _, planStep := range steps {
+		if planStep.ID == "" {
+			return fmt.Errorf("step %s.%s has no id", planStep.Call.Name, planStep.Call.Action)
+		}
+		if _, ok := byID[planStep.ID]; ok {
+			return fmt.Errorf("duplicate step id %s", planStep.ID)
+		}
+		byID[planStep.ID] = planStep
+	}
+	for _, planStep := range steps {
+		for _, dep := range planStep.DependsOn {
+			if _, ok := byID[dep]; !ok {
+				return fmt.Errorf("step %s depends on unknown step %s", planStep.ID, dep)
+			}
+		}
+		for _, ref := range stepRefs(planStep.Call.Args) {
+			if !slices.Contains(planStep.DependsOn, ref) {
+				return fmt.Errorf("step %s references %s without depending on it", planStep.ID, ref)
+			}
+		}
+	}
+
+	// Depth-first search for cycles: 1 = visiting, 2 = done
+	marks := make(map[string]int, len(steps))
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch marks[id] {
+		case 1:
+			return fmt.Errorf("steps depend on each other in a cycle through %s", id)
+		case 2:
+			return nil
+		}
+		marks[id] = 1
+		for _, dep := range byID[id].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		marks[id] = 2
+		return nil
+	}
+	for _, planStep := range steps {
+		if err := visit(planStep.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stepRefs lists the step IDs referenced by string args, at any depth.
+func stepRefs(value any) []string {
+	var refs []string
+	switch typed := value.(type) {
+	case string:
+		for _, match := range stepRefPattern.FindAllStringSubmatch(typed, -1) {
+			refs = append(refs, match[1])
+		}
+	case map[string]any:
+		for _, item := range typed {
+			refs = append(refs, stepRefs(item)...)
+		}
+	case []any:
+		for _, item := range typed {
+			refs = append(refs, stepRefs(item)...)
+		}
+	}
+	return refs
+}
+
+// resolveStepArgs replaces step references in args with the data of earlier steps.
+func resolveStepArgs(args map[string]any, outputs map[string]any) (map[string]any, error) {
+	if args == nil {
+		return nil, nil
+	}
+	resolved, err := resolveStepValue(args, outputs)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]any), nil
+}
+
+func resolveStepValue(value any, outputs map[string]any) (any, error) {
+	switch typed := value.(type) {
+	case string:
+		return resolveStepString(typed, outputs)
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for key, item := range typed {
+			resolved, err := resolveStepValue(item, outputs)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(typed))
+		for i, item := range typed {
+			resolved, err := resolveStepValue(item, outputs)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+func resolveStepString(s string, outputs map[string]any) (any, error) {
+	matches := stepRefPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	// A reference making up the whole string keeps the type of the data
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		return selectStepRef(s, matches[0], outputs)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range matches {
+		value, err := selectStepRef(s, match, outputs)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(s[last:match[0]])
+		if text, ok := value.(string); ok {
+			sb.WriteString(text)
+		} else {
+			raw, _ := json.Marshal(value)
+			sb.Write(raw)
+		}
+		last = match[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String(), nil
+}
+
+// selectStepRef reads the data a reference names. A * path segment maps the
+// rest of the path over an array. Missing or empty data is an error, since the
+// plan expected the dependency to return it.
+func selectStepRef(s string, match []int, outputs map[string]any) (any, error) {
+	id := s[match[2]:match[3]]
+	path := ""
+	if match[4] >= 0 {
+		path = s[match[4]:match[5]]
+	}
+	data, ok := outputs[id]
+	if !ok {
+		return nil, fmt.Errorf("%s has no result", id)
+	}
+
+	var value any
+	var err error
+	if before, after, wildcard := strings.Cut(path, "*"); wildcard {
+		value, err = selectEach(data, strings.TrimSuffix(before, "."), strings.TrimPrefix(after, "."))
+	} else {
+		value, err = lookupPath(data, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s returned no %s: %s", id, path, err.Error())
+	}
+	if isEmptyValue(value) {
+		return nil, fmt.Errorf("%s returned no %s", id, path)
+	}
+	return value, nil
+}
+
+// selectEach collects the value at rest of every item of the array at path,
+// skipping items that lack it.
+func selectEach(data any, path, rest string) (any, error) {
+	list, err := lookupPath(data, path)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := list.([]any)
+	if !ok {
+		return nil, fmt.Errorf("path %s is not an array", path)
+	}
+	values := make([]any, 0, len(items))
+	for _, item := range items {
+		if value, err := lookupPath(item, rest); err == nil {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+func isEmptyValue(value any) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case string:
+		return typed == ""
+	case []any:
+		return len(typed) == 0
+	case map[string]any:
+		return len(typed) == 0
+	}
+	return false
+}
+
+// readySteps reduces a multi-step plan to the calls of the steps that depend on
+// nothing, for runners that execute one round of calls per planner step.
+func readySteps(plan Plan) Plan {
+	calls := make([]ToolCall, 0, len(plan.Steps))
+	for _, planStep := range plan.Steps {
+		if len(planStep.DependsOn) == 0 {
+			calls = append(calls, planStep.Call)
+		}
+	}
+	return Plan{Type: PlanToolCalls, ToolCalls: calls, Usage: plan.Usage}
+}
@@ -0,0 +1,171 @@
+package agentengine
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// countingPlanner returns plans in order, then answers directly, and counts its calls.
+type countingPlanner struct {
+	plans []Plan
+	calls int
+}
+
+func (p *countingPlanner) Plan(ctx context.Context, input PlanInput) (Plan, error) {
+	p.calls++
+	if p.calls > len(p.plans) {
+		return Plan{Type: PlanDirect}, nil
+	}
+	return p.plans[p.calls-1], nil
+}
+
+// bugTracker finds bugs, fails on "fail" and records the calls it ran.
+type bugTracker struct {
+	mu    sync.Mutex
+	calls []ToolCall
+}
+
+func (b *bugTracker) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	b.mu.Lock()
+	b.calls = append(b.calls, call)
+	b.mu.Unlock()
+	switch call.Action {
+	case "bugs":
+		return &ToolResult{Success: true, Data: map[string]any{"issues": []any{
+			map[string]any{"key": "PROJ-1"},
+			map[string]any{"key": "PROJ-2"},
+		}}}, nil
+	case "fail":
+		return &ToolResult{Success: false, Message: "jira unavailable"}, nil
+	}
+	return &ToolResult{Success: true, Data: map[string]any{"prs": []any{"#7"}}}, nil
+}
+
+func (b *bugTracker) call(action string) (ToolCall, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, call := range b.calls {
+		if call.Action == action {
+			return call, true
+		}
+	}
+	return ToolCall{}, false
+}
+
+func planTestEngine(t *testing.T, planner Planner, executor ToolExecutor) *Engine {
+	t.Helper()
+	engine, err := NewEngine(Config{
+		Planner: planner,
+		LLM:     &staticLLM{text: "summary"},
+		Tools: &staticTools{tools: []ToolDef{
+			{Name: "jira", Actions: []ToolAction{{Name: "bugs", Access: AccessRead}, {Name: "fail", Access: AccessRead}}},
+			{Name: "github", Actions: []ToolAction{{Name: "prs", Access: AccessRead}}},
+		}},
+		Executor: executor,
+		Context:  plainAssembler{},
+		MaxSteps: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func traceEvents(trace *Trace, name string) []TraceEvent {
+	var events []TraceEvent
+	for _, ev := range trace.Events {
+		if ev.Name == name {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func TestPlanStepsFeedResultsForwardWithoutReplanning(t *testing.T) {
+	planner := &countingPlanner{plans: []Plan{{Type: PlanSteps, Steps: []PlanStep{
+		{ID: "s1", Call: ToolCall{Name: "jira", Action: "bugs"}},
+		{ID: "s2", DependsOn: []string{"s1"}, Call: ToolCall{Name: "github", Action: "prs", Args: map[string]any{
+			"issues": "{{s1.issues.*.key}}",
+			"query":  "fixes {{s1.issues.0.key}}",
+		}}},
+	}}}}
+	tracker := &bugTracker{}
+	resp, err := planTestEngine(t, planner, tracker).Run(context.Background(), Request{Query: "critical bugs and their PRs"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if planner.calls != 1 || resp.Text != "summary" || len(resp.Observations) != 2 {
+		t.Fatalf("expected one plan executed then answered, got %d plans and %+v", planner.calls, resp)
+	}
+	prs, ok := tracker.call("prs")
+	if !ok || !reflect.DeepEqual(prs.Args["issues"], []any{"PROJ-1", "PROJ-2"}) || prs.Args["query"] != "fixes PROJ-1" {
+		t.Fatalf("expected s2 args resolved from s1, got %+v", prs.Args)
+	}
+
+	steps := traceEvents(resp.Trace, "plan.step")
+	if len(steps) != 2 || steps[0].Attrs["outcome"] != "executed" || steps[1].Attrs["observation"] != "obs2" {
+		t.Fatalf("unexpected plan.step events: %+v", steps)
+	}
+	if plan := traceEvents(resp.Trace, "plan"); len(plan) != 1 || len(plan[0].Attrs["steps"].([]map[string]any)) != 2 {
+		t.Fatalf("expected the plan's steps on the plan event, got %+v", plan)
+	}
+}
+
+func TestPlanStepsReplanAfterFailure(t *testing.T) {
+	planner := &countingPlanner{plans: []Plan{{Type: PlanSteps, Steps: []PlanStep{
+		{ID: "s1", Call: ToolCall{Name: "jira", Action: "fail"}},
+		{ID: "s2", DependsOn: []string{"s1"}, Call: ToolCall{Name: "github", Action: "prs", Args: map[string]any{"issues": "{{s1.issues.*.key}}"}}},
+	}}}}
+	tracker := &bugTracker{}
+	resp, err := planTestEngine(t, planner, tracker).Run(context.Background(), Request{Query: "critical bugs and their PRs"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if planner.calls != 2 {
+		t.Fatalf("expected a re-plan after the failed step, got %d plans", planner.calls)
+	}
+	if _, ran := tracker.call("prs"); ran {
+		t.Fatalf("expected the dependent step skipped")
+	}
+	steps := traceEvents(resp.Trace, "plan.step")
+	if len(steps) != 2 || steps[0].Attrs["outcome"] != "failed" || steps[1].Attrs["outcome"] != "skipped" {
+		t.Fatalf("unexpected plan.step events: %+v", steps)
+	}
+	if replan := traceEvents(resp.Trace, "plan.replan"); len(replan) != 1 || replan[0].Detail != "step s1 (jira) failed: jira unavailable" {
+		t.Fatalf("unexpected plan.replan events: %+v", replan)
+	}
+}
+
+func TestPlanStepsRejectInvalidPlans(t *testing.T) {
+	cases := map[string][]PlanStep{
+		"cycle": {
+			{ID: "a", DependsOn: []string{"b"}, Call: ToolCall{Name: "jira", Action: "bugs"}},
+			{ID: "b", DependsOn: []string{"a"}, Call: ToolCall{Name: "jira", Action: "bugs"}},
+		},
+		"undeclared reference": {
+			{ID: "a", Call: ToolCall{Name: "jira", Action: "bugs"}},
+			{ID: "b", Call: ToolCall{Name: "github", Action: "prs", Args: map[string]any{"issues": "{{a.issues}}"}}},
+		},
+		"unknown dependency": {
+			{ID: "a", DependsOn: []string{"z"}, Call: ToolCall{Name: "jira", Action: "bugs"}},
+		},
+	}
+	for name, steps := range cases {
+		t.Run(name, func(t *testing.T) {
+			planner := &countingPlanner{plans: []Plan{{Type: PlanSteps, Steps: steps}}}
+			tracker := &bugTracker{}
+			resp, err := planTestEngine(t, planner, tracker).Run(context.Background(), Request{Query: "bugs"})
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if len(tracker.calls) != 0 || planner.calls != 2 {
+				t.Fatalf("expected no calls and a re-plan, got %d calls and %d plans", len(tracker.calls), planner.calls)
+			}
+			if len(resp.Observations) != 1 || resp.Observations[0].ToolName != "plan" {
+				t.Fatalf("expected the plan error as an observation, got %+v", resp.Observations)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package agentengine
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Source identifies a record a tool result came from, such as a knowledge
+// graph node, a dataset or a workflow execution.
+type Source struct {
+	ID    string
+	Kind  string // node, dataset, execution or issue
+	Title string `json:",omitempty"`
+	URL   string `json:",omitempty"`
+}
+
+// Citation is a source the answer cited, resolved to the observation that provided it.
+type Citation struct {
+	ID            string
+	Kind          string // A Source kind, or observation when the answer cited a whole observation
+	Title         string `json:",omitempty"`
+	URL           string `json:",omitempty"`
+	Tool          string
+	ObservationID string
+}
+
+// maxObservationSources bounds the sources kept per observation.
+const maxObservationSources = 50
+
+// sourceKeys maps the result and argument fields that hold source IDs to their kind.
+var sourceKeys = map[string]string{
+	"nodeId":       "node",
+	"node_id":      "node",
+	"sourceNodeId": "node",
+	"datasetId":    "dataset",
+	"dataset_id":   "dataset",
+	"executionId":  "execution",
+	"execution_id": "execution",
+	"key":          "issue",
+}
+
+var (
+	// issueKeyPattern matches tracker keys such as PROJ-123; other "key" fields are not sources.
+	issueKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]+-[0-9]+$`)
+	// citationPattern matches citation markers such as [PROJ-123] or [obs2].
+	citationPattern = regexp.MustCompile(`\[([A-Za-z0-9][A-Za-z0-9._:/-]{0,127})\]`)
+)
+
+const citationInstructions = "## Citations\n" +
+	"Cite the observations that support your answer with their source IDs or observation IDs in square brackets, " +
+	"e.g. [PROJ-123] or [obs2]. Only cite IDs that appear in the tool observations."
+
+// observationSources collects the sources of a tool result: IDs named by the
+// call's arguments and by fields of the result data, in the order found.
+func observationSources(call ToolCall, result *ToolResult) []Source {
+	var sources []Source
+	seen := make(map[string]bool)
+	add := func(source Source) {
+		if source.ID == "" || seen[source.ID] || len(sources) >= maxObservationSources {
+			return
+		}
+		seen[source.ID] = true
+		sources = append(sources, source)
+	}
+
+	collectSources(normalizeJSON(call.Args), add)
+	if result != nil && result.Success {
+		collectSources(normalizeJSON(result.Data), add)
+	}
+	return sources
+}
+
+// collectSources walks JSON data and reports every object field that holds a source ID,
+// with the object's title and URL.
+func collectSources(data any, add func(Source)) {
+	switch typed := data.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			kind, ok := sourceKeys[key]
+			id, isString := typed[key].(string)
+			if !ok || !isString || (kind == "issue" && !issueKeyPattern.MatchString(id)) {
+				continue
+			}
+			add(Source{ID: id, Kind: kind, Title: firstString(typed, "title", "label", "summary", "name"), URL: firstString(typed, "url")})
+		}
+		for _, key := range keys {
+			collectSources(typed[key], add)
+		}
+	case []any:
+		for _, item := range typed {
+			collectSources(item, add)
+		}
+	}
+}
+
+func firstString(obj map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := obj[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// citationPrompt asks for citations when the observations carry anything to cite.
+// Structured answers are bare JSON, so they are never asked to cite.
+func citationPrompt(prompt string, observations []Observation, structured bool) string {
+	if structured {
+		return prompt
+	}
+	for _, obs := range observations {
+		if obs.Error == "" && obs.Result != nil {
+			return prompt + "\n\n" + citationInstructions
+		}
+	}
+	return prompt
+}
+
+// resolveCitations matches the citation markers of an answer against the sources
+// and IDs of the run's observations. It returns the citations in order of first
+// mention, the markers that name nothing the run observed, and the text with
+// those markers' brackets removed, so they do not read as verified citations.
+func resolveCitations(text string, observations []Observation) ([]Citation, []string, string) {
+	known := make(map[string]Citation)
+	for _, obs := range observations {
+		if obs.Error != "" || obs.Result == nil {
+			continue
+		}
+		if obs.ID != "" {
+			known[obs.ID] = Citation{ID: obs.ID, Kind: "observation", Tool: obs.ToolName, ObservationID: obs.ID}
+		}
+		for _, source := range obs.Sources {
+			if _, ok := known[source.ID]; ok {
+				continue
+			}
+			known[source.ID] = Citation{
+				ID:            source.ID,
+				Kind:          source.Kind,
+				Title:         source.Title,
+				URL:           source.URL,
+				Tool:          obs.ToolName,
+				ObservationID: obs.ID,
+			}
+		}
+	}
+
+	var citations []Citation
+	var unknown []string
+	var cleaned strings.Builder
+	last := 0
+	seen := make(map[string]bool)
+	for _, match := range citationPattern.FindAllStringSubmatchIndex(text, -1) {
+		// Markdown links, [text](url), are not citations
+		if strings.HasPrefix(text[match[1]:], "(") {
+			continue
+		}
+		id := text[match[2]:match[3]]
+		citation, ok := known[id]
+		if !ok {
+			cleaned.WriteString(text[last:match[0]])
+			cleaned.WriteString(id)
+			last = match[1]
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if ok {
+			citations = append(citations, citation)
+		} else {
+			unknown = append(unknown, id)
+		}
+	}
+	cleaned.WriteString(text[last:])
+	return citations, unknown, cleaned.String()
+}
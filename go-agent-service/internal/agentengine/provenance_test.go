@@ -0,0 +1,84 @@
+package agentengine
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// brainHit mirrors nucleus.BrainSearchHit, a typed struct inside tool result data.
+type brainHit struct {
+	NodeID string `json:"nodeId"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// searchExecutor returns brain search hits and tickets.
+type searchExecutor struct{}
+
+func (searchExecutor) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	return &ToolResult{Success: true, Data: map[string]any{
+		"hits": []brainHit{{NodeID: "node-7", Title: "Login design", URL: "https://kb/node-7"}},
+		"issues": []any{
+			map[string]any{"key": "PROJ-1", "summary": "Login fails"},
+			map[string]any{"key": "not a ticket"},
+		},
+	}}, nil
+}
+
+func TestObservationSourcesCiteAndValidate(t *testing.T) {
+	llm := &replyingLLM{replies: []string{
+		"Login fails [PROJ-1], see the design [node-7] and [obs1]. Also [PROJ-9] and [docs](https://kb).",
+	}}
+	engine, err := NewEngine(Config{
+		Planner: &scriptedPlanner{plans: []Plan{{Type: PlanToolCalls, ToolCalls: []ToolCall{
+			{Name: "nucleus", Action: "brain_search", Args: map[string]any{"dataset_id": "ds-1"}},
+		}}}},
+		LLM:      llm,
+		Tools:    &staticTools{tools: []ToolDef{{Name: "nucleus", Actions: []ToolAction{{Name: "brain_search", Access: AccessRead}}}}},
+		Executor: searchExecutor{},
+		Context:  plainAssembler{},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	resp, err := engine.Run(context.Background(), Request{Query: "why does login fail?"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var ids []string
+	for _, source := range resp.Observations[0].Sources {
+		ids = append(ids, source.Kind+":"+source.ID)
+	}
+	if strings.Join(ids, ",") != "dataset:ds-1,node:node-7,issue:PROJ-1" {
+		t.Fatalf("unexpected sources: %v", ids)
+	}
+	if !strings.Contains(llm.requests[0].Prompt, citationInstructions) {
+		t.Fatalf("expected the answer prompt to ask for citations")
+	}
+
+	if len(resp.Citations) != 3 {
+		t.Fatalf("expected three citations, got %+v", resp.Citations)
+	}
+	node := resp.Citations[1]
+	if resp.Citations[0].ID != "PROJ-1" || node.ID != "node-7" || node.Title != "Login design" || node.URL != "https://kb/node-7" || node.ObservationID != "obs1" || node.Tool != "nucleus" {
+		t.Fatalf("unexpected citations: %+v", resp.Citations)
+	}
+	if resp.Citations[2].Kind != "observation" {
+		t.Fatalf("expected the observation ID cited as a whole observation, got %+v", resp.Citations[2])
+	}
+
+	var unknown []string
+	for _, ev := range resp.Trace.Events {
+		if ev.Name == "citations.unknown" {
+			unknown = ev.Attrs["ids"].([]string)
+		}
+	}
+	if len(unknown) != 1 || unknown[0] != "PROJ-9" {
+		t.Fatalf("expected only PROJ-9 traced as unknown, got %v", unknown)
+	}
+	if !strings.Contains(resp.Text, "Also PROJ-9 and [docs](https://kb).") || !strings.Contains(resp.Text, "[PROJ-1]") {
+		t.Fatalf("expected only the unknown marker unbracketed, got %q", resp.Text)
+	}
+}
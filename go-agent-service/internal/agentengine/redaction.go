@@ -0,0 +1,209 @@
+package agentengine
+
+import (
+	"context"
+	"strings"
+)
+
+// Redactor masks sensitive values in text sent to the LLM with placeholders,
+// which Restore turns back into the original values.
+type Redactor interface {
+	Redact(text string) string
+	Restore(text string) string
+	// Placeholders returns the masked values by placeholder, so a paused run can resume with them.
+	Placeholders() map[string]string
+}
+
+// Redaction decides how the text of a run is redacted before it leaves for the LLM.
+type Redaction interface {
+	// ForRun returns the redactor of a run, nil when its project does not redact.
+	// placeholders are the values a paused run had already masked.
+	ForRun(ctx context.Context, req Request, placeholders map[string]string) Redactor
+}
+
+// maxPlaceholderLen bounds how much streamed text is held back as a possible placeholder.
+const maxPlaceholderLen = 48
+
+// redactor returns the redactor of a run, or nil.
+func (e *Engine) redactor(ctx context.Context, req Request, placeholders map[string]string) Redactor {
+	if e.redaction == nil {
+		return nil
+	}
+	return e.redaction.ForRun(ctx, req, placeholders)
+}
+
+// appendObservations adds observations to the prompt, redacting what it appends.
+func (e *Engine) appendObservations(state *runState, prompt string, observations []Observation) (string, error) {
+	out, err := e.context.AppendObservations(prompt, observations)
+	if err != nil || state.redactor == nil {
+		return out, err
+	}
+	if appended, ok := strings.CutPrefix(out, prompt); ok {
+		return prompt + state.redactor.Redact(appended), nil
+	}
+	return state.redactor.Redact(out), nil
+}
+
+func (s *runState) redact(text string) string {
+	if s.redactor == nil {
+		return text
+	}
+	return s.redactor.Redact(text)
+}
+
+func (s *runState) restore(text string) string {
+	if s.redactor == nil {
+		return text
+	}
+	return s.redactor.Restore(text)
+}
+
+// placeholders returns the values the run masked so far, nil when it does not redact.
+func (s *runState) placeholders() map[string]string {
+	if s.redactor == nil {
+		return nil
+	}
+	return s.redactor.Placeholders()
+}
+
+// outbound returns the request as the LLM may see it: query and history redacted.
+func (s *runState) outbound() Request {
+	req := s.req
+	if s.redactor == nil {
+		return req
+	}
+	req.Query = s.redactor.Redact(req.Query)
+	req.History = make([]HistoryMessage, len(s.req.History))
+	for i, msg := range s.req.History {
+		req.History[i] = HistoryMessage{Role: msg.Role, Content: s.redactor.Redact(msg.Content)}
+	}
+	return req
+}
+
+// redactObservations copies observations with their text and result data redacted.
+func (s *runState) redactObservations(observations []Observation) []Observation {
+	if s.redactor == nil {
+		return observations
+	}
+	out := make([]Observation, len(observations))
+	for i, obs := range observations {
+		obs.Error = s.redactor.Redact(obs.Error)
+		obs.Note = s.redactor.Redact(obs.Note)
+		if obs.Result != nil {
+			result := *obs.Result
+			result.Message = s.redactor.Redact(result.Message)
+			if data, ok := mapStrings(result.Data, s.redactor.Redact).(map[string]any); ok {
+				result.Data = data
+			}
+			obs.Result = &result
+		}
+		out[i] = obs
+	}
+	return out
+}
+
+// restorePlan puts the values behind placeholders back into the plan's
+// clarification, so the user sees real values. Tool args only get back values
+// the user wrote in the request: a value masked in a tool result stays masked,
+// so a plan cannot carry a secret it read from one tool into another.
+func (s *runState) restorePlan(plan Plan) Plan {
+	if s.redactor == nil {
+		return plan
+	}
+	plan.Clarification = s.redactor.Restore(plan.Clarification)
+	restore := s.inputRestorer().Replace
+	if len(plan.ToolCalls) > 0 {
+		calls := make([]ToolCall, len(plan.ToolCalls))
+		for i, call := range plan.ToolCalls {
+			calls[i] = restoreCall(call, restore)
+		}
+		plan.ToolCalls = calls
+	}
+	if len(plan.Steps) > 0 {
+		steps := make([]PlanStep, len(plan.Steps))
+		for i, step := range plan.Steps {
+			step.Call = restoreCall(step.Call, restore)
+			steps[i] = step
+		}
+		plan.Steps = steps
+	}
+	return plan
+}
+
+// inputRestorer replaces the placeholders of values found in the query or the
+// user's history messages.
+func (s *runState) inputRestorer() *strings.Replacer {
+	var pairs []string
+	for placeholder, value := range s.redactor.Placeholders() {
+		if s.userWrote(value) {
+			pairs = append(pairs, placeholder, value)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func (s *runState) userWrote(value string) bool {
+	if strings.Contains(s.req.Query, value) {
+		return true
+	}
+	for _, msg := range s.req.History {
+		if msg.Role == "user" && strings.Contains(msg.Content, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func restoreCall(call ToolCall, restore func(string) string) ToolCall {
+	if args, ok := mapStrings(call.Args, restore).(map[string]any); ok {
+		call.Args = args
+	}
+	return call
+}
+
+// mapStrings copies JSON-like data with fn applied to every string in it.
+func mapStrings(value any, fn func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = mapStrings(item, fn)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = mapStrings(item, fn)
+		}
+		return out
+	}
+	return value
+}
+
+// restoreStream restores placeholders in streamed deltas. It holds back a
+// trailing "[" fragment that may be the start of a placeholder split across deltas.
+type restoreStream struct {
+	redactor Redactor
+	pending  string
+}
+
+func (s *restoreStream) next(delta string) string {
+	text := s.pending + delta
+	cut := len(text)
+	if i := strings.LastIndex(text, "["); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i <= maxPlaceholderLen {
+		cut = i
+	}
+	s.pending = text[cut:]
+	return s.redactor.Restore(text[:cut])
+}
+
+func (s *restoreStream) flush() string {
+	rest := s.pending
+	s.pending = ""
+	return s.redactor.Restore(rest)
+}
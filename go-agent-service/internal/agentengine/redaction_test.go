@@ -0,0 +1,181 @@
+package agentengine
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeRedaction masks one email address as [EMAIL_1].
+type fakeRedaction struct {
+	resumed map[string]string // Placeholders the last run resumed with
+}
+
+func (f *fakeRedaction) ForRun(ctx context.Context, req Request, placeholders map[string]string) Redactor {
+	f.resumed = placeholders
+	return &fakeRedactor{values: map[string]string{}}
+}
+
+type fakeRedactor struct {
+	values map[string]string
+}
+
+func (r *fakeRedactor) Redact(text string) string {
+	if !strings.Contains(text, "ana@example.com") {
+		return text
+	}
+	r.values["[EMAIL_1]"] = "ana@example.com"
+	return strings.ReplaceAll(text, "ana@example.com", "[EMAIL_1]")
+}
+
+func (r *fakeRedactor) Restore(text string) string {
+	return strings.ReplaceAll(text, "[EMAIL_1]", "ana@example.com")
+}
+
+func (r *fakeRedactor) Placeholders() map[string]string {
+	return r.values
+}
+
+// recordingPlanner plans one write with the placeholder it was given and records its input.
+type recordingPlanner struct {
+	inputs []PlanInput
+}
+
+func (p *recordingPlanner) Plan(ctx context.Context, input PlanInput) (Plan, error) {
+	p.inputs = append(p.inputs, input)
+	if input.Step > 1 {
+		return Plan{Type: PlanDirect}, nil
+	}
+	return Plan{Type: PlanToolCalls, ToolCalls: []ToolCall{
+		{Name: "mail", Action: "send_mail", Args: map[string]any{"to": []any{"[EMAIL_1]"}}},
+	}}, nil
+}
+
+type mailExecutor struct {
+	to []any
+}
+
+func (m *mailExecutor) Execute(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	m.to, _ = call.Args["to"].([]any)
+	return &ToolResult{Success: true, Message: "sent to ana@example.com"}, nil
+}
+
+// recordingStreamLLM streams a placeholder split across deltas and records its input.
+type recordingStreamLLM struct {
+	input LLMRequest
+}
+
+func (l *recordingStreamLLM) Respond(ctx context.Context, input LLMRequest) (LLMResponse, error) {
+	l.input = input
+	return LLMResponse{Text: "Mailed [EMAIL_1]."}, nil
+}
+
+func (l *recordingStreamLLM) RespondStream(ctx context.Context, input LLMRequest, onDelta func(delta string) error) (LLMResponse, error) {
+	l.input = input
+	deltas := []string{"Mailed [EM", "AIL_1", "]."}
+	for _, delta := range deltas {
+		if err := onDelta(delta); err != nil {
+			return LLMResponse{}, err
+		}
+	}
+	return LLMResponse{Text: strings.Join(deltas, "")}, nil
+}
+
+func TestRunRedactsLLMInputAndRestoresOutput(t *testing.T) {
+	planner := &recordingPlanner{}
+	llm := &recordingStreamLLM{}
+	executor := &mailExecutor{}
+	redaction := &fakeRedaction{}
+	engine, err := NewEngine(Config{
+		Planner:       planner,
+		LLM:           llm,
+		Tools:         &staticTools{tools: []ToolDef{{Name: "mail", Actions: []ToolAction{{Name: "send_mail"}}}}},
+		Executor:      executor,
+		Context:       plainAssembler{},
+		Runs:          NewMemoryRunStore(),
+		ApproveWrites: true,
+		Redaction:     redaction,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	ctx := context.Background()
+
+	resp, err := engine.Run(ctx, Request{Query: "email ana@example.com the report"})
+	if err != nil || resp.Status != RunAwaitingApproval {
+		t.Fatalf("expected a paused run, got %+v, %v", resp, err)
+	}
+	first := planner.inputs[0]
+	if first.Request.Query != "email [EMAIL_1] the report" || strings.Contains(first.Prompt, "ana@") {
+		t.Fatalf("expected the planner to see redacted text, got %q / %q", first.Request.Query, first.Prompt)
+	}
+	if resp.Trace.Query != first.Request.Query {
+		t.Fatalf("expected the traced query redacted, got %q", resp.Trace.Query)
+	}
+	if to := resp.PendingCalls[0].Call.Args["to"].([]any); to[0] != "ana@example.com" {
+		t.Fatalf("expected the pending call restored, got %v", to)
+	}
+
+	var deltas []string
+	resumed, err := engine.DecideStream(ctx, RunOwner{}, resp.RunID, []ApprovalDecision{{CallID: resp.PendingCalls[0].ID, Approved: true}}, func(ev Event) error {
+		if ev.Type == EventToken {
+			deltas = append(deltas, ev.Delta)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DecideStream: %v", err)
+	}
+	if redaction.resumed["[EMAIL_1]"] != "ana@example.com" {
+		t.Fatalf("expected the run to resume with its placeholders, got %v", redaction.resumed)
+	}
+	if executor.to[0] != "ana@example.com" {
+		t.Fatalf("expected the tool to get the real address, got %v", executor.to)
+	}
+	if strings.Contains(llm.input.Prompt+llm.input.Query+llm.input.Observations[0].Result.Message, "ana@") {
+		t.Fatalf("expected the answer request redacted, got %+v", llm.input)
+	}
+	if resumed.Text != "Mailed ana@example.com." || strings.Join(deltas, "") != resumed.Text {
+		t.Fatalf("expected the answer restored, got %q and deltas %q", resumed.Text, deltas)
+	}
+	if resumed.Observations[0].Result.Message != "sent to ana@example.com" {
+		t.Fatalf("expected observations returned unredacted, got %+v", resumed.Observations[0])
+	}
+}
+
+// lookupPlanner looks up an address, then mails whatever placeholder it was shown.
+type lookupPlanner struct{}
+
+func (lookupPlanner) Plan(ctx context.Context, input PlanInput) (Plan, error) {
+	switch input.Step {
+	case 1:
+		return Plan{Type: PlanToolCalls, ToolCalls: []ToolCall{{Name: "mail", Action: "lookup"}}}, nil
+	case 2:
+		return Plan{Type: PlanToolCalls, ToolCalls: []ToolCall{
+			{Name: "mail", Action: "send_mail", Args: map[string]any{"to": []any{"[EMAIL_1]"}}},
+		}}, nil
+	}
+	return Plan{Type: PlanDirect}, nil
+}
+
+func TestRunKeepsToolResultValuesMaskedInArgs(t *testing.T) {
+	executor := &mailExecutor{}
+	engine, err := NewEngine(Config{
+		Planner:   lookupPlanner{},
+		LLM:       &recordingStreamLLM{},
+		Tools:     &staticTools{tools: []ToolDef{{Name: "mail", Actions: []ToolAction{{Name: "lookup"}, {Name: "send_mail"}}}}},
+		Executor:  executor,
+		Context:   plainAssembler{},
+		Redaction: &fakeRedaction{},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	if _, err := engine.Run(context.Background(), Request{Query: "mail the report to the address on file"}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(executor.to) != 1 || executor.to[0] != "[EMAIL_1]" {
+		t.Fatalf("expected an address read from a tool to stay masked, got %v", executor.to)
+	}
+}
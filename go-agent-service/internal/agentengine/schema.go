@@ -0,0 +1,475 @@
+// Package agentengine validates tool arguments against JSON Schema.
+package agentengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// reservedArgs are injected by planners for tool routing and are exempt from schema checks.
+var reservedArgs = map[string]bool{
+	"userId":    true,
+	"projectId": true,
+}
+
+// maxSchemaErrors caps the number of violations reported back to the planner.
+const maxSchemaErrors = 8
+
+// ValidateArgs validates args against a JSON Schema document, coercing scalar
+// values where the schema makes the intent unambiguous (e.g. "50" for an integer).
+// It returns the coerced args and a list of violations, each prefixed with its path.
+func ValidateArgs(schema string, args map[string]any) (map[string]any, []string, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	if strings.TrimSpace(schema) == "" {
+		return args, nil, nil
+	}
+
+	var root map[string]any
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		return args, nil, fmt.Errorf("invalid input schema: %w", err)
+	}
+
+	v := &schemaValidator{}
+	coerced := v.validate("", root, normalizeJSON(args), true)
+	out, _ := coerced.(map[string]any)
+	if out == nil {
+		out = args
+	}
+	return out, v.errors, nil
+}
+
+// ValidateValue validates a decoded JSON value against a parsed JSON Schema,
+// coercing scalars like ValidateArgs. It returns the value and its violations.
+func ValidateValue(schema map[string]any, value any) (any, []string) {
+	v := &schemaValidator{subject: "response"}
+	out := v.validate("", schema, normalizeJSON(value), false)
+	return out, v.errors
+}
+
+type schemaValidator struct {
+	subject string // Names the root in violations; defaults to "arguments"
+	errors  []string
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	if len(v.errors) >= maxSchemaErrors {
+		return
+	}
+	if path == "" {
+		path = v.subject
+	}
+	if path == "" {
+		path = "arguments"
+	}
+	v.errors = append(v.errors, path+": "+fmt.Sprintf(format, args...))
+}
+
+// validate checks value against schema and returns the (possibly coerced) value.
+func (v *schemaValidator) validate(path string, schema map[string]any, value any, root bool) any {
+	if schema == nil {
+		return value
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		value = v.validateAnyOf(path, anyOf, value)
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		value = v.validateOneOf(path, oneOf, value)
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		var ok bool
+		value, ok = coerceType(value, types)
+		if !ok {
+			v.fail(path, "expected %s, got %s", strings.Join(types, " or "), describeValue(value))
+			return value
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		v.fail(path, "must be one of %s, got %s", formatEnum(enum), describeValue(value))
+	}
+	if constValue, ok := schema["const"]; ok && !reflect.DeepEqual(normalizeJSON(constValue), value) {
+		v.fail(path, "must equal %s", describeValue(constValue))
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		return v.validateObject(path, schema, typed, root)
+	case []any:
+		return v.validateArray(path, schema, typed)
+	case string:
+		v.validateString(path, schema, typed)
+	case float64:
+		v.validateNumber(path, schema, typed)
+	}
+	return value
+}
+
+func (v *schemaValidator) validateAnyOf(path string, options []any, value any) any {
+	for _, option := range options {
+		optionSchema, ok := option.(map[string]any)
+		if !ok {
+			continue
+		}
+		probe := &schemaValidator{}
+		coerced := probe.validate(path, optionSchema, value, false)
+		if len(probe.errors) == 0 {
+			return coerced
+		}
+	}
+	if len(options) > 0 {
+		v.fail(path, "does not match any allowed schema")
+	}
+	return value
+}
+
+// validateOneOf requires value to match exactly one of options.
+func (v *schemaValidator) validateOneOf(path string, options []any, value any) any {
+	matches := 0
+	result := value
+	for _, option := range options {
+		optionSchema, ok := option.(map[string]any)
+		if !ok {
+			continue
+		}
+		probe := &schemaValidator{}
+		coerced := probe.validate(path, optionSchema, value, false)
+		if len(probe.errors) == 0 {
+			if matches == 0 {
+				result = coerced
+			}
+			matches++
+		}
+	}
+	switch {
+	case matches > 1:
+		v.fail(path, "matches %d allowed schemas, expected exactly one", matches)
+	case matches == 0 && len(options) > 0:
+		v.fail(path, "does not match any allowed schema")
+	}
+	return result
+}
+
+func (v *schemaValidator) validateObject(path string, schema map[string]any, obj map[string]any, root bool) any {
+	props, _ := schema["properties"].(map[string]any)
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, item := range required {
+			name, ok := item.(string)
+			if !ok {
+				continue
+			}
+			if _, exists := obj[name]; !exists {
+				v.fail(joinPath(path, name), "is required")
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if root && reservedArgs[key] {
+			continue
+		}
+		if propSchema, ok := props[key].(map[string]any); ok {
+			obj[key] = v.validate(joinPath(path, key), propSchema, obj[key], false)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(joinPath(path, key), "is not an allowed property (allowed: %s)", propertyNames(props))
+			}
+		case map[string]any:
+			obj[key] = v.validate(joinPath(path, key), additional, obj[key], false)
+		}
+	}
+
+	if min, ok := schemaInt(schema, "minProperties"); ok && len(obj) < min {
+		v.fail(path, "must have at least %d properties", min)
+	}
+	if max, ok := schemaInt(schema, "maxProperties"); ok && len(obj) > max {
+		v.fail(path, "must have at most %d properties", max)
+	}
+	return obj
+}
+
+func (v *schemaValidator) validateArray(path string, schema map[string]any, arr []any) any {
+	if itemSchema, ok := schema["items"].(map[string]any); ok {
+		for i := range arr {
+			arr[i] = v.validate(fmt.Sprintf("%s[%d]", path, i), itemSchema, arr[i], false)
+		}
+	}
+	if min, ok := schemaInt(schema, "minItems"); ok && len(arr) < min {
+		v.fail(path, "must have at least %d items, got %d", min, len(arr))
+	}
+	if max, ok := schemaInt(schema, "maxItems"); ok && len(arr) > max {
+		v.fail(path, "must have at most %d items, got %d", max, len(arr))
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if reflect.DeepEqual(arr[i], arr[j]) {
+					v.fail(path, "items must be unique (duplicate at index %d)", j)
+					return arr
+				}
+			}
+		}
+	}
+	return arr
+}
+
+func (v *schemaValidator) validateString(path string, schema map[string]any, s string) {
+	length := utf8.RuneCountInString(s)
+	if min, ok := schemaInt(schema, "minLength"); ok && length < min {
+		v.fail(path, "must be at least %d characters", min)
+	}
+	if max, ok := schemaInt(schema, "maxLength"); ok && length > max {
+		v.fail(path, "must be at most %d characters", max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err == nil && !re.MatchString(s) {
+			v.fail(path, "must match pattern %s", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(path string, schema map[string]any, n float64) {
+	if min, ok := schema["minimum"].(float64); ok && n < min {
+		v.fail(path, "must be >= %s, got %s", formatNumber(min), formatNumber(n))
+	}
+	if max, ok := schema["maximum"].(float64); ok && n > max {
+		v.fail(path, "must be <= %s, got %s", formatNumber(max), formatNumber(n))
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+		v.fail(path, "must be > %s, got %s", formatNumber(min), formatNumber(n))
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+		v.fail(path, "must be < %s, got %s", formatNumber(max), formatNumber(n))
+	}
+	if multiple, ok := schema["multipleOf"].(float64); ok && multiple > 0 {
+		if q := n / multiple; math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(path, "must be a multiple of %s", formatNumber(multiple))
+		}
+	}
+}
+
+// coerceType returns value converted to one of the allowed types, or false when no
+// lossless conversion exists.
+func coerceType(value any, types []string) (any, bool) {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return value, true
+		}
+	}
+	for _, t := range types {
+		if coerced, ok := coerceScalar(value, t); ok {
+			return coerced, true
+		}
+	}
+	return value, false
+}
+
+func matchesType(value any, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func coerceScalar(value any, t string) (any, bool) {
+	switch t {
+	case "integer":
+		if s, ok := value.(string); ok {
+			if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+				return float64(n), true
+			}
+		}
+	case "number":
+		if s, ok := value.(string); ok {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+				return n, true
+			}
+		}
+	case "boolean":
+		if s, ok := value.(string); ok {
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case "true":
+				return true, true
+			case "false":
+				return false, true
+			}
+		}
+	case "string":
+		switch typed := value.(type) {
+		case float64:
+			return formatNumber(typed), true
+		case bool:
+			return strconv.FormatBool(typed), true
+		}
+	case "array":
+		// A JSON-encoded array passed as a string
+		if s, ok := value.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "[") {
+			var arr []any
+			if err := json.Unmarshal([]byte(s), &arr); err == nil {
+				return arr, true
+			}
+		}
+	case "object":
+		if s, ok := value.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "{") {
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(s), &obj); err == nil {
+				return obj, true
+			}
+		}
+	}
+	return value, false
+}
+
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func schemaInt(schema map[string]any, key string) (int, bool) {
+	n, ok := schema[key].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// normalizeJSON converts Go values produced by planners (int, []string, ...) into
+// their encoding/json equivalents so validation sees a single representation.
+func normalizeJSON(value any) any {
+	switch typed := value.(type) {
+	case nil, string, bool, float64:
+		return typed
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for k, item := range typed {
+			out[k] = normalizeJSON(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(typed))
+		for i, item := range typed {
+			out[i] = normalizeJSON(item)
+		}
+		return out
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var out any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return value
+	}
+	return out
+}
+
+func containsValue(list []any, value any) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(normalizeJSON(item), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeValue(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", typed)
+	case float64:
+		return "number " + formatNumber(typed)
+	case bool:
+		return "boolean " + strconv.FormatBool(typed)
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func formatEnum(enum []any) string {
+	parts := make([]string, 0, len(enum))
+	for _, item := range enum {
+		raw, _ := json.Marshal(item)
+		parts = append(parts, string(raw))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func propertyNames(props map[string]any) string {
+	if len(props) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,122 @@
+package agentengine
+
+import (
+	"strings"
+	"testing"
+)
+
+const searchSchema = `{
+	"type": "object",
+	"properties": {
+		"jql": {"type": "string", "minLength": 1},
+		"limit": {"type": "integer", "minimum": 1, "maximum": 100},
+		"includeClosed": {"type": "boolean"},
+		"status": {"type": "string", "enum": ["open", "closed"]},
+		"labels": {"type": "array", "items": {"type": "string"}, "maxItems": 3},
+		"filter": {
+			"type": "object",
+			"properties": {"assignee": {"type": "string"}},
+			"required": ["assignee"],
+			"additionalProperties": false
+		}
+	},
+	"required": ["jql"],
+	"additionalProperties": false
+}`
+
+func TestValidateArgsCoercesScalars(t *testing.T) {
+	args, violations, err := ValidateArgs(searchSchema, map[string]any{
+		"jql":           "project = MOBILE",
+		"limit":         "50",
+		"includeClosed": "false",
+		"labels":        []string{"bug", "p1"},
+		"userId":        "user-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("unexpected violations: %v", violations)
+	}
+	if args["limit"] != float64(50) || args["includeClosed"] != false {
+		t.Fatalf("expected coerced scalars, got %+v", args)
+	}
+	if args["userId"] != "user-1" {
+		t.Fatalf("expected reserved args to pass through, got %+v", args)
+	}
+}
+
+func TestValidateArgsReportsViolations(t *testing.T) {
+	_, violations, err := ValidateArgs(searchSchema, map[string]any{
+		"limit":  "lots",
+		"status": "pending",
+		"labels": []any{"a", "b", "c", "d"},
+		"filter": map[string]any{"team": "core"},
+		"extra":  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	joined := strings.Join(violations, "\n")
+	for _, want := range []string{
+		"jql: is required",
+		`limit: expected integer, got string "lots"`,
+		`status: must be one of ["open", "closed"], got string "pending"`,
+		"labels: must have at most 3 items, got 4",
+		"filter.assignee: is required",
+		"filter.team: is not an allowed property (allowed: assignee)",
+		"extra: is not an allowed property",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected violation %q, got:\n%s", want, joined)
+		}
+	}
+}
+
+func TestValidateArgsRangeAndInvalidSchema(t *testing.T) {
+	_, violations, err := ValidateArgs(searchSchema, map[string]any{"jql": "x", "limit": 500})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0] != "limit: must be <= 100, got 500" {
+		t.Fatalf("unexpected violations: %v", violations)
+	}
+
+	if _, _, err := ValidateArgs(`{"type": "object",`, map[string]any{}); err == nil {
+		t.Fatalf("expected invalid schema error")
+	}
+}
+
+func TestValidateToolCallReturnsObservationText(t *testing.T) {
+	tools := []ToolDef{{Name: "jira", Actions: []ToolAction{{Name: "search", InputSchema: searchSchema}}}}
+
+	call, msg := validateToolCall(ToolCall{Name: "jira", Action: "search", Args: map[string]any{"jql": "x", "limit": "10"}}, tools)
+	if msg != "" || call.Args["limit"] != float64(10) {
+		t.Fatalf("expected coerced valid call, got %+v (%s)", call, msg)
+	}
+
+	_, msg = validateToolCall(ToolCall{Name: "jira", Action: "search", Args: map[string]any{"limit": 0}}, tools)
+	if !strings.HasPrefix(msg, "invalid arguments for jira.search: ") || !strings.Contains(msg, "jql: is required") {
+		t.Fatalf("unexpected message: %s", msg)
+	}
+}
+
+func TestValidateArgsOneOfRequiresExactlyOneMatch(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"target": {"oneOf": [{"type": "string", "pattern": "^[A-Z]+-[0-9]+$"}, {"type": "string", "minLength": 3}]}
+		}
+	}`
+	args, violations, err := ValidateArgs(schema, map[string]any{"target": "ab"})
+	if err != nil || len(violations) != 1 || violations[0] != "target: does not match any allowed schema" {
+		t.Fatalf("expected no match reported, got %v, %v", violations, err)
+	}
+	if _, violations, _ = ValidateArgs(schema, map[string]any{"target": "MOBILE-1"}); len(violations) != 1 || violations[0] != "target: matches 2 allowed schemas, expected exactly one" {
+		t.Fatalf("expected an ambiguous match reported, got %v", violations)
+	}
+	if args, violations, _ = ValidateArgs(schema, map[string]any{"target": "mobile"}); len(violations) != 0 || args["target"] != "mobile" {
+		t.Fatalf("expected a single match accepted, got %v, %v", args, violations)
+	}
+}
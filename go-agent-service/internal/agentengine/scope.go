@@ -0,0 +1,56 @@
+package agentengine
+
+import "context"
+
+// CacheStatus is how a caching executor served a tool call.
+type CacheStatus string
+
+const (
+	CacheHit        CacheStatus = "hit"
+	CacheMiss       CacheStatus = "miss"
+	CacheInvalidate CacheStatus = "invalidate" // A write dropped cached results of its app
+)
+
+// ToolCallScope describes the run a tool call belongs to. The engine attaches it
+// to the context of every tool call for executors that depend on it, such as caches.
+type ToolCallScope struct {
+	SessionID string
+	UserID    string
+	ProjectID string
+	Access    AccessKind
+
+	cache *CacheStatus
+}
+
+type toolCallScopeKey struct{}
+
+// ToolCallScopeFrom returns the scope of the tool call ctx belongs to, if any.
+func ToolCallScopeFrom(ctx context.Context) (ToolCallScope, bool) {
+	scope, ok := ctx.Value(toolCallScopeKey{}).(ToolCallScope)
+	return scope, ok
+}
+
+// ReportCache records how the call was served; the engine traces it as tool.cache.
+func (s ToolCallScope) ReportCache(status CacheStatus) {
+	if s.cache != nil {
+		*s.cache = status
+	}
+}
+
+// WithToolCallScope attaches a scope to ctx for tool calls made outside the engine.
+// Cache use reported on it is not traced.
+func WithToolCallScope(ctx context.Context, scope ToolCallScope) context.Context {
+	return context.WithValue(ctx, toolCallScopeKey{}, scope)
+}
+
+// withToolCallScope attaches the scope of a call and returns where executors report cache use.
+func withToolCallScope(ctx context.Context, state *runState, access AccessKind) (context.Context, *CacheStatus) {
+	status := new(CacheStatus)
+	return WithToolCallScope(ctx, ToolCallScope{
+		SessionID: state.req.SessionID,
+		UserID:    state.req.UserID,
+		ProjectID: state.req.ProjectID,
+		Access:    access,
+		cache:     status,
+	}), status
+}
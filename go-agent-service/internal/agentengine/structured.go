@@ -0,0 +1,102 @@
+package agentengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidStructuredOutput is returned when the final answer still does not
+// match Request.ResponseSchema after the retries.
+var ErrInvalidStructuredOutput = errors.New("response does not match the response schema")
+
+// defaultStructuredRetries is the number of corrective attempts after the first answer.
+const defaultStructuredRetries = 2
+
+// structuredInstructions asks for JSON matching the schema, for providers
+// without a native JSON mode.
+func structuredInstructions(schema map[string]any) string {
+	raw, _ := json.Marshal(schema)
+	return "## Response Format\nReply with a single JSON value that matches this JSON Schema, with no prose or code fences:\n" + string(raw)
+}
+
+// ParseStructured decodes a JSON answer, with any code fence stripped, and validates it against the schema. It
+// returns the coerced value and the violations, if any.
+func ParseStructured(text string, schema map[string]any) (any, []string) {
+	var value any
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &value); err != nil {
+		return nil, []string{"response is not valid JSON: " + err.Error()}
+	}
+	return ValidateValue(schema, value)
+}
+
+// stripCodeFence removes a markdown code fence around the answer, if any.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+		text = text[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// correctionPrompt repeats the prompt with the rejected answer and its violations.
+func correctionPrompt(prompt, answer string, violations []string) string {
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\n## Rejected Response\n")
+	sb.WriteString(answer)
+	sb.WriteString("\n\n## Validation Errors\n")
+	for _, violation := range violations {
+		sb.WriteString("- " + violation + "\n")
+	}
+	sb.WriteString("Reply again with only the corrected JSON.")
+	return sb.String()
+}
+
+func structuredError(violations []string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidStructuredOutput, strings.Join(violations, "; "))
+}
+
+// answer generates the final answer. With a response schema the answer must be
+// JSON matching it: invalid answers are retried with their validation errors,
+// and only the accepted answer is emitted, re-encoded after coercion.
+func (e *Engine) answer(ctx context.Context, state *runState, input LLMRequest, step int, emit func(Event) error) (LLMResponse, error) {
+	if input.ResponseSchema == nil {
+		return e.respond(ctx, state, input, step, emit)
+	}
+	prompt := input.Prompt + "\n\n" + structuredInstructions(input.ResponseSchema)
+	input.Prompt = prompt
+	for attempt := 1; ; attempt++ {
+		reply, err := e.respond(ctx, state, input, step, emit)
+		if err != nil {
+			return LLMResponse{}, err
+		}
+		value, violations := ParseStructured(reply.Text, input.ResponseSchema)
+		if len(violations) == 0 {
+			// The text carries the coerced value so it agrees with Response.Structured
+			if raw, err := json.Marshal(value); err == nil {
+				reply.Text = string(raw)
+			}
+			state.structured = value
+			if err := emit(Event{Type: EventToken, Step: step, Delta: reply.Text}); err != nil {
+				return LLMResponse{}, err
+			}
+			return reply, nil
+		}
+		state.trace.Record(TraceEvent{Name: "response.invalid", Step: step, Detail: strings.Join(violations, "; "), Attrs: map[string]any{"attempt": attempt}})
+		if attempt > defaultStructuredRetries {
+			return LLMResponse{}, structuredError(violations)
+		}
+		if err := e.checkBudget(state); err != nil {
+			state.trace.Record(TraceEvent{Name: "budget.exceeded", Step: step, Detail: err.Error()})
+			return LLMResponse{}, err
+		}
+		input.Prompt = correctionPrompt(prompt, state.redact(reply.Text), violations)
+	}
+}
@@ -0,0 +1,93 @@
+package agentengine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// replyingLLM answers with replies in order, repeating the last, and records the requests.
+type replyingLLM struct {
+	replies  []string
+	requests []LLMRequest
+}
+
+func (l *replyingLLM) Respond(ctx context.Context, input LLMRequest) (LLMResponse, error) {
+	l.requests = append(l.requests, input)
+	reply := l.replies[min(len(l.requests), len(l.replies))-1]
+	return LLMResponse{Text: reply, Usage: Usage{TotalTokens: 1}}, nil
+}
+
+var ticketSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"key":      map[string]any{"type": "string"},
+		"priority": map[string]any{"type": "integer", "minimum": 1.0},
+	},
+	"required": []any{"key", "priority"},
+}
+
+func structuredTestEngine(t *testing.T, llm LLMClient) *Engine {
+	t.Helper()
+	engine, err := NewEngine(Config{
+		Planner:  &scriptedPlanner{},
+		LLM:      llm,
+		Tools:    &staticTools{},
+		Executor: echoExecutor{},
+		Context:  plainAssembler{},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestStructuredOutputIsValidatedAndRetried(t *testing.T) {
+	llm := &replyingLLM{replies: []string{
+		`The ticket is PROJ-1`,
+		"```json\n{\"key\": \"PROJ-1\", \"priority\": \"2\"}\n```",
+	}}
+	engine := structuredTestEngine(t, llm)
+
+	var tokens []string
+	resp, err := engine.RunStream(context.Background(), Request{Query: "top ticket", ResponseSchema: ticketSchema}, func(ev Event) error {
+		if ev.Type == EventToken {
+			tokens = append(tokens, ev.Delta)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunStream: %v", err)
+	}
+	if len(llm.requests) != 2 || llm.requests[0].ResponseSchema == nil {
+		t.Fatalf("expected a schema request and one retry, got %d requests", len(llm.requests))
+	}
+	if retry := llm.requests[1].Prompt; !strings.Contains(retry, "The ticket is PROJ-1") || !strings.Contains(retry, "not valid JSON") {
+		t.Fatalf("expected the retry to carry the rejected answer and its errors, got %q", retry)
+	}
+
+	structured, _ := resp.Structured.(map[string]any)
+	if structured["key"] != "PROJ-1" || structured["priority"] != 2.0 {
+		t.Fatalf("expected the coerced object, got %#v", resp.Structured)
+	}
+	if resp.Text != `{"key":"PROJ-1","priority":2}` || len(tokens) != 1 || tokens[0] != resp.Text {
+		t.Fatalf("expected only the accepted answer emitted, got %q and %q", resp.Text, tokens)
+	}
+	if resp.Usage.TotalTokens != 2 {
+		t.Fatalf("expected usage of both attempts, got %+v", resp.Usage)
+	}
+}
+
+func TestStructuredOutputFailsAfterRetries(t *testing.T) {
+	llm := &replyingLLM{replies: []string{`{"key": "PROJ-1", "priority": 0}`}}
+	engine := structuredTestEngine(t, llm)
+
+	_, err := engine.Run(context.Background(), Request{Query: "top ticket", ResponseSchema: ticketSchema})
+	if !errors.Is(err, ErrInvalidStructuredOutput) || !strings.Contains(err.Error(), "priority") {
+		t.Fatalf("expected ErrInvalidStructuredOutput naming the field, got %v", err)
+	}
+	if len(llm.requests) != 1+defaultStructuredRetries {
+		t.Fatalf("expected %d attempts, got %d", 1+defaultStructuredRetries, len(llm.requests))
+	}
+}
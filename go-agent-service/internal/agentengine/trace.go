@@ -1,20 +1,72 @@
-// Package agentengine provides lightweight tracing for agent runs.
+// Package agentengine provides structured tracing for agent runs.
 package agentengine
 
-import "time"
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
 
-// Trace captures lifecycle events for a single run.
+// ErrTraceNotFound is returned when a trace does not exist.
+var ErrTraceNotFound = errors.New("agent trace not found")
+
+// Trace captures lifecycle events for a single run. A run resumed after approval
+// gets its own trace sharing the RunID of the paused run.
 type Trace struct {
-	ID      string
-	Started time.Time
-	Events  []TraceEvent
+	ID        string
+	RunID     string // Paused or resumed run, if any
+	ParentID  string // Trace of the delegating run, for sub-agent runs
+	SessionID string
+	UserID    string
+	ProjectID string
+	Query     string
+	Status    string // completed, awaiting_approval or failed
+	Error     string
+	Started   time.Time
+	Finished  time.Time
+	Events    []TraceEvent
+
+	// PromptVersion is the system prompt template version the run used, if the
+	// context assembler reported one.
+	PromptVersion string
+
+	mu sync.Mutex
 }
 
 // TraceEvent represents a single event in a trace.
 type TraceEvent struct {
-	Name   string
-	Detail string
-	At     time.Time
+	Name     string
+	Detail   string
+	Step     int
+	Duration time.Duration
+	Attrs    map[string]any
+	At       time.Time
+}
+
+// TraceStore persists finished traces.
+type TraceStore interface {
+	SaveTrace(ctx context.Context, trace *Trace) error
+	GetTrace(ctx context.Context, id string) (*Trace, error)
+	// ListTraces returns the most recent traces of a session, newest first.
+	ListTraces(ctx context.Context, sessionID string, limit int) ([]*Trace, error)
+}
+
+type promptVersionKey struct{}
+
+// ReportPromptVersion records which prompt template version a context assembler
+// rendered; the engine keeps it on the run's trace.
+func ReportPromptVersion(ctx context.Context, version string) {
+	if slot, ok := ctx.Value(promptVersionKey{}).(*string); ok {
+		*slot = version
+	}
+}
+
+// withPromptVersion returns where ReportPromptVersion stores the version for ctx.
+func withPromptVersion(ctx context.Context) (context.Context, *string) {
+	slot := new(string)
+	return context.WithValue(ctx, promptVersionKey{}, slot), slot
 }
 
 // NewTrace creates a new trace.
@@ -26,14 +78,130 @@ func NewTrace(id string) *Trace {
 	}
 }
 
+// OwnedBy reports whether the traced run was started for owner.
+func (t *Trace) OwnedBy(owner RunOwner) bool {
+	return t.UserID == owner.UserID && t.ProjectID == owner.ProjectID
+}
+
 // AddEvent appends an event to the trace.
 func (t *Trace) AddEvent(name, detail string) {
+	t.Record(TraceEvent{Name: name, Detail: detail})
+}
+
+// Record appends a structured event to the trace. It is safe for concurrent use.
+func (t *Trace) Record(ev TraceEvent) {
 	if t == nil {
 		return
 	}
-	t.Events = append(t.Events, TraceEvent{
-		Name:   name,
-		Detail: detail,
-		At:     time.Now(),
-	})
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Events = append(t.Events, ev)
 }
+
+// Snapshot returns a copy of the trace that is safe to hand to a store.
+func (t *Trace) Snapshot() *Trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &Trace{
+		ID:        t.ID,
+		RunID:     t.RunID,
+		ParentID:  t.ParentID,
+		SessionID: t.SessionID,
+		UserID:    t.UserID,
+		ProjectID: t.ProjectID,
+		Query:     t.Query,
+		Status:    t.Status,
+		Error:     t.Error,
+		Started:   t.Started,
+		Finished:  t.Finished,
+		Events:    append([]TraceEvent(nil), t.Events...),
+
+		PromptVersion: t.PromptVersion,
+	}
+}
+
+// Duration returns how long the run took, or zero while it is still running.
+func (t *Trace) Duration() time.Duration {
+	if t.Finished.IsZero() {
+		return 0
+	}
+	return t.Finished.Sub(t.Started)
+}
+
+func (t *Trace) finish(status, errMsg string) {
+	now := time.Now()
+	t.Record(TraceEvent{Name: "run." + status, Detail: errMsg, Duration: now.Sub(t.Started), At: now})
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Status = status
+	t.Error = errMsg
+	t.Finished = now
+}
+
+// MemoryTraceStore keeps the most recent traces in process memory.
+type MemoryTraceStore struct {
+	mu     sync.Mutex
+	limit  int
+	traces map[string]*Trace
+	order  []string
+}
+
+// NewMemoryTraceStore creates an in-memory trace store holding up to limit traces
+// (500 when limit <= 0).
+func NewMemoryTraceStore(limit int) *MemoryTraceStore {
+	if limit <= 0 {
+		limit = 500
+	}
+	return &MemoryTraceStore{limit: limit, traces: make(map[string]*Trace)}
+}
+
+// SaveTrace implements TraceStore.
+func (s *MemoryTraceStore) SaveTrace(ctx context.Context, trace *Trace) error {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.traces[trace.ID]; !ok {
+		s.order = append(s.order, trace.ID)
+	}
+	s.traces[trace.ID] = trace.Snapshot()
+	for len(s.order) > s.limit {
+		delete(s.traces, s.order[0])
+		s.order = s.order[1:]
+	}
+	return nil
+}
+
+// GetTrace implements TraceStore.
+func (s *MemoryTraceStore) GetTrace(ctx context.Context, id string) (*Trace, error) {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trace, ok := s.traces[id]
+	if !ok {
+		return nil, ErrTraceNotFound
+	}
+	return trace.Snapshot(), nil
+}
+
+// ListTraces implements TraceStore.
+func (s *MemoryTraceStore) ListTraces(ctx context.Context, sessionID string, limit int) ([]*Trace, error) {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*Trace
+	for _, id := range s.order {
+		if trace := s.traces[id]; trace.SessionID == sessionID {
+			out = append(out, trace.Snapshot())
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Started.After(out[j].Started) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+var _ TraceStore = (*MemoryTraceStore)(nil)
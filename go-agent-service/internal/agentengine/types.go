@@ -12,10 +12,15 @@ type Request struct {
 	SessionID       string
 	UserID          string
 	ProjectID       string
+	UserRole        string // Optional role of the user, for the system prompt
 	ContextEntities []string
 	History         []HistoryMessage
 	Provider        string
 	Model           string
+	Planner         string // Optional planner mode override (auto, llm, heuristic)
+	// ResponseSchema is an optional JSON Schema the final answer must match.
+	// The answer is then JSON, parsed into Response.Structured.
+	ResponseSchema map[string]any
 }
 
 // HistoryMessage is a normalized chat history entry.
@@ -31,6 +36,12 @@ type Response struct {
 	Model        string
 	Observations []Observation
 	Trace        *Trace
+	Status       RunStatus
+	RunID        string        // Set when Status is RunAwaitingApproval, and for durable runs
+	PendingCalls []PendingCall // Calls awaiting approval
+	Usage        Usage         // Planner, LLM and sub-agent usage of this run (excludes the paused part of a resumed run)
+	Structured   any           // Parsed answer when the request set a ResponseSchema
+	Citations    []Citation    // Observation sources the answer cites, in order of first mention
 }
 
 // PlanType describes the planner decision.
@@ -40,13 +51,28 @@ const (
 	PlanDirect            PlanType = "direct"
 	PlanToolCalls         PlanType = "tool_calls"
 	PlanNeedClarification PlanType = "need_clarification"
+	// PlanSteps is a multi-step plan: tool calls with dependencies, executed
+	// without re-planning unless a step fails or returns something unexpected.
+	PlanSteps PlanType = "steps"
 )
 
 // Plan describes a planner decision.
 type Plan struct {
 	Type          PlanType
 	ToolCalls     []ToolCall
+	Steps         []PlanStep // Set for PlanSteps
 	Clarification string
+	Usage         Usage // LLM usage spent planning, if any
+}
+
+// PlanStep is a tool call of a multi-step plan. String args may reference the
+// result data of the steps it depends on as {{stepID.path}}, e.g.
+// {{s1.issues.*.key}}; a reference that makes up a whole arg keeps its JSON type.
+type PlanStep struct {
+	ID        string
+	Call      ToolCall
+	DependsOn []string `json:",omitempty"`
+	Purpose   string   `json:",omitempty"`
 }
 
 // PlanInput is passed to the planner.
@@ -70,6 +96,7 @@ type ToolAction struct {
 	Name        string
 	Description string
 	InputSchema string
+	Access      AccessKind // Optional; classified from the action name when empty
 }
 
 // ToolCall is a structured tool invocation.
@@ -77,6 +104,9 @@ type ToolCall struct {
 	Name   string
 	Action string
 	Args   map[string]any
+	// IdempotencyKey is assigned to write calls so executors can retry them safely.
+	// Omitted from JSON when empty so cassette keys of reads are unchanged.
+	IdempotencyKey string `json:",omitempty"`
 }
 
 // ToolResult is the structured result of a tool invocation.
@@ -88,9 +118,16 @@ type ToolResult struct {
 
 // Observation records the result of a tool call.
 type Observation struct {
+	ID       string // Unique within a run, e.g. obs3
 	ToolName string
 	Result   *ToolResult
 	Error    string
+	// Note explains how a result was shortened for the prompt; empty for raw observations.
+	Note string
+	// Sources are the records the result came from, which the answer may cite.
+	Sources []Source `json:",omitempty"`
+	// Injection lists the signals of instruction-like content the InjectionDetector found.
+	Injection []string `json:",omitempty"`
 }
 
 // LLMRequest is the payload for LLM inference.
@@ -101,6 +138,8 @@ type LLMRequest struct {
 	History      []HistoryMessage
 	Provider     string
 	Model        string
+	// ResponseSchema requests JSON output matching the schema (JSON mode where supported).
+	ResponseSchema map[string]any `json:",omitempty"`
 }
 
 // LLMResponse is the output of LLM inference.
@@ -108,6 +147,7 @@ type LLMResponse struct {
 	Text     string
 	Provider string
 	Model    string
+	Usage    Usage
 }
 
 // Planner decides whether and how to use tools.
@@ -120,11 +160,29 @@ type LLMClient interface {
 	Respond(ctx context.Context, input LLMRequest) (LLMResponse, error)
 }
 
+// StreamingLLMClient is an LLMClient that can deliver text deltas as they are generated.
+type StreamingLLMClient interface {
+	LLMClient
+	RespondStream(ctx context.Context, input LLMRequest, onDelta func(delta string) error) (LLMResponse, error)
+}
+
 // ToolRegistry provides available tools for a user/project.
 type ToolRegistry interface {
 	ListTools(ctx context.Context, userID, projectID string) ([]ToolDef, error)
 }
 
+// DegradedTool is a tool temporarily withheld from the planner.
+type DegradedTool struct {
+	Name   string
+	Reason string
+}
+
+// ToolHealth is implemented by tool registries that withhold failing tools.
+// The engine tells the planner which tools are degraded.
+type ToolHealth interface {
+	DegradedTools() []DegradedTool
+}
+
 // ToolExecutor executes a tool call.
 type ToolExecutor interface {
 	Execute(ctx context.Context, call ToolCall) (*ToolResult, error)
@@ -142,7 +200,35 @@ type ContextAssembler interface {
 	AppendObservations(prompt string, observations []Observation) (string, error)
 }
 
+// PolicyOutcome is the result of a policy evaluation.
+type PolicyOutcome string
+
+const (
+	PolicyAllow           PolicyOutcome = "allow"
+	PolicyDeny            PolicyOutcome = "deny"
+	PolicyRequireApproval PolicyOutcome = "require_approval"
+)
+
+// PolicyContext describes a tool call being authorized.
+type PolicyContext struct {
+	SessionID string
+	UserID    string
+	ProjectID string
+	Tool      string
+	Action    string
+	Args      map[string]any
+	Access    AccessKind
+	CallCount int // Calls of this tool action already made in the current run
+}
+
+// PolicyDecision is returned by a policy for a single tool call.
+type PolicyDecision struct {
+	Outcome PolicyOutcome
+	Reason  string
+	Rule    string // Matching rule ID, if any
+}
+
 // Policy controls tool access and budgets.
 type Policy interface {
-	AllowTool(name string) bool
+	Evaluate(ctx context.Context, input PolicyContext) PolicyDecision
 }
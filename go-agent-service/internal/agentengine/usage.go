@@ -0,0 +1,363 @@
+// Package agentengine provides token usage accounting and budgets for agent runs.
+package agentengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned when a run would exceed a token or cost budget.
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// Usage counts LLM tokens and their estimated cost in USD.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	CostUSD          float64
+	Unpriced         bool // Some tokens were spent on a model without a known price
+}
+
+// Add returns the sum of two usages.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+		CostUSD:          u.CostUSD + other.CostUSD,
+		Unpriced:         u.Unpriced || other.Unpriced,
+	}
+}
+
+// IsZero reports whether no tokens were counted.
+func (u Usage) IsZero() bool {
+	return u.TotalTokens == 0 && u.PromptTokens == 0 && u.CompletionTokens == 0 && u.CostUSD == 0
+}
+
+// UsageRecord is the usage of a single run, attributed to its session, user and project.
+type UsageRecord struct {
+	TraceID   string
+	RunID     string // Paused or resumed run, if any
+	SessionID string
+	UserID    string
+	ProjectID string
+	Provider  string
+	Model     string
+	Usage     Usage
+	CreatedAt time.Time
+}
+
+// UsageGroup selects the dimension a usage report is grouped by.
+type UsageGroup string
+
+const (
+	UsageBySession UsageGroup = "session"
+	UsageByUser    UsageGroup = "user"
+	UsageByProject UsageGroup = "project"
+	UsageByModel   UsageGroup = "model"
+)
+
+// ParseUsageGroup validates a report grouping. An empty value means no grouping.
+func ParseUsageGroup(value string) (UsageGroup, error) {
+	switch group := UsageGroup(value); group {
+	case "", UsageBySession, UsageByUser, UsageByProject, UsageByModel:
+		return group, nil
+	}
+	return "", fmt.Errorf("unknown usage grouping %q", value)
+}
+
+// UsageFilter restricts usage records. Empty fields match everything; Until is exclusive.
+type UsageFilter struct {
+	SessionID string
+	UserID    string
+	ProjectID string
+	Since     time.Time
+	Until     time.Time
+}
+
+// UsageSummary aggregates usage for one group key.
+type UsageSummary struct {
+	Key   string // Session, user or project ID, or provider/model; empty when ungrouped
+	Runs  int
+	Usage Usage
+}
+
+// UsageStore persists per-run usage for budgets and chargeback reports.
+type UsageStore interface {
+	RecordUsage(ctx context.Context, record UsageRecord) error
+	// SumUsage totals the usage matching filter.
+	SumUsage(ctx context.Context, filter UsageFilter) (Usage, error)
+	// ReportUsage totals usage matching filter per group key, highest cost first.
+	ReportUsage(ctx context.Context, filter UsageFilter, group UsageGroup) ([]UsageSummary, error)
+}
+
+// Budget caps token and cost usage. Zero values are unlimited. Session budgets
+// cover the whole session; project budgets reset at the start of each UTC month.
+type Budget struct {
+	RunTokens      int
+	RunCostUSD     float64
+	SessionTokens  int
+	SessionCostUSD float64
+	ProjectTokens  int
+	ProjectCostUSD float64
+}
+
+func (b Budget) scoped() bool {
+	return b.SessionTokens > 0 || b.SessionCostUSD > 0 || b.ProjectTokens > 0 || b.ProjectCostUSD > 0
+}
+
+// BudgetError describes which budget a run exceeded.
+type BudgetError struct {
+	Scope string // run, session or project
+	Limit string
+	Used  string
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s budget exceeded: used %s of %s", e.Scope, e.Used, e.Limit)
+}
+
+// Is makes errors.Is(err, ErrBudgetExceeded) match.
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// checkLimit returns a BudgetError when used reaches a non-zero token or cost limit.
+func checkLimit(scope string, used Usage, tokens int, cost float64) error {
+	if tokens > 0 && used.TotalTokens >= tokens {
+		return &BudgetError{Scope: scope, Limit: fmt.Sprintf("%d tokens", tokens), Used: fmt.Sprintf("%d tokens", used.TotalTokens)}
+	}
+	if cost > 0 && used.CostUSD >= cost {
+		return &BudgetError{Scope: scope, Limit: fmt.Sprintf("$%.4f", cost), Used: fmt.Sprintf("$%.4f", used.CostUSD)}
+	}
+	return nil
+}
+
+// monthStart returns the start of the UTC month containing t.
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MemoryUsageStore keeps usage records in process memory.
+type MemoryUsageStore struct {
+	mu      sync.Mutex
+	records []UsageRecord
+}
+
+// NewMemoryUsageStore creates an in-memory usage store.
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{}
+}
+
+// RecordUsage implements UsageStore.
+func (s *MemoryUsageStore) RecordUsage(ctx context.Context, record UsageRecord) error {
+	_ = ctx
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+// SumUsage implements UsageStore.
+func (s *MemoryUsageStore) SumUsage(ctx context.Context, filter UsageFilter) (Usage, error) {
+	summaries, err := s.ReportUsage(ctx, filter, "")
+	if err != nil || len(summaries) == 0 {
+		return Usage{}, err
+	}
+	return summaries[0].Usage, nil
+}
+
+// ReportUsage implements UsageStore.
+func (s *MemoryUsageStore) ReportUsage(ctx context.Context, filter UsageFilter, group UsageGroup) ([]UsageSummary, error) {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	byKey := make(map[string]*UsageSummary)
+	var out []*UsageSummary
+	for _, record := range s.records {
+		if !filter.matches(record) {
+			continue
+		}
+		key := record.groupKey(group)
+		summary, ok := byKey[key]
+		if !ok {
+			summary = &UsageSummary{Key: key}
+			byKey[key] = summary
+			out = append(out, summary)
+		}
+		summary.Runs++
+		summary.Usage = summary.Usage.Add(record.Usage)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Usage.CostUSD > out[j].Usage.CostUSD })
+	summaries := make([]UsageSummary, 0, len(out))
+	for _, summary := range out {
+		summaries = append(summaries, *summary)
+	}
+	return summaries, nil
+}
+
+func (f UsageFilter) matches(record UsageRecord) bool {
+	switch {
+	case f.SessionID != "" && record.SessionID != f.SessionID:
+		return false
+	case f.UserID != "" && record.UserID != f.UserID:
+		return false
+	case f.ProjectID != "" && record.ProjectID != f.ProjectID:
+		return false
+	case !f.Since.IsZero() && record.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !record.CreatedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+func (r UsageRecord) groupKey(group UsageGroup) string {
+	switch group {
+	case UsageBySession:
+		return r.SessionID
+	case UsageByUser:
+		return r.UserID
+	case UsageByProject:
+		return r.ProjectID
+	case UsageByModel:
+		return r.Provider + "/" + r.Model
+	}
+	return ""
+}
+
+var _ UsageStore = (*MemoryUsageStore)(nil)
+
+// priorUsage is the usage already recorded against a run's session and project budgets.
+type priorUsage struct {
+	session Usage
+	project Usage
+}
+
+// loadPriorUsage loads the session and project usage that count toward scoped budgets
+// and fails when either budget is already spent.
+func (e *Engine) loadPriorUsage(ctx context.Context, req Request) (priorUsage, error) {
+	var prior priorUsage
+	if e.usage == nil || !e.budget.scoped() {
+		return prior, nil
+	}
+	var err error
+	if req.SessionID != "" && (e.budget.SessionTokens > 0 || e.budget.SessionCostUSD > 0) {
+		if prior.session, err = e.usage.SumUsage(ctx, UsageFilter{SessionID: req.SessionID}); err != nil {
+			return prior, fmt.Errorf("load session usage: %w", err)
+		}
+	}
+	if req.ProjectID != "" && (e.budget.ProjectTokens > 0 || e.budget.ProjectCostUSD > 0) {
+		filter := UsageFilter{ProjectID: req.ProjectID, Since: monthStart(e.clock())}
+		if prior.project, err = e.usage.SumUsage(ctx, filter); err != nil {
+			return prior, fmt.Errorf("load project usage: %w", err)
+		}
+	}
+	state := runState{req: req, prior: prior}
+	return prior, e.checkBudget(&state)
+}
+
+// checkBudget fails once the run, or the run plus prior usage, reaches a budget.
+// It is checked before each LLM call, so a single call may overshoot a budget.
+func (e *Engine) checkBudget(state *runState) error {
+	run := state.runUsage()
+	if err := checkLimit("run", run, e.budget.RunTokens, e.budget.RunCostUSD); err != nil {
+		return err
+	}
+	if state.req.SessionID != "" {
+		if err := checkLimit("session", state.prior.session.Add(run), e.budget.SessionTokens, e.budget.SessionCostUSD); err != nil {
+			return err
+		}
+	}
+	if state.req.ProjectID != "" {
+		if err := checkLimit("project", state.prior.project.Add(run), e.budget.ProjectTokens, e.budget.ProjectCostUSD); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordUsage persists the run's usage, including runs that failed after calling the LLM.
+// Store errors are ignored so accounting never fails a run.
+// Sub-agents record their own usage, so it is left out here.
+func (e *Engine) recordUsage(ctx context.Context, state *runState, resp *Response) {
+	usage := state.ownUsage()
+	if e.usage == nil || usage.IsZero() {
+		return
+	}
+	record := UsageRecord{
+		TraceID:   state.trace.ID,
+		RunID:     state.trace.RunID,
+		SessionID: state.req.SessionID,
+		UserID:    state.req.UserID,
+		ProjectID: state.req.ProjectID,
+		Provider:  state.req.Provider,
+		Model:     state.req.Model,
+		Usage:     usage,
+		CreatedAt: e.clock(),
+	}
+	if resp != nil && resp.Model != "" {
+		record.Provider = resp.Provider
+		record.Model = resp.Model
+	}
+	_ = e.usage.RecordUsage(context.WithoutCancel(ctx), record)
+}
+
+func (s *runState) addUsage(usage Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage = s.usage.Add(usage)
+}
+
+// noteUnpriced records a cost.unknown event for usage of a model without a
+// known price, which cost budgets and reports do not see.
+func (s *runState) noteUnpriced(step int, provider, model string, usage Usage) {
+	if !usage.Unpriced {
+		return
+	}
+	s.trace.Record(TraceEvent{Name: "cost.unknown", Step: step, Attrs: map[string]any{
+		"provider":    provider,
+		"model":       model,
+		"totalTokens": usage.TotalTokens,
+	}})
+}
+
+// addDelegatedUsage counts a sub-agent run toward this run's usage and budgets.
+func (s *runState) addDelegatedUsage(usage Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delegated = s.delegated.Add(usage)
+}
+
+// runUsage is the usage of this run and its sub-agents.
+func (s *runState) runUsage() Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage.Add(s.delegated)
+}
+
+// ownUsage excludes sub-agent runs.
+func (s *runState) ownUsage() Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
+// addUsageAttrs adds token counts and cost to trace event attributes.
+func addUsageAttrs(attrs map[string]any, usage Usage) {
+	if usage.IsZero() {
+		return
+	}
+	attrs["promptTokens"] = usage.PromptTokens
+	attrs["completionTokens"] = usage.CompletionTokens
+	attrs["totalTokens"] = usage.TotalTokens
+	attrs["costUsd"] = usage.CostUSD
+}
@@ -0,0 +1,332 @@
+package agenteval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// Runner runs scenarios through a fresh engine per scenario. LLM and Planner are
+// shared, so the same runner can evaluate real, replayed or stubbed models.
+type Runner struct {
+	LLM      agentengine.LLMClient
+	Planner  agentengine.Planner
+	MaxSteps int // Engine step limit; defaults to the server's 3
+
+	// Clock is used to measure latency; defaults to time.Now.
+	Clock func() time.Time
+}
+
+// Report is the JSON output of an evaluation. It holds no timestamps so reports
+// of different commits diff cleanly apart from latency.
+type Report struct {
+	Suites  []SuiteResult `json:"suites"`
+	Summary Summary       `json:"summary"`
+}
+
+// SuiteResult holds the results of one suite.
+type SuiteResult struct {
+	Name      string   `json:"name"`
+	Scenarios []Result `json:"scenarios"`
+	Summary   Summary  `json:"summary"`
+}
+
+// Result scores a single scenario run.
+type Result struct {
+	Name      string   `json:"name"`
+	Passed    bool     `json:"passed"`
+	Failures  []string `json:"failures,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	ToolCalls []string `json:"toolCalls"`
+	Precision float64  `json:"precision"`
+	Recall    float64  `json:"recall"`
+	Steps     int      `json:"steps"`
+	LatencyMs int64    `json:"latencyMs"`
+	Answer    string   `json:"answer"`
+	Tokens    int      `json:"tokens"`
+	CostUSD   float64  `json:"costUsd"`
+}
+
+// Summary aggregates results; precision, recall, steps and latency are means,
+// tokens and cost are totals.
+type Summary struct {
+	Scenarios int     `json:"scenarios"`
+	Passed    int     `json:"passed"`
+	Failed    int     `json:"failed"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	Steps     float64 `json:"steps"`
+	LatencyMs float64 `json:"latencyMs"`
+	Tokens    int     `json:"tokens"`
+	CostUSD   float64 `json:"costUsd"`
+}
+
+// Run evaluates every scenario of the given suites in order.
+func (r *Runner) Run(ctx context.Context, suites []Suite) Report {
+	report := Report{Suites: make([]SuiteResult, 0, len(suites))}
+	var all []Result
+	for _, suite := range suites {
+		results := make([]Result, 0, len(suite.Scenarios))
+		for _, sc := range suite.Scenarios {
+			results = append(results, r.RunScenario(ctx, sc))
+		}
+		all = append(all, results...)
+		report.Suites = append(report.Suites, SuiteResult{Name: suite.Name, Scenarios: results, Summary: summarize(results)})
+	}
+	report.Summary = summarize(all)
+	return report
+}
+
+// RunScenario runs one scenario and scores it against its expectation.
+func (r *Runner) RunScenario(ctx context.Context, sc Scenario) Result {
+	clock := r.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	maxSteps := r.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = 3
+	}
+
+	planner := &countingPlanner{inner: r.Planner}
+	executor := &scriptedExecutor{scenario: sc}
+	result := Result{Name: sc.Name, ToolCalls: []string{}}
+	engine, err := agentengine.NewEngine(agentengine.Config{
+		Planner:  planner,
+		LLM:      r.LLM,
+		Tools:    scenarioTools(sc.toolDefs()),
+		Executor: executor,
+		Context:  queryAssembler{},
+		MaxSteps: maxSteps,
+	})
+	if err != nil {
+		result.Error = err.Error()
+		result.Failures = []string{"engine: " + err.Error()}
+		return result
+	}
+
+	started := clock()
+	resp, err := engine.Run(ctx, agentengine.Request{
+		Query:     sc.Query,
+		SessionID: "eval:" + sc.Name,
+		UserID:    sc.UserID,
+		ProjectID: sc.ProjectID,
+		Provider:  sc.Provider,
+		Model:     sc.Model,
+		Planner:   sc.Planner,
+	})
+	result.LatencyMs = clock().Sub(started).Milliseconds()
+	result.Steps = planner.steps()
+
+	calls := executor.made()
+	for _, call := range calls {
+		result.ToolCalls = append(result.ToolCalls, call.Name+"."+call.Action)
+	}
+	result.Precision, result.Recall = score(sc.Expect.ToolCalls, calls)
+	if err != nil {
+		result.Error = err.Error()
+		result.Failures = append(result.Failures, "run failed: "+err.Error())
+	} else {
+		result.Answer = resp.Text
+		result.Tokens = resp.Usage.TotalTokens
+		result.CostUSD = resp.Usage.CostUSD
+	}
+	result.Failures = append(result.Failures, check(sc.Expect, result)...)
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+// check lists how a run misses its expectation.
+func check(expect Expectation, result Result) []string {
+	var failures []string
+	if result.Recall < 1 {
+		failures = append(failures, fmt.Sprintf("missing expected tool calls (recall %.2f)", result.Recall))
+	}
+	if result.Precision < 1 {
+		failures = append(failures, fmt.Sprintf("unexpected tool calls (precision %.2f)", result.Precision))
+	}
+	if expect.MaxSteps > 0 && result.Steps > expect.MaxSteps {
+		failures = append(failures, fmt.Sprintf("took %d steps, expected at most %d", result.Steps, expect.MaxSteps))
+	}
+	if result.Error == "" {
+		answer := strings.ToLower(result.Answer)
+		for _, fact := range expect.AnswerFacts {
+			if !strings.Contains(answer, strings.ToLower(fact)) {
+				failures = append(failures, fmt.Sprintf("answer is missing %q", fact))
+			}
+		}
+	}
+	return failures
+}
+
+// score computes tool-selection precision and recall, matching each expected
+// call to at most one made call. A run without calls has precision 1; a
+// scenario expecting none has recall 1.
+func score(expected []ExpectedCall, made []agentengine.ToolCall) (precision, recall float64) {
+	used := make([]bool, len(made))
+	matched := 0
+	for _, want := range expected {
+		for i, call := range made {
+			if !used[i] && matches(want, call) {
+				used[i] = true
+				matched++
+				break
+			}
+		}
+	}
+	precision, recall = 1, 1
+	if len(made) > 0 {
+		precision = float64(matched) / float64(len(made))
+	}
+	if len(expected) > 0 {
+		recall = float64(matched) / float64(len(expected))
+	}
+	return precision, recall
+}
+
+func matches(want ExpectedCall, call agentengine.ToolCall) bool {
+	if want.Tool != call.Name || want.Action != call.Action {
+		return false
+	}
+	if len(want.Args) == 0 {
+		return true
+	}
+	// Compare through JSON so planner ints match the float64s of scenario files
+	var args map[string]any
+	data, err := json.Marshal(call.Args)
+	if err != nil || json.Unmarshal(data, &args) != nil {
+		return false
+	}
+	for key, value := range want.Args {
+		if !reflect.DeepEqual(args[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+func summarize(results []Result) Summary {
+	summary := Summary{Scenarios: len(results)}
+	if len(results) == 0 {
+		return summary
+	}
+	for _, result := range results {
+		if result.Passed {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		summary.Precision += result.Precision
+		summary.Recall += result.Recall
+		summary.Steps += float64(result.Steps)
+		summary.LatencyMs += float64(result.LatencyMs)
+		summary.Tokens += result.Tokens
+		summary.CostUSD += result.CostUSD
+	}
+	n := float64(len(results))
+	summary.Precision /= n
+	summary.Recall /= n
+	summary.Steps /= n
+	summary.LatencyMs /= n
+	return summary
+}
+
+// countingPlanner counts planner steps.
+type countingPlanner struct {
+	inner agentengine.Planner
+
+	mu sync.Mutex
+	n  int
+}
+
+func (p *countingPlanner) Plan(ctx context.Context, input agentengine.PlanInput) (agentengine.Plan, error) {
+	p.mu.Lock()
+	p.n++
+	p.mu.Unlock()
+	return p.inner.Plan(ctx, input)
+}
+
+func (p *countingPlanner) steps() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.n
+}
+
+// scriptedExecutor records calls and returns the scenario's scripted results.
+type scriptedExecutor struct {
+	scenario Scenario
+
+	mu    sync.Mutex
+	calls []agentengine.ToolCall
+}
+
+func (e *scriptedExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	_ = ctx
+	e.mu.Lock()
+	e.calls = append(e.calls, call)
+	e.mu.Unlock()
+
+	action, ok := e.scenario.action(call.Name, call.Action)
+	if !ok {
+		return nil, fmt.Errorf("no scripted result for %s.%s", call.Name, call.Action)
+	}
+	if action.Error != "" {
+		return nil, fmt.Errorf("%s", action.Error)
+	}
+	return &agentengine.ToolResult{Success: true, Data: action.Result, Message: action.Message}, nil
+}
+
+func (e *scriptedExecutor) made() []agentengine.ToolCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]agentengine.ToolCall(nil), e.calls...)
+}
+
+type scenarioTools []agentengine.ToolDef
+
+func (t scenarioTools) ListTools(ctx context.Context, userID, projectID string) ([]agentengine.ToolDef, error) {
+	return t, nil
+}
+
+// queryAssembler builds a prompt from the query and tool results only, keeping
+// evaluations independent of memory and the knowledge graph.
+type queryAssembler struct{}
+
+func (queryAssembler) Build(ctx context.Context, req agentengine.Request, tools []agentengine.ToolDef) (string, error) {
+	return req.Query, nil
+}
+
+func (queryAssembler) AppendObservations(prompt string, observations []agentengine.Observation) (string, error) {
+	var b strings.Builder
+	b.WriteString(prompt)
+	for _, obs := range observations {
+		b.WriteString("\n\n")
+		b.WriteString(describeObservation(obs))
+	}
+	return b.String(), nil
+}
+
+func describeObservation(obs agentengine.Observation) string {
+	if obs.Error != "" {
+		return obs.ToolName + " failed: " + obs.Error
+	}
+	if obs.Result == nil {
+		return obs.ToolName + ": no result"
+	}
+	data, _ := json.Marshal(obs.Result.Data)
+	if obs.Result.Message != "" {
+		return fmt.Sprintf("%s: %s %s", obs.ToolName, obs.Result.Message, data)
+	}
+	return fmt.Sprintf("%s: %s", obs.ToolName, data)
+}
+
+var (
+	_ agentengine.Planner      = (*countingPlanner)(nil)
+	_ agentengine.ToolExecutor = (*scriptedExecutor)(nil)
+)
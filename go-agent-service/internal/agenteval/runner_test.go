@@ -0,0 +1,81 @@
+package agenteval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+	"github.com/antigravity/go-agent-service/internal/agentengine/adapters"
+)
+
+func TestGoldenSuitesPassWithHeuristicPlanner(t *testing.T) {
+	suites, err := LoadSuites("../../evals")
+	if err != nil {
+		t.Fatalf("LoadSuites: %v", err)
+	}
+	runner := &Runner{LLM: StubLLM{}, Planner: adapters.NewHeuristicPlanner()}
+	report := runner.Run(context.Background(), suites)
+	for _, suite := range report.Suites {
+		for _, result := range suite.Scenarios {
+			if !result.Passed {
+				t.Errorf("%s/%s failed: %v (calls %v, answer %q)", suite.Name, result.Name, result.Failures, result.ToolCalls, result.Answer)
+			}
+		}
+	}
+	if report.Summary.Scenarios == 0 || report.Summary.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", report.Summary)
+	}
+}
+
+type fixedPlanner struct{ calls []agentengine.ToolCall }
+
+func (p fixedPlanner) Plan(ctx context.Context, input agentengine.PlanInput) (agentengine.Plan, error) {
+	if input.Step > 1 {
+		return agentengine.Plan{Type: agentengine.PlanDirect, Usage: agentengine.Usage{TotalTokens: 10, CostUSD: 0.01}}, nil
+	}
+	return agentengine.Plan{Type: agentengine.PlanToolCalls, ToolCalls: p.calls, Usage: agentengine.Usage{TotalTokens: 10, CostUSD: 0.01}}, nil
+}
+
+func TestRunScenarioScoresToolSelection(t *testing.T) {
+	sc := Scenario{
+		Name:  "wrong-tool",
+		Query: "critical bugs",
+		Tools: []Tool{
+			{Name: "jira", Actions: []Action{{Name: "search", Result: map[string]any{"keys": []any{"MOBILE-1"}}}}},
+			{Name: "github", Actions: []Action{{Name: "search"}}},
+		},
+		Expect: Expectation{
+			ToolCalls: []ExpectedCall{
+				{Tool: "jira", Action: "search", Args: map[string]any{"limit": float64(5)}},
+				{Tool: "jira", Action: "get"},
+			},
+			AnswerFacts: []string{"mobile-1"},
+			MaxSteps:    1,
+		},
+	}
+	planner := fixedPlanner{calls: []agentengine.ToolCall{
+		{Name: "jira", Action: "search", Args: map[string]any{"limit": 5}},
+		{Name: "github", Action: "search"},
+	}}
+	now := time.Unix(0, 0)
+	clock := func() time.Time {
+		now = now.Add(250 * time.Millisecond)
+		return now
+	}
+	result := (&Runner{LLM: StubLLM{}, Planner: planner, Clock: clock}).RunScenario(context.Background(), sc)
+
+	if result.Passed || result.Error != "" {
+		t.Fatalf("expected a scored failure, got %+v", result)
+	}
+	if result.Precision != 0.5 || result.Recall != 0.5 {
+		t.Fatalf("expected precision and recall 0.5, got %.2f and %.2f", result.Precision, result.Recall)
+	}
+	if result.Steps != 2 || result.LatencyMs != 250 || result.Tokens != 20 {
+		t.Fatalf("unexpected steps, latency or tokens: %+v", result)
+	}
+	// Missing call, extra call and step limit fail; the answer fact is present
+	if len(result.Failures) != 3 {
+		t.Fatalf("expected 3 failures, got %v", result.Failures)
+	}
+}
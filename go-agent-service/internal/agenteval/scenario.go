@@ -0,0 +1,169 @@
+// Package agenteval runs golden agent scenarios through agentengine.Engine and
+// scores tool selection, step counts, latency and answers.
+package agenteval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// Suite is a named set of scenarios, stored as one JSON file.
+type Suite struct {
+	Name      string     `json:"name"`
+	Scenarios []Scenario `json:"scenarios"`
+}
+
+// Scenario is a single query with the tools it may use and what a good run looks like.
+type Scenario struct {
+	Name      string      `json:"name"`
+	Query     string      `json:"query"`
+	UserID    string      `json:"userId,omitempty"`
+	ProjectID string      `json:"projectId,omitempty"`
+	Provider  string      `json:"provider,omitempty"`
+	Model     string      `json:"model,omitempty"`
+	Planner   string      `json:"planner,omitempty"` // Optional planner mode override (auto, llm, heuristic)
+	Tools     []Tool      `json:"tools,omitempty"`
+	Expect    Expectation `json:"expect"`
+}
+
+// Tool is a tool available to a scenario, with scripted results per action.
+type Tool struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Actions     []Action `json:"actions"`
+}
+
+// Action is a tool action and the result it returns when called.
+type Action struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema string         `json:"inputSchema,omitempty"`
+	Result      map[string]any `json:"result,omitempty"`
+	Message     string         `json:"message,omitempty"`
+	Error       string         `json:"error,omitempty"` // Fails the call instead of returning Result
+}
+
+// Expectation describes a passing run.
+type Expectation struct {
+	ToolCalls   []ExpectedCall `json:"toolCalls,omitempty"`   // An empty list expects no tool calls
+	AnswerFacts []string       `json:"answerFacts,omitempty"` // Substrings the answer must contain, case-insensitive
+	MaxSteps    int            `json:"maxSteps,omitempty"`    // Planner steps allowed; 0 means unchecked
+}
+
+// ExpectedCall matches a tool call by tool and action. Args, when set, must be
+// a subset of the call's arguments.
+type ExpectedCall struct {
+	Tool   string         `json:"tool"`
+	Action string         `json:"action"`
+	Args   map[string]any `json:"args,omitempty"`
+}
+
+// LoadSuites reads a suite file, or every *.json suite in a directory in name order.
+func LoadSuites(path string) ([]Suite, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		suite, err := LoadSuite(path)
+		if err != nil {
+			return nil, err
+		}
+		return []Suite{suite}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	suites := make([]Suite, 0, len(files))
+	for _, file := range files {
+		suite, err := LoadSuite(file)
+		if err != nil {
+			return nil, err
+		}
+		suites = append(suites, suite)
+	}
+	if len(suites) == 0 {
+		return nil, fmt.Errorf("no suites in %s", path)
+	}
+	return suites, nil
+}
+
+// LoadSuite reads and validates a single suite file. The suite is named after
+// the file when it has no name.
+func LoadSuite(path string) (Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Suite{}, err
+	}
+	var suite Suite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return Suite{}, fmt.Errorf("suite %s: %w", path, err)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := suite.Validate(); err != nil {
+		return Suite{}, fmt.Errorf("suite %s: %w", path, err)
+	}
+	return suite, nil
+}
+
+// Validate checks that scenarios are named uniquely and have a query.
+func (s Suite) Validate() error {
+	seen := make(map[string]bool, len(s.Scenarios))
+	var errs []error
+	for i, sc := range s.Scenarios {
+		switch {
+		case sc.Name == "":
+			errs = append(errs, fmt.Errorf("scenario %d has no name", i))
+		case seen[sc.Name]:
+			errs = append(errs, fmt.Errorf("duplicate scenario %q", sc.Name))
+		case strings.TrimSpace(sc.Query) == "":
+			errs = append(errs, fmt.Errorf("scenario %q has no query", sc.Name))
+		}
+		seen[sc.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// toolDefs converts the scenario tools to engine tool definitions.
+func (sc Scenario) toolDefs() []agentengine.ToolDef {
+	defs := make([]agentengine.ToolDef, 0, len(sc.Tools))
+	for _, tool := range sc.Tools {
+		def := agentengine.ToolDef{Name: tool.Name, Description: tool.Description}
+		for _, action := range tool.Actions {
+			def.Actions = append(def.Actions, agentengine.ToolAction{
+				Name:        action.Name,
+				Description: action.Description,
+				InputSchema: action.InputSchema,
+			})
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// action finds the scripted action for a call.
+func (sc Scenario) action(tool, action string) (Action, bool) {
+	for _, t := range sc.Tools {
+		if t.Name != tool {
+			continue
+		}
+		for _, a := range t.Actions {
+			if a.Name == action {
+				return a, true
+			}
+		}
+	}
+	return Action{}, false
+}
@@ -0,0 +1,27 @@
+package agenteval
+
+import (
+	"context"
+	"strings"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// StubLLM answers offline by echoing the tool results it was given, so answer
+// facts taken from scripted results can be checked without a model.
+type StubLLM struct{}
+
+// Respond implements agentengine.LLMClient.
+func (StubLLM) Respond(ctx context.Context, input agentengine.LLMRequest) (agentengine.LLMResponse, error) {
+	_ = ctx
+	if len(input.Observations) == 0 {
+		return agentengine.LLMResponse{Text: "No tool results for: " + input.Query, Provider: "stub", Model: "stub"}, nil
+	}
+	lines := make([]string, 0, len(input.Observations))
+	for _, obs := range input.Observations {
+		lines = append(lines, describeObservation(obs))
+	}
+	return agentengine.LLMResponse{Text: strings.Join(lines, "\n"), Provider: "stub", Model: "stub"}, nil
+}
+
+var _ agentengine.LLMClient = StubLLM{}
@@ -4,6 +4,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // NucleusConfig holds Nucleus platform connection settings
@@ -27,6 +29,42 @@ type KeyStoreConfig struct {
 	DatabaseURL string
 }
 
+// TelemetryConfig holds OpenTelemetry tracing settings (standard OTEL_* variables)
+type TelemetryConfig struct {
+	ServiceName  string  // Overrides the per-binary default service name
+	Exporter     string  // none, stdout, file, otlp
+	OTLPEndpoint string  // Collector URL, e.g. http://localhost:4317
+	OTLPProtocol string  // grpc or http/protobuf
+	File         string  // Output path for the file exporter
+	SampleRatio  float64 // Fraction of new traces to sample (0-1)
+}
+
+// BudgetConfig holds agent token and cost budgets (0 = unlimited)
+type BudgetConfig struct {
+	RunTokens      int
+	RunCostUSD     float64
+	SessionTokens  int
+	SessionCostUSD float64
+	ProjectTokens  int     // Per UTC month
+	ProjectCostUSD float64 // Per UTC month
+}
+
+// ToolRetryConfig controls retries and circuit breaking of agent tool calls
+type ToolRetryConfig struct {
+	Attempts         int // Attempts per call, including the first (1 disables retries)
+	InitialBackoff   time.Duration
+	MaxBackoff       time.Duration
+	BreakerThreshold int           // Consecutive transient failures that open a tool's circuit (0 disables)
+	BreakerCooldown  time.Duration // How long an open circuit hides the tool
+}
+
+// ToolCacheConfig controls session-scoped caching of read-only agent tool results
+type ToolCacheConfig struct {
+	TTL        time.Duration            // 0 disables caching
+	ActionTTLs map[string]time.Duration // Per "tool.action" or action name; 0 disables caching for it
+	MaxEntries int
+}
+
 // Config holds all configuration values
 type Config struct {
 	GRPCPort      int
@@ -34,19 +72,63 @@ type Config struct {
 	UCLGatewayURL string // Legacy - use Nucleus.UCLURL
 	MCPServerURL  string
 	MCPAuthToken  string
+	MCPTimeout    time.Duration
 	GeminiAPIKey  string
 	OpenAIAPIKey  string
+	GroqAPIKey    string
 	PostgresURL   string
 	TemporalHost  string
+	AgentPlanner  string // Planner mode: auto, llm, heuristic
+
+	LLMProvidersFile   string // Optional JSON document adding or replacing LLM providers
+	LLMDefaultProvider string // Provider for requests naming none (empty = first keyed provider)
+
+	AgentMaxParallelTools int    // Max concurrent tool calls per plan step
+	AgentPolicyFile       string // Optional JSON policy document
+	AgentPolicyDefault    string // Effect when no rule matches: allow, deny, require_approval
+	AgentPolicyDB         bool   // Load additional policy rules from Postgres
+	AgentApproveWrites    bool   // Pause runs for human approval of write actions
+	AgentRunStore         string // Paused run storage: memory, postgres
+	AgentTraceStore       string // Run trace storage: memory, postgres
+	AgentUsageStore       string // Token usage storage: memory, postgres
+	AgentBudget           BudgetConfig
+	AgentToolRetry        ToolRetryConfig
+	AgentRecordCassette   string // Append LLM and tool exchanges to this JSONL cassette
+	AgentDelegatesFile    string // Optional JSON list of sub-agents exposed as tools
+	AgentDelegationDepth  int    // Max nested delegation depth (0 = engine default of 1)
+
+	AgentObservationTokens int  // Prompt budget per tool result (0 = engine default, negative disables)
+	AgentObservationItems  int  // Array items kept when truncating tool results (0 = engine default)
+	AgentSummarizeResults  bool // Summarize oversized tool results with the LLM instead of truncating
+
+	AgentMaxSteps int // Planner steps per run; a multi-step plan runs in one step
+
+	AgentToolCache ToolCacheConfig
+
+	AgentDurableRuns bool          // Run chat requests as Temporal workflows that survive restarts
+	AgentDurableWait time.Duration // How long Chat waits on a durable run before replying that it is still working
+
+	AgentPromptTemplatesDB bool // Render system prompts from per-project templates in Postgres
+
+	AgentRedaction     bool   // Mask PII and secrets before they reach the LLM, for projects without their own setting
+	AgentRedactionFile string // Optional JSON document of patterns, allowlists and per-project settings
+
+	AgentInjectionGuard bool // Flag instruction-like tool output and hold writes derived from it for approval
 
 	// Nucleus platform config
-	Nucleus  NucleusConfig
-	KeyStore KeyStoreConfig
+	Nucleus   NucleusConfig
+	KeyStore  KeyStoreConfig
+	Telemetry TelemetryConfig
 }
 
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
 	port, _ := strconv.Atoi(getEnv("GRPC_PORT", "9000"))
+	maxParallelTools, _ := strconv.Atoi(getEnv("AGENT_MAX_PARALLEL_TOOLS", "4"))
+	sampleRatio, err := strconv.ParseFloat(getEnv("OTEL_TRACES_SAMPLER_ARG", "1"), 64)
+	if err != nil {
+		sampleRatio = 1
+	}
 
 	return &Config{
 		GRPCPort:      port,
@@ -54,10 +136,64 @@ func Load() (*Config, error) {
 		UCLGatewayURL: getEnv("UCL_GATEWAY_URL", "localhost:50051"),
 		MCPServerURL:  getEnv("MCP_SERVER_URL", "http://localhost:9100"),
 		MCPAuthToken:  getEnv("MCP_BEARER_TOKEN", ""),
+		MCPTimeout:    time.Duration(getEnvIntDefault("MCP_TIMEOUT_SECONDS", 15)) * time.Second,
 		GeminiAPIKey:  getEnv("GEMINI_API_KEY", ""),
 		OpenAIAPIKey:  getEnv("OPENAI_API_KEY", ""),
+		GroqAPIKey:    getEnv("GROQ_API_KEY", ""),
 		PostgresURL:   getEnv("POSTGRES_URL", "postgres://localhost:5432/agent"),
 		TemporalHost:  getEnv("TEMPORAL_HOST", "localhost:7233"),
+		AgentPlanner:  getEnv("AGENT_PLANNER", "auto"),
+
+		LLMProvidersFile:   getEnv("LLM_PROVIDERS_FILE", ""),
+		LLMDefaultProvider: getEnv("LLM_DEFAULT_PROVIDER", ""),
+
+		AgentMaxParallelTools: maxParallelTools,
+		AgentPolicyFile:       getEnv("AGENT_POLICY_FILE", ""),
+		AgentPolicyDefault:    getEnv("AGENT_POLICY_DEFAULT", "allow"),
+		AgentPolicyDB:         getEnv("AGENT_POLICY_DB", "false") == "true",
+		AgentApproveWrites:    getEnv("AGENT_APPROVE_WRITES", "true") == "true",
+		AgentRunStore:         getEnv("AGENT_RUN_STORE", "memory"),
+		AgentTraceStore:       getEnv("AGENT_TRACE_STORE", "memory"),
+		AgentUsageStore:       getEnv("AGENT_USAGE_STORE", "memory"),
+		AgentRecordCassette:   getEnv("AGENT_RECORD_CASSETTE", ""),
+		AgentDelegatesFile:    getEnv("AGENT_DELEGATES_FILE", ""),
+		AgentDelegationDepth:  getEnvInt("AGENT_MAX_DELEGATION_DEPTH"),
+		AgentBudget: BudgetConfig{
+			RunTokens:      getEnvInt("AGENT_BUDGET_RUN_TOKENS"),
+			RunCostUSD:     getEnvFloat("AGENT_BUDGET_RUN_COST_USD"),
+			SessionTokens:  getEnvInt("AGENT_BUDGET_SESSION_TOKENS"),
+			SessionCostUSD: getEnvFloat("AGENT_BUDGET_SESSION_COST_USD"),
+			ProjectTokens:  getEnvInt("AGENT_BUDGET_PROJECT_TOKENS"),
+			ProjectCostUSD: getEnvFloat("AGENT_BUDGET_PROJECT_COST_USD"),
+		},
+		AgentToolRetry: ToolRetryConfig{
+			Attempts:         getEnvIntDefault("AGENT_TOOL_RETRY_ATTEMPTS", 3),
+			InitialBackoff:   time.Duration(getEnvIntDefault("AGENT_TOOL_RETRY_BACKOFF_MS", 200)) * time.Millisecond,
+			MaxBackoff:       time.Duration(getEnvIntDefault("AGENT_TOOL_RETRY_MAX_BACKOFF_MS", 2000)) * time.Millisecond,
+			BreakerThreshold: getEnvIntDefault("AGENT_TOOL_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:  time.Duration(getEnvIntDefault("AGENT_TOOL_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second,
+		},
+		AgentObservationTokens: getEnvInt("AGENT_OBSERVATION_TOKENS"),
+		AgentObservationItems:  getEnvInt("AGENT_OBSERVATION_ITEMS"),
+		AgentSummarizeResults:  getEnv("AGENT_SUMMARIZE_OBSERVATIONS", "false") == "true",
+
+		AgentMaxSteps: getEnvIntDefault("AGENT_MAX_STEPS", 3),
+
+		AgentToolCache: ToolCacheConfig{
+			TTL:        time.Duration(getEnvIntDefault("AGENT_TOOL_CACHE_TTL_SECONDS", 60)) * time.Second,
+			ActionTTLs: getEnvSeconds("AGENT_TOOL_CACHE_TTLS"),
+			MaxEntries: getEnvIntDefault("AGENT_TOOL_CACHE_MAX_ENTRIES", 1000),
+		},
+
+		AgentDurableRuns: getEnv("AGENT_DURABLE_RUNS", "false") == "true",
+		AgentDurableWait: time.Duration(getEnvIntDefault("AGENT_DURABLE_WAIT_SECONDS", 60)) * time.Second,
+
+		AgentPromptTemplatesDB: getEnv("AGENT_PROMPT_TEMPLATES_DB", "false") == "true",
+
+		AgentRedaction:     getEnv("AGENT_REDACTION", "false") == "true",
+		AgentRedactionFile: getEnv("AGENT_REDACTION_FILE", ""),
+
+		AgentInjectionGuard: getEnv("AGENT_INJECTION_GUARD", "true") == "true",
 
 		Nucleus: NucleusConfig{
 			APIURL:               getEnv("NUCLEUS_API_URL", "http://localhost:4000/graphql"),
@@ -76,6 +212,14 @@ func Load() (*Config, error) {
 		KeyStore: KeyStoreConfig{
 			DatabaseURL: getEnv("KEYSTORE_DATABASE_URL", getEnv("POSTGRES_URL", "postgres://localhost:5432/agent")),
 		},
+		Telemetry: TelemetryConfig{
+			ServiceName:  getEnv("OTEL_SERVICE_NAME", ""),
+			Exporter:     getEnv("OTEL_TRACES_EXPORTER", "none"),
+			OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			OTLPProtocol: getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc"),
+			File:         getEnv("OTEL_TRACES_FILE", "traces.jsonl"),
+			SampleRatio:  sampleRatio,
+		},
 	}, nil
 }
 
@@ -85,3 +229,38 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt reads an integer variable, returning 0 when unset or invalid
+func getEnvInt(key string) int {
+	value, _ := strconv.Atoi(os.Getenv(key))
+	return value
+}
+
+// getEnvIntDefault reads an integer variable, returning defaultValue when unset or invalid
+func getEnvIntDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat reads a float variable, returning 0 when unset or invalid
+func getEnvFloat(key string) float64 {
+	value, _ := strconv.ParseFloat(os.Getenv(key), 64)
+	return value
+}
+
+// getEnvSeconds reads a comma-separated list of name=seconds pairs, skipping invalid ones
+func getEnvSeconds(key string) map[string]time.Duration {
+	out := make(map[string]time.Duration)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		seconds, err := strconv.Atoi(strings.TrimSpace(raw))
+		if !ok || err != nil || name == "" {
+			continue
+		}
+		out[strings.TrimSpace(name)] = time.Duration(seconds) * time.Second
+	}
+	return out
+}
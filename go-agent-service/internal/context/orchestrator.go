@@ -16,8 +16,12 @@ import (
 type Orchestrator struct {
 	nucleus *nucleus.Client
 	logger  *zap.SugaredLogger
+	logMask LogMask
 }
 
+// LogMask masks sensitive values of a project's text before it is logged
+type LogMask func(projectID, text string) string
+
 // NewOrchestrator creates a new context orchestrator
 func NewOrchestrator(nucleusClient *nucleus.Client, logger *zap.SugaredLogger) *Orchestrator {
 	return &Orchestrator{
@@ -26,6 +30,12 @@ func NewOrchestrator(nucleusClient *nucleus.Client, logger *zap.SugaredLogger) *
 	}
 }
 
+// WithLogMask masks queries before they are logged
+func (o *Orchestrator) WithLogMask(mask LogMask) *Orchestrator {
+	o.logMask = mask
+	return o
+}
+
 // Entity represents an extracted entity from query
 type Entity struct {
 	Type  string `json:"type"`  // ticket, pr, file, service, user
@@ -44,9 +54,13 @@ type Context struct {
 }
 
 // Process extracts entities and builds context from a query
-func (o *Orchestrator) Process(ctx context.Context, query string, contextEntities []string) (*Context, error) {
+func (o *Orchestrator) Process(ctx context.Context, projectID, query string, contextEntities []string) (*Context, error) {
+	logged := query
+	if o.logMask != nil {
+		logged = o.logMask(projectID, query)
+	}
 	o.logger.Debugw("Processing query for context",
-		"query", query,
+		"query", logged,
 		"provided_entities", len(contextEntities),
 	)
 
@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"go.uber.org/zap"
+
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 )
 
 // HTTPServer exposes keystore operations over HTTP.
@@ -28,7 +30,7 @@ func (s *HTTPServer) Handler() http.Handler {
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.Write([]byte("OK"))
 	})
-	return mux
+	return telemetry.Middleware(mux, "keystore")
 }
 
 func (s *HTTPServer) handleCredentials(w http.ResponseWriter, r *http.Request) {
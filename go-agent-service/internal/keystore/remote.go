@@ -10,9 +10,15 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 )
 
+var tracer = telemetry.Tracer("github.com/antigravity/go-agent-service/internal/keystore")
+
 // RemoteStore implements Store over HTTP.
 type RemoteStore struct {
 	baseURL string
@@ -58,7 +64,7 @@ func (r *RemoteStore) Store(ctx context.Context, cred *StoredCredential) (string
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := r.http.Do(req)
+	resp, err := r.do(req, "store")
 	if err != nil {
 		return "", err
 	}
@@ -82,7 +88,7 @@ func (r *RemoteStore) Get(ctx context.Context, keyToken string) (*StoredCredenti
 		return nil, err
 	}
 
-	resp, err := r.http.Do(req)
+	resp, err := r.do(req, "get")
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +118,7 @@ func (r *RemoteStore) Delete(ctx context.Context, keyToken string) error {
 		return err
 	}
 
-	resp, err := r.http.Do(req)
+	resp, err := r.do(req, "delete")
 	if err != nil {
 		return err
 	}
@@ -144,7 +150,7 @@ func (r *RemoteStore) Refresh(ctx context.Context, keyToken string, newAccessTok
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := r.http.Do(req)
+	resp, err := r.do(req, "refresh")
 	if err != nil {
 		return err
 	}
@@ -159,4 +165,20 @@ func (r *RemoteStore) Refresh(ctx context.Context, keyToken string, newAccessTok
 	return nil
 }
 
+// do sends req inside a client span, propagating trace context. Key tokens are
+// kept out of span attributes.
+func (r *RemoteStore) do(req *http.Request, op string) (*http.Response, error) {
+	ctx, span := tracer.Start(req.Context(), "keystore."+op, trace.WithSpanKind(trace.SpanKindClient))
+
+	telemetry.Inject(ctx, req.Header)
+	resp, err := r.http.Do(req.WithContext(ctx))
+	if err != nil {
+		telemetry.End(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	span.End()
+	return resp, nil
+}
+
 // request/response types live in types.go
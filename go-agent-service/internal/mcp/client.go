@@ -12,8 +12,12 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 )
 
+var tracer = telemetry.Tracer("github.com/antigravity/go-agent-service/internal/mcp")
+
 // Client is an HTTP client for the MCP service.
 type Client struct {
 	baseURL   string
@@ -26,6 +30,32 @@ type Client struct {
 type ClientConfig struct {
 	BaseURL   string
 	AuthToken string
+	Timeout   time.Duration // Per request; defaults to 15s
+}
+
+// StatusError is returned when the MCP service answers with a non-2xx status.
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("mcp %s failed: %s", e.Op, e.Status)
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey attaches the idempotency key of a write call to ctx. The
+// tool registry passes it on in ToolCall.IdempotencyKey.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFrom returns the idempotency key attached to ctx, if any.
+func IdempotencyKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
 }
 
 // NewClient creates a new MCP client.
@@ -40,10 +70,15 @@ func NewClientWithConfig(cfg ClientConfig, logger *zap.SugaredLogger) *Client {
 		baseURL = "http://localhost:9100"
 	}
 	baseURL = strings.TrimRight(baseURL, "/")
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
 	return &Client{
 		baseURL: baseURL,
 		http: &http.Client{
-			Timeout: 15 * time.Second,
+			Timeout:   timeout,
+			Transport: telemetry.Transport(nil),
 		},
 		logger:    logger,
 		authToken: cfg.AuthToken,
@@ -51,7 +86,10 @@ func NewClientWithConfig(cfg ClientConfig, logger *zap.SugaredLogger) *Client {
 }
 
 // ListTools returns available tools from the MCP server.
-func (c *Client) ListTools(ctx context.Context, userID, projectID string) ([]ToolDefinition, error) {
+func (c *Client) ListTools(ctx context.Context, userID, projectID string) (tools []ToolDefinition, err error) {
+	ctx, span := tracer.Start(ctx, "mcp.ListTools", telemetry.Attrs("mcp.user_id", userID, "mcp.project_id", projectID))
+	defer func() { telemetry.End(span, err) }()
+
 	endpoint := c.baseURL + "/v1/tools"
 	if userID != "" || projectID != "" {
 		query := make([]string, 0, 2)
@@ -79,10 +117,9 @@ func (c *Client) ListTools(ctx context.Context, userID, projectID string) ([]Too
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("mcp list tools failed: %s", resp.Status)
+		return nil, &StatusError{Op: "list tools", StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
-	var tools []ToolDefinition
 	if err := json.NewDecoder(resp.Body).Decode(&tools); err != nil {
 		c.logger.Warnw("MCP ListTools decode failed", "error", err)
 		return nil, err
@@ -91,7 +128,10 @@ func (c *Client) ListTools(ctx context.Context, userID, projectID string) ([]Too
 }
 
 // ExecuteTool calls the MCP server to execute a tool action.
-func (c *Client) ExecuteTool(ctx context.Context, call ToolCall) (*Result, error) {
+func (c *Client) ExecuteTool(ctx context.Context, call ToolCall) (res *Result, err error) {
+	ctx, span := tracer.Start(ctx, "mcp.ExecuteTool", telemetry.Attrs("tool.name", call.Name, "tool.action", call.Action))
+	defer func() { telemetry.End(span, err) }()
+
 	body, err := json.Marshal(call)
 	if err != nil {
 		return nil, err
@@ -102,6 +142,9 @@ func (c *Client) ExecuteTool(ctx context.Context, call ToolCall) (*Result, error
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if call.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", call.IdempotencyKey)
+	}
 	if c.authToken != "" {
 		req.Header.Set("Authorization", "Bearer "+c.authToken)
 	}
@@ -113,7 +156,7 @@ func (c *Client) ExecuteTool(ctx context.Context, call ToolCall) (*Result, error
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("mcp execute failed: %s", resp.Status)
+		return nil, &StatusError{Op: "execute", StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	var result Result
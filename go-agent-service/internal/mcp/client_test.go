@@ -3,9 +3,11 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -65,3 +67,50 @@ func TestExecuteToolUsesAuthHeader(t *testing.T) {
 		t.Fatalf("expected success")
 	}
 }
+
+func TestExecuteToolSendsIdempotencyKeyAndReportsStatus(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	var gotKey string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("Idempotency-Key")
+		http.Error(w, "backend down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := NewClientWithConfig(ClientConfig{BaseURL: srv.URL}, logger)
+	_, err := client.ExecuteTool(context.Background(), ToolCall{Name: "tool", Action: "create", IdempotencyKey: "k1"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 status error, got %v", err)
+	}
+	if gotKey != "k1" {
+		t.Fatalf("expected idempotency key header, got %q", gotKey)
+	}
+}
+
+func TestIdempotencyCacheRunsKeyOnce(t *testing.T) {
+	cache := newIdempotencyCache(time.Minute)
+	calls := 0
+	fn := func(ctx context.Context) (*Result, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("transient")
+		}
+		return &Result{Success: true, Message: "created"}, nil
+	}
+
+	// Failures are forgotten so the retry executes; later retries share its result
+	if _, err := cache.do(context.Background(), "k", fn); err == nil {
+		t.Fatalf("expected first call to fail")
+	}
+	for i := 0; i < 2; i++ {
+		res, err := cache.do(context.Background(), "k", fn)
+		if err != nil || res.Message != "created" {
+			t.Fatalf("unexpected result %+v, %v", res, err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 executions, got %d", calls)
+	}
+}
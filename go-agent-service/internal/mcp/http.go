@@ -6,8 +6,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 )
 
 // Service wraps tool discovery/execution over HTTP.
@@ -15,6 +20,7 @@ type Service struct {
 	uclServer   *Server
 	nucleusTool Tool
 	logger      *zap.SugaredLogger
+	idempotency *idempotencyCache
 }
 
 // Tool is the minimal interface for an extra MCP tool.
@@ -29,6 +35,7 @@ func NewService(uclServer *Server, nucleusTool Tool, logger *zap.SugaredLogger)
 		uclServer:   uclServer,
 		nucleusTool: nucleusTool,
 		logger:      logger,
+		idempotency: newIdempotencyCache(10 * time.Minute),
 	}
 }
 
@@ -40,7 +47,7 @@ func (s *Service) Handler() http.Handler {
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.Write([]byte("OK"))
 	})
-	return mux
+	return telemetry.Middleware(mux, "mcp")
 }
 
 func (s *Service) handleListTools(w http.ResponseWriter, r *http.Request) {
@@ -85,22 +92,26 @@ func (s *Service) handleExecuteTool(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = r.Header.Get("Idempotency-Key")
+	}
+
+	ctx, span := tracer.Start(r.Context(), "mcp.Service.ExecuteTool", telemetry.Attrs("tool.name", req.Name, "tool.action", req.Action))
 	var result *Result
 	var err error
-
-	if req.Name == "nucleus_search" && s.nucleusTool != nil {
-		params := req.Params
-		if params == nil {
-			params = map[string]any{}
-		}
-		params["action"] = req.Action
-		result, err = s.nucleusTool.Execute(r.Context(), params)
+	if req.IdempotencyKey != "" {
+		// Keys are scoped to the caller so they cannot collide across users
+		key := req.UserID + "/" + req.ProjectID + "/" + req.IdempotencyKey
+		result, err = s.idempotency.do(ctx, key, func(ctx context.Context) (*Result, error) {
+			return s.execute(ctx, req)
+		})
 	} else {
-		result, err = s.uclServer.ExecuteTool(r.Context(), req)
+		result, err = s.execute(ctx, req)
 	}
+	telemetry.End(span, err)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), httpStatus(err))
 		return
 	}
 
@@ -109,3 +120,26 @@ func (s *Service) handleExecuteTool(w http.ResponseWriter, r *http.Request) {
 }
 
  
+
+func (s *Service) execute(ctx context.Context, req ToolCall) (*Result, error) {
+	if req.Name == "nucleus_search" && s.nucleusTool != nil {
+		params := req.Params
+		if params == nil {
+			params = map[string]any{}
+		}
+		params["action"] = req.Action
+		return s.nucleusTool.Execute(ctx, params)
+	}
+	return s.uclServer.ExecuteTool(ctx, req)
+}
+
+// httpStatus maps transient gRPC failures from UCL to 503/504 so clients know to retry.
+func httpStatus(err error) int {
+	switch status.Code(err) {
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// idempotentCallTimeout bounds keyed calls, which keep running when their
+// client gives up so that its retry can pick up the result.
+const idempotentCallTimeout = 2 * time.Minute
+
+// idempotencyCache executes each idempotency key once. Concurrent and later
+// calls with the same key share the first call's result until it expires;
+// failed calls are forgotten so they can be retried.
+type idempotencyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotentCall
+}
+
+type idempotentCall struct {
+	done    chan struct{}
+	result  *Result
+	err     error
+	expires time.Time
+}
+
+func newIdempotencyCache(ttl time.Duration) *idempotencyCache {
+	return &idempotencyCache{ttl: ttl, now: time.Now, entries: make(map[string]*idempotentCall)}
+}
+
+func (c *idempotencyCache) do(ctx context.Context, key string, fn func(ctx context.Context) (*Result, error)) (*Result, error) {
+	c.mu.Lock()
+	now := c.now()
+	for k, entry := range c.entries {
+		if !entry.expires.IsZero() && now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if entry, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-entry.done:
+			return entry.result, entry.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	entry := &idempotentCall{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotentCallTimeout)
+	defer cancel()
+	result, err := fn(callCtx)
+
+	c.mu.Lock()
+	entry.result, entry.err = result, err
+	if err != nil {
+		delete(c.entries, key)
+	} else {
+		entry.expires = c.now().Add(c.ttl)
+	}
+	c.mu.Unlock()
+	close(entry.done)
+	return result, err
+}
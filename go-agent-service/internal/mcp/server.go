@@ -39,6 +39,8 @@ type ToolCall struct {
 	UserID     string         `json:"userId,omitempty"`
 	ProjectID  string         `json:"projectId,omitempty"`
 	Params     map[string]any `json:"params"`
+	// IdempotencyKey makes retried write calls execute once; also sent as the Idempotency-Key header.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // Result represents the result of a tool execution
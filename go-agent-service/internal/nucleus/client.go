@@ -15,8 +15,12 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 )
 
+var tracer = telemetry.Tracer("github.com/antigravity/go-agent-service/internal/nucleus")
+
 // Client is the Nucleus GraphQL client
 type Client struct {
 	url                  string
@@ -57,7 +61,8 @@ func NewClient(url string, logger *zap.SugaredLogger) *Client {
 		url:      url,
 		tenantID: "default",
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 		logger: logger,
 	}
@@ -78,7 +83,8 @@ func NewClientWithConfig(cfg ClientConfig, logger *zap.SugaredLogger) *Client {
 		keycloakUsername:     cfg.KeycloakUsername,
 		keycloakPassword:     cfg.KeycloakPassword,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 		logger: logger,
 	}
@@ -186,6 +192,15 @@ type graphqlError struct {
 	Message string `json:"message"`
 }
 
+// graphqlOperation returns the operation name of a query ("query ListProjects {" -> "ListProjects").
+func graphqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) < 2 || (fields[0] != "query" && fields[0] != "mutation") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.SplitN(fields[1], "(", 2)[0], "{")
+}
+
 func extractTemplateID(config map[string]any) string {
 	if config == nil {
 		return ""
@@ -200,7 +215,10 @@ func extractTemplateID(config map[string]any) string {
 }
 
 // execute sends a GraphQL query to Nucleus
-func (c *Client) execute(ctx context.Context, query string, variables map[string]any) (json.RawMessage, error) {
+func (c *Client) execute(ctx context.Context, query string, variables map[string]any) (data json.RawMessage, err error) {
+	ctx, span := tracer.Start(ctx, "nucleus.graphql", telemetry.Attrs("graphql.operation", graphqlOperation(query)))
+	defer func() { telemetry.End(span, err) }()
+
 	reqBody := graphqlRequest{
 		Query:     query,
 		Variables: variables,
@@ -0,0 +1,56 @@
+package policy
+
+import "time"
+
+// Effect is the outcome of a matching rule.
+type Effect string
+
+const (
+	EffectAllow           Effect = "allow"
+	EffectDeny            Effect = "deny"
+	EffectRequireApproval Effect = "require_approval"
+)
+
+// Rule is a declarative tool policy rule.
+//
+// Tools holds patterns matched against the call target: app tools use their tool
+// name (app/{appId}/{action}), other tools use {tool}/{action}. "*" matches one
+// path segment or part of it, so "app/*/create_*" matches every create action
+// on any app; a bare "*" matches everything.
+type Rule struct {
+	ID             string    `json:"id"`
+	Description    string    `json:"description,omitempty"`
+	ProjectID      string    `json:"projectId,omitempty"` // Empty applies to all projects
+	Users          []string  `json:"users,omitempty"`     // Empty applies to all users
+	Tools          []string  `json:"tools,omitempty"`     // Empty applies to all tools
+	Access         string    `json:"access,omitempty"`    // read, write, or empty for both
+	Effect         Effect    `json:"effect,omitempty"`    // Empty for budget-only rules
+	MaxCallsPerRun int       `json:"maxCallsPerRun,omitempty"`
+	Priority       int       `json:"priority,omitempty"`
+	Disabled       bool      `json:"disabled,omitempty"`
+	CreatedAt      time.Time `json:"createdAt,omitempty"`
+	UpdatedAt      time.Time `json:"updatedAt,omitempty"`
+}
+
+// Document is the on-disk policy format.
+type Document struct {
+	DefaultEffect Effect `json:"defaultEffect,omitempty"`
+	Rules         []Rule `json:"rules"`
+}
+
+// Request describes a tool call to evaluate.
+type Request struct {
+	UserID    string
+	ProjectID string
+	Tool      string
+	Action    string
+	Access    string // read or write
+	CallCount int    // Prior calls of this tool action in the run
+}
+
+// Decision is the evaluation result.
+type Decision struct {
+	Effect Effect
+	Reason string
+	RuleID string
+}
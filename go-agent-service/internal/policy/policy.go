@@ -0,0 +1,184 @@
+// Package policy implements declarative tool policies for agent runs.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Evaluator applies static rules (from config) and stored rules (from the DB) to tool calls.
+type Evaluator struct {
+	defaultEffect Effect
+	rules         []Rule
+	store         Store
+}
+
+// NewEvaluator creates an evaluator. Calls that match no rule get defaultEffect
+// (allow when empty). store may be nil.
+func NewEvaluator(defaultEffect Effect, rules []Rule, store Store) *Evaluator {
+	if defaultEffect == "" {
+		defaultEffect = EffectAllow
+	}
+	return &Evaluator{
+		defaultEffect: defaultEffect,
+		rules:         rules,
+		store:         store,
+	}
+}
+
+// LoadFile reads a JSON policy document.
+func LoadFile(filename string) (*Document, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read policy file: %w", err)
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse policy file: %w", err)
+	}
+	for i, rule := range doc.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("policy rule %d: %w", i, err)
+		}
+	}
+	if err := validateEffect(doc.DefaultEffect); err != nil {
+		return nil, fmt.Errorf("defaultEffect: %w", err)
+	}
+	return &doc, nil
+}
+
+// Validate checks a rule for unknown effects, access kinds and malformed patterns.
+func (r Rule) Validate() error {
+	if err := validateEffect(r.Effect); err != nil {
+		return err
+	}
+	if r.Effect == "" && r.MaxCallsPerRun <= 0 {
+		return fmt.Errorf("rule %q needs an effect or maxCallsPerRun", r.ID)
+	}
+	switch r.Access {
+	case "", "read", "write":
+	default:
+		return fmt.Errorf("unknown access %q", r.Access)
+	}
+	for _, pattern := range r.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func validateEffect(effect Effect) error {
+	switch effect {
+	case "", EffectAllow, EffectDeny, EffectRequireApproval:
+		return nil
+	}
+	return fmt.Errorf("unknown effect %q", effect)
+}
+
+// Evaluate returns the decision for a tool call. Among matching rules the most
+// restrictive effect wins (deny > require_approval > allow); an exhausted call
+// budget denies. If stored rules cannot be loaded the call is denied.
+func (e *Evaluator) Evaluate(ctx context.Context, req Request) Decision {
+	rules := e.rules
+	if e.store != nil {
+		stored, err := e.store.ListRules(ctx, req.ProjectID)
+		if err != nil {
+			return Decision{Effect: EffectDeny, Reason: "policy rules unavailable: " + err.Error()}
+		}
+		rules = append(append([]Rule{}, rules...), stored...)
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+
+	target := Target(req.Tool, req.Action)
+	var decision *Decision
+	for _, rule := range rules {
+		if !rule.matches(req, target) {
+			continue
+		}
+		if rule.MaxCallsPerRun > 0 && req.CallCount >= rule.MaxCallsPerRun {
+			return Decision{
+				Effect: EffectDeny,
+				Reason: fmt.Sprintf("budget of %d call(s) per run exhausted for %s", rule.MaxCallsPerRun, target),
+				RuleID: rule.ID,
+			}
+		}
+		if rule.Effect == "" {
+			continue
+		}
+		if decision == nil || severity(rule.Effect) > severity(decision.Effect) {
+			decision = &Decision{Effect: rule.Effect, Reason: rule.Description, RuleID: rule.ID}
+		}
+	}
+
+	if decision == nil {
+		return Decision{Effect: e.defaultEffect}
+	}
+	return *decision
+}
+
+func (r Rule) matches(req Request, target string) bool {
+	if r.Disabled {
+		return false
+	}
+	if r.ProjectID != "" && r.ProjectID != req.ProjectID {
+		return false
+	}
+	if r.Access != "" && r.Access != req.Access {
+		return false
+	}
+	if len(r.Users) > 0 && !containsString(r.Users, req.UserID) {
+		return false
+	}
+	if len(r.Tools) == 0 {
+		return true
+	}
+	for _, pattern := range r.Tools {
+		if MatchPattern(pattern, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Target returns the string rule patterns are matched against. App tools are
+// already action-scoped (app/{appId}/{action}); other tools become {tool}/{action}.
+func Target(tool, action string) string {
+	if action == "" || strings.HasSuffix(tool, "/"+action) {
+		return tool
+	}
+	return tool + "/" + action
+}
+
+// MatchPattern reports whether target matches a rule pattern.
+func MatchPattern(pattern, target string) bool {
+	if pattern == "*" {
+		return true
+	}
+	ok, err := path.Match(pattern, target)
+	return err == nil && ok
+}
+
+func severity(effect Effect) int {
+	switch effect {
+	case EffectDeny:
+		return 2
+	case EffectRequireApproval:
+		return 1
+	}
+	return 0
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
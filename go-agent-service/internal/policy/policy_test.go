@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type stubStore struct {
+	rules []Rule
+	err   error
+}
+
+func (s *stubStore) ListRules(ctx context.Context, projectID string) ([]Rule, error) {
+	_ = ctx
+	var out []Rule
+	for _, rule := range s.rules {
+		if rule.ProjectID == "" || rule.ProjectID == projectID {
+			out = append(out, rule)
+		}
+	}
+	return out, s.err
+}
+
+func (s *stubStore) UpsertRule(ctx context.Context, rule Rule) (*Rule, error) {
+	_ = ctx
+	s.rules = append(s.rules, rule)
+	return &rule, nil
+}
+
+func (s *stubStore) DeleteRule(ctx context.Context, id string) error {
+	_ = ctx
+	_ = id
+	return nil
+}
+
+func TestEvaluateMostRestrictiveRuleWins(t *testing.T) {
+	evaluator := NewEvaluator(EffectAllow, []Rule{
+		{ID: "writes-need-approval", Access: "write", Effect: EffectRequireApproval, Description: "writes are reviewed"},
+		{ID: "no-deletes", Tools: []string{"app/*/delete_*"}, Effect: EffectDeny},
+		{ID: "jira-ok", Tools: []string{"app/jira-1/*"}, Effect: EffectAllow},
+	}, nil)
+
+	cases := []struct {
+		tool, action, access string
+		want                 Effect
+		rule                 string
+	}{
+		{"app/jira-1/search_issues", "search_issues", "read", EffectAllow, "jira-ok"},
+		{"app/jira-1/create_issue", "create_issue", "write", EffectRequireApproval, "writes-need-approval"},
+		{"app/jira-1/delete_issue", "delete_issue", "write", EffectDeny, "no-deletes"},
+		{"store", "kv_get", "read", EffectAllow, ""},
+	}
+	for _, tc := range cases {
+		got := evaluator.Evaluate(context.Background(), Request{Tool: tc.tool, Action: tc.action, Access: tc.access})
+		if got.Effect != tc.want || got.RuleID != tc.rule {
+			t.Fatalf("%s: expected %s (%s), got %+v", tc.tool, tc.want, tc.rule, got)
+		}
+	}
+}
+
+func TestEvaluateScopesAndBudgets(t *testing.T) {
+	store := &stubStore{rules: []Rule{
+		{ID: "slack-budget", Tools: []string{"app/*/post_message"}, MaxCallsPerRun: 2},
+		{ID: "deny-bob", ProjectID: "p1", Users: []string{"bob"}, Effect: EffectDeny},
+	}}
+	evaluator := NewEvaluator(EffectAllow, nil, store)
+	ctx := context.Background()
+
+	post := Request{ProjectID: "p1", UserID: "alice", Tool: "app/slack-1/post_message", Action: "post_message", Access: "write"}
+	if got := evaluator.Evaluate(ctx, post); got.Effect != EffectAllow {
+		t.Fatalf("expected first post allowed, got %+v", got)
+	}
+	post.CallCount = 2
+	if got := evaluator.Evaluate(ctx, post); got.Effect != EffectDeny || got.RuleID != "slack-budget" {
+		t.Fatalf("expected budget denial, got %+v", got)
+	}
+
+	bob := Request{ProjectID: "p1", UserID: "bob", Tool: "store", Action: "kv_get", Access: "read"}
+	if got := evaluator.Evaluate(ctx, bob); got.Effect != EffectDeny {
+		t.Fatalf("expected bob denied in p1, got %+v", got)
+	}
+	bob.ProjectID = "p2"
+	if got := evaluator.Evaluate(ctx, bob); got.Effect != EffectAllow {
+		t.Fatalf("expected bob allowed in p2, got %+v", got)
+	}
+
+	store.err = errors.New("connection refused")
+	if got := evaluator.Evaluate(ctx, bob); got.Effect != EffectDeny {
+		t.Fatalf("expected fail-closed on store error, got %+v", got)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(good, []byte(`{
+		"defaultEffect": "allow",
+		"rules": [{"id": "writes", "access": "write", "effect": "require_approval"}]
+	}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := LoadFile(good)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(doc.Rules) != 1 || doc.Rules[0].Effect != EffectRequireApproval {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"rules": [{"id": "x", "effect": "maybe"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(bad); err == nil {
+		t.Fatalf("expected unknown effect error")
+	}
+}
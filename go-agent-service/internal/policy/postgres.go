@@ -0,0 +1,142 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// PostgresStore implements Store using PostgreSQL.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL-backed policy store.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) ListRules(ctx context.Context, projectID string) ([]Rule, error) {
+	query := `
+		SELECT id, description, project_id, users, tools, access, effect,
+			max_calls_per_run, priority, enabled, created_at, updated_at
+		FROM agent_policy_rules
+		WHERE enabled = TRUE AND (project_id = '' OR project_id = $1)
+		ORDER BY priority, created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Rule
+	for rows.Next() {
+		var rule Rule
+		var usersBytes []byte
+		var toolsBytes []byte
+		var effect string
+		var enabled bool
+		if err := rows.Scan(
+			&rule.ID,
+			&rule.Description,
+			&rule.ProjectID,
+			&usersBytes,
+			&toolsBytes,
+			&rule.Access,
+			&effect,
+			&rule.MaxCallsPerRun,
+			&rule.Priority,
+			&enabled,
+			&rule.CreatedAt,
+			&rule.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if len(usersBytes) > 0 {
+			if err := json.Unmarshal(usersBytes, &rule.Users); err != nil {
+				return nil, fmt.Errorf("rule %s users: %w", rule.ID, err)
+			}
+		}
+		if len(toolsBytes) > 0 {
+			if err := json.Unmarshal(toolsBytes, &rule.Tools); err != nil {
+				return nil, fmt.Errorf("rule %s tools: %w", rule.ID, err)
+			}
+		}
+		rule.Effect = Effect(effect)
+		rule.Disabled = !enabled
+		results = append(results, rule)
+	}
+	return results, rows.Err()
+}
+
+func (s *PostgresStore) UpsertRule(ctx context.Context, rule Rule) (*Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	if rule.ID == "" {
+		rule.ID = uuid.New().String()
+	}
+	usersBytes, err := json.Marshal(rule.Users)
+	if err != nil {
+		return nil, err
+	}
+	toolsBytes, err := json.Marshal(rule.Tools)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO agent_policy_rules (
+			id, description, project_id, users, tools, access, effect,
+			max_calls_per_run, priority, enabled
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			description = EXCLUDED.description,
+			project_id = EXCLUDED.project_id,
+			users = EXCLUDED.users,
+			tools = EXCLUDED.tools,
+			access = EXCLUDED.access,
+			effect = EXCLUDED.effect,
+			max_calls_per_run = EXCLUDED.max_calls_per_run,
+			priority = EXCLUDED.priority,
+			enabled = EXCLUDED.enabled,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+	if err := s.db.QueryRowContext(ctx, query,
+		rule.ID,
+		rule.Description,
+		rule.ProjectID,
+		usersBytes,
+		toolsBytes,
+		rule.Access,
+		string(rule.Effect),
+		rule.MaxCallsPerRun,
+		rule.Priority,
+		!rule.Disabled,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *PostgresStore) DeleteRule(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM agent_policy_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+var _ Store = (*PostgresStore)(nil)
@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rulesDriver serves one stored rule row with the users and tools columns it holds.
+type rulesDriver struct {
+	users, tools []byte
+}
+
+func (d *rulesDriver) Open(name string) (driver.Conn, error) { return rulesConn{d}, nil }
+
+type rulesConn struct{ d *rulesDriver }
+
+func (c rulesConn) Prepare(query string) (driver.Stmt, error) { return rulesStmt{c.d}, nil }
+func (c rulesConn) Close() error                              { return nil }
+func (c rulesConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type rulesStmt struct{ d *rulesDriver }
+
+func (s rulesStmt) Close() error  { return nil }
+func (s rulesStmt) NumInput() int { return -1 }
+
+func (s rulesStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s rulesStmt) Query(args []driver.Value) (driver.Rows, error) {
+	now := time.Now()
+	row := []driver.Value{"r1", "", "", s.d.users, s.d.tools, "write", "deny", int64(0), int64(0), true, now, now}
+	return &rulesRows{rows: [][]driver.Value{row}}, nil
+}
+
+type rulesRows struct {
+	rows [][]driver.Value
+}
+
+func (r *rulesRows) Columns() []string {
+	return strings.Fields("id description project_id users tools access effect max_calls_per_run priority enabled created_at updated_at")
+}
+func (r *rulesRows) Close() error { return nil }
+
+func (r *rulesRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestListRulesReportsMalformedColumns(t *testing.T) {
+	fake := &rulesDriver{users: []byte(`["u1"]`), tools: []byte(`["jira.*"]`)}
+	sql.Register("policy-fake", fake)
+	db, err := sql.Open("policy-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := NewPostgresStore(db)
+
+	rules, err := store.ListRules(context.Background(), "p1")
+	if err != nil || len(rules) != 1 || rules[0].Users[0] != "u1" || rules[0].Tools[0] != "jira.*" {
+		t.Fatalf("unexpected rules %+v, %v", rules, err)
+	}
+	fake.tools = []byte(`"jira.*"`)
+	if _, err := store.ListRules(context.Background(), "p1"); err == nil || !strings.Contains(err.Error(), "rule r1 tools") {
+		t.Fatalf("expected the malformed tools column reported, got %v", err)
+	}
+}
+
+func TestUpsertRuleValidates(t *testing.T) {
+	store := NewPostgresStore(nil)
+	if _, err := store.UpsertRule(context.Background(), Rule{ID: "r1", Effect: "maybe"}); err == nil {
+		t.Fatalf("expected an unknown effect rejected before it is stored")
+	}
+}
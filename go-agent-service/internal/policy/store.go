@@ -0,0 +1,16 @@
+package policy
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNotFound = errors.New("policy: not found")
+
+// Store defines persistence operations for policy rules.
+type Store interface {
+	// ListRules returns enabled rules that apply to the project (global rules included).
+	ListRules(ctx context.Context, projectID string) ([]Rule, error)
+	UpsertRule(ctx context.Context, rule Rule) (*Rule, error)
+	DeleteRule(ctx context.Context, id string) error
+}
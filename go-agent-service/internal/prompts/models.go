@@ -0,0 +1,55 @@
+package prompts
+
+import (
+	"fmt"
+	"time"
+)
+
+// Template is one version of a project's system prompt, written as a Go
+// text/template rendered with Vars.
+type Template struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"projectId,omitempty"` // Empty applies to projects without their own template
+	Version     int       `json:"version"`             // Assigned per project on create; 0 is the built-in template
+	Body        string    `json:"body"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active,omitempty"` // The version used for new runs
+	CreatedBy   string    `json:"createdBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+}
+
+// Label names the template version in traces, e.g. "p1@v3", "global@v2" or "builtin".
+func (t Template) Label() string {
+	if t.Version == 0 {
+		return "builtin"
+	}
+	scope := t.ProjectID
+	if scope == "" {
+		scope = "global"
+	}
+	return fmt.Sprintf("%s@v%d", scope, t.Version)
+}
+
+// Vars are the variables a template is rendered with.
+type Vars struct {
+	ProjectID   string
+	ProjectName string // Falls back to ProjectID
+	UserID      string
+	UserRole    string
+	Date        string // YYYY-MM-DD
+	Tools       []Tool // The tools available to the run
+}
+
+// Tool describes an available tool to a template.
+type Tool struct {
+	Name        string
+	Description string
+	Actions     []Action
+}
+
+// Action describes a tool action to a template.
+type Action struct {
+	Name        string
+	Description string
+	Access      string // read or write
+}
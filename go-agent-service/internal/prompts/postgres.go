@@ -0,0 +1,145 @@
+package prompts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// PostgresStore implements Store using PostgreSQL.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL-backed prompt template store.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const selectTemplate = `
+	SELECT id, project_id, version, body, description, active, created_by, created_at
+	FROM agent_prompt_templates`
+
+func (s *PostgresStore) ActiveTemplate(ctx context.Context, projectID string) (*Template, error) {
+	row := s.db.QueryRowContext(ctx, selectTemplate+` WHERE project_id = $1 AND active`, projectID)
+	t, err := scanTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *PostgresStore) ListTemplates(ctx context.Context, projectID string) ([]Template, error) {
+	rows, err := s.db.QueryContext(ctx, selectTemplate+` WHERE project_id = $1 ORDER BY version DESC`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, t)
+	}
+	return results, rows.Err()
+}
+
+func (s *PostgresStore) CreateTemplate(ctx context.Context, t Template) (*Template, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Serialize version numbering per project
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('agent_prompt_templates:' || $1))`, t.ProjectID); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) + 1 FROM agent_prompt_templates WHERE project_id = $1`, t.ProjectID,
+	).Scan(&t.Version); err != nil {
+		return nil, err
+	}
+	if t.Active {
+		if _, err := tx.ExecContext(ctx, `UPDATE agent_prompt_templates SET active = FALSE WHERE project_id = $1 AND active`, t.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	t.ID = uuid.New().String()
+	query := `
+		INSERT INTO agent_prompt_templates (id, project_id, version, body, description, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+	if err := tx.QueryRowContext(ctx, query,
+		t.ID,
+		t.ProjectID,
+		t.Version,
+		t.Body,
+		t.Description,
+		t.Active,
+		t.CreatedBy,
+	).Scan(&t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *PostgresStore) ActivateTemplate(ctx context.Context, projectID string, version int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM agent_prompt_templates WHERE project_id = $1 AND version = $2)`,
+		projectID, version,
+	).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE agent_prompt_templates SET active = (version = $2) WHERE project_id = $1 AND (active OR version = $2)`,
+		projectID, version,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTemplate(row scanner) (Template, error) {
+	var t Template
+	err := row.Scan(
+		&t.ID,
+		&t.ProjectID,
+		&t.Version,
+		&t.Body,
+		&t.Description,
+		&t.Active,
+		&t.CreatedBy,
+		&t.CreatedAt,
+	)
+	return t, err
+}
+
+var _ Store = (*PostgresStore)(nil)
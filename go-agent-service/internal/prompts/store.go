@@ -0,0 +1,21 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNotFound = errors.New("prompts: not found")
+
+// Store defines persistence operations for prompt template versions.
+type Store interface {
+	// ActiveTemplate returns the active version for the project, or ErrNotFound.
+	// An empty projectID returns the global template.
+	ActiveTemplate(ctx context.Context, projectID string) (*Template, error)
+	// ListTemplates returns the project's versions, newest first.
+	ListTemplates(ctx context.Context, projectID string) ([]Template, error)
+	// CreateTemplate stores a template as the project's next version, active when t.Active is set.
+	CreateTemplate(ctx context.Context, t Template) (*Template, error)
+	// ActivateTemplate makes a version the project's active one.
+	ActivateTemplate(ctx context.Context, projectID string, version int) error
+}
@@ -0,0 +1,169 @@
+// Package prompts renders versioned system prompt templates per project.
+package prompts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// BuiltinBody is the system prompt used when neither the project nor the
+// global scope has an active template.
+const BuiltinBody = `You are the Antigravity Agent, a Graph-Native developer assistant designed to help with software development tasks{{if .ProjectName}} in the {{.ProjectName}} project{{end}}.
+{{- if .UserRole}}
+You are assisting a user with the {{.UserRole}} role.{{end}}
+{{- if .Date}}
+Today is {{.Date}}.{{end}}
+
+## Core Capabilities
+- Analyze and fix bugs in code
+- Review pull requests and provide feedback
+- Generate documentation
+- Create and manage workflows
+- Query the Nucleus Knowledge Graph for context
+
+## Response Format
+Always structure your responses with:
+1. Clear explanation of what you found/understood
+2. Proposed solution or action
+3. Any artifacts (code, docs, configs) needed
+
+## Tool Usage
+{{- if .Tools}}
+You have access to these tools; their actions and input schemas are listed under Available Tools:
+{{- range .Tools}}
+- {{.Name}}{{if .Description}}: {{.Description}}{{end}}
+{{- end}}
+{{- else}}
+No tools are available for this request. Answer from the context you have and say what you could not check.
+{{- end}}
+
+## Citation Format
+When referencing entities from tool observations, cite their IDs in square brackets, e.g. [PROJ-123].
+Only cite IDs that appear in the tool observations; name other entities without brackets.
+
+## Glass Box Reasoning
+Always show your thought process:
+1. What information you're retrieving
+2. What analysis you're performing
+3. What synthesis leads to your conclusion
+4. What action you're proposing
+
+Be concise but thorough. Prioritize actionable insights over verbose explanations.`
+
+// Builtin is the built-in template, version 0.
+var Builtin = Template{ID: "builtin", Body: BuiltinBody, Active: true}
+
+var funcs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// Parse parses a template body.
+func Parse(body string) (*template.Template, error) {
+	return template.New("prompt").Funcs(funcs).Option("missingkey=error").Parse(body)
+}
+
+// Validate checks that a template body parses and renders with example variables,
+// which catches references to variables that do not exist.
+func Validate(body string) error {
+	_, err := Render(body, Vars{
+		ProjectID:   "project",
+		ProjectName: "Project",
+		UserID:      "user",
+		UserRole:    "member",
+		Date:        "2006-01-02",
+		Tools:       []Tool{{Name: "tool", Description: "A tool", Actions: []Action{{Name: "action", Access: "read"}}}},
+	})
+	return err
+}
+
+// Render renders a template body with vars.
+func Render(body string, vars Vars) (string, error) {
+	tmpl, err := Parse(body)
+	if err != nil {
+		return "", err
+	}
+	return execute(tmpl, vars)
+}
+
+func execute(tmpl *template.Template, vars Vars) (string, error) {
+	if vars.ProjectName == "" {
+		vars.ProjectName = vars.ProjectID
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// Library selects and renders the active template of a project. Parsed
+// templates are cached by ID, since versions never change once stored.
+type Library struct {
+	store Store
+
+	mu     sync.Mutex
+	parsed map[string]*template.Template
+}
+
+// NewLibrary creates a template library. A nil store always uses the built-in template.
+func NewLibrary(store Store) *Library {
+	return &Library{store: store, parsed: make(map[string]*template.Template)}
+}
+
+// Store returns the library's template store, nil when not configured.
+func (l *Library) Store() Store {
+	return l.store
+}
+
+// Select returns the project's active template, else the global one, else the
+// built-in template. A store error falls back to the built-in template and is returned.
+func (l *Library) Select(ctx context.Context, projectID string) (Template, error) {
+	if l == nil || l.store == nil {
+		return Builtin, nil
+	}
+	scopes := []string{""}
+	if projectID != "" {
+		scopes = []string{projectID, ""}
+	}
+	for _, scope := range scopes {
+		t, err := l.store.ActiveTemplate(ctx, scope)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Builtin, fmt.Errorf("load prompt template: %w", err)
+		}
+		return *t, nil
+	}
+	return Builtin, nil
+}
+
+// Render renders t with vars.
+func (l *Library) Render(t Template, vars Vars) (string, error) {
+	if l == nil || t.ID == "" {
+		return Render(t.Body, vars)
+	}
+	l.mu.Lock()
+	tmpl, ok := l.parsed[t.ID]
+	l.mu.Unlock()
+	if !ok {
+		var err error
+		if tmpl, err = Parse(t.Body); err != nil {
+			return "", fmt.Errorf("parse prompt template %s: %w", t.Label(), err)
+		}
+		l.mu.Lock()
+		l.parsed[t.ID] = tmpl
+		l.mu.Unlock()
+	}
+	out, err := execute(tmpl, vars)
+	if err != nil {
+		return "", fmt.Errorf("render prompt template %s: %w", t.Label(), err)
+	}
+	return out, nil
+}
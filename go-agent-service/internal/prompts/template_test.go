@@ -0,0 +1,108 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// memoryStore keeps active templates by project.
+type memoryStore struct {
+	active map[string]Template
+	err    error
+}
+
+func (s *memoryStore) ActiveTemplate(ctx context.Context, projectID string) (*Template, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	t, ok := s.active[projectID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+func (s *memoryStore) ListTemplates(ctx context.Context, projectID string) ([]Template, error) {
+	return nil, nil
+}
+
+func (s *memoryStore) CreateTemplate(ctx context.Context, t Template) (*Template, error) {
+	return &t, nil
+}
+
+func (s *memoryStore) ActivateTemplate(ctx context.Context, projectID string, version int) error {
+	return nil
+}
+
+func TestBuiltinRendersAvailableTools(t *testing.T) {
+	out, err := Render(BuiltinBody, Vars{
+		ProjectID: "p1",
+		UserRole:  "admin",
+		Date:      "2026-10-16",
+		Tools:     []Tool{{Name: "app/a1/list_issues", Description: "Jira action list_issues"}},
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, want := range []string{"in the p1 project", "with the admin role", "Today is 2026-10-16", "- app/a1/list_issues: Jira action list_issues"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in prompt:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "pagerduty") {
+		t.Fatalf("expected no tools beyond the available ones:\n%s", out)
+	}
+	if strings.Contains(out, "[file.ts:42]") || !strings.Contains(out, "Only cite IDs that appear in the tool observations") {
+		t.Fatalf("expected citations limited to observed IDs:\n%s", out)
+	}
+
+	out, _ = Render(BuiltinBody, Vars{})
+	if !strings.Contains(out, "No tools are available") {
+		t.Fatalf("expected the no-tools note:\n%s", out)
+	}
+}
+
+func TestValidateRejectsBadTemplates(t *testing.T) {
+	if err := Validate("Project {{.ProjectName}} has {{len .Tools}} tools: {{range .Tools}}{{.Name}} {{end}}"); err != nil {
+		t.Fatalf("expected a valid template, got %v", err)
+	}
+	for _, body := range []string{"{{.Team}}", "{{range .Tools}}", "{{nope .UserID}}"} {
+		if err := Validate(body); err == nil {
+			t.Fatalf("expected %q rejected", body)
+		}
+	}
+}
+
+func TestLibrarySelectsProjectThenGlobalThenBuiltin(t *testing.T) {
+	store := &memoryStore{active: map[string]Template{
+		"p1": {ID: "t3", ProjectID: "p1", Version: 3, Body: "Project {{.ProjectName}}"},
+		"":   {ID: "t1", Version: 1, Body: "Global"},
+	}}
+	library := NewLibrary(store)
+
+	cases := map[string]string{"p1": "p1@v3", "p2": "global@v1", "": "global@v1"}
+	for project, want := range cases {
+		got, err := library.Select(context.Background(), project)
+		if err != nil || got.Label() != want {
+			t.Fatalf("project %q: expected %s, got %s, %v", project, want, got.Label(), err)
+		}
+	}
+	got, _ := library.Select(context.Background(), "p1")
+	if out, err := library.Render(got, Vars{ProjectID: "p1", ProjectName: "Payments"}); err != nil || out != "Project Payments" {
+		t.Fatalf("unexpected render: %q, %v", out, err)
+	}
+
+	delete(store.active, "")
+	if got, _ := library.Select(context.Background(), "p2"); got.Label() != "builtin" {
+		t.Fatalf("expected the built-in template, got %s", got.Label())
+	}
+	store.err = errors.New("db down")
+	if got, err := library.Select(context.Background(), "p1"); err == nil || got.Label() != "builtin" {
+		t.Fatalf("expected the built-in template and the error, got %s, %v", got.Label(), err)
+	}
+	if got, err := NewLibrary(nil).Select(context.Background(), "p1"); err != nil || got.Label() != "builtin" {
+		t.Fatalf("expected the built-in template without a store, got %s, %v", got.Label(), err)
+	}
+}
@@ -0,0 +1,21 @@
+package redact
+
+// Document is the on-disk redaction format.
+//
+// Patterns add rules by placeholder label, e.g. "EMPLOYEE_ID": "EMP-[0-9]{6}";
+// when a pattern has a group, only the group is masked. Allow holds patterns
+// of values that are never masked, such as a support address.
+type Document struct {
+	Enabled  bool                       `json:"enabled"`            // Default for projects without their own setting
+	Disable  []string                   `json:"disable,omitempty"`  // Built-in rules to skip: SECRET, EMAIL, CARD, PHONE
+	Patterns map[string]string          `json:"patterns,omitempty"` // Extra rules by label
+	Allow    []string                   `json:"allow,omitempty"`
+	Projects map[string]ProjectSettings `json:"projects,omitempty"` // Keyed by project ID
+}
+
+// ProjectSettings override the document defaults for one project.
+type ProjectSettings struct {
+	Enabled  *bool             `json:"enabled,omitempty"`  // Unset keeps the default
+	Patterns map[string]string `json:"patterns,omitempty"` // Added to the document patterns
+	Allow    []string          `json:"allow,omitempty"`    // Added to the document allowlist
+}
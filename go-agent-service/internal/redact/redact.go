@@ -0,0 +1,196 @@
+// Package redact masks PII and secrets in text sent to LLMs with reversible placeholders.
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// Policy decides per project whether and how runs are redacted. It implements
+// agentengine.Redaction.
+type Policy struct {
+	enabled  bool
+	base     *ruleset
+	projects map[string]projectRules
+}
+
+type projectRules struct {
+	enabled bool
+	rules   *ruleset
+}
+
+// LoadFile reads a JSON redaction document.
+func LoadFile(filename string) (*Document, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read redaction file: %w", err)
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse redaction file: %w", err)
+	}
+	return &doc, nil
+}
+
+// New compiles a redaction document.
+func New(doc Document) (*Policy, error) {
+	for _, label := range doc.Disable {
+		switch strings.ToUpper(label) {
+		case LabelSecret, LabelEmail, LabelCard, LabelPhone:
+		default:
+			return nil, fmt.Errorf("disable: unknown rule %q", label)
+		}
+	}
+	base, err := newRuleset(doc.Disable, doc.Patterns, doc.Allow)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{enabled: doc.Enabled, base: base, projects: make(map[string]projectRules, len(doc.Projects))}
+	for projectID, settings := range doc.Projects {
+		rules, err := base.with(settings.Patterns, settings.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %w", projectID, err)
+		}
+		enabled := doc.Enabled
+		if settings.Enabled != nil {
+			enabled = *settings.Enabled
+		}
+		p.projects[projectID] = projectRules{enabled: enabled, rules: rules}
+	}
+	return p, nil
+}
+
+// Enabled reports whether runs of a project are redacted.
+func (p *Policy) Enabled(projectID string) bool {
+	return p.rules(projectID) != nil
+}
+
+// rules returns the ruleset of a project, nil when it is not redacted.
+func (p *Policy) rules(projectID string) *ruleset {
+	if p == nil {
+		return nil
+	}
+	if project, ok := p.projects[projectID]; ok {
+		if !project.enabled {
+			return nil
+		}
+		return project.rules
+	}
+	if !p.enabled {
+		return nil
+	}
+	return p.base
+}
+
+// ForRun implements agentengine.Redaction.
+func (p *Policy) ForRun(ctx context.Context, req agentengine.Request, placeholders map[string]string) agentengine.Redactor {
+	rules := p.rules(req.ProjectID)
+	if rules == nil {
+		return nil
+	}
+	return newVault(rules, placeholders)
+}
+
+// Mask masks sensitive values for logs, without placeholders to restore them:
+// an email becomes [EMAIL]. Text of projects that are not redacted is returned as is.
+func (p *Policy) Mask(projectID, text string) string {
+	rules := p.rules(projectID)
+	if rules == nil {
+		return text
+	}
+	return rules.replace(text, func(label, _ string) string { return "[" + label + "]" })
+}
+
+// vault masks values of one run with numbered placeholders and remembers them
+// so they can be restored. The same value always gets the same placeholder.
+type vault struct {
+	rules *ruleset
+
+	mu       sync.Mutex
+	byValue  map[string]string
+	values   map[string]string // Value by placeholder
+	counters map[string]int    // Last number used per label
+}
+
+func newVault(rules *ruleset, placeholders map[string]string) *vault {
+	v := &vault{
+		rules:    rules,
+		byValue:  make(map[string]string, len(placeholders)),
+		values:   make(map[string]string, len(placeholders)),
+		counters: make(map[string]int),
+	}
+	for placeholder, value := range placeholders {
+		v.values[placeholder] = value
+		v.byValue[value] = placeholder
+		label, n := parsePlaceholder(placeholder)
+		if n > v.counters[label] {
+			v.counters[label] = n
+		}
+	}
+	return v
+}
+
+// Redact implements agentengine.Redactor.
+func (v *vault) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	return v.rules.replace(text, v.placeholder)
+}
+
+// Restore implements agentengine.Redactor. Unknown placeholders are left as is.
+func (v *vault) Restore(text string) string {
+	if !strings.Contains(text, "[") {
+		return text
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if value, ok := v.values[placeholder]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// Placeholders implements agentengine.Redactor.
+func (v *vault) Placeholders() map[string]string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := make(map[string]string, len(v.values))
+	for placeholder, value := range v.values {
+		out[placeholder] = value
+	}
+	return out
+}
+
+func (v *vault) placeholder(label, value string) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if placeholder, ok := v.byValue[value]; ok {
+		return placeholder
+	}
+	v.counters[label]++
+	placeholder := fmt.Sprintf("[%s_%d]", label, v.counters[label])
+	v.byValue[value] = placeholder
+	v.values[placeholder] = value
+	return placeholder
+}
+
+// parsePlaceholder splits [EMAIL_2] into EMAIL and 2.
+func parsePlaceholder(placeholder string) (string, int) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(placeholder, "["), "]")
+	i := strings.LastIndex(inner, "_")
+	if i < 0 {
+		return inner, 0
+	}
+	n, _ := strconv.Atoi(inner[i+1:])
+	return inner[:i], n
+}
@@ -0,0 +1,113 @@
+package redact
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+func mustPolicy(t *testing.T, doc Document) *Policy {
+	t.Helper()
+	p, err := New(doc)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return p
+}
+
+func TestRedactMasksBuiltinsAndRestores(t *testing.T) {
+	p := mustPolicy(t, Document{Enabled: true})
+	r := p.ForRun(context.Background(), agentengine.Request{ProjectID: "p1"}, nil)
+	text := "Mail ana@example.com or call +1 415-555-0123; card 4111 1111 1111 1111, " +
+		"key sk-proj-abcdefghijklmnopqrstuvwx, password: hunter2pass. Again ana@example.com. " +
+		"Version 1.2.3 from 10.0.0.12 on 2026-10-16, order 1234567890123."
+
+	got := r.Redact(text)
+	for _, secret := range []string{"ana@example.com", "415-555-0123", "4111 1111", "sk-proj", "hunter2pass"} {
+		if strings.Contains(got, secret) {
+			t.Fatalf("expected %q masked, got %q", secret, got)
+		}
+	}
+	for _, want := range []string{"[EMAIL_1] or", "Again [EMAIL_1]", "[PHONE_1]", "[CARD_1]", "key [SECRET_1]", "password: [SECRET_2]", "1.2.3", "10.0.0.12", "2026-10-16", "1234567890123"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in %q", want, got)
+		}
+	}
+	if restored := r.Restore(got); restored != text {
+		t.Fatalf("expected the text restored, got %q", restored)
+	}
+	if r.Redact(got) != got {
+		t.Fatalf("expected redacted text left alone")
+	}
+}
+
+func TestRedactResumesWithPlaceholders(t *testing.T) {
+	p := mustPolicy(t, Document{Enabled: true})
+	first := p.ForRun(context.Background(), agentengine.Request{}, nil)
+	first.Redact("a@example.com and b@example.com")
+
+	resumed := p.ForRun(context.Background(), agentengine.Request{}, first.Placeholders())
+	if got := resumed.Redact("b@example.com, c@example.com"); got != "[EMAIL_2], [EMAIL_3]" {
+		t.Fatalf("expected known values kept and numbering continued, got %q", got)
+	}
+	if got := resumed.Restore("[EMAIL_1] [EMAIL_9]"); got != "a@example.com [EMAIL_9]" {
+		t.Fatalf("unexpected restore: %q", got)
+	}
+}
+
+func TestRedactProjectSettingsPatternsAndAllowlist(t *testing.T) {
+	off := false
+	p := mustPolicy(t, Document{
+		Enabled:  true,
+		Disable:  []string{"phone"},
+		Patterns: map[string]string{"employee_id": `EMP-[0-9]{6}`},
+		Allow:    []string{`^support@example\.com$`},
+		Projects: map[string]ProjectSettings{
+			"internal": {Enabled: &off},
+			"acme":     {Patterns: map[string]string{"TICKET": `ticket #([0-9]+)`}},
+		},
+	})
+	if p.Enabled("internal") || !p.Enabled("acme") || !p.Enabled("other") {
+		t.Fatalf("unexpected project settings")
+	}
+	if p.ForRun(context.Background(), agentengine.Request{ProjectID: "internal"}, nil) != nil {
+		t.Fatalf("expected no redactor for a disabled project")
+	}
+
+	text := "EMP-123456 asked support@example.com about ticket #42, call 415-555-0123"
+	if got := p.Mask("acme", text); got != "[EMPLOYEE_ID] asked support@example.com about ticket #[TICKET], call 415-555-0123" {
+		t.Fatalf("unexpected mask: %q", got)
+	}
+	if got := p.Mask("other", text); !strings.Contains(got, "ticket #42") {
+		t.Fatalf("expected project patterns not applied elsewhere, got %q", got)
+	}
+	if got := p.Mask("internal", text); got != text {
+		t.Fatalf("expected a disabled project unmasked, got %q", got)
+	}
+	var none *Policy
+	if none.Mask("p1", text) != text {
+		t.Fatalf("expected a nil policy to mask nothing")
+	}
+
+	if _, err := New(Document{Patterns: map[string]string{"bad label": "x"}}); err == nil {
+		t.Fatalf("expected an invalid label rejected")
+	}
+	if _, err := New(Document{Disable: []string{"ssn"}}); err == nil {
+		t.Fatalf("expected an unknown built-in rejected")
+	}
+}
+
+func TestRedactAppliesOverlappingPatternsInLabelOrder(t *testing.T) {
+	for range 20 {
+		p := mustPolicy(t, Document{Enabled: true, Patterns: map[string]string{
+			"ticket": `ID-[0-9]+`,
+			"asset":  `ID-[0-9]+`,
+			"order":  `ID-[0-9]+`,
+		}})
+		if got := p.Mask("", "see ID-42"); got != "see [ASSET]" {
+			t.Fatalf("expected the first label to mask the value, got %q", got)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package redact
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Built-in rule labels, used in placeholders such as [EMAIL_1].
+const (
+	LabelSecret = "SECRET"
+	LabelEmail  = "EMAIL"
+	LabelCard   = "CARD"
+	LabelPhone  = "PHONE"
+)
+
+// rule masks matches of a pattern. When the pattern has a group, only the
+// first group is masked, so "token=abc" keeps its "token=".
+type rule struct {
+	label   string
+	pattern *regexp.Regexp
+	valid   func(value string) bool // Optional check of a match
+}
+
+// builtinRules run in order: secrets first, so tokens containing "@" or digit
+// runs are not split into emails or phone numbers.
+var builtinRules = []rule{
+	{label: LabelSecret, pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{label: LabelSecret, pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},  // JWT
+	{label: LabelSecret, pattern: regexp.MustCompile(`\b(?:sk|rk|pk)-(?:[a-z]+-)?[A-Za-z0-9_-]{20,}`)},                 // OpenAI, Stripe
+	{label: LabelSecret, pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},                                 // AWS access key
+	{label: LabelSecret, pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)}, // GitHub
+	{label: LabelSecret, pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},                               // Slack
+	{label: LabelSecret, pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},                                       // Google API key
+	{label: LabelSecret, pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{16,})`)},                      // Authorization header
+	{label: LabelSecret, pattern: regexp.MustCompile(`(?i)\b(?:api[_-]?key|access[_-]?key|secret|token|password|passwd|pwd)["']?\s*[:=]\s*["']?([^\s"',;]{6,})`)},
+	{label: LabelEmail, pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	{label: LabelCard, pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhn},
+	{label: LabelPhone, pattern: regexp.MustCompile(`(?:^|[^\w+])((?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)[ .-]?)?\d{2,4}[ .-]\d{3,4}[ .-]?\d{3,4}|\+\d{9,15})\b`), valid: phone},
+}
+
+// placeholderPattern matches placeholders such as [EMAIL_1] or [EMPLOYEE_ID_12].
+var placeholderPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_[0-9]+\]`)
+
+var labelPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// ruleset is the rules and allowlist applied to a project.
+type ruleset struct {
+	rules []rule
+	allow []*regexp.Regexp
+}
+
+func newRuleset(disable []string, patterns map[string]string, allow []string) (*ruleset, error) {
+	rs := &ruleset{}
+	for _, r := range builtinRules {
+		if !containsFold(disable, r.label) {
+			rs.rules = append(rs.rules, r)
+		}
+	}
+	if err := rs.add(patterns, allow); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// with returns a copy of the ruleset with more patterns and allowed values.
+func (rs *ruleset) with(patterns map[string]string, allow []string) (*ruleset, error) {
+	out := &ruleset{
+		rules: append([]rule(nil), rs.rules...),
+		allow: append([]*regexp.Regexp(nil), rs.allow...),
+	}
+	if err := out.add(patterns, allow); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// add appends patterns in label order, so overlapping patterns always apply
+// in the same order.
+func (rs *ruleset) add(patterns map[string]string, allow []string) error {
+	for _, name := range slices.Sorted(maps.Keys(patterns)) {
+		expr := patterns[name]
+		label := strings.ToUpper(name)
+		if !labelPattern.MatchString(label) {
+			return fmt.Errorf("pattern label %q must be letters, digits and underscores", label)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("pattern %s: %w", label, err)
+		}
+		rs.rules = append(rs.rules, rule{label: label, pattern: re})
+	}
+	for _, expr := range allow {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("allow pattern %q: %w", expr, err)
+		}
+		rs.allow = append(rs.allow, re)
+	}
+	return nil
+}
+
+// replace applies every rule to text, replacing each sensitive value with mask(label, value).
+func (rs *ruleset) replace(text string, mask func(label, value string) string) string {
+	for _, r := range rs.rules {
+		text = r.replace(text, rs.allowed, mask)
+	}
+	return text
+}
+
+func (rs *ruleset) allowed(value string) bool {
+	for _, re := range rs.allow {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rule) replace(text string, allowed func(string) bool, mask func(label, value string) string) string {
+	matches := r.pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) > 2 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		value := text[start:end]
+		if placeholderPattern.MatchString(value) || allowed(value) || (r.valid != nil && !r.valid(value)) {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(mask(r.label, value))
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// luhn reports whether the digits of value pass the card number checksum.
+func luhn(value string) bool {
+	sum, n := 0, 0
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// phone accepts 9 to 15 digits ending in a group of at least four, which
+// leaves dotted version numbers and IP addresses alone.
+func phone(value string) bool {
+	digits, group := 0, 0
+	for _, c := range value {
+		if c >= '0' && c <= '9' {
+			digits++
+			group++
+		} else {
+			group = 0
+		}
+	}
+	return digits >= 9 && digits <= 15 && group >= 4
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Package runstore persists agent runs paused for human approval and the
+// checkpoints of durable runs.
+package runstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// PostgresStore implements agentengine.RunStore using PostgreSQL.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL-backed run store.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) SaveRun(ctx context.Context, run *agentengine.PendingRun) error {
+	payload, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO agent_pending_runs (
+			id, session_id, user_id, project_id, payload, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			payload = EXCLUDED.payload,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err = s.db.ExecContext(ctx, query,
+		run.ID,
+		run.Request.SessionID,
+		run.Request.UserID,
+		run.Request.ProjectID,
+		payload,
+		run.CreatedAt,
+		run.UpdatedAt,
+	)
+	return err
+}
+
+func (s *PostgresStore) GetRun(ctx context.Context, id string) (*agentengine.PendingRun, error) {
+	var payload []byte
+	if err := s.db.QueryRowContext(ctx, `SELECT payload FROM agent_pending_runs WHERE id = $1`, id).Scan(&payload); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, agentengine.ErrRunNotFound
+		}
+		return nil, err
+	}
+
+	var run agentengine.PendingRun
+	if err := json.Unmarshal(payload, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (s *PostgresStore) DeleteRun(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM agent_pending_runs WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		// Another decider claimed the run first
+		return agentengine.ErrRunNotFound
+	}
+	return nil
+}
+
+var _ agentengine.RunStore = (*PostgresStore)(nil)
+
+// SaveCheckpoint stores a version of a durable run's state and drops the
+// versions before the one it replaces.
+func (s *PostgresStore) SaveCheckpoint(ctx context.Context, runID string, version int, state agentengine.CheckpointState) error {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO agent_run_checkpoints (run_id, version, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (run_id, version) DO UPDATE SET payload = EXCLUDED.payload
+	`
+	if _, err := s.db.ExecContext(ctx, query, runID, version, payload); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `DELETE FROM agent_run_checkpoints WHERE run_id = $1 AND version < $2`, runID, version-1)
+	return err
+}
+
+func (s *PostgresStore) LoadCheckpoint(ctx context.Context, runID string, version int) (agentengine.CheckpointState, error) {
+	var payload []byte
+	query := `SELECT payload FROM agent_run_checkpoints WHERE run_id = $1 AND version = $2`
+	if err := s.db.QueryRowContext(ctx, query, runID, version).Scan(&payload); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return agentengine.CheckpointState{}, agentengine.ErrCheckpointNotFound
+		}
+		return agentengine.CheckpointState{}, err
+	}
+
+	var state agentengine.CheckpointState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return agentengine.CheckpointState{}, err
+	}
+	return state, nil
+}
+
+func (s *PostgresStore) DeleteCheckpoints(ctx context.Context, runID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM agent_run_checkpoints WHERE run_id = $1`, runID)
+	return err
+}
+
+var _ agentengine.CheckpointStore = (*PostgresStore)(nil)
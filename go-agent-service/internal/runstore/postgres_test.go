@@ -0,0 +1,160 @@
+package runstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// fakeDriver serves the run store's queries from a set of run IDs and
+// checkpoint payloads by run ID and version.
+type fakeDriver struct {
+	mu          sync.Mutex
+	runs        map[string]bool
+	checkpoints map[string]map[int64][]byte
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: strings.TrimSpace(query)}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	id := args[0].(string)
+	switch {
+	case strings.Contains(s.query, "agent_run_checkpoints"):
+		return s.execCheckpoint(id, args)
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.d.runs[id] = true
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "DELETE"):
+		if !s.d.runs[id] {
+			return driver.RowsAffected(0), nil
+		}
+		delete(s.d.runs, id)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+func (s *fakeStmt) execCheckpoint(runID string, args []driver.Value) (driver.Result, error) {
+	versions := s.d.checkpoints[runID]
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		if versions == nil {
+			versions = make(map[int64][]byte)
+			s.d.checkpoints[runID] = versions
+		}
+		versions[args[1].(int64)] = args[2].([]byte)
+	case strings.Contains(s.query, "version <"):
+		for version := range versions {
+			if version < args[1].(int64) {
+				delete(versions, version)
+			}
+		}
+	default:
+		delete(s.d.checkpoints, runID)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	rows := &fakeRows{}
+	if payload, ok := s.d.checkpoints[args[0].(string)][args[1].(int64)]; ok {
+		rows.payloads = [][]byte{payload}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	payloads [][]byte
+}
+
+func (r *fakeRows) Columns() []string { return []string{"payload"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.payloads) == 0 {
+		return io.EOF
+	}
+	dest[0], r.payloads = r.payloads[0], r.payloads[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("runstore-fake", &fakeDriver{runs: make(map[string]bool), checkpoints: make(map[string]map[int64][]byte)})
+}
+
+func newFakeStore(t *testing.T) *PostgresStore {
+	t.Helper()
+	db, err := sql.Open("runstore-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewPostgresStore(db)
+}
+
+func TestDeleteRunClaimsOnce(t *testing.T) {
+	store := newFakeStore(t)
+	ctx := context.Background()
+
+	if err := store.SaveRun(ctx, &agentengine.PendingRun{ID: "run-1"}); err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+	if err := store.DeleteRun(ctx, "run-1"); err != nil {
+		t.Fatalf("expected the first claim to succeed, got %v", err)
+	}
+	if err := store.DeleteRun(ctx, "run-1"); !errors.Is(err, agentengine.ErrRunNotFound) {
+		t.Fatalf("expected the second claim to find the run gone, got %v", err)
+	}
+}
+
+func TestCheckpointsKeepTheVersionBeingReplaced(t *testing.T) {
+	store := newFakeStore(t)
+	ctx := context.Background()
+
+	for version := 1; version <= 3; version++ {
+		state := agentengine.CheckpointState{Prompt: fmt.Sprintf("prompt %d", version)}
+		if err := store.SaveCheckpoint(ctx, "agent-run-1", version, state); err != nil {
+			t.Fatalf("SaveCheckpoint: %v", err)
+		}
+	}
+	if _, err := store.LoadCheckpoint(ctx, "agent-run-1", 1); !errors.Is(err, agentengine.ErrCheckpointNotFound) {
+		t.Fatalf("expected version 1 dropped, got %v", err)
+	}
+	if state, err := store.LoadCheckpoint(ctx, "agent-run-1", 2); err != nil || state.Prompt != "prompt 2" {
+		t.Fatalf("expected version 2 kept for retries, got %+v, %v", state, err)
+	}
+	if err := store.DeleteCheckpoints(ctx, "agent-run-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.LoadCheckpoint(ctx, "agent-run-1", 3); !errors.Is(err, agentengine.ErrCheckpointNotFound) {
+		t.Fatalf("expected every version deleted, got %v", err)
+	}
+}
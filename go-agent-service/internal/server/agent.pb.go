@@ -32,9 +32,13 @@ type ChatRequest struct {
 	Provider *string `protobuf:"bytes,5,opt,name=provider,proto3,oneof" json:"provider,omitempty"` // gemini, openai, groq
 	Model    *string `protobuf:"bytes,6,opt,name=model,proto3,oneof" json:"model,omitempty"`       // gemini-2.0-flash, gpt-4o-mini, etc.
 	// Conversation history for multi-turn context
-	History       []*HistoryMessage `protobuf:"bytes,7,rep,name=history,proto3" json:"history,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	History []*HistoryMessage `protobuf:"bytes,7,rep,name=history,proto3" json:"history,omitempty"`
+	// Planner selection override
+	Planner *string `protobuf:"bytes,8,opt,name=planner,proto3,oneof" json:"planner,omitempty"` // auto, llm, heuristic
+	// JSON Schema the answer must match; the response then carries it as structured
+	ResponseSchema *string `protobuf:"bytes,9,opt,name=response_schema,json=responseSchema,proto3,oneof" json:"response_schema,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *ChatRequest) Reset() {
@@ -116,6 +120,20 @@ func (x *ChatRequest) GetHistory() []*HistoryMessage {
 	return nil
 }
 
+func (x *ChatRequest) GetPlanner() string {
+	if x != nil && x.Planner != nil {
+		return *x.Planner
+	}
+	return ""
+}
+
+func (x *ChatRequest) GetResponseSchema() string {
+	if x != nil && x.ResponseSchema != nil {
+		return *x.ResponseSchema
+	}
+	return ""
+}
+
 // HistoryMessage represents a message in conversation history
 type HistoryMessage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -175,8 +193,13 @@ type ChatResponse struct {
 	Response        string                 `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
 	Reasoning       []*ReasoningStep       `protobuf:"bytes,2,rep,name=reasoning,proto3" json:"reasoning,omitempty"`
 	Artifacts       []*Artifact            `protobuf:"bytes,3,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
-	Citations       []string               `protobuf:"bytes,4,rep,name=citations,proto3" json:"citations,omitempty"`
+	Citations       []string               `protobuf:"bytes,4,rep,name=citations,proto3" json:"citations,omitempty"` // Source IDs the answer cites, e.g. PROJ-123 or a knowledge graph node ID
 	ProposedActions []*ProposedAction      `protobuf:"bytes,5,rep,name=proposed_actions,json=proposedActions,proto3" json:"proposed_actions,omitempty"`
+	RunId           *string                `protobuf:"bytes,6,opt,name=run_id,json=runId,proto3,oneof" json:"run_id,omitempty"`       // Set when the run is paused for approval
+	Status          *string                `protobuf:"bytes,7,opt,name=status,proto3,oneof" json:"status,omitempty"`                  // completed, awaiting_approval
+	TraceId         *string                `protobuf:"bytes,8,opt,name=trace_id,json=traceId,proto3,oneof" json:"trace_id,omitempty"` // Inspect via GET /traces/{id}
+	Usage           *TokenUsage            `protobuf:"bytes,9,opt,name=usage,proto3,oneof" json:"usage,omitempty"`
+	Structured      *string                `protobuf:"bytes,10,opt,name=structured,proto3,oneof" json:"structured,omitempty"` // JSON answer matching response_schema
 	unknownFields   protoimpl.UnknownFields
 	sizeCache       protoimpl.SizeCache
 }
@@ -246,14 +269,55 @@ func (x *ChatResponse) GetProposedActions() []*ProposedAction {
 	return nil
 }
 
+func (x *ChatResponse) GetRunId() string {
+	if x != nil && x.RunId != nil {
+		return *x.RunId
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetStatus() string {
+	if x != nil && x.Status != nil {
+		return *x.Status
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetTraceId() string {
+	if x != nil && x.TraceId != nil {
+		return *x.TraceId
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetUsage() *TokenUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *ChatResponse) GetStructured() string {
+	if x != nil && x.Structured != nil {
+		return *x.Structured
+	}
+	return ""
+}
+
 // ChatChunk represents a streaming chunk
 type ChatChunk struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
-	Done          bool                   `protobuf:"varint,2,opt,name=done,proto3" json:"done,omitempty"`
-	Reasoning     *ReasoningStep         `protobuf:"bytes,3,opt,name=reasoning,proto3,oneof" json:"reasoning,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Content         string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Done            bool                   `protobuf:"varint,2,opt,name=done,proto3" json:"done,omitempty"`
+	Reasoning       *ReasoningStep         `protobuf:"bytes,3,opt,name=reasoning,proto3,oneof" json:"reasoning,omitempty"`
+	ProposedActions []*ProposedAction      `protobuf:"bytes,4,rep,name=proposed_actions,json=proposedActions,proto3" json:"proposed_actions,omitempty"`
+	RunId           *string                `protobuf:"bytes,5,opt,name=run_id,json=runId,proto3,oneof" json:"run_id,omitempty"`
+	TraceId         *string                `protobuf:"bytes,6,opt,name=trace_id,json=traceId,proto3,oneof" json:"trace_id,omitempty"` // Set on the final chunk
+	Usage           *TokenUsage            `protobuf:"bytes,7,opt,name=usage,proto3,oneof" json:"usage,omitempty"`                    // Set on the final chunk
+	Structured      *string                `protobuf:"bytes,8,opt,name=structured,proto3,oneof" json:"structured,omitempty"`          // Set on the final chunk when response_schema is given
+	Citations       []string               `protobuf:"bytes,9,rep,name=citations,proto3" json:"citations,omitempty"`                  // Set on the final chunk
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *ChatChunk) Reset() {
@@ -307,6 +371,117 @@ func (x *ChatChunk) GetReasoning() *ReasoningStep {
 	return nil
 }
 
+func (x *ChatChunk) GetProposedActions() []*ProposedAction {
+	if x != nil {
+		return x.ProposedActions
+	}
+	return nil
+}
+
+func (x *ChatChunk) GetRunId() string {
+	if x != nil && x.RunId != nil {
+		return *x.RunId
+	}
+	return ""
+}
+
+func (x *ChatChunk) GetTraceId() string {
+	if x != nil && x.TraceId != nil {
+		return *x.TraceId
+	}
+	return ""
+}
+
+func (x *ChatChunk) GetUsage() *TokenUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *ChatChunk) GetStructured() string {
+	if x != nil && x.Structured != nil {
+		return *x.Structured
+	}
+	return ""
+}
+
+func (x *ChatChunk) GetCitations() []string {
+	if x != nil {
+		return x.Citations
+	}
+	return nil
+}
+
+// TokenUsage reports the LLM tokens consumed by a run and their estimated cost
+type TokenUsage struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	PromptTokens     int32                  `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int32                  `protobuf:"varint,2,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens      int32                  `protobuf:"varint,3,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	CostUsd          float64                `protobuf:"fixed64,4,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *TokenUsage) Reset() {
+	*x = TokenUsage{}
+	mi := &file_api_proto_agent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenUsage) ProtoMessage() {}
+
+func (x *TokenUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenUsage.ProtoReflect.Descriptor instead.
+func (*TokenUsage) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TokenUsage) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetCostUsd() float64 {
+	if x != nil {
+		return x.CostUsd
+	}
+	return 0
+}
+
 // ReasoningStep represents a step in the agent's reasoning
 type ReasoningStep struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -320,7 +495,7 @@ type ReasoningStep struct {
 
 func (x *ReasoningStep) Reset() {
 	*x = ReasoningStep{}
-	mi := &file_api_proto_agent_proto_msgTypes[4]
+	mi := &file_api_proto_agent_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -332,7 +507,7 @@ func (x *ReasoningStep) String() string {
 func (*ReasoningStep) ProtoMessage() {}
 
 func (x *ReasoningStep) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[4]
+	mi := &file_api_proto_agent_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -345,7 +520,7 @@ func (x *ReasoningStep) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReasoningStep.ProtoReflect.Descriptor instead.
 func (*ReasoningStep) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{4}
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *ReasoningStep) GetStep() int32 {
@@ -390,7 +565,7 @@ type Artifact struct {
 
 func (x *Artifact) Reset() {
 	*x = Artifact{}
-	mi := &file_api_proto_agent_proto_msgTypes[5]
+	mi := &file_api_proto_agent_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -402,7 +577,7 @@ func (x *Artifact) String() string {
 func (*Artifact) ProtoMessage() {}
 
 func (x *Artifact) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[5]
+	mi := &file_api_proto_agent_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -415,7 +590,7 @@ func (x *Artifact) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Artifact.ProtoReflect.Descriptor instead.
 func (*Artifact) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{5}
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *Artifact) GetId() string {
@@ -460,13 +635,17 @@ type ProposedAction struct {
 	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // approve, execute, confirm
 	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
 	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	RunId         *string                `protobuf:"bytes,5,opt,name=run_id,json=runId,proto3,oneof" json:"run_id,omitempty"`
+	Tool          *string                `protobuf:"bytes,6,opt,name=tool,proto3,oneof" json:"tool,omitempty"`
+	Action        *string                `protobuf:"bytes,7,opt,name=action,proto3,oneof" json:"action,omitempty"`
+	Payload       *string                `protobuf:"bytes,8,opt,name=payload,proto3,oneof" json:"payload,omitempty"` // JSON string of tool arguments
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ProposedAction) Reset() {
 	*x = ProposedAction{}
-	mi := &file_api_proto_agent_proto_msgTypes[6]
+	mi := &file_api_proto_agent_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -478,7 +657,7 @@ func (x *ProposedAction) String() string {
 func (*ProposedAction) ProtoMessage() {}
 
 func (x *ProposedAction) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[6]
+	mi := &file_api_proto_agent_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -491,7 +670,7 @@ func (x *ProposedAction) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProposedAction.ProtoReflect.Descriptor instead.
 func (*ProposedAction) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{6}
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ProposedAction) GetId() string {
@@ -522,6 +701,148 @@ func (x *ProposedAction) GetDescription() string {
 	return ""
 }
 
+func (x *ProposedAction) GetRunId() string {
+	if x != nil && x.RunId != nil {
+		return *x.RunId
+	}
+	return ""
+}
+
+func (x *ProposedAction) GetTool() string {
+	if x != nil && x.Tool != nil {
+		return *x.Tool
+	}
+	return ""
+}
+
+func (x *ProposedAction) GetAction() string {
+	if x != nil && x.Action != nil {
+		return *x.Action
+	}
+	return ""
+}
+
+func (x *ProposedAction) GetPayload() string {
+	if x != nil && x.Payload != nil {
+		return *x.Payload
+	}
+	return ""
+}
+
+// ResolveActionRequest carries decisions for a paused run's proposed actions
+type ResolveActionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Decisions     []*ActionDecision      `protobuf:"bytes,2,rep,name=decisions,proto3" json:"decisions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolveActionRequest) Reset() {
+	*x = ResolveActionRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveActionRequest) ProtoMessage() {}
+
+func (x *ResolveActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveActionRequest.ProtoReflect.Descriptor instead.
+func (*ResolveActionRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ResolveActionRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *ResolveActionRequest) GetDecisions() []*ActionDecision {
+	if x != nil {
+		return x.Decisions
+	}
+	return nil
+}
+
+// ActionDecision approves or rejects a single proposed action
+type ActionDecision struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ActionId      string                 `protobuf:"bytes,1,opt,name=action_id,json=actionId,proto3" json:"action_id,omitempty"`
+	Approved      bool                   `protobuf:"varint,2,opt,name=approved,proto3" json:"approved,omitempty"`
+	Reason        *string                `protobuf:"bytes,3,opt,name=reason,proto3,oneof" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActionDecision) Reset() {
+	*x = ActionDecision{}
+	mi := &file_api_proto_agent_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActionDecision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActionDecision) ProtoMessage() {}
+
+func (x *ActionDecision) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActionDecision.ProtoReflect.Descriptor instead.
+func (*ActionDecision) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ActionDecision) GetActionId() string {
+	if x != nil {
+		return x.ActionId
+	}
+	return ""
+}
+
+func (x *ActionDecision) GetApproved() bool {
+	if x != nil {
+		return x.Approved
+	}
+	return false
+}
+
+func (x *ActionDecision) GetReason() string {
+	if x != nil && x.Reason != nil {
+		return *x.Reason
+	}
+	return ""
+}
+
 // ActionRequest represents a UCL action request
 type ActionRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
@@ -537,7 +858,7 @@ type ActionRequest struct {
 
 func (x *ActionRequest) Reset() {
 	*x = ActionRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[7]
+	mi := &file_api_proto_agent_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -549,7 +870,7 @@ func (x *ActionRequest) String() string {
 func (*ActionRequest) ProtoMessage() {}
 
 func (x *ActionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[7]
+	mi := &file_api_proto_agent_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -562,7 +883,7 @@ func (x *ActionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ActionRequest.ProtoReflect.Descriptor instead.
 func (*ActionRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{7}
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *ActionRequest) GetActionType() string {
@@ -623,7 +944,7 @@ type ActionResponse struct {
 
 func (x *ActionResponse) Reset() {
 	*x = ActionResponse{}
-	mi := &file_api_proto_agent_proto_msgTypes[8]
+	mi := &file_api_proto_agent_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -635,7 +956,7 @@ func (x *ActionResponse) String() string {
 func (*ActionResponse) ProtoMessage() {}
 
 func (x *ActionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[8]
+	mi := &file_api_proto_agent_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -648,7 +969,7 @@ func (x *ActionResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ActionResponse.ProtoReflect.Descriptor instead.
 func (*ActionResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{8}
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *ActionResponse) GetSuccess() bool {
@@ -704,7 +1025,7 @@ var File_api_proto_agent_proto protoreflect.FileDescriptor
 
 const file_api_proto_agent_proto_rawDesc = "" +
 	"\n" +
-	"\x15api/proto/agent.proto\x12\x05agent\"\xae\x02\n" +
+	"\x15api/proto/agent.proto\x12\x05agent\"\x9b\x03\n" +
 	"\vChatRequest\x12\x14\n" +
 	"\x05query\x18\x01 \x01(\tR\x05query\x12'\n" +
 	"\x0fconversation_id\x18\x02 \x01(\tR\x0econversationId\x12)\n" +
@@ -713,25 +1034,61 @@ const file_api_proto_agent_proto_rawDesc = "" +
 	"session_id\x18\x04 \x01(\tH\x00R\tsessionId\x88\x01\x01\x12\x1f\n" +
 	"\bprovider\x18\x05 \x01(\tH\x01R\bprovider\x88\x01\x01\x12\x19\n" +
 	"\x05model\x18\x06 \x01(\tH\x02R\x05model\x88\x01\x01\x12/\n" +
-	"\ahistory\x18\a \x03(\v2\x15.agent.HistoryMessageR\ahistoryB\r\n" +
+	"\ahistory\x18\a \x03(\v2\x15.agent.HistoryMessageR\ahistory\x12\x1d\n" +
+	"\aplanner\x18\b \x01(\tH\x03R\aplanner\x88\x01\x01\x12,\n" +
+	"\x0fresponse_schema\x18\t \x01(\tH\x04R\x0eresponseSchema\x88\x01\x01B\r\n" +
 	"\v_session_idB\v\n" +
 	"\t_providerB\b\n" +
-	"\x06_model\">\n" +
+	"\x06_modelB\n" +
+	"\n" +
+	"\b_plannerB\x12\n" +
+	"\x10_response_schema\">\n" +
 	"\x0eHistoryMessage\x12\x12\n" +
 	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
-	"\acontent\x18\x02 \x01(\tR\acontent\"\xed\x01\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"\xd5\x03\n" +
 	"\fChatResponse\x12\x1a\n" +
 	"\bresponse\x18\x01 \x01(\tR\bresponse\x122\n" +
 	"\treasoning\x18\x02 \x03(\v2\x14.agent.ReasoningStepR\treasoning\x12-\n" +
 	"\tartifacts\x18\x03 \x03(\v2\x0f.agent.ArtifactR\tartifacts\x12\x1c\n" +
 	"\tcitations\x18\x04 \x03(\tR\tcitations\x12@\n" +
-	"\x10proposed_actions\x18\x05 \x03(\v2\x15.agent.ProposedActionR\x0fproposedActions\"\x80\x01\n" +
+	"\x10proposed_actions\x18\x05 \x03(\v2\x15.agent.ProposedActionR\x0fproposedActions\x12\x1a\n" +
+	"\x06run_id\x18\x06 \x01(\tH\x00R\x05runId\x88\x01\x01\x12\x1b\n" +
+	"\x06status\x18\a \x01(\tH\x01R\x06status\x88\x01\x01\x12\x1e\n" +
+	"\btrace_id\x18\b \x01(\tH\x02R\atraceId\x88\x01\x01\x12,\n" +
+	"\x05usage\x18\t \x01(\v2\x11.agent.TokenUsageH\x03R\x05usage\x88\x01\x01\x12#\n" +
+	"\n" +
+	"structured\x18\n" +
+	" \x01(\tH\x04R\n" +
+	"structured\x88\x01\x01B\t\n" +
+	"\a_run_idB\t\n" +
+	"\a_statusB\v\n" +
+	"\t_trace_idB\b\n" +
+	"\x06_usageB\r\n" +
+	"\v_structured\"\xa0\x03\n" +
 	"\tChatChunk\x12\x18\n" +
 	"\acontent\x18\x01 \x01(\tR\acontent\x12\x12\n" +
 	"\x04done\x18\x02 \x01(\bR\x04done\x127\n" +
-	"\treasoning\x18\x03 \x01(\v2\x14.agent.ReasoningStepH\x00R\treasoning\x88\x01\x01B\f\n" +
+	"\treasoning\x18\x03 \x01(\v2\x14.agent.ReasoningStepH\x00R\treasoning\x88\x01\x01\x12@\n" +
+	"\x10proposed_actions\x18\x04 \x03(\v2\x15.agent.ProposedActionR\x0fproposedActions\x12\x1a\n" +
+	"\x06run_id\x18\x05 \x01(\tH\x01R\x05runId\x88\x01\x01\x12\x1e\n" +
+	"\btrace_id\x18\x06 \x01(\tH\x02R\atraceId\x88\x01\x01\x12,\n" +
+	"\x05usage\x18\a \x01(\v2\x11.agent.TokenUsageH\x03R\x05usage\x88\x01\x01\x12#\n" +
 	"\n" +
-	"_reasoning\"\x87\x01\n" +
+	"structured\x18\b \x01(\tH\x04R\n" +
+	"structured\x88\x01\x01\x12\x1c\n" +
+	"\tcitations\x18\t \x03(\tR\tcitationsB\f\n" +
+	"\n" +
+	"_reasoningB\t\n" +
+	"\a_run_idB\v\n" +
+	"\t_trace_idB\b\n" +
+	"\x06_usageB\r\n" +
+	"\v_structured\"\x9c\x01\n" +
+	"\n" +
+	"TokenUsage\x12#\n" +
+	"\rprompt_tokens\x18\x01 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x02 \x01(\x05R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\x03 \x01(\x05R\vtotalTokens\x12\x19\n" +
+	"\bcost_usd\x18\x04 \x01(\x01R\acostUsd\"\x87\x01\n" +
 	"\rReasoningStep\x12\x12\n" +
 	"\x04step\x18\x01 \x01(\x05R\x04step\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12\x18\n" +
@@ -745,12 +1102,29 @@ const file_api_proto_agent_proto_rawDesc = "" +
 	"\x05title\x18\x03 \x01(\tR\x05title\x12\x18\n" +
 	"\acontent\x18\x04 \x01(\tR\acontent\x12\x1f\n" +
 	"\blanguage\x18\x05 \x01(\tH\x00R\blanguage\x88\x01\x01B\v\n" +
-	"\t_language\"l\n" +
+	"\t_language\"\x88\x02\n" +
 	"\x0eProposedAction\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
 	"\x05title\x18\x03 \x01(\tR\x05title\x12 \n" +
-	"\vdescription\x18\x04 \x01(\tR\vdescription\"\xe2\x01\n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x1a\n" +
+	"\x06run_id\x18\x05 \x01(\tH\x00R\x05runId\x88\x01\x01\x12\x17\n" +
+	"\x04tool\x18\x06 \x01(\tH\x01R\x04tool\x88\x01\x01\x12\x1b\n" +
+	"\x06action\x18\a \x01(\tH\x02R\x06action\x88\x01\x01\x12\x1d\n" +
+	"\apayload\x18\b \x01(\tH\x03R\apayload\x88\x01\x01B\t\n" +
+	"\a_run_idB\a\n" +
+	"\x05_toolB\t\n" +
+	"\a_actionB\n" +
+	"\n" +
+	"\b_payload\"b\n" +
+	"\x14ResolveActionRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x123\n" +
+	"\tdecisions\x18\x02 \x03(\v2\x15.agent.ActionDecisionR\tdecisions\"q\n" +
+	"\x0eActionDecision\x12\x1b\n" +
+	"\taction_id\x18\x01 \x01(\tR\bactionId\x12\x1a\n" +
+	"\bapproved\x18\x02 \x01(\bR\bapproved\x12\x1b\n" +
+	"\x06reason\x18\x03 \x01(\tH\x00R\x06reason\x88\x01\x01B\t\n" +
+	"\a_reason\"\xe2\x01\n" +
 	"\rActionRequest\x12\x1f\n" +
 	"\vaction_type\x18\x01 \x01(\tR\n" +
 	"actionType\x12\x1b\n" +
@@ -774,12 +1148,13 @@ const file_api_proto_agent_proto_rawDesc = "" +
 	"_timestampB\x11\n" +
 	"\x0f_previous_stateB\f\n" +
 	"\n" +
-	"_new_state2\xb3\x01\n" +
+	"_new_state2\xf6\x01\n" +
 	"\fAgentService\x12/\n" +
 	"\x04Chat\x12\x12.agent.ChatRequest\x1a\x13.agent.ChatResponse\x124\n" +
 	"\n" +
 	"StreamChat\x12\x12.agent.ChatRequest\x1a\x10.agent.ChatChunk0\x01\x12<\n" +
-	"\rExecuteAction\x12\x14.agent.ActionRequest\x1a\x15.agent.ActionResponseB9Z7github.com/antigravity/go-agent-service/internal/serverb\x06proto3"
+	"\rExecuteAction\x12\x14.agent.ActionRequest\x1a\x15.agent.ActionResponse\x12A\n" +
+	"\rResolveAction\x12\x1b.agent.ResolveActionRequest\x1a\x13.agent.ChatResponseB9Z7github.com/antigravity/go-agent-service/internal/serverb\x06proto3"
 
 var (
 	file_api_proto_agent_proto_rawDescOnce sync.Once
@@ -793,35 +1168,44 @@ func file_api_proto_agent_proto_rawDescGZIP() []byte {
 	return file_api_proto_agent_proto_rawDescData
 }
 
-var file_api_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_api_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_api_proto_agent_proto_goTypes = []any{
-	(*ChatRequest)(nil),    // 0: agent.ChatRequest
-	(*HistoryMessage)(nil), // 1: agent.HistoryMessage
-	(*ChatResponse)(nil),   // 2: agent.ChatResponse
-	(*ChatChunk)(nil),      // 3: agent.ChatChunk
-	(*ReasoningStep)(nil),  // 4: agent.ReasoningStep
-	(*Artifact)(nil),       // 5: agent.Artifact
-	(*ProposedAction)(nil), // 6: agent.ProposedAction
-	(*ActionRequest)(nil),  // 7: agent.ActionRequest
-	(*ActionResponse)(nil), // 8: agent.ActionResponse
+	(*ChatRequest)(nil),          // 0: agent.ChatRequest
+	(*HistoryMessage)(nil),       // 1: agent.HistoryMessage
+	(*ChatResponse)(nil),         // 2: agent.ChatResponse
+	(*ChatChunk)(nil),            // 3: agent.ChatChunk
+	(*TokenUsage)(nil),           // 4: agent.TokenUsage
+	(*ReasoningStep)(nil),        // 5: agent.ReasoningStep
+	(*Artifact)(nil),             // 6: agent.Artifact
+	(*ProposedAction)(nil),       // 7: agent.ProposedAction
+	(*ResolveActionRequest)(nil), // 8: agent.ResolveActionRequest
+	(*ActionDecision)(nil),       // 9: agent.ActionDecision
+	(*ActionRequest)(nil),        // 10: agent.ActionRequest
+	(*ActionResponse)(nil),       // 11: agent.ActionResponse
 }
 var file_api_proto_agent_proto_depIdxs = []int32{
-	1, // 0: agent.ChatRequest.history:type_name -> agent.HistoryMessage
-	4, // 1: agent.ChatResponse.reasoning:type_name -> agent.ReasoningStep
-	5, // 2: agent.ChatResponse.artifacts:type_name -> agent.Artifact
-	6, // 3: agent.ChatResponse.proposed_actions:type_name -> agent.ProposedAction
-	4, // 4: agent.ChatChunk.reasoning:type_name -> agent.ReasoningStep
-	0, // 5: agent.AgentService.Chat:input_type -> agent.ChatRequest
-	0, // 6: agent.AgentService.StreamChat:input_type -> agent.ChatRequest
-	7, // 7: agent.AgentService.ExecuteAction:input_type -> agent.ActionRequest
-	2, // 8: agent.AgentService.Chat:output_type -> agent.ChatResponse
-	3, // 9: agent.AgentService.StreamChat:output_type -> agent.ChatChunk
-	8, // 10: agent.AgentService.ExecuteAction:output_type -> agent.ActionResponse
-	8, // [8:11] is the sub-list for method output_type
-	5, // [5:8] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	1,  // 0: agent.ChatRequest.history:type_name -> agent.HistoryMessage
+	5,  // 1: agent.ChatResponse.reasoning:type_name -> agent.ReasoningStep
+	6,  // 2: agent.ChatResponse.artifacts:type_name -> agent.Artifact
+	7,  // 3: agent.ChatResponse.proposed_actions:type_name -> agent.ProposedAction
+	4,  // 4: agent.ChatResponse.usage:type_name -> agent.TokenUsage
+	5,  // 5: agent.ChatChunk.reasoning:type_name -> agent.ReasoningStep
+	7,  // 6: agent.ChatChunk.proposed_actions:type_name -> agent.ProposedAction
+	4,  // 7: agent.ChatChunk.usage:type_name -> agent.TokenUsage
+	9,  // 8: agent.ResolveActionRequest.decisions:type_name -> agent.ActionDecision
+	0,  // 9: agent.AgentService.Chat:input_type -> agent.ChatRequest
+	0,  // 10: agent.AgentService.StreamChat:input_type -> agent.ChatRequest
+	10, // 11: agent.AgentService.ExecuteAction:input_type -> agent.ActionRequest
+	8,  // 12: agent.AgentService.ResolveAction:input_type -> agent.ResolveActionRequest
+	2,  // 13: agent.AgentService.Chat:output_type -> agent.ChatResponse
+	3,  // 14: agent.AgentService.StreamChat:output_type -> agent.ChatChunk
+	11, // 15: agent.AgentService.ExecuteAction:output_type -> agent.ActionResponse
+	2,  // 16: agent.AgentService.ResolveAction:output_type -> agent.ChatResponse
+	13, // [13:17] is the sub-list for method output_type
+	9,  // [9:13] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_agent_proto_init() }
@@ -830,18 +1214,21 @@ func file_api_proto_agent_proto_init() {
 		return
 	}
 	file_api_proto_agent_proto_msgTypes[0].OneofWrappers = []any{}
+	file_api_proto_agent_proto_msgTypes[2].OneofWrappers = []any{}
 	file_api_proto_agent_proto_msgTypes[3].OneofWrappers = []any{}
-	file_api_proto_agent_proto_msgTypes[4].OneofWrappers = []any{}
 	file_api_proto_agent_proto_msgTypes[5].OneofWrappers = []any{}
+	file_api_proto_agent_proto_msgTypes[6].OneofWrappers = []any{}
 	file_api_proto_agent_proto_msgTypes[7].OneofWrappers = []any{}
-	file_api_proto_agent_proto_msgTypes[8].OneofWrappers = []any{}
+	file_api_proto_agent_proto_msgTypes[9].OneofWrappers = []any{}
+	file_api_proto_agent_proto_msgTypes[10].OneofWrappers = []any{}
+	file_api_proto_agent_proto_msgTypes[11].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_agent_proto_rawDesc), len(file_api_proto_agent_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
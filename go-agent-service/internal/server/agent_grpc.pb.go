@@ -22,6 +22,7 @@ const (
 	AgentService_Chat_FullMethodName          = "/agent.AgentService/Chat"
 	AgentService_StreamChat_FullMethodName    = "/agent.AgentService/StreamChat"
 	AgentService_ExecuteAction_FullMethodName = "/agent.AgentService/ExecuteAction"
+	AgentService_ResolveAction_FullMethodName = "/agent.AgentService/ResolveAction"
 )
 
 // AgentServiceClient is the client API for AgentService service.
@@ -36,6 +37,8 @@ type AgentServiceClient interface {
 	StreamChat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatChunk], error)
 	// ExecuteAction executes a UCL action
 	ExecuteAction(ctx context.Context, in *ActionRequest, opts ...grpc.CallOption) (*ActionResponse, error)
+	// ResolveAction approves or rejects proposed actions and resumes the paused run
+	ResolveAction(ctx context.Context, in *ResolveActionRequest, opts ...grpc.CallOption) (*ChatResponse, error)
 }
 
 type agentServiceClient struct {
@@ -85,6 +88,16 @@ func (c *agentServiceClient) ExecuteAction(ctx context.Context, in *ActionReques
 	return out, nil
 }
 
+func (c *agentServiceClient) ResolveAction(ctx context.Context, in *ResolveActionRequest, opts ...grpc.CallOption) (*ChatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChatResponse)
+	err := c.cc.Invoke(ctx, AgentService_ResolveAction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServiceServer is the server API for AgentService service.
 // All implementations must embed UnimplementedAgentServiceServer
 // for forward compatibility.
@@ -97,6 +110,8 @@ type AgentServiceServer interface {
 	StreamChat(*ChatRequest, grpc.ServerStreamingServer[ChatChunk]) error
 	// ExecuteAction executes a UCL action
 	ExecuteAction(context.Context, *ActionRequest) (*ActionResponse, error)
+	// ResolveAction approves or rejects proposed actions and resumes the paused run
+	ResolveAction(context.Context, *ResolveActionRequest) (*ChatResponse, error)
 	mustEmbedUnimplementedAgentServiceServer()
 }
 
@@ -116,6 +131,9 @@ func (UnimplementedAgentServiceServer) StreamChat(*ChatRequest, grpc.ServerStrea
 func (UnimplementedAgentServiceServer) ExecuteAction(context.Context, *ActionRequest) (*ActionResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ExecuteAction not implemented")
 }
+func (UnimplementedAgentServiceServer) ResolveAction(context.Context, *ResolveActionRequest) (*ChatResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ResolveAction not implemented")
+}
 func (UnimplementedAgentServiceServer) mustEmbedUnimplementedAgentServiceServer() {}
 func (UnimplementedAgentServiceServer) testEmbeddedByValue()                      {}
 
@@ -184,6 +202,24 @@ func _AgentService_ExecuteAction_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_ResolveAction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ResolveAction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_ResolveAction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ResolveAction(ctx, req.(*ResolveActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AgentService_ServiceDesc is the grpc.ServiceDesc for AgentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -199,6 +235,10 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ExecuteAction",
 			Handler:    _AgentService_ExecuteAction_Handler,
 		},
+		{
+			MethodName: "ResolveAction",
+			Handler:    _AgentService_ResolveAction_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -5,22 +5,33 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/antigravity/go-agent-service/internal/agent"
 	"github.com/antigravity/go-agent-service/internal/agentengine"
 	"github.com/antigravity/go-agent-service/internal/agentengine/adapters"
+	"github.com/antigravity/go-agent-service/internal/agentengine/cassette"
 	"github.com/antigravity/go-agent-service/internal/appregistry"
 	"github.com/antigravity/go-agent-service/internal/config"
 	agentctx "github.com/antigravity/go-agent-service/internal/context"
 	"github.com/antigravity/go-agent-service/internal/memory"
 	"github.com/antigravity/go-agent-service/internal/nucleus"
+	"github.com/antigravity/go-agent-service/internal/policy"
+	"github.com/antigravity/go-agent-service/internal/prompts"
+	"github.com/antigravity/go-agent-service/internal/redact"
+	"github.com/antigravity/go-agent-service/internal/runstore"
 	"github.com/antigravity/go-agent-service/internal/tools"
+	"github.com/antigravity/go-agent-service/internal/tracestore"
 	"github.com/antigravity/go-agent-service/internal/ucl"
+	"github.com/antigravity/go-agent-service/internal/usagestore"
 	"github.com/antigravity/go-agent-service/internal/workflow"
 )
 
@@ -29,8 +40,7 @@ type AgentServer struct {
 	UnimplementedAgentServiceServer
 	config         *config.Config
 	logger         *zap.SugaredLogger
-	runner         *agent.Runner
-	llmRouter      *agent.LLMRouter
+	providers      *agent.Registry
 	orchestrator   *agentctx.Orchestrator
 	memory         memory.Store
 	episodicMemory memory.MemoryStore
@@ -42,13 +52,18 @@ type AgentServer struct {
 	toolRegistry   *tools.Registry // Dynamic tool registry (MCP + Store)
 	appRegistry    appregistry.Store
 	appRegistryDB  *sql.DB
+	traces         agentengine.TraceStore
+	usage          agentengine.UsageStore
+	prompts        *prompts.Library
+	promptContext  *adapters.DefaultContextAssembler
+	toolSource     agentengine.ToolRegistry
+	redaction      *redact.Policy
 }
 
 // NewAgentServer creates a new agent server instance
 func NewAgentServer(cfg *config.Config, logger *zap.SugaredLogger) *AgentServer {
 	// Initialize components
-	runner := agent.NewRunner(cfg.GeminiAPIKey, logger)
-	llmRouter := agent.NewLLMRouter(cfg.GeminiAPIKey, cfg.OpenAIAPIKey)
+	providers := newProviderRegistry(cfg, logger)
 	memStore := memory.NewShortTermStore()
 	nucleusClient := nucleus.NewClientWithConfig(nucleus.ClientConfig{
 		APIURL:               cfg.Nucleus.APIURL,
@@ -75,9 +90,6 @@ func NewAgentServer(cfg *config.Config, logger *zap.SugaredLogger) *AgentServer
 		} else {
 			episodicStore = store
 			logger.Info("Episodic memory initialized with pgvector")
-
-			// Wire memory to runner for context-aware chat
-			runner.WithMemory(store, nil)
 		}
 	}
 
@@ -121,18 +133,97 @@ func NewAgentServer(cfg *config.Config, logger *zap.SugaredLogger) *AgentServer
 		}
 	}
 
+	// LLM planner uses native function calling; heuristic planner stays as the offline fallback
+	heuristicPlanner := adapters.NewHeuristicPlanner()
+	planner := adapters.NewPlannerSelector(
+		cfg.AgentPlanner,
+		adapters.NewLLMPlanner(providers, heuristicPlanner),
+		heuristicPlanner,
+		providers.SupportsFunctionCalling,
+	).WithStepPlanner(adapters.NewStepPlanner(adapters.NewRegistryLLMClient(providers), heuristicPlanner))
+
+	traces := newTraceStore(cfg, appRegistryDB, logger)
+	usage := newUsageStore(cfg, appRegistryDB, logger)
+	var llm agentengine.LLMClient = adapters.NewRegistryLLMClient(providers)
+	var executor agentengine.ToolExecutor = adapters.NewRetryingExecutor(adapters.NewRegistryExecutor(toolRegistry), adapters.RetryPolicy{
+		MaxAttempts:    cfg.AgentToolRetry.Attempts,
+		InitialBackoff: cfg.AgentToolRetry.InitialBackoff,
+		MaxBackoff:     cfg.AgentToolRetry.MaxBackoff,
+	})
+	var toolSource agentengine.ToolRegistry = adapters.NewRegistryToolSource(toolRegistry)
+	if cfg.AgentToolRetry.BreakerThreshold > 0 {
+		breaker := adapters.NewCircuitBreaker(adapters.BreakerConfig{
+			Threshold: cfg.AgentToolRetry.BreakerThreshold,
+			Cooldown:  cfg.AgentToolRetry.BreakerCooldown,
+		})
+		executor = breaker.Executor(executor)
+		toolSource = breaker.Registry(toolSource)
+	}
+	if cfg.AgentToolCache.TTL > 0 || len(cfg.AgentToolCache.ActionTTLs) > 0 {
+		executor = adapters.NewToolCache(adapters.CacheConfig{
+			TTL:        cfg.AgentToolCache.TTL,
+			ActionTTLs: cfg.AgentToolCache.ActionTTLs,
+			MaxEntries: cfg.AgentToolCache.MaxEntries,
+		}).Executor(executor)
+	}
+	if cfg.AgentRecordCassette != "" {
+		recorder, err := cassette.NewRecorder(cfg.AgentRecordCassette)
+		if err != nil {
+			logger.Warnw("Failed to open cassette, not recording", "path", cfg.AgentRecordCassette, "error", err)
+		} else {
+			logger.Infow("Recording LLM and tool exchanges", "path", cfg.AgentRecordCassette)
+			llm = recorder.LLM(llm)
+			executor = recorder.Executor(executor)
+		}
+	}
+
+	promptLibrary := newPromptLibrary(cfg, appRegistryDB, logger)
+	assembler := adapters.NewDefaultContextAssembler(orchestrator, episodicStore, logger).
+		WithTemplates(promptLibrary, newProjectNamer(nucleusClient))
+
 	var engine *agentengine.Engine
 	engineConfig := agentengine.Config{
-		Planner:     adapters.NewHeuristicPlanner(),
-		LLM:         adapters.NewRouterLLMClient(llmRouter),
-		Tools:       adapters.NewRegistryToolSource(toolRegistry),
-		Executor:    adapters.NewRegistryExecutor(toolRegistry),
+		Planner:     planner,
+		LLM:         llm,
+		Tools:       toolSource,
+		Executor:    executor,
 		Memory:      adapters.NewMemoryAdapter(episodicStore),
-		Context:     adapters.NewDefaultContextAssembler(orchestrator, episodicStore, logger),
-		Policy:      adapters.NewAllowAllPolicy(),
+		Context:     assembler,
+		Policy:      adapters.NewRulePolicy(newPolicyEvaluator(cfg, appRegistryDB, logger)),
 		ToolTimeout: 20 * time.Second,
-		MaxSteps:    3,
+		MaxSteps:    cfg.AgentMaxSteps,
+
+		MaxParallelTools: cfg.AgentMaxParallelTools,
+		Runs:             newRunStore(cfg, appRegistryDB, logger),
+		ApproveWrites:    cfg.AgentApproveWrites,
+		Traces:           traces,
+		Usage:            usage,
+		Budget: agentengine.Budget{
+			RunTokens:      cfg.AgentBudget.RunTokens,
+			RunCostUSD:     cfg.AgentBudget.RunCostUSD,
+			SessionTokens:  cfg.AgentBudget.SessionTokens,
+			SessionCostUSD: cfg.AgentBudget.SessionCostUSD,
+			ProjectTokens:  cfg.AgentBudget.ProjectTokens,
+			ProjectCostUSD: cfg.AgentBudget.ProjectCostUSD,
+		},
+		MaxDelegationDepth: cfg.AgentDelegationDepth,
+
+		ObservationTokens:     cfg.AgentObservationTokens,
+		ObservationItems:      cfg.AgentObservationItems,
+		SummarizeObservations: cfg.AgentSummarizeResults,
+	}
+	redaction := newRedaction(cfg, logger)
+	if redaction != nil {
+		engineConfig.Redaction = redaction
 	}
+	orchestrator.WithLogMask(redaction.Mask)
+	if cfg.AgentInjectionGuard {
+		engineConfig.Injection = adapters.NewInjectionDetector()
+	}
+	if checkpoints := newCheckpointStore(cfg, appRegistryDB); checkpoints != nil {
+		engineConfig.Checkpoints = checkpoints
+	}
+	engineConfig.Delegates = newDelegates(cfg, engineConfig, logger)
 	engine, err = agentengine.NewEngine(engineConfig)
 	if err != nil {
 		logger.Warnw("Failed to initialize AgentEngine", "error", err)
@@ -141,8 +232,7 @@ func NewAgentServer(cfg *config.Config, logger *zap.SugaredLogger) *AgentServer
 	return &AgentServer{
 		config:         cfg,
 		logger:         logger,
-		runner:         runner,
-		llmRouter:      llmRouter,
+		providers:      providers,
 		orchestrator:   orchestrator,
 		memory:         memStore,
 		episodicMemory: episodicStore,
@@ -154,7 +244,193 @@ func NewAgentServer(cfg *config.Config, logger *zap.SugaredLogger) *AgentServer
 		toolRegistry:   toolRegistry,
 		appRegistry:    appRegistry,
 		appRegistryDB:  appRegistryDB,
+		traces:         traces,
+		usage:          usage,
+		prompts:        promptLibrary,
+		promptContext:  assembler,
+		toolSource:     toolSource,
+		redaction:      redaction,
+	}
+}
+
+// newPromptLibrary selects where system prompt templates come from: Postgres
+// when enabled, else the built-in template.
+func newPromptLibrary(cfg *config.Config, db *sql.DB, logger *zap.SugaredLogger) *prompts.Library {
+	if cfg.AgentPromptTemplatesDB {
+		if db != nil {
+			return prompts.NewLibrary(prompts.NewPostgresStore(db))
+		}
+		logger.Warnw("Postgres prompt templates requested without database, using built-in template")
+	}
+	return prompts.NewLibrary(nil)
+}
+
+// newProjectNamer resolves project display names from Nucleus for prompt
+// templates. Names are cached; failed lookups fall back to the project ID.
+func newProjectNamer(client *nucleus.Client) adapters.ProjectNamer {
+	var names sync.Map
+	return func(ctx context.Context, projectID string) string {
+		if name, ok := names.Load(projectID); ok {
+			return name.(string)
+		}
+		project, err := client.GetProject(ctx, projectID)
+		if err != nil || project == nil || project.DisplayName == "" {
+			return projectID
+		}
+		names.Store(projectID, project.DisplayName)
+		return project.DisplayName
+	}
+}
+
+// newRedaction builds the redaction policy, nil when redaction is off and no file
+// is configured. A redaction file that fails to load falls back to masking
+// every project with the built-in rules.
+func newRedaction(cfg *config.Config, logger *zap.SugaredLogger) *redact.Policy {
+	if !cfg.AgentRedaction && cfg.AgentRedactionFile == "" {
+		return nil
+	}
+	doc := redact.Document{Enabled: cfg.AgentRedaction}
+	if cfg.AgentRedactionFile != "" {
+		loaded, err := redact.LoadFile(cfg.AgentRedactionFile)
+		if err == nil {
+			doc = *loaded
+			doc.Enabled = doc.Enabled || cfg.AgentRedaction
+		} else {
+			logger.Errorw("Failed to load redaction file, redacting all projects", "path", cfg.AgentRedactionFile, "error", err)
+			doc = redact.Document{Enabled: true}
+		}
+	}
+	redaction, err := redact.New(doc)
+	if err != nil {
+		logger.Errorw("Invalid redaction file, redacting all projects", "path", cfg.AgentRedactionFile, "error", err)
+		redaction, _ = redact.New(redact.Document{Enabled: true})
+	} else {
+		logger.Infow("Redaction configured", "path", cfg.AgentRedactionFile, "default", doc.Enabled, "projects", len(doc.Projects))
+	}
+	return redaction
+}
+
+// newProviderRegistry registers the LLM providers of the config. Providers that
+// fail to load are logged and left out.
+func newProviderRegistry(cfg *config.Config, logger *zap.SugaredLogger) *agent.Registry {
+	providers, errs := agent.NewRegistryFromSettings(agent.RegistrySettings{
+		GeminiAPIKey:    cfg.GeminiAPIKey,
+		OpenAIAPIKey:    cfg.OpenAIAPIKey,
+		GroqAPIKey:      cfg.GroqAPIKey,
+		ProvidersFile:   cfg.LLMProvidersFile,
+		DefaultProvider: cfg.LLMDefaultProvider,
+	})
+	for _, err := range errs {
+		logger.Errorw("Failed to configure LLM provider", "path", cfg.LLMProvidersFile, "error", err)
+	}
+	var names []string
+	defaultName := ""
+	for _, info := range providers.Providers() {
+		names = append(names, info.Name)
+		if info.Default {
+			defaultName = info.Name
+		}
+	}
+	logger.Infow("LLM providers configured", "providers", names, "default", defaultName)
+	return providers
+}
+
+// maskLog masks PII and secrets in text before it is logged, for projects that redact.
+func (s *AgentServer) maskLog(projectID, text string) string {
+	return s.redaction.Mask(projectID, text)
+}
+
+// newPolicyEvaluator builds the tool policy from the policy file and, when enabled, Postgres rules.
+// A policy file that fails to load falls back to requiring approval for every write.
+func newPolicyEvaluator(cfg *config.Config, db *sql.DB, logger *zap.SugaredLogger) *policy.Evaluator {
+	defaultEffect := policy.Effect(cfg.AgentPolicyDefault)
+	var rules []policy.Rule
+	if cfg.AgentPolicyFile != "" {
+		doc, err := policy.LoadFile(cfg.AgentPolicyFile)
+		if err != nil {
+			logger.Errorw("Failed to load agent policy file, requiring approval for writes", "path", cfg.AgentPolicyFile, "error", err)
+			rules = []policy.Rule{{ID: "fallback-writes", Access: "write", Effect: policy.EffectRequireApproval}}
+		} else {
+			rules = doc.Rules
+			if doc.DefaultEffect != "" {
+				defaultEffect = doc.DefaultEffect
+			}
+			logger.Infow("Agent policy loaded", "path", cfg.AgentPolicyFile, "rules", len(rules))
+		}
+	}
+
+	var store policy.Store
+	if cfg.AgentPolicyDB && db != nil {
+		store = policy.NewPostgresStore(db)
+	}
+	return policy.NewEvaluator(defaultEffect, rules, store)
+}
+
+// newDelegates builds the sub-agents of the delegates file. They share one engine
+// with the main agent's dependencies; each applies its own instructions, tools and model.
+func newDelegates(cfg *config.Config, engineConfig agentengine.Config, logger *zap.SugaredLogger) []agentengine.Delegate {
+	if cfg.AgentDelegatesFile == "" {
+		return nil
+	}
+	specs, err := adapters.LoadDelegateSpecs(cfg.AgentDelegatesFile)
+	if err != nil {
+		logger.Errorw("Failed to load agent delegates, running without sub-agents", "path", cfg.AgentDelegatesFile, "error", err)
+		return nil
+	}
+	engine, err := agentengine.NewEngine(engineConfig)
+	if err != nil {
+		logger.Warnw("Failed to initialize sub-agent engine", "error", err)
+		return nil
+	}
+	delegates := make([]agentengine.Delegate, 0, len(specs))
+	for _, spec := range specs {
+		delegates = append(delegates, spec.Delegate(engine))
+	}
+	logger.Infow("Agent delegates loaded", "path", cfg.AgentDelegatesFile, "delegates", len(delegates))
+	return delegates
+}
+
+// newRunStore selects where runs paused for approval are kept.
+func newRunStore(cfg *config.Config, db *sql.DB, logger *zap.SugaredLogger) agentengine.RunStore {
+	if cfg.AgentRunStore == "postgres" {
+		if db != nil {
+			return runstore.NewPostgresStore(db)
+		}
+		logger.Warnw("Postgres run store requested without database, using memory")
+	}
+	return agentengine.NewMemoryRunStore()
+}
+
+// newCheckpointStore keeps the state of durable runs between steps in Postgres,
+// next to paused runs. Without it, checkpoints carry their state through the
+// workflow history.
+func newCheckpointStore(cfg *config.Config, db *sql.DB) agentengine.CheckpointStore {
+	if !cfg.AgentDurableRuns || cfg.AgentRunStore != "postgres" || db == nil {
+		return nil
+	}
+	return runstore.NewPostgresStore(db)
+}
+
+// newTraceStore selects where run traces are kept.
+func newTraceStore(cfg *config.Config, db *sql.DB, logger *zap.SugaredLogger) agentengine.TraceStore {
+	if cfg.AgentTraceStore == "postgres" {
+		if db != nil {
+			return tracestore.NewPostgresStore(db)
+		}
+		logger.Warnw("Postgres trace store requested without database, using memory")
+	}
+	return agentengine.NewMemoryTraceStore(0)
+}
+
+// newUsageStore selects where per-run token usage is kept.
+func newUsageStore(cfg *config.Config, db *sql.DB, logger *zap.SugaredLogger) agentengine.UsageStore {
+	if cfg.AgentUsageStore == "postgres" {
+		if db != nil {
+			return usagestore.NewPostgresStore(db)
+		}
+		logger.Warnw("Postgres usage store requested without database, using memory")
 	}
+	return agentengine.NewMemoryUsageStore()
 }
 
 // GetWorkflowEngine returns the workflow engine instance
@@ -162,6 +438,11 @@ func (s *AgentServer) GetWorkflowEngine() *workflow.Engine {
 	return s.workflowEngine
 }
 
+// GetAgentEngine returns the agent engine instance, nil when not configured.
+func (s *AgentServer) GetAgentEngine() *agentengine.Engine {
+	return s.agentEngine
+}
+
 // GetToolRegistry returns the tool registry instance
 func (s *AgentServer) GetToolRegistry() *tools.Registry {
 	return s.toolRegistry
@@ -172,6 +453,74 @@ func (s *AgentServer) GetAppRegistry() appregistry.Store {
 	return s.appRegistry
 }
 
+// GetTraceStore returns the run trace store instance.
+func (s *AgentServer) GetTraceStore() agentengine.TraceStore {
+	return s.traces
+}
+
+// GetUsageStore returns the token usage store instance.
+func (s *AgentServer) GetUsageStore() agentengine.UsageStore {
+	return s.usage
+}
+
+// GetPromptStore returns the prompt template store, nil when templates come from the built-in one.
+func (s *AgentServer) GetPromptStore() prompts.Store {
+	return s.prompts.Store()
+}
+
+// PromptPreview is the system prompt a run would start with.
+type PromptPreview struct {
+	Template prompts.Template
+	Prompt   string
+	Tools    int
+	Warning  string // Set when tool discovery failed
+}
+
+// PreviewPrompt renders the system prompt of a run for the user and project of
+// req, with the tools they would have. It renders draft when set, the project's
+// version when positive, else the template the run would select.
+func (s *AgentServer) PreviewPrompt(ctx context.Context, req agentengine.Request, version int, draft string) (*PromptPreview, error) {
+	preview := &PromptPreview{}
+	tools, err := s.toolSource.ListTools(ctx, req.UserID, req.ProjectID)
+	if err != nil {
+		preview.Warning = fmt.Sprintf("Tool discovery failed; previewing without tools: %s", err.Error())
+	}
+	preview.Tools = len(tools)
+
+	switch {
+	case draft != "":
+		preview.Template = prompts.Template{ProjectID: req.ProjectID, Body: draft}
+	case version > 0:
+		store := s.GetPromptStore()
+		if store == nil {
+			return nil, prompts.ErrNotFound
+		}
+		versions, err := store.ListTemplates(ctx, req.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, t := range versions {
+			if t.Version == version {
+				preview.Template, found = t, true
+				break
+			}
+		}
+		if !found {
+			return nil, prompts.ErrNotFound
+		}
+	default:
+		preview.Prompt, preview.Template = s.promptContext.SystemPrompt(ctx, req, tools)
+		return preview, nil
+	}
+
+	preview.Prompt, err = s.promptContext.RenderTemplate(ctx, preview.Template, req, tools)
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
 // GetNucleusClient returns the Nucleus client instance.
 func (s *AgentServer) GetNucleusClient() *nucleus.Client {
 	return s.nucleus
@@ -182,62 +531,251 @@ func (s *AgentServer) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse
 	provider := req.GetProvider()
 	model := req.GetModel()
 
+	_, projectID := getUserProject(ctx)
 	s.logger.Infow("Chat request received",
-		"query", req.Query,
+		"query", s.maskLog(projectID, req.Query),
 		"conversation_id", req.ConversationId,
 		"provider", provider,
 		"model", model,
 	)
 
-	// Convert history from proto to engine format
-	engineHistory := make([]agentengine.HistoryMessage, 0, len(req.History))
-	for _, h := range req.History {
-		engineHistory = append(engineHistory, agentengine.HistoryMessage{
-			Role:    h.Role,
-			Content: h.Content,
-		})
-	}
-
-	userID, projectID := getUserProject(ctx)
-	engineReq := agentengine.Request{
-		Query:           req.Query,
-		SessionID:       req.ConversationId,
-		UserID:          userID,
-		ProjectID:       projectID,
-		ContextEntities: req.ContextEntities,
-		History:         engineHistory,
-		Provider:        provider,
-		Model:           model,
+	engineReq, err := s.engineRequest(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
 	if s.agentEngine == nil {
 		return nil, fmt.Errorf("agent engine not configured")
 	}
 
-	engineResp, err := s.agentEngine.Run(ctx, engineReq)
+	var engineResp *agentengine.Response
+	if s.durableRuns() {
+		engineResp, err = s.runDurable(ctx, engineReq)
+	} else {
+		engineResp, err = s.agentEngine.Run(ctx, engineReq)
+	}
 	if err != nil {
 		s.logger.Errorw("Agent engine failed", "error", err)
-		return nil, err
+		return nil, engineError(err)
 	}
 
-	// Mock artifact generation for workflow creation (for UI verification)
-	var artifacts []*Artifact
-	if strings.Contains(strings.ToLower(req.Query), "create workflow") || strings.Contains(strings.ToLower(req.Query), "draft workflow") {
-		artifacts = append(artifacts, &Artifact{
+	resp := chatResponse(engineResp)
+
+	// Mock artifact generation for workflow creation (for UI verification);
+	// structured answers are left as the bare JSON the caller asked for
+	query := strings.ToLower(req.Query)
+	if engineReq.ResponseSchema == nil && (strings.Contains(query, "create workflow") || strings.Contains(query, "draft workflow")) {
+		resp.Artifacts = append(resp.Artifacts, &Artifact{
 			Id:       fmt.Sprintf("art-%d", time.Now().UnixNano()),
 			Type:     "workflow_draft",
 			Title:    "Workflow Draft",
 			Content:  `{"summary": "Data Pipeline Workflow", "schedule": "@daily", "status": "draft", "yaml": "name: data-pipeline\nsteps:\n  - name: fetch-data\n    action: ucl.fetch\n"}`,
 			Language: stringPtr("json"),
 		})
-		engineResp.Text += "\n\nI've created a draft workflow for you. You can review it below."
+		resp.Response += "\n\nI've created a draft workflow for you. You can review it below."
 	}
 
-	return &ChatResponse{
-		Response:  engineResp.Text,
-		Reasoning: nil,
-		Artifacts: artifacts,
-		Citations: nil,
+	return resp, nil
+}
+
+// durableRuns reports whether chat requests run as Temporal workflows
+func (s *AgentServer) durableRuns() bool {
+	return s.config.AgentDurableRuns && s.workflowEngine != nil
+}
+
+// runDurable starts a durable agent run and waits up to AgentDurableWait for it
+// to complete or pause for approval
+func (s *AgentServer) runDurable(ctx context.Context, req agentengine.Request) (*agentengine.Response, error) {
+	runID, err := s.workflowEngine.StartAgentRun(ctx, workflow.AgentRunInput{
+		Request:          req,
+		MaxParallelTools: s.config.AgentMaxParallelTools,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.waitDurable(ctx, runID, 0)
+}
+
+// waitDurable waits up to AgentDurableWait for a durable run to move past update after
+func (s *AgentServer) waitDurable(ctx context.Context, runID string, after int) (*agentengine.Response, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, s.config.AgentDurableWait)
+	defer cancel()
+	return s.workflowEngine.WaitAgentRun(waitCtx, runID, after)
+}
+
+// chatResponse converts an engine response, including actions awaiting approval
+func chatResponse(engineResp *agentengine.Response) *ChatResponse {
+	resp := &ChatResponse{
+		Response:        engineResp.Text,
+		ProposedActions: proposedActions(engineResp.PendingCalls),
+	}
+	if engineResp.Status != "" {
+		resp.Status = stringPtr(string(engineResp.Status))
+	}
+	if engineResp.RunID != "" {
+		resp.RunId = stringPtr(engineResp.RunID)
+	}
+	if engineResp.Trace != nil {
+		resp.TraceId = stringPtr(engineResp.Trace.ID)
+	}
+	resp.Usage = tokenUsage(engineResp.Usage)
+	resp.Structured = structuredJSON(engineResp.Structured)
+	resp.Citations = citationIDs(engineResp.Citations)
+	resp.Reasoning = reasoningFromTrace(engineResp.Trace)
+	return resp
+}
+
+// citationIDs lists the IDs of the sources an answer cites
+func citationIDs(citations []agentengine.Citation) []string {
+	if len(citations) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(citations))
+	for _, citation := range citations {
+		ids = append(ids, citation.ID)
+	}
+	return ids
+}
+
+// structuredJSON encodes a structured answer, nil when the run had no response schema
+func structuredJSON(value any) *string {
+	if value == nil {
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return stringPtr(string(raw))
+}
+
+// engineError maps exhausted usage budgets onto ResourceExhausted and answers
+// that never matched the response schema onto Aborted
+func engineError(err error) error {
+	if errors.Is(err, agentengine.ErrBudgetExceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if errors.Is(err, agentengine.ErrInvalidStructuredOutput) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	if errors.Is(err, agentengine.ErrRunNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+// tokenUsage maps run usage onto the proto message, nil when no tokens were counted
+func tokenUsage(usage agentengine.Usage) *TokenUsage {
+	if usage.IsZero() {
+		return nil
+	}
+	return &TokenUsage{
+		PromptTokens:     int32(usage.PromptTokens),
+		CompletionTokens: int32(usage.CompletionTokens),
+		TotalTokens:      int32(usage.TotalTokens),
+		CostUsd:          usage.CostUSD,
+	}
+}
+
+// proposedActions maps calls awaiting approval onto ProposedAction messages
+func proposedActions(calls []agentengine.PendingCall) []*ProposedAction {
+	if len(calls) == 0 {
+		return nil
+	}
+	actions := make([]*ProposedAction, 0, len(calls))
+	for _, pending := range calls {
+		runID, _ := agentengine.ParsePendingCallID(pending.ID)
+		payload, _ := json.Marshal(pending.Call.Args)
+		actions = append(actions, &ProposedAction{
+			Id:          pending.ID,
+			Type:        "approve",
+			Title:       "Run " + toolCallLabel(pending.Call),
+			Description: fmt.Sprintf("Approval needed (%s) to run %s with %s", pending.Reason, toolCallLabel(pending.Call), string(payload)),
+			RunId:       stringPtr(runID),
+			Tool:        stringPtr(pending.Call.Name),
+			Action:      stringPtr(pending.Call.Action),
+			Payload:     stringPtr(string(payload)),
+		})
+	}
+	return actions
+}
+
+// ResolveAction approves or rejects proposed actions and resumes the paused run
+func (s *AgentServer) ResolveAction(ctx context.Context, req *ResolveActionRequest) (*ChatResponse, error) {
+	s.logger.Infow("Resolve action request received",
+		"run_id", req.RunId,
+		"decisions", len(req.Decisions),
+	)
+
+	if s.agentEngine == nil {
+		return nil, fmt.Errorf("agent engine not configured")
+	}
+
+	decisions := make([]agentengine.ApprovalDecision, 0, len(req.Decisions))
+	for _, d := range req.Decisions {
+		decisions = append(decisions, agentengine.ApprovalDecision{
+			CallID:   d.ActionId,
+			Approved: d.Approved,
+			Reason:   d.GetReason(),
+		})
+	}
+
+	userID, projectID := getUserProject(ctx)
+	owner := agentengine.RunOwner{UserID: userID, ProjectID: projectID}
+	var engineResp *agentengine.Response
+	var err error
+	if strings.HasPrefix(req.RunId, workflow.AgentRunIDPrefix) && s.workflowEngine != nil {
+		var updates int
+		updates, err = s.workflowEngine.DecideAgentRun(ctx, owner, req.RunId, decisions)
+		if err == nil {
+			engineResp, err = s.waitDurable(ctx, req.RunId, updates)
+		}
+	} else {
+		engineResp, err = s.agentEngine.Decide(ctx, owner, req.RunId, decisions)
+	}
+	if err != nil {
+		s.logger.Errorw("Resolve action failed", "run_id", req.RunId, "error", err)
+		return nil, engineError(err)
+	}
+	return chatResponse(engineResp), nil
+}
+
+// resolveProposedAction handles agent.approve / agent.reject sent through ExecuteAction
+func (s *AgentServer) resolveProposedAction(ctx context.Context, req *ActionRequest, approved bool) (*ActionResponse, error) {
+	runID, err := agentengine.ParsePendingCallID(req.EntityId)
+	if err != nil {
+		return nil, err
+	}
+	reason := ""
+	if params := parsePayload(req.Payload); params != nil {
+		reason, _ = params["reason"].(string)
+	}
+
+	resp, err := s.ResolveAction(ctx, &ResolveActionRequest{
+		RunId: runID,
+		Decisions: []*ActionDecision{{
+			ActionId: req.EntityId,
+			Approved: approved,
+			Reason:   stringPtr(reason),
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newState, _ := json.Marshal(map[string]any{
+		"runId":           resp.GetRunId(),
+		"status":          resp.GetStatus(),
+		"traceId":         resp.GetTraceId(),
+		"response":        resp.Response,
+		"proposedActions": resp.ProposedActions,
+	})
+	return &ActionResponse{
+		Success:    true,
+		ActionType: req.ActionType,
+		EntityId:   req.EntityId,
+		Message:    resp.Response,
+		NewState:   stringPtr(string(newState)),
 	}, nil
 }
 
@@ -245,53 +783,262 @@ func stringPtr(s string) *string {
 	return &s
 }
 
-// StreamChat handles a streaming chat request
-func (s *AgentServer) StreamChat(req *ChatRequest, stream AgentService_StreamChatServer) error {
-	s.logger.Infow("Stream chat request received", "query", req.Query)
+// engineRequest converts a proto chat request into an AgentEngine request
+func (s *AgentServer) engineRequest(ctx context.Context, req *ChatRequest) (agentengine.Request, error) {
+	var responseSchema map[string]any
+	if raw := strings.TrimSpace(req.GetResponseSchema()); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &responseSchema); err != nil {
+			return agentengine.Request{}, status.Errorf(codes.InvalidArgument, "response_schema is not a JSON object: %v", err)
+		}
+	}
 
-	// Run agent
-	agentReq := &agent.ChatRequest{
+	engineHistory := make([]agentengine.HistoryMessage, 0, len(req.History))
+	for _, h := range req.History {
+		engineHistory = append(engineHistory, agentengine.HistoryMessage{
+			Role:    h.Role,
+			Content: h.Content,
+		})
+	}
+
+	userID, projectID := getUserProject(ctx)
+	return agentengine.Request{
 		Query:           req.Query,
-		ConversationID:  req.ConversationId,
+		SessionID:       req.ConversationId,
+		UserID:          userID,
+		ProjectID:       projectID,
+		UserRole:        getUserRole(ctx),
 		ContextEntities: req.ContextEntities,
+		History:         engineHistory,
+		Provider:        req.GetProvider(),
+		Model:           req.GetModel(),
+		Planner:         req.GetPlanner(),
+		ResponseSchema:  responseSchema,
+	}, nil
+}
+
+// StreamChat handles a streaming chat request
+func (s *AgentServer) StreamChat(req *ChatRequest, stream AgentService_StreamChatServer) error {
+	_, projectID := getUserProject(stream.Context())
+	s.logger.Infow("Stream chat request received",
+		"query", s.maskLog(projectID, req.Query),
+		"conversation_id", req.ConversationId,
+		"provider", req.GetProvider(),
+		"model", req.GetModel(),
+	)
+
+	if s.agentEngine == nil {
+		return fmt.Errorf("agent engine not configured")
 	}
 
-	// Use context.Background() as stream context doesn't implement full Context interface
-	ctx := context.Background()
-	agentResp, err := s.runner.Chat(ctx, agentReq)
+	ctx := stream.Context()
+	engineReq, err := s.engineRequest(ctx, req)
 	if err != nil {
 		return err
 	}
 
-	// Stream reasoning steps
-	for _, step := range agentResp.Reasoning {
-		durationMs := step.DurationMs
-		if err := stream.Send(&ChatChunk{
-			Reasoning: &ReasoningStep{
-				Step:       int32(step.Step),
-				Type:       step.Type,
-				Content:    step.Content,
-				DurationMs: &durationMs,
-			},
-		}); err != nil {
-			return err
+	reasoningStep := int32(0)
+	_, err = s.agentEngine.RunStream(ctx, engineReq, func(ev agentengine.Event) error {
+		switch ev.Type {
+		case agentengine.EventToken:
+			if ev.Delta == "" {
+				return nil
+			}
+			return stream.Send(&ChatChunk{Content: ev.Delta})
+		case agentengine.EventApprovalRequired:
+			chunk := &ChatChunk{ProposedActions: proposedActions(ev.PendingCalls)}
+			if len(ev.PendingCalls) > 0 {
+				runID, _ := agentengine.ParsePendingCallID(ev.PendingCalls[0].ID)
+				chunk.RunId = stringPtr(runID)
+			}
+			return stream.Send(chunk)
+		case agentengine.EventFinal:
+			chunk := &ChatChunk{Done: true}
+			if ev.Response != nil && ev.Response.Trace != nil {
+				chunk.TraceId = stringPtr(ev.Response.Trace.ID)
+			}
+			if ev.Response != nil {
+				chunk.Usage = tokenUsage(ev.Response.Usage)
+				chunk.Structured = structuredJSON(ev.Response.Structured)
+				chunk.Citations = citationIDs(ev.Response.Citations)
+			}
+			return stream.Send(chunk)
+		}
+
+		step := reasoningForEvent(ev)
+		if step == nil {
+			return nil
+		}
+		reasoningStep++
+		step.Step = reasoningStep
+		return stream.Send(&ChatChunk{Reasoning: step})
+	})
+	if err != nil {
+		s.logger.Errorw("Agent engine stream failed", "error", err)
+		return engineError(err)
+	}
+	return nil
+}
+
+// reasoningForEvent maps plan and tool events onto UI reasoning steps
+func reasoningForEvent(ev agentengine.Event) *ReasoningStep {
+	switch ev.Type {
+	case agentengine.EventPlan:
+		if ev.Plan == nil {
+			return nil
+		}
+		content := ""
+		switch ev.Plan.Type {
+		case agentengine.PlanDirect:
+			content = "Answering directly"
+		case agentengine.PlanNeedClarification:
+			content = "Asking for clarification"
+		case agentengine.PlanToolCalls:
+			names := make([]string, 0, len(ev.Plan.ToolCalls))
+			for _, call := range ev.Plan.ToolCalls {
+				names = append(names, toolCallLabel(call))
+			}
+			content = fmt.Sprintf("Planning %d tool call(s): %s", len(names), strings.Join(names, ", "))
+		case agentengine.PlanSteps:
+			names := make([]string, 0, len(ev.Plan.Steps))
+			for _, planStep := range ev.Plan.Steps {
+				names = append(names, planStep.ID+" "+toolCallLabel(planStep.Call))
+			}
+			content = fmt.Sprintf("Planning %d step(s): %s", len(names), strings.Join(names, ", "))
+		default:
+			return nil
+		}
+		return &ReasoningStep{Type: "analysis", Content: content}
+	case agentengine.EventToolCallStarted:
+		if ev.ToolCall == nil {
+			return nil
+		}
+		return &ReasoningStep{Type: "action", Content: "Calling " + toolCallLabel(*ev.ToolCall)}
+	case agentengine.EventToolCallFinished:
+		if ev.ToolCall == nil || ev.Observation == nil {
+			return nil
+		}
+		content := toolCallLabel(*ev.ToolCall) + " completed"
+		if ev.Observation.Error != "" {
+			content = toolCallLabel(*ev.ToolCall) + " failed: " + ev.Observation.Error
+		} else if ev.Observation.Result != nil && ev.Observation.Result.Message != "" {
+			content += ": " + ev.Observation.Result.Message
+		}
+		durationMs := ev.Duration.Milliseconds()
+		return &ReasoningStep{Type: "retrieval", Content: content, DurationMs: &durationMs}
+	}
+	return nil
+}
+
+// reasoningFromTrace maps the plan, tool and answer events of a run trace onto
+// UI reasoning steps, the same steps StreamChat sends as they happen
+func reasoningFromTrace(trace *agentengine.Trace) []*ReasoningStep {
+	if trace == nil {
+		return nil
+	}
+	var steps []*ReasoningStep
+	for _, ev := range trace.Events {
+		var step *ReasoningStep
+		switch ev.Name {
+		case "plan":
+			step = &ReasoningStep{Type: "analysis", Content: planSummary(ev)}
+		case "tool.call":
+			step = &ReasoningStep{Type: "retrieval", Content: toolCallSummary(ev)}
+		case "plan.replan":
+			step = &ReasoningStep{Type: "analysis", Content: "Re-planning: " + ev.Detail}
+		case "tool.pending":
+			step = &ReasoningStep{Type: "action", Content: "Awaiting approval for " + eventCallLabel(ev)}
+		case "delegate":
+			agentName, _ := ev.Attrs["agent"].(string)
+			step = &ReasoningStep{Type: "action", Content: "Delegated to the " + agentName + " agent"}
+		case "citations.unknown":
+			step = &ReasoningStep{Type: "synthesis", Content: "Answer cites unknown sources: " + strings.Join(attrStrings(ev.Attrs["ids"]), ", ")}
+		default:
+			continue
 		}
+		if step.Content == "" {
+			continue
+		}
+		durationMs := ev.Duration.Milliseconds()
+		step.Step = int32(len(steps) + 1)
+		step.DurationMs = &durationMs
+		steps = append(steps, step)
 	}
+	return steps
+}
 
-	// Stream response
-	words := splitWords(agentResp.Response)
-	for i, word := range words {
-		if err := stream.Send(&ChatChunk{
-			Content: word + " ",
-			Done:    i == len(words)-1,
-		}); err != nil {
-			return err
+func planSummary(ev agentengine.TraceEvent) string {
+	planType, _ := ev.Attrs["type"].(string)
+	switch agentengine.PlanType(planType) {
+	case agentengine.PlanDirect:
+		return "Answering directly"
+	case agentengine.PlanNeedClarification:
+		return "Asking for clarification"
+	case agentengine.PlanToolCalls:
+		calls := attrStrings(ev.Attrs["calls"])
+		return fmt.Sprintf("Planning %d tool call(s): %s", len(calls), strings.Join(calls, ", "))
+	case agentengine.PlanSteps:
+		// Steps are recorded as objects, which a trace decoded from JSON holds as generic maps
+		var steps []struct {
+			ID   string `json:"id"`
+			Call string `json:"call"`
 		}
+		raw, _ := json.Marshal(ev.Attrs["steps"])
+		_ = json.Unmarshal(raw, &steps)
+		names := make([]string, 0, len(steps))
+		for _, planStep := range steps {
+			names = append(names, planStep.ID+" "+planStep.Call)
+		}
+		return fmt.Sprintf("Planning %d step(s): %s", len(names), strings.Join(names, ", "))
+	}
+	return ""
+}
+
+func toolCallSummary(ev agentengine.TraceEvent) string {
+	label := eventCallLabel(ev)
+	outcome, _ := ev.Attrs["outcome"].(string)
+	switch {
+	case ev.Detail != "" && outcome == "rejected":
+		return label + " rejected: " + ev.Detail
+	case ev.Detail != "":
+		return label + " failed: " + ev.Detail
 	}
+	content := label + " completed"
+	if sources := attrStrings(ev.Attrs["sources"]); len(sources) > 0 {
+		content += fmt.Sprintf(" (%d source(s))", len(sources))
+	}
+	return content
+}
 
+func eventCallLabel(ev agentengine.TraceEvent) string {
+	name, _ := ev.Attrs["tool"].(string)
+	action, _ := ev.Attrs["action"].(string)
+	return toolCallLabel(agentengine.ToolCall{Name: name, Action: action})
+}
+
+// attrStrings reads a string list attribute, as recorded or decoded from JSON
+func attrStrings(value any) []string {
+	switch typed := value.(type) {
+	case []string:
+		return typed
+	case []any:
+		out := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
 	return nil
 }
 
+func toolCallLabel(call agentengine.ToolCall) string {
+	if call.Action == "" || strings.HasSuffix(call.Name, "/"+call.Action) {
+		return call.Name
+	}
+	return call.Name + "." + call.Action
+}
+
 // ExecuteAction handles an action execution request
 func (s *AgentServer) ExecuteAction(ctx context.Context, req *ActionRequest) (*ActionResponse, error) {
 	s.logger.Infow("Action request received",
@@ -299,6 +1046,14 @@ func (s *AgentServer) ExecuteAction(ctx context.Context, req *ActionRequest) (*A
 		"entity_id", req.EntityId,
 	)
 
+	// Approval decisions for actions proposed by the agent
+	switch req.ActionType {
+	case "agent.approve":
+		return s.resolveProposedAction(ctx, req, true)
+	case "agent.reject":
+		return s.resolveProposedAction(ctx, req, false)
+	}
+
 	// 1. Try unified Tool Registry first (Dynamic/Unified path)
 	if s.toolRegistry != nil {
 		// Parse ActionType logic
@@ -388,25 +1143,6 @@ func parsePayload(payload string) map[string]any {
 	return params
 }
 
-func splitWords(s string) []string {
-	words := []string{}
-	current := ""
-	for _, c := range s {
-		if c == ' ' || c == '\n' {
-			if current != "" {
-				words = append(words, current)
-				current = ""
-			}
-		} else {
-			current += string(c)
-		}
-	}
-	if current != "" {
-		words = append(words, current)
-	}
-	return words
-}
-
 func matchesTool(actionType, toolName string) bool {
 	if len(actionType) >= len(toolName) {
 		return actionType[:len(toolName)] == toolName
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+	"github.com/antigravity/go-agent-service/internal/config"
+)
+
+type directPlanner struct{}
+
+func (directPlanner) Plan(ctx context.Context, input agentengine.PlanInput) (agentengine.Plan, error) {
+	return agentengine.Plan{Type: agentengine.PlanDirect}, nil
+}
+
+// writePlanner proposes a write on the first step, then answers directly.
+type writePlanner struct{}
+
+func (writePlanner) Plan(ctx context.Context, input agentengine.PlanInput) (agentengine.Plan, error) {
+	if len(input.Observations) > 0 {
+		return agentengine.Plan{Type: agentengine.PlanDirect}, nil
+	}
+	return agentengine.Plan{Type: agentengine.PlanToolCalls, ToolCalls: []agentengine.ToolCall{
+		{Name: "jira", Action: "create_issue", Args: map[string]any{"summary": "Crash on login"}},
+	}}, nil
+}
+
+type noTools struct{}
+
+func (noTools) ListTools(ctx context.Context, userID, projectID string) ([]agentengine.ToolDef, error) {
+	return nil, nil
+}
+
+type jiraTools struct{}
+
+func (jiraTools) ListTools(ctx context.Context, userID, projectID string) ([]agentengine.ToolDef, error) {
+	return []agentengine.ToolDef{{Name: "jira", Actions: []agentengine.ToolAction{{Name: "create_issue"}}}}, nil
+}
+
+type noExecutor struct{}
+
+func (noExecutor) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	return &agentengine.ToolResult{Success: true}, nil
+}
+
+type queryAssembler struct{}
+
+func (queryAssembler) Build(ctx context.Context, req agentengine.Request, tools []agentengine.ToolDef) (string, error) {
+	return req.Query, nil
+}
+
+func (queryAssembler) AppendObservations(prompt string, observations []agentengine.Observation) (string, error) {
+	return prompt, nil
+}
+
+// staticLLM answers every prompt with the same text.
+type staticLLM struct {
+	text string
+}
+
+func (l staticLLM) Respond(ctx context.Context, input agentengine.LLMRequest) (agentengine.LLMResponse, error) {
+	return agentengine.LLMResponse{Text: l.text}, nil
+}
+
+func newTestServer(t *testing.T, cfg agentengine.Config) *AgentServer {
+	t.Helper()
+	if cfg.Planner == nil {
+		cfg.Planner = directPlanner{}
+	}
+	if cfg.Tools == nil {
+		cfg.Tools = noTools{}
+	}
+	cfg.Executor = noExecutor{}
+	cfg.Context = queryAssembler{}
+	engine, err := agentengine.NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return &AgentServer{
+		config:      &config.Config{},
+		logger:      zap.NewNop().Sugar(),
+		agentEngine: engine,
+	}
+}
+
+func TestChatStructuredResponseSkipsWorkflowDraft(t *testing.T) {
+	s := newTestServer(t, agentengine.Config{LLM: staticLLM{text: `{"status": "ok"}`}})
+	schema := `{"type": "object", "properties": {"status": {"type": "string"}}, "required": ["status"]}`
+	resp, err := s.Chat(context.Background(), &ChatRequest{Query: "Summarize the draft workflow", ResponseSchema: &schema})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal([]byte(resp.Response), &out); err != nil || out["status"] != "ok" {
+		t.Fatalf("expected the bare JSON answer, got %q: %v", resp.Response, err)
+	}
+	if len(resp.Artifacts) != 0 {
+		t.Fatalf("expected no workflow artifact, got %+v", resp.Artifacts)
+	}
+}
+
+func TestResolveActionRejectsOtherUsers(t *testing.T) {
+	s := newTestServer(t, agentengine.Config{
+		Planner:       writePlanner{},
+		LLM:           staticLLM{text: "done"},
+		Tools:         jiraTools{},
+		Runs:          agentengine.NewMemoryRunStore(),
+		ApproveWrites: true,
+	})
+	owner := withUserProject(context.Background(), "u1", "p1")
+	resp, err := s.Chat(owner, &ChatRequest{Query: "file a bug"})
+	if err != nil || len(resp.ProposedActions) != 1 {
+		t.Fatalf("expected a write awaiting approval, got %+v, %v", resp, err)
+	}
+	action := resp.ProposedActions[0]
+
+	other := withUserProject(context.Background(), "u2", "p1")
+	_, err = s.ResolveAction(other, &ResolveActionRequest{
+		RunId:     action.GetRunId(),
+		Decisions: []*ActionDecision{{ActionId: action.Id, Approved: true}},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected another user's approval refused as not found, got %v", err)
+	}
+	if _, err := s.ExecuteAction(other, &ActionRequest{ActionType: "agent.approve", EntityId: action.Id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected another user's agent.approve refused as not found, got %v", err)
+	}
+
+	approved, err := s.ExecuteAction(owner, &ActionRequest{ActionType: "agent.approve", EntityId: action.Id})
+	if err != nil || !approved.Success || approved.Message != "done" {
+		t.Fatalf("expected the owner's approval to resume the run, got %+v, %v", approved, err)
+	}
+}
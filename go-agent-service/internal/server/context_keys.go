@@ -7,6 +7,7 @@ type contextKey string
 const (
 	contextUserIDKey    contextKey = "userId"
 	contextProjectIDKey contextKey = "projectId"
+	contextUserRoleKey  contextKey = "userRole"
 )
 
 func withUserProject(ctx context.Context, userID, projectID string) context.Context {
@@ -34,3 +35,15 @@ func getUserProject(ctx context.Context) (string, string) {
 	}
 	return userID, projectID
 }
+
+func withUserRole(ctx context.Context, role string) context.Context {
+	if role == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextUserRoleKey, role)
+}
+
+func getUserRole(ctx context.Context) string {
+	role, _ := ctx.Value(contextUserRoleKey).(string)
+	return role
+}
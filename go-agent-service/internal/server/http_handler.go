@@ -3,13 +3,20 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/antigravity/go-agent-service/internal/agentengine"
 	"github.com/antigravity/go-agent-service/internal/appregistry"
+	"github.com/antigravity/go-agent-service/internal/prompts"
 	"github.com/antigravity/go-agent-service/internal/workflow"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // HTTPHandler wraps the AgentServer for HTTP requests
@@ -34,8 +41,11 @@ type ChatHTTPRequest struct {
 	SessionID       *string          `json:"session_id,omitempty"`
 	Provider        *string          `json:"provider,omitempty"`
 	Model           *string          `json:"model,omitempty"`
+	Planner         *string          `json:"planner,omitempty"`
+	ResponseSchema  json.RawMessage  `json:"response_schema,omitempty"`
 	UserID          *string          `json:"userId,omitempty"`
 	ProjectID       *string          `json:"projectId,omitempty"`
+	UserRole        *string          `json:"userRole,omitempty"` // Rendered into the system prompt template
 	History         []HistoryMessage `json:"history"`
 	AttachedFiles   []AttachedFile   `json:"attachedFiles,omitempty"`
 }
@@ -49,10 +59,37 @@ type AttachedFile struct {
 
 // ChatHTTPResponse matches the rust-gateway response format
 type ChatHTTPResponse struct {
-	Response  string              `json:"response"`
-	Reasoning []ReasoningStepJSON `json:"reasoning"`
-	Artifacts []ArtifactJSON      `json:"artifacts,omitempty"`
-	Citations []string            `json:"citations,omitempty"`
+	Response        string               `json:"response"`
+	Reasoning       []ReasoningStepJSON  `json:"reasoning"`
+	Artifacts       []ArtifactJSON       `json:"artifacts,omitempty"`
+	Citations       []string             `json:"citations,omitempty"`
+	ProposedActions []ProposedActionJSON `json:"proposed_actions,omitempty"`
+	RunID           string               `json:"run_id,omitempty"`
+	Status          string               `json:"status,omitempty"`
+	TraceID         string               `json:"trace_id,omitempty"`
+	Usage           *UsageJSON           `json:"usage,omitempty"`
+	Structured      json.RawMessage      `json:"structured,omitempty"`
+}
+
+// UsageJSON reports LLM token usage and estimated cost for HTTP JSON responses
+type UsageJSON struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// ProposedActionJSON for HTTP JSON response; approve or reject via POST /action
+// with action_type agent.approve / agent.reject and entity_id set to the action ID
+type ProposedActionJSON struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	RunID       string `json:"run_id,omitempty"`
+	Tool        string `json:"tool,omitempty"`
+	Action      string `json:"action,omitempty"`
+	Payload     string `json:"payload,omitempty"`
 }
 
 // ReasoningStepJSON for HTTP JSON response
@@ -86,8 +123,12 @@ func (h *HTTPHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	logProject := ""
+	if req.ProjectID != nil {
+		logProject = *req.ProjectID
+	}
 	h.logger.Infow("HTTP Chat request",
-		"query", req.Query,
+		"query", h.agent.maskLog(logProject, req.Query),
 		"conversation_id", req.ConversationID,
 		"provider", req.Provider,
 		"model", req.Model,
@@ -123,6 +164,12 @@ func (h *HTTPHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 	if req.Model != nil {
 		grpcReq.Model = req.Model
 	}
+	if req.Planner != nil {
+		grpcReq.Planner = req.Planner
+	}
+	if len(req.ResponseSchema) > 0 {
+		grpcReq.ResponseSchema = stringPtr(string(req.ResponseSchema))
+	}
 
 	// Convert history
 	for i := range req.History {
@@ -145,11 +192,25 @@ func (h *HTTPHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 		}
 		ctx = withUserProject(ctx, userID, projectID)
 	}
+	if req.UserRole != nil {
+		ctx = withUserRole(ctx, *req.UserRole)
+	}
 
 	// Call the gRPC handler internally
 	resp, err := h.agent.Chat(ctx, grpcReq)
 	if err != nil {
 		h.logger.Errorw("Chat failed", "error", err)
+		switch status.Code(err) {
+		case codes.ResourceExhausted:
+			http.Error(w, status.Convert(err).Message(), http.StatusTooManyRequests)
+			return
+		case codes.InvalidArgument:
+			http.Error(w, status.Convert(err).Message(), http.StatusBadRequest)
+			return
+		case codes.Aborted:
+			http.Error(w, status.Convert(err).Message(), http.StatusUnprocessableEntity)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -159,6 +220,20 @@ func (h *HTTPHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 		Response:  resp.Response,
 		Reasoning: make([]ReasoningStepJSON, 0),
 		Citations: resp.Citations,
+		RunID:     resp.GetRunId(),
+		Status:    resp.GetStatus(),
+		TraceID:   resp.GetTraceId(),
+	}
+	if resp.Structured != nil {
+		httpResp.Structured = json.RawMessage(resp.GetStructured())
+	}
+	if u := resp.GetUsage(); u != nil {
+		httpResp.Usage = &UsageJSON{
+			PromptTokens:     int(u.PromptTokens),
+			CompletionTokens: int(u.CompletionTokens),
+			TotalTokens:      int(u.TotalTokens),
+			CostUSD:          u.CostUsd,
+		}
 	}
 
 	for _, r := range resp.Reasoning {
@@ -180,6 +255,8 @@ func (h *HTTPHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	httpResp.ProposedActions = proposedActionsJSON(resp.ProposedActions)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(httpResp)
 }
@@ -235,6 +312,97 @@ func (h *HTTPHandler) HandleCancelWorkflow(w http.ResponseWriter, r *http.Reques
 	w.Write([]byte(`{"success": true}`))
 }
 
+// HandleAgentRun handles GET /agent-runs/:id?userId=&projectId= (progress) and
+// POST /agent-runs/:id/cancel?userId=&projectId=. Only the run's user and project
+// may read or cancel it.
+func (h *HTTPHandler) HandleAgentRun(w http.ResponseWriter, r *http.Request, id string, cancel bool) {
+	if (cancel && r.Method != http.MethodPost) || (!cancel && r.Method != http.MethodGet) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.agent.GetWorkflowEngine() == nil {
+		http.Error(w, "Workflow engine not available", http.StatusServiceUnavailable)
+		return
+	}
+	query := r.URL.Query()
+	owner := agentengine.RunOwner{UserID: query.Get("userId"), ProjectID: query.Get("projectId")}
+
+	if cancel {
+		if err := h.agent.GetWorkflowEngine().CancelAgentRun(r.Context(), owner, id); err != nil {
+			h.logger.Errorw("Failed to cancel agent run", "run_id", id, "error", err)
+			http.Error(w, err.Error(), agentRunStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success": true}`))
+		return
+	}
+
+	progress, err := h.agent.GetWorkflowEngine().AgentRunProgress(r.Context(), owner, id)
+	if err != nil {
+		h.logger.Errorw("Failed to query agent run", "run_id", id, "error", err)
+		http.Error(w, err.Error(), agentRunStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(agentRunJSON(progress))
+}
+
+// agentRunStatus maps an agent run lookup error to an HTTP status
+func agentRunStatus(err error) int {
+	switch {
+	case errors.Is(err, agentengine.ErrRunNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, workflow.ErrAgentRunForbidden):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// AgentRunJSON for HTTP JSON response
+type AgentRunJSON struct {
+	RunID           string               `json:"run_id"`
+	TraceID         string               `json:"trace_id,omitempty"`
+	Status          string               `json:"status"`
+	Step            int                  `json:"step"`
+	LastPlan        string               `json:"last_plan,omitempty"`
+	Observations    int                  `json:"observations"`
+	ProposedActions []ProposedActionJSON `json:"proposed_actions,omitempty"`
+	Usage           UsageJSON            `json:"usage"`
+	Error           string               `json:"error,omitempty"`
+}
+
+func agentRunJSON(progress *workflow.AgentRunProgress) AgentRunJSON {
+	return AgentRunJSON{
+		RunID:           progress.RunID,
+		TraceID:         progress.TraceID,
+		Status:          string(progress.Status),
+		Step:            progress.Step,
+		LastPlan:        string(progress.LastPlan),
+		Observations:    progress.Observations,
+		ProposedActions: proposedActionsJSON(proposedActions(progress.PendingCalls)),
+		Usage:           usageJSON(progress.Usage),
+		Error:           progress.Error,
+	}
+}
+
+func proposedActionsJSON(actions []*ProposedAction) []ProposedActionJSON {
+	var out []ProposedActionJSON
+	for _, a := range actions {
+		out = append(out, ProposedActionJSON{
+			ID:          a.Id,
+			Type:        a.Type,
+			Title:       a.Title,
+			Description: a.Description,
+			RunID:       a.GetRunId(),
+			Tool:        a.GetTool(),
+			Action:      a.GetAction(),
+			Payload:     a.GetPayload(),
+		})
+	}
+	return out
+}
+
 // HandleCreateWorkflow handles POST /workflows/create
 func (h *HTTPHandler) HandleCreateWorkflow(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -289,6 +457,16 @@ func (h *HTTPHandler) HandleListTools(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(toolsList)
 }
 
+// HandleListLLMProviders handles GET /llm-providers - lists the providers ChatRequest.provider can select
+func (h *HTTPHandler) HandleListLLMProviders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.agent.providers.Providers())
+}
+
 // ExecuteRequest for HTTP API
 type ExecuteRequest struct {
 	Name       string         `json:"name"`
@@ -300,11 +478,14 @@ type ExecuteRequest struct {
 	ProjectID  string         `json:"projectId,omitempty"`
 }
 
-// ActionHTTPRequest for legacy /action endpoint
+// ActionHTTPRequest for legacy /action endpoint; approvals must name the user
+// and project the run was started for
 type ActionHTTPRequest struct {
 	ActionType string `json:"action_type"`
 	EntityID   string `json:"entity_id"`
 	Payload    string `json:"payload"`
+	UserID     string `json:"userId,omitempty"`
+	ProjectID  string `json:"projectId,omitempty"`
 }
 
 // HandleExecuteTool handles POST /tools/execute
@@ -366,13 +547,18 @@ func (h *HTTPHandler) HandleExecuteAction(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	resp, err := h.agent.ExecuteAction(r.Context(), &ActionRequest{
+	ctx := withUserProject(r.Context(), req.UserID, req.ProjectID)
+	resp, err := h.agent.ExecuteAction(ctx, &ActionRequest{
 		ActionType: req.ActionType,
 		EntityId:   req.EntityID,
 		Payload:    req.Payload,
 	})
 	if err != nil {
 		h.logger.Errorw("Action execution failed", "error", err)
+		if status.Code(err) == codes.NotFound {
+			http.Error(w, status.Convert(err).Message(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -685,3 +871,399 @@ func (h *HTTPHandler) HandleProjectApps(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
+
+// TraceJSON for HTTP JSON response
+type TraceJSON struct {
+	ID         string           `json:"id"`
+	RunID      string           `json:"run_id,omitempty"`
+	ParentID   string           `json:"parent_id,omitempty"`
+	SessionID  string           `json:"session_id"`
+	UserID     string           `json:"user_id,omitempty"`
+	ProjectID  string           `json:"project_id,omitempty"`
+	Query      string           `json:"query"`
+	Status     string           `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	DurationMs int64            `json:"duration_ms"`
+	Events     []TraceEventJSON `json:"events"`
+
+	PromptVersion string `json:"prompt_version,omitempty"`
+}
+
+// TraceEventJSON for HTTP JSON response
+type TraceEventJSON struct {
+	Name       string         `json:"name"`
+	Detail     string         `json:"detail,omitempty"`
+	Step       int            `json:"step,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+	Attrs      map[string]any `json:"attrs,omitempty"`
+	At         time.Time      `json:"at"`
+}
+
+// HandleGetTrace handles GET /traces/:id?userId=&projectId=. Traces of other
+// users and projects are reported as not found.
+func (h *HTTPHandler) HandleGetTrace(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	trace, err := h.agent.GetTraceStore().GetTrace(r.Context(), id)
+	if err == nil && !trace.OwnedBy(traceOwner(r)) {
+		err = agentengine.ErrTraceNotFound
+	}
+	if errors.Is(err, agentengine.ErrTraceNotFound) {
+		http.Error(w, "Trace not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Errorw("Get trace failed", "error", err, "trace_id", id)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(traceJSON(trace))
+}
+
+// HandleListSessionTraces handles GET /sessions/:id/traces?userId=&projectId=&limit=20.
+// Only traces of the given user and project are listed.
+func (h *HTTPHandler) HandleListSessionTraces(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	traces, err := h.agent.GetTraceStore().ListTraces(r.Context(), sessionID, limit)
+	if err != nil {
+		h.logger.Errorw("List traces failed", "error", err, "session_id", sessionID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	owner := traceOwner(r)
+	result := make([]TraceJSON, 0, len(traces))
+	for _, trace := range traces {
+		if trace.OwnedBy(owner) {
+			result = append(result, traceJSON(trace))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// traceOwner reads the user and project a trace request is made for
+func traceOwner(r *http.Request) agentengine.RunOwner {
+	query := r.URL.Query()
+	return agentengine.RunOwner{UserID: query.Get("userId"), ProjectID: query.Get("projectId")}
+}
+
+func traceJSON(trace *agentengine.Trace) TraceJSON {
+	out := TraceJSON{
+		ID:         trace.ID,
+		RunID:      trace.RunID,
+		ParentID:   trace.ParentID,
+		SessionID:  trace.SessionID,
+		UserID:     trace.UserID,
+		ProjectID:  trace.ProjectID,
+		Query:      trace.Query,
+		Status:     trace.Status,
+		Error:      trace.Error,
+		StartedAt:  trace.Started,
+		DurationMs: trace.Duration().Milliseconds(),
+		Events:     make([]TraceEventJSON, 0, len(trace.Events)),
+
+		PromptVersion: trace.PromptVersion,
+	}
+	if !trace.Finished.IsZero() {
+		finished := trace.Finished
+		out.FinishedAt = &finished
+	}
+	for _, ev := range trace.Events {
+		out.Events = append(out.Events, TraceEventJSON{
+			Name:       ev.Name,
+			Detail:     ev.Detail,
+			Step:       ev.Step,
+			DurationMs: ev.Duration.Milliseconds(),
+			Attrs:      ev.Attrs,
+			At:         ev.At,
+		})
+	}
+	return out
+}
+
+// UsageReportJSON for HTTP JSON response
+type UsageReportJSON struct {
+	GroupBy string           `json:"group_by,omitempty"`
+	Since   *time.Time       `json:"since,omitempty"`
+	Until   *time.Time       `json:"until,omitempty"`
+	Total   UsageJSON        `json:"total"`
+	Runs    int              `json:"runs"`
+	Groups  []UsageGroupJSON `json:"groups"`
+}
+
+// UsageGroupJSON is one row of a usage report
+type UsageGroupJSON struct {
+	Key  string `json:"key"`
+	Runs int    `json:"runs"`
+	UsageJSON
+}
+
+// HandleUsageReport handles GET /usage?group_by=project&session_id=&user_id=&project_id=&since=&until=
+// since and until are RFC 3339 timestamps; until is exclusive.
+func (h *HTTPHandler) HandleUsageReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	group, err := agentengine.ParseUsageGroup(query.Get("group_by"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := agentengine.UsageFilter{
+		SessionID: query.Get("session_id"),
+		UserID:    query.Get("user_id"),
+		ProjectID: query.Get("project_id"),
+	}
+	for _, bound := range []struct {
+		name string
+		dest *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		raw := query.Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, bound.name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*bound.dest = parsed
+	}
+
+	summaries, err := h.agent.GetUsageStore().ReportUsage(r.Context(), filter, group)
+	if err != nil {
+		h.logger.Errorw("Usage report failed", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report := UsageReportJSON{
+		GroupBy: string(group),
+		Groups:  make([]UsageGroupJSON, 0, len(summaries)),
+	}
+	if !filter.Since.IsZero() {
+		report.Since = &filter.Since
+	}
+	if !filter.Until.IsZero() {
+		report.Until = &filter.Until
+	}
+	var total agentengine.Usage
+	for _, summary := range summaries {
+		total = total.Add(summary.Usage)
+		report.Runs += summary.Runs
+		if group != "" {
+			report.Groups = append(report.Groups, UsageGroupJSON{
+				Key:       summary.Key,
+				Runs:      summary.Runs,
+				UsageJSON: usageJSON(summary.Usage),
+			})
+		}
+	}
+	report.Total = usageJSON(total)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func usageJSON(usage agentengine.Usage) UsageJSON {
+	return UsageJSON{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		CostUSD:          usage.CostUSD,
+	}
+}
+
+// ========================
+// Prompt Template Handlers
+// ========================
+
+// PromptTemplateRequest creates a template version.
+type PromptTemplateRequest struct {
+	ProjectID   string `json:"projectId,omitempty"` // Empty for the global template
+	Body        string `json:"body"`
+	Description string `json:"description,omitempty"`
+	Active      bool   `json:"active,omitempty"`
+	CreatedBy   string `json:"createdBy,omitempty"`
+}
+
+// PromptActivateRequest makes a template version active.
+type PromptActivateRequest struct {
+	ProjectID string `json:"projectId,omitempty"`
+	Version   int    `json:"version"`
+}
+
+// PromptPreviewRequest renders a system prompt. Body previews a draft and
+// Version a stored version; neither previews the template runs would use.
+type PromptPreviewRequest struct {
+	ProjectID string `json:"projectId,omitempty"`
+	UserID    string `json:"userId,omitempty"`
+	UserRole  string `json:"userRole,omitempty"`
+	Version   int    `json:"version,omitempty"`
+	Body      string `json:"body,omitempty"`
+}
+
+// PromptPreviewJSON for HTTP JSON response
+type PromptPreviewJSON struct {
+	Template string `json:"template"` // Version label, e.g. "p1@v3", "builtin" or "draft"
+	Prompt   string `json:"prompt"`
+	Tools    int    `json:"tools"`
+	Warning  string `json:"warning,omitempty"`
+}
+
+// HandlePromptTemplates handles GET /prompt-templates?projectId= and POST /prompt-templates
+func (h *HTTPHandler) HandlePromptTemplates(w http.ResponseWriter, r *http.Request) {
+	store := h.agent.GetPromptStore()
+	if store == nil {
+		http.Error(w, "Prompt templates unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		templates, err := store.ListTemplates(r.Context(), r.URL.Query().Get("projectId"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if templates == nil {
+			templates = []prompts.Template{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(templates)
+	case http.MethodPost:
+		var req PromptTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Body) == "" {
+			http.Error(w, "body is required", http.StatusBadRequest)
+			return
+		}
+		if err := prompts.Validate(req.Body); err != nil {
+			http.Error(w, fmt.Sprintf("invalid template: %v", err), http.StatusBadRequest)
+			return
+		}
+		template, err := store.CreateTemplate(r.Context(), prompts.Template{
+			ProjectID:   req.ProjectID,
+			Body:        req.Body,
+			Description: req.Description,
+			Active:      req.Active,
+			CreatedBy:   req.CreatedBy,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(template)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleActivatePromptTemplate handles POST /prompt-templates/activate
+func (h *HTTPHandler) HandleActivatePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	store := h.agent.GetPromptStore()
+	if store == nil {
+		http.Error(w, "Prompt templates unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req PromptActivateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Version <= 0 {
+		http.Error(w, "version must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	err := store.ActivateTemplate(r.Context(), req.ProjectID, req.Version)
+	if errors.Is(err, prompts.ErrNotFound) {
+		http.Error(w, "Prompt template not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlePreviewPrompt handles POST /prompt-templates/preview
+func (h *HTTPHandler) HandlePreviewPrompt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PromptPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Body != "" {
+		if err := prompts.Validate(req.Body); err != nil {
+			http.Error(w, fmt.Sprintf("invalid template: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	preview, err := h.agent.PreviewPrompt(r.Context(), agentengine.Request{
+		UserID:    req.UserID,
+		ProjectID: req.ProjectID,
+		UserRole:  req.UserRole,
+	}, req.Version, req.Body)
+	if errors.Is(err, prompts.ErrNotFound) {
+		http.Error(w, "Prompt template not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Errorw("Prompt preview failed", "error", err, "project_id", req.ProjectID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	label := preview.Template.Label()
+	if req.Body != "" {
+		label = "draft"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(PromptPreviewJSON{
+		Template: label,
+		Prompt:   preview.Prompt,
+		Tools:    preview.Tools,
+		Warning:  preview.Warning,
+	})
+}
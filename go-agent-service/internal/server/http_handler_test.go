@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+func TestTraceEndpointsAreScopedToOwner(t *testing.T) {
+	traces := agentengine.NewMemoryTraceStore(10)
+	for id, user := range map[string]string{"trace-1": "u1", "trace-2": "u2"} {
+		trace := agentengine.NewTrace(id)
+		trace.SessionID, trace.UserID, trace.ProjectID = "s1", user, "p1"
+		if err := traces.SaveTrace(context.Background(), trace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewHTTPHandler(&AgentServer{traces: traces}, zap.NewNop().Sugar())
+
+	get := func(id, user string) int {
+		w := httptest.NewRecorder()
+		h.HandleGetTrace(w, httptest.NewRequest(http.MethodGet, "/traces/"+id+"?userId="+user+"&projectId=p1", nil), id)
+		return w.Code
+	}
+	if code := get("trace-1", "u1"); code != http.StatusOK {
+		t.Fatalf("expected the owner's trace, got %d", code)
+	}
+	if code := get("trace-1", "u2"); code != http.StatusNotFound {
+		t.Fatalf("expected another user's trace reported as not found, got %d", code)
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleListSessionTraces(w, httptest.NewRequest(http.MethodGet, "/sessions/s1/traces?userId=u2&projectId=p1", nil), "s1")
+	var listed []TraceJSON
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil || len(listed) != 1 || listed[0].ID != "trace-2" {
+		t.Fatalf("expected only the caller's traces listed, got %+v, %v", listed, err)
+	}
+}
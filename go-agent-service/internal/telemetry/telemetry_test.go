@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/antigravity/go-agent-service/internal/config"
+)
+
+func TestFileExporterAndPropagation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), config.TelemetryConfig{Exporter: "file", File: file, SampleRatio: 1}, "test-service")
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+
+	var gotParent string
+	var serverTrace trace.TraceID
+	srv := httptest.NewServer(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotParent = r.Header.Get("traceparent")
+		serverTrace = trace.SpanContextFromContext(r.Context()).TraceID()
+	}), "test"))
+	defer srv.Close()
+
+	ctx, span := Tracer("test").Start(context.Background(), "client.call")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/tools", nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	End(span, nil)
+
+	if gotParent == "" {
+		t.Fatalf("expected traceparent header")
+	}
+	if serverTrace != span.SpanContext().TraceID() {
+		t.Fatalf("expected server span in client trace %s, got %s", span.SpanContext().TraceID(), serverTrace)
+	}
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read trace file: %v", err)
+	}
+	out := string(data)
+	for _, name := range []string{`"Name":"client.call"`, `"Name":"test GET"`, `"test-service"`} {
+		if !strings.Contains(out, name) {
+			t.Fatalf("expected %s in exported spans:\n%s", name, out)
+		}
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), config.TelemetryConfig{Exporter: "zipkin"}, "svc"); err == nil {
+		t.Fatalf("expected error for unknown exporter")
+	}
+}
@@ -24,6 +24,7 @@ func NewRegistry(cfg *config.Config, logger *zap.SugaredLogger) *Registry {
 	mcpClient := mcp.NewClientWithConfig(mcp.ClientConfig{
 		BaseURL:   cfg.MCPServerURL,
 		AuthToken: cfg.MCPAuthToken,
+		Timeout:   cfg.MCPTimeout,
 	}, logger)
 
 	// Store Core URL (default to localhost:9099)
@@ -105,13 +106,14 @@ func (r *Registry) Execute(ctx context.Context, name, action string, params map[
 			delete(params, "projectId")
 		}
 		mcpResult, err := r.mcpClient.ExecuteTool(ctx, mcp.ToolCall{
-			Name:       name,
-			Action:     action,
-			EndpointID: getStringParam(params, "endpointId"),
-			KeyToken:   getStringParam(params, "keyToken"),
-			UserID:     userID,
-			ProjectID:  projectID,
-			Params:     params,
+			Name:           name,
+			Action:         action,
+			EndpointID:     getStringParam(params, "endpointId"),
+			KeyToken:       getStringParam(params, "keyToken"),
+			UserID:         userID,
+			ProjectID:      projectID,
+			Params:         params,
+			IdempotencyKey: mcp.IdempotencyKeyFrom(ctx),
 		})
 		if err != nil {
 			return nil, err
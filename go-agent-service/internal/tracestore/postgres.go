@@ -0,0 +1,147 @@
+// Package tracestore persists agent run traces.
+package tracestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// PostgresStore implements agentengine.TraceStore using PostgreSQL.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL-backed trace store.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) SaveTrace(ctx context.Context, trace *agentengine.Trace) error {
+	events, err := json.Marshal(trace.Events)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO agent_traces (
+			id, run_id, parent_id, session_id, user_id, project_id, query, status, error,
+			started_at, finished_at, duration_ms, events, prompt_version
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE SET
+			run_id = EXCLUDED.run_id,
+			status = EXCLUDED.status,
+			error = EXCLUDED.error,
+			finished_at = EXCLUDED.finished_at,
+			duration_ms = EXCLUDED.duration_ms,
+			events = EXCLUDED.events,
+			prompt_version = EXCLUDED.prompt_version
+	`
+	_, err = s.db.ExecContext(ctx, query,
+		trace.ID,
+		nullString(trace.RunID),
+		nullString(trace.ParentID),
+		trace.SessionID,
+		trace.UserID,
+		trace.ProjectID,
+		trace.Query,
+		trace.Status,
+		nullString(trace.Error),
+		trace.Started,
+		nullTime(trace),
+		trace.Duration().Milliseconds(),
+		events,
+		nullString(trace.PromptVersion),
+	)
+	return err
+}
+
+func (s *PostgresStore) GetTrace(ctx context.Context, id string) (*agentengine.Trace, error) {
+	row := s.db.QueryRowContext(ctx, selectTrace+` WHERE id = $1`, id)
+	trace, err := scanTrace(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, agentengine.ErrTraceNotFound
+	}
+	return trace, err
+}
+
+func (s *PostgresStore) ListTraces(ctx context.Context, sessionID string, limit int) ([]*agentengine.Trace, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.db.QueryContext(ctx, selectTrace+` WHERE session_id = $1 ORDER BY started_at DESC LIMIT $2`, sessionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var traces []*agentengine.Trace
+	for rows.Next() {
+		trace, err := scanTrace(rows)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
+	}
+	return traces, rows.Err()
+}
+
+const selectTrace = `
+	SELECT id, run_id, parent_id, session_id, user_id, project_id, query, status, error,
+		started_at, finished_at, events, prompt_version
+	FROM agent_traces`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTrace(row scanner) (*agentengine.Trace, error) {
+	var (
+		trace    agentengine.Trace
+		runID    sql.NullString
+		parentID sql.NullString
+		errMsg   sql.NullString
+		finished sql.NullTime
+		events   []byte
+		version  sql.NullString
+	)
+	if err := row.Scan(
+		&trace.ID,
+		&runID,
+		&parentID,
+		&trace.SessionID,
+		&trace.UserID,
+		&trace.ProjectID,
+		&trace.Query,
+		&trace.Status,
+		&errMsg,
+		&trace.Started,
+		&finished,
+		&events,
+		&version,
+	); err != nil {
+		return nil, err
+	}
+	trace.RunID = runID.String
+	trace.ParentID = parentID.String
+	trace.Error = errMsg.String
+	trace.Finished = finished.Time
+	trace.PromptVersion = version.String
+	if err := json.Unmarshal(events, &trace.Events); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func nullTime(trace *agentengine.Trace) sql.NullTime {
+	return sql.NullTime{Time: trace.Finished, Valid: !trace.Finished.IsZero()}
+}
+
+var _ agentengine.TraceStore = (*PostgresStore)(nil)
@@ -0,0 +1,161 @@
+package tracestore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// fakeDriver keeps saved traces as the rows their insert arguments describe.
+type fakeDriver struct {
+	mu     sync.Mutex
+	traces map[string][]driver.Value
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: strings.TrimSpace(query)}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if !strings.HasPrefix(s.query, "INSERT") {
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	// The row holds every inserted column but duration_ms.
+	row := append(append([]driver.Value{}, args[:11]...), args[12:]...)
+	s.d.traces[args[0].(string)] = row
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	rows := &fakeRows{}
+	if strings.Contains(s.query, "WHERE id =") {
+		if row, ok := s.d.traces[args[0].(string)]; ok {
+			rows.rows = append(rows.rows, row)
+		}
+		return rows, nil
+	}
+	for _, row := range s.d.traces {
+		if row[3] == args[0] {
+			rows.rows = append(rows.rows, row)
+		}
+	}
+	sort.Slice(rows.rows, func(i, j int) bool {
+		return rows.rows[i][9].(time.Time).After(rows.rows[j][9].(time.Time))
+	})
+	if limit := int(args[1].(int64)); len(rows.rows) > limit {
+		rows.rows = rows.rows[:limit]
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return strings.Fields("id run_id parent_id session_id user_id project_id query status error started_at finished_at events prompt_version")
+}
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("tracestore-fake", &fakeDriver{traces: make(map[string][]driver.Value)})
+}
+
+func newFakeStore(t *testing.T) *PostgresStore {
+	t.Helper()
+	db, err := sql.Open("tracestore-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewPostgresStore(db)
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	store := newFakeStore(t)
+	ctx := context.Background()
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	trace := agentengine.NewTrace("trace-1")
+	trace.SessionID, trace.UserID, trace.Query = "s1", "u1", "bugs"
+	trace.Started = started
+	trace.Record(agentengine.TraceEvent{Name: "plan", Step: 1, Attrs: map[string]any{"type": "direct"}})
+	if err := store.SaveTrace(ctx, trace); err != nil {
+		t.Fatalf("SaveTrace: %v", err)
+	}
+
+	got, err := store.GetTrace(ctx, "trace-1")
+	if err != nil {
+		t.Fatalf("GetTrace: %v", err)
+	}
+	if got.RunID != "" || got.UserID != "u1" || !got.Finished.IsZero() || len(got.Events) != 1 || got.Events[0].Attrs["type"] != "direct" {
+		t.Fatalf("unexpected trace %+v", got)
+	}
+	if _, err := store.GetTrace(ctx, "trace-2"); !errors.Is(err, agentengine.ErrTraceNotFound) {
+		t.Fatalf("expected an unknown trace reported as not found, got %v", err)
+	}
+
+	trace.RunID = "run-1"
+	trace.Finished = started.Add(time.Second)
+	if err := store.SaveTrace(ctx, trace); err != nil {
+		t.Fatalf("SaveTrace: %v", err)
+	}
+	if got, _ = store.GetTrace(ctx, "trace-1"); got.RunID != "run-1" || !got.Finished.Equal(trace.Finished) {
+		t.Fatalf("expected the finished trace saved over the running one, got %+v", got)
+	}
+}
+
+func TestListTracesNewestFirst(t *testing.T) {
+	store := newFakeStore(t)
+	ctx := context.Background()
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for i, id := range []string{"list-1", "list-2", "list-3"} {
+		trace := agentengine.NewTrace(id)
+		trace.SessionID = "list-session"
+		trace.Started = started.Add(time.Duration(i) * time.Minute)
+		if err := store.SaveTrace(ctx, trace); err != nil {
+			t.Fatalf("SaveTrace: %v", err)
+		}
+	}
+	traces, err := store.ListTraces(ctx, "list-session", 2)
+	if err != nil || len(traces) != 2 || traces[0].ID != "list-3" || traces[1].ID != "list-2" {
+		t.Fatalf("expected the two newest traces, got %v, %v", traces, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/antigravity/go-agent-service/internal/telemetry"
 	"github.com/antigravity/go-agent-service/internal/ucl/gatewaypb"
 	"github.com/antigravity/go-agent-service/internal/ucl/uclpb"
 
@@ -30,6 +31,7 @@ type Client struct {
 func NewClient(address string, logger *zap.SugaredLogger) (*Client, error) {
 	conn, err := grpc.NewClient(address,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		telemetry.GRPCDialOption(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to UCL: %w", err)
@@ -0,0 +1,138 @@
+// Package usagestore persists agent token usage.
+package usagestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// PostgresStore implements agentengine.UsageStore using PostgreSQL.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL-backed usage store.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) RecordUsage(ctx context.Context, record agentengine.UsageRecord) error {
+	query := `
+		INSERT INTO agent_usage (
+			trace_id, run_id, session_id, user_id, project_id, provider, model,
+			prompt_tokens, completion_tokens, total_tokens, cost_usd, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err := s.db.ExecContext(ctx, query,
+		record.TraceID,
+		nullString(record.RunID),
+		record.SessionID,
+		record.UserID,
+		record.ProjectID,
+		record.Provider,
+		record.Model,
+		record.Usage.PromptTokens,
+		record.Usage.CompletionTokens,
+		record.Usage.TotalTokens,
+		record.Usage.CostUSD,
+		record.CreatedAt,
+	)
+	return err
+}
+
+func (s *PostgresStore) SumUsage(ctx context.Context, filter agentengine.UsageFilter) (agentengine.Usage, error) {
+	summaries, err := s.ReportUsage(ctx, filter, "")
+	if err != nil || len(summaries) == 0 {
+		return agentengine.Usage{}, err
+	}
+	return summaries[0].Usage, nil
+}
+
+func (s *PostgresStore) ReportUsage(ctx context.Context, filter agentengine.UsageFilter, group agentengine.UsageGroup) ([]agentengine.UsageSummary, error) {
+	key, ok := groupColumns[group]
+	if !ok {
+		return nil, fmt.Errorf("unknown usage grouping %q", group)
+	}
+	where, args := whereClause(filter)
+	query := fmt.Sprintf(`
+		SELECT %s, COUNT(*),
+			COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0),
+			COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost_usd), 0)::float8
+		FROM agent_usage%s`, key, where)
+	if group != "" {
+		query += " GROUP BY 1"
+	}
+	query += " ORDER BY 6 DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []agentengine.UsageSummary
+	for rows.Next() {
+		var summary agentengine.UsageSummary
+		if err := rows.Scan(
+			&summary.Key,
+			&summary.Runs,
+			&summary.Usage.PromptTokens,
+			&summary.Usage.CompletionTokens,
+			&summary.Usage.TotalTokens,
+			&summary.Usage.CostUSD,
+		); err != nil {
+			return nil, err
+		}
+		if summary.Runs > 0 {
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries, rows.Err()
+}
+
+// groupColumns maps report groupings to their key expression.
+var groupColumns = map[agentengine.UsageGroup]string{
+	"":                         "''",
+	agentengine.UsageBySession: "COALESCE(session_id, '')",
+	agentengine.UsageByUser:    "COALESCE(user_id, '')",
+	agentengine.UsageByProject: "COALESCE(project_id, '')",
+	agentengine.UsageByModel:   "COALESCE(provider, '') || '/' || COALESCE(model, '')",
+}
+
+func whereClause(filter agentengine.UsageFilter) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, value any) {
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.SessionID != "" {
+		add("session_id = $%d", filter.SessionID)
+	}
+	if filter.UserID != "" {
+		add("user_id = $%d", filter.UserID)
+	}
+	if filter.ProjectID != "" {
+		add("project_id = $%d", filter.ProjectID)
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+var _ agentengine.UsageStore = (*PostgresStore)(nil)
@@ -0,0 +1,126 @@
+package usagestore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// fakeDriver records the statements it runs and answers queries with rows.
+type fakeDriver struct {
+	query string
+	args  []driver.Value
+	rows  [][]driver.Value
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.query, s.d.args = s.query, args
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.query, s.d.args = s.query, args
+	return &fakeRows{rows: s.d.rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return strings.Fields("key runs prompt_tokens completion_tokens total_tokens cost_usd")
+}
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func newFakeStore(t *testing.T, name string) (*PostgresStore, *fakeDriver) {
+	t.Helper()
+	fake := &fakeDriver{}
+	sql.Register(name, fake)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewPostgresStore(db), fake
+}
+
+func TestRecordUsageLeavesMissingRunsNull(t *testing.T) {
+	store, fake := newFakeStore(t, "usagestore-record")
+	record := agentengine.UsageRecord{
+		TraceID:   "trace-1",
+		SessionID: "s1",
+		Usage:     agentengine.Usage{PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40, CostUSD: 0.004},
+		CreatedAt: time.Now(),
+	}
+	if err := store.RecordUsage(context.Background(), record); err != nil {
+		t.Fatalf("RecordUsage: %v", err)
+	}
+	if fake.args[1] != nil || fake.args[2] != "s1" || fake.args[9] != int64(40) || fake.args[10] != 0.004 {
+		t.Fatalf("unexpected insert args %v", fake.args)
+	}
+}
+
+func TestReportUsageFiltersAndGroups(t *testing.T) {
+	store, fake := newFakeStore(t, "usagestore-report")
+	fake.rows = [][]driver.Value{
+		{"p1", int64(2), int64(80), int64(20), int64(100), 0.5},
+		{"p2", int64(0), int64(0), int64(0), int64(0), 0.0},
+	}
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	summaries, err := store.ReportUsage(context.Background(), agentengine.UsageFilter{UserID: "u1", Since: since}, agentengine.UsageByProject)
+	if err != nil {
+		t.Fatalf("ReportUsage: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].Key != "p1" || summaries[0].Runs != 2 || summaries[0].Usage.CostUSD != 0.5 {
+		t.Fatalf("expected groups without runs dropped, got %+v", summaries)
+	}
+	if !strings.Contains(fake.query, "WHERE user_id = $1 AND created_at >= $2") || !strings.Contains(fake.query, "GROUP BY 1") {
+		t.Fatalf("unexpected report query %s", fake.query)
+	}
+	if len(fake.args) != 2 || fake.args[0] != "u1" || fake.args[1] != since {
+		t.Fatalf("unexpected report args %v", fake.args)
+	}
+
+	fake.rows = [][]driver.Value{{"", int64(3), int64(90), int64(30), int64(120), 0.25}}
+	total, err := store.SumUsage(context.Background(), agentengine.UsageFilter{})
+	if err != nil || total.TotalTokens != 120 || strings.Contains(fake.query, "WHERE") || strings.Contains(fake.query, "GROUP BY") {
+		t.Fatalf("expected an ungrouped total, got %+v, %v (%s)", total, err, fake.query)
+	}
+
+	if _, err := store.ReportUsage(context.Background(), agentengine.UsageFilter{}, "tool"); err == nil {
+		t.Fatalf("expected an unknown grouping rejected")
+	}
+}
@@ -0,0 +1,104 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// AgentActivities runs the steps of durable agent runs on an agent engine
+type AgentActivities struct {
+	engine *agentengine.Engine
+}
+
+// NewAgentActivities creates the activities of AgentRunWorkflow
+func NewAgentActivities(engine *agentengine.Engine) *AgentActivities {
+	return &AgentActivities{engine: engine}
+}
+
+// BeginAgentRunActivity discovers tools and builds the prompt
+func (a *AgentActivities) BeginAgentRunActivity(ctx context.Context, runID string, req agentengine.Request) (agentengine.RunCheckpoint, error) {
+	cp, err := a.engine.BeginRun(ctx, runID, req)
+	if err != nil {
+		return agentengine.RunCheckpoint{}, agentActivityError(err)
+	}
+	return *cp, nil
+}
+
+// PlanAgentStepActivity asks the planner for the next step
+func (a *AgentActivities) PlanAgentStepActivity(ctx context.Context, cp agentengine.RunCheckpoint) (agentPlanResult, error) {
+	plan, err := a.engine.PlanStep(ctx, &cp)
+	if err != nil {
+		return agentPlanResult{}, agentActivityError(err)
+	}
+	return agentPlanResult{Checkpoint: cp, Plan: plan}, nil
+}
+
+// AuthorizeAgentCallsActivity applies validation, policy and the approval gate to planned calls
+func (a *AgentActivities) AuthorizeAgentCallsActivity(ctx context.Context, cp agentengine.RunCheckpoint, calls []agentengine.ToolCall) (agentAuthorizeResult, error) {
+	reviews, pending, err := a.engine.AuthorizeCalls(ctx, &cp, calls)
+	if err != nil {
+		return agentAuthorizeResult{}, err
+	}
+	return agentAuthorizeResult{Checkpoint: cp, Reviews: reviews, Pending: pending}, nil
+}
+
+// ExecuteAgentCallActivity runs one tool or sub-agent call
+func (a *AgentActivities) ExecuteAgentCallActivity(ctx context.Context, cp agentengine.RunCheckpoint, review agentengine.CallReview) (agentengine.CallOutcome, error) {
+	return a.engine.ExecuteCall(ctx, &cp, review)
+}
+
+// ObserveAgentCallsActivity adds a step's observations to the run
+func (a *AgentActivities) ObserveAgentCallsActivity(ctx context.Context, cp agentengine.RunCheckpoint, outcomes []agentengine.CallOutcome) (agentengine.RunCheckpoint, error) {
+	if err := a.engine.ObserveCalls(ctx, &cp, outcomes); err != nil {
+		return agentengine.RunCheckpoint{}, err
+	}
+	return cp, nil
+}
+
+// AnswerAgentRunActivity generates the final answer
+func (a *AgentActivities) AnswerAgentRunActivity(ctx context.Context, cp agentengine.RunCheckpoint) (agentAnswerResult, error) {
+	reply, err := a.engine.AnswerStep(ctx, &cp)
+	if err != nil {
+		return agentAnswerResult{}, agentActivityError(err)
+	}
+	return agentAnswerResult{Checkpoint: cp, Reply: reply}, nil
+}
+
+// CompleteAgentRunActivity records the answer, usage and trace of a finished run.
+// The trace is persisted by the engine, so the workflow result only carries its
+// ID and the events callers turn into reasoning steps.
+func (a *AgentActivities) CompleteAgentRunActivity(ctx context.Context, cp agentengine.RunCheckpoint, reply agentengine.LLMResponse) (*agentengine.Response, error) {
+	resp, err := a.engine.CompleteRun(ctx, &cp, reply)
+	if err != nil {
+		return nil, err
+	}
+	trace := resp.Trace.Snapshot()
+	resp.Trace = &agentengine.Trace{ID: trace.ID, RunID: trace.RunID, Status: trace.Status, Events: trace.Events}
+	return resp, nil
+}
+
+// FailAgentRunActivity records the usage and trace of a failed or cancelled run
+func (a *AgentActivities) FailAgentRunActivity(ctx context.Context, cp agentengine.RunCheckpoint, cause string) error {
+	return a.engine.FailRun(ctx, &cp, cause)
+}
+
+// agentRunErrors are the engine errors a retry cannot fix, by application error type
+var agentRunErrors = map[string]error{
+	"BudgetExceeded":          agentengine.ErrBudgetExceeded,
+	"MaxStepsExceeded":        agentengine.ErrMaxStepsExceeded,
+	"InvalidStructuredOutput": agentengine.ErrInvalidStructuredOutput,
+}
+
+// agentActivityError stops Temporal from retrying errors a retry cannot fix
+func agentActivityError(err error) error {
+	for errType, sentinel := range agentRunErrors {
+		if errors.Is(err, sentinel) {
+			return temporal.NewNonRetryableApplicationError(err.Error(), errType, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// agentRunPollInterval is how often WaitAgentRun queries a run's progress
+const agentRunPollInterval = 250 * time.Millisecond
+
+// StartAgentRun starts a durable agent run and returns its run ID
+func (e *Engine) StartAgentRun(ctx context.Context, input AgentRunInput) (string, error) {
+	runID := AgentRunIDPrefix + uuid.NewString()
+	options := client.StartWorkflowOptions{
+		ID:        runID,
+		TaskQueue: "agent-task-queue",
+	}
+	if _, err := e.client.ExecuteWorkflow(ctx, options, AgentRunWorkflow, input); err != nil {
+		return "", fmt.Errorf("failed to start agent run: %w", err)
+	}
+	e.logger.Infow("Started durable agent run", "run_id", runID)
+	return runID, nil
+}
+
+// ErrAgentRunForbidden is returned for durable runs started by another user or project
+var ErrAgentRunForbidden = errors.New("agent run belongs to another user")
+
+// AgentRunProgress queries the progress of a durable agent run owned by owner.
+// Unknown runs are reported as agentengine.ErrRunNotFound, runs of someone else
+// as ErrAgentRunForbidden.
+func (e *Engine) AgentRunProgress(ctx context.Context, owner agentengine.RunOwner, runID string) (*AgentRunProgress, error) {
+	progress, err := e.queryAgentRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if progress.Owner != owner {
+		return nil, ErrAgentRunForbidden
+	}
+	return progress, nil
+}
+
+// queryAgentRun queries the progress of a durable agent run, whoever owns it
+func (e *Engine) queryAgentRun(ctx context.Context, runID string) (*AgentRunProgress, error) {
+	value, err := e.client.QueryWorkflow(ctx, runID, "", AgentProgressQuery)
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%w: %s", agentengine.ErrRunNotFound, runID)
+		}
+		return nil, fmt.Errorf("failed to query agent run %s: %w", runID, err)
+	}
+	var progress AgentRunProgress
+	if err := value.Get(&progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// DecideAgentRun checks approval decisions against the pending calls of a durable
+// run and signals them to it. It returns the progress update the decisions follow,
+// for WaitAgentRun. Runs owned by someone else than owner are reported as
+// agentengine.ErrRunNotFound.
+func (e *Engine) DecideAgentRun(ctx context.Context, owner agentengine.RunOwner, runID string, decisions []agentengine.ApprovalDecision) (int, error) {
+	progress, err := e.queryAgentRun(ctx, runID)
+	if err != nil {
+		return 0, err
+	}
+	if progress.Owner != owner {
+		return 0, agentengine.ErrRunNotFound
+	}
+	if progress.Status != agentengine.RunAwaitingApproval {
+		return 0, fmt.Errorf("agent run %s is %s, not awaiting approval", runID, progress.Status)
+	}
+	run := &agentengine.PendingRun{ID: runID, Calls: progress.PendingCalls}
+	if err := run.ApplyDecisions(decisions); err != nil {
+		return 0, err
+	}
+	if err := e.client.SignalWorkflow(ctx, runID, "", AgentApprovalSignal, decisions); err != nil {
+		return 0, fmt.Errorf("failed to signal agent run %s: %w", runID, err)
+	}
+	return progress.Updates, nil
+}
+
+// CancelAgentRun cancels a durable agent run owned by owner, with the errors of AgentRunProgress
+func (e *Engine) CancelAgentRun(ctx context.Context, owner agentengine.RunOwner, runID string) error {
+	if _, err := e.AgentRunProgress(ctx, owner, runID); err != nil {
+		return err
+	}
+	e.logger.Infow("Cancelling durable agent run", "run_id", runID)
+	return e.client.CancelWorkflow(ctx, runID, "")
+}
+
+// WaitAgentRun waits until a durable run has changed since update after and
+// completed, failed or paused for approval. When ctx ends first, the response
+// reports the run as still running.
+func (e *Engine) WaitAgentRun(ctx context.Context, runID string, after int) (*agentengine.Response, error) {
+	ticker := time.NewTicker(agentRunPollInterval)
+	defer ticker.Stop()
+	for {
+		progress, err := e.queryAgentRun(ctx, runID)
+		if err == nil && progress.Updates > after && progress.Status != agentengine.RunRunning {
+			return e.agentRunResponse(ctx, progress)
+		}
+		select {
+		case <-ctx.Done():
+			return &agentengine.Response{
+				Text:   "The agent is still working on this. Check the run's progress for the result.",
+				Status: agentengine.RunRunning,
+				RunID:  runID,
+			}, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// agentRunResponse builds the response of a run that completed, failed or paused
+func (e *Engine) agentRunResponse(ctx context.Context, progress *AgentRunProgress) (*agentengine.Response, error) {
+	if progress.Status == agentengine.RunAwaitingApproval {
+		return &agentengine.Response{
+			Text:         agentengine.ApprovalMessage(progress.PendingCalls),
+			Status:       agentengine.RunAwaitingApproval,
+			RunID:        progress.RunID,
+			PendingCalls: progress.PendingCalls,
+			Usage:        progress.Usage,
+			Trace:        &agentengine.Trace{ID: progress.TraceID, RunID: progress.RunID},
+		}, nil
+	}
+
+	var resp agentengine.Response
+	if err := e.client.GetWorkflow(ctx, progress.RunID, "").Get(ctx, &resp); err != nil {
+		var appErr *temporal.ApplicationError
+		if errors.As(err, &appErr) {
+			if sentinel, ok := agentRunErrors[appErr.Type()]; ok {
+				return nil, &agentRunError{message: appErr.Message(), sentinel: sentinel}
+			}
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// agentRunError carries an engine error's message and sentinel across the workflow boundary
+type agentRunError struct {
+	message  string
+	sentinel error
+}
+
+func (e *agentRunError) Error() string { return e.message }
+
+func (e *agentRunError) Unwrap() error { return e.sentinel }
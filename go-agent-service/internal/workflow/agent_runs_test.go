@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.uber.org/zap"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// progressClient answers progress queries for one run and records cancellations.
+type progressClient struct {
+	client.Client
+	progress  AgentRunProgress
+	cancelled []string
+}
+
+func (c *progressClient) QueryWorkflow(ctx context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	if workflowID != c.progress.RunID {
+		return nil, serviceerror.NewNotFound("workflow not found")
+	}
+	return progressValue{c.progress}, nil
+}
+
+func (c *progressClient) CancelWorkflow(ctx context.Context, workflowID, runID string) error {
+	c.cancelled = append(c.cancelled, workflowID)
+	return nil
+}
+
+// progressValue is a query result holding a run's progress.
+type progressValue struct {
+	progress AgentRunProgress
+}
+
+func (v progressValue) HasValue() bool { return true }
+
+func (v progressValue) Get(valuePtr interface{}) error {
+	*valuePtr.(*AgentRunProgress) = v.progress
+	return nil
+}
+
+func TestAgentRunAccessIsScopedToOwner(t *testing.T) {
+	owner := agentengine.RunOwner{UserID: "u1", ProjectID: "p1"}
+	temporal := &progressClient{progress: AgentRunProgress{RunID: "agent-run-1", Owner: owner}}
+	engine := &Engine{logger: zap.NewNop().Sugar(), client: &TemporalClient{Client: temporal}}
+	ctx := context.Background()
+
+	if _, err := engine.AgentRunProgress(ctx, owner, "agent-run-2"); !errors.Is(err, agentengine.ErrRunNotFound) {
+		t.Fatalf("expected an unknown run reported as not found, got %v", err)
+	}
+	other := agentengine.RunOwner{UserID: "u2", ProjectID: "p1"}
+	if _, err := engine.AgentRunProgress(ctx, other, "agent-run-1"); !errors.Is(err, ErrAgentRunForbidden) {
+		t.Fatalf("expected another user's progress refused, got %v", err)
+	}
+	if err := engine.CancelAgentRun(ctx, other, "agent-run-1"); !errors.Is(err, ErrAgentRunForbidden) || len(temporal.cancelled) != 0 {
+		t.Fatalf("expected another user's cancel refused, got %v (%v)", err, temporal.cancelled)
+	}
+
+	if progress, err := engine.AgentRunProgress(ctx, owner, "agent-run-1"); err != nil || progress.RunID != "agent-run-1" {
+		t.Fatalf("expected the owner's progress, got %+v, %v", progress, err)
+	}
+	if err := engine.CancelAgentRun(ctx, owner, "agent-run-1"); err != nil || len(temporal.cancelled) != 1 {
+		t.Fatalf("expected the owner's cancel sent, got %v (%v)", err, temporal.cancelled)
+	}
+}
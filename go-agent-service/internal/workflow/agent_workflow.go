@@ -0,0 +1,233 @@
+package workflow
+
+import (
+	"errors"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+const (
+	// AgentRunIDPrefix marks workflow IDs, and so run IDs, of durable agent runs
+	AgentRunIDPrefix = "agent-run-"
+	// AgentApprovalSignal carries []agentengine.ApprovalDecision for a paused run
+	AgentApprovalSignal = "agent_approval"
+	// AgentProgressQuery returns the AgentRunProgress of a run
+	AgentProgressQuery = "agent_progress"
+)
+
+// AgentRunInput starts a durable agent run
+type AgentRunInput struct {
+	Request          agentengine.Request
+	MaxParallelTools int // Tool activities run concurrently per step (default 1)
+}
+
+// AgentRunProgress is the state of a durable agent run, served by AgentProgressQuery
+type AgentRunProgress struct {
+	RunID        string
+	TraceID      string
+	Owner        agentengine.RunOwner  // Who may decide the run's pending calls
+	Status       agentengine.RunStatus // running, awaiting_approval, completed, failed or canceled
+	Step         int
+	LastPlan     agentengine.PlanType
+	Observations int
+	PendingCalls []agentengine.PendingCall
+	Usage        agentengine.Usage
+	Error        string
+	Updates      int // Incremented on every change, so callers can wait for the next one
+}
+
+// Activity results carry the checkpoint along with the step's output
+type agentPlanResult struct {
+	Checkpoint agentengine.RunCheckpoint
+	Plan       agentengine.Plan
+}
+
+type agentAuthorizeResult struct {
+	Checkpoint agentengine.RunCheckpoint
+	Reviews    []agentengine.CallReview
+	Pending    []agentengine.PendingCall
+}
+
+type agentAnswerResult struct {
+	Checkpoint agentengine.RunCheckpoint
+	Reply      agentengine.LLMResponse
+}
+
+// AgentRunWorkflow runs an agent engine run durably: every planner, tool and
+// LLM call is an activity, calls awaiting approval wait for AgentApprovalSignal,
+// and cancelling the workflow stops the run.
+func AgentRunWorkflow(ctx workflow.Context, input AgentRunInput) (*agentengine.Response, error) {
+	logger := workflow.GetLogger(ctx)
+	runID := workflow.GetInfo(ctx).WorkflowExecution.ID
+
+	progress := AgentRunProgress{
+		RunID:   runID,
+		Owner:   agentengine.RunOwner{UserID: input.Request.UserID, ProjectID: input.Request.ProjectID},
+		Status:  agentengine.RunRunning,
+		Updates: 1,
+	}
+	if err := workflow.SetQueryHandler(ctx, AgentProgressQuery, func() (AgentRunProgress, error) {
+		return progress, nil
+	}); err != nil {
+		return nil, err
+	}
+	update := func() { progress.Updates++ }
+
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+	})
+	var activities *AgentActivities
+
+	var cp agentengine.RunCheckpoint
+	if err := workflow.ExecuteActivity(ctx, activities.BeginAgentRunActivity, runID, input.Request).Get(ctx, &cp); err != nil {
+		progress.Status, progress.Error = agentengine.RunFailed, err.Error()
+		update()
+		return nil, err
+	}
+	progress.TraceID = cp.TraceID
+
+	// fail persists the trace of a failed or cancelled run before returning err
+	fail := func(err error) (*agentengine.Response, error) {
+		status := agentengine.RunFailed
+		finishCtx := ctx
+		if ctx.Err() != nil {
+			status = agentengine.RunCanceled
+			finishCtx, _ = workflow.NewDisconnectedContext(ctx)
+		}
+		if finishErr := workflow.ExecuteActivity(finishCtx, activities.FailAgentRunActivity, cp, err.Error()).Get(finishCtx, nil); finishErr != nil {
+			logger.Warn("Failed to record agent run failure", "error", finishErr)
+		}
+		progress.Status, progress.Error = status, err.Error()
+		update()
+		return nil, err
+	}
+	complete := func(reply agentengine.LLMResponse) (*agentengine.Response, error) {
+		var resp agentengine.Response
+		if err := workflow.ExecuteActivity(ctx, activities.CompleteAgentRunActivity, cp, reply).Get(ctx, &resp); err != nil {
+			return fail(err)
+		}
+		progress.Status, progress.Usage = agentengine.RunCompleted, resp.Usage
+		update()
+		return &resp, nil
+	}
+
+	for {
+		var planned agentPlanResult
+		if err := workflow.ExecuteActivity(ctx, activities.PlanAgentStepActivity, cp).Get(ctx, &planned); err != nil {
+			return fail(err)
+		}
+		cp = planned.Checkpoint
+		plan := planned.Plan
+		progress.Step, progress.LastPlan, progress.Usage = cp.Step, plan.Type, cp.Usage.Add(cp.Delegated)
+		update()
+
+		switch plan.Type {
+		case agentengine.PlanDirect:
+			var answered agentAnswerResult
+			if err := workflow.ExecuteActivity(ctx, activities.AnswerAgentRunActivity, cp).Get(ctx, &answered); err != nil {
+				return fail(err)
+			}
+			cp = answered.Checkpoint
+			return complete(answered.Reply)
+		case agentengine.PlanNeedClarification:
+			return complete(agentengine.LLMResponse{Text: plan.Clarification})
+		}
+		if len(plan.ToolCalls) == 0 {
+			return fail(errors.New("planner returned tool plan with no calls"))
+		}
+
+		var authorized agentAuthorizeResult
+		if err := workflow.ExecuteActivity(ctx, activities.AuthorizeAgentCallsActivity, cp, plan.ToolCalls).Get(ctx, &authorized); err != nil {
+			return fail(err)
+		}
+		cp = authorized.Checkpoint
+		outcomes, err := executeAgentCalls(ctx, activities, cp, authorized.Reviews, input.MaxParallelTools)
+		if err != nil {
+			return fail(err)
+		}
+
+		if len(authorized.Pending) > 0 {
+			decided, err := awaitDecisions(ctx, &progress, update, runID, authorized.Pending)
+			if err != nil {
+				return fail(err)
+			}
+			reviews := make([]agentengine.CallReview, 0, len(decided))
+			for _, call := range decided {
+				reviews = append(reviews, call.Review())
+			}
+			more, err := executeAgentCalls(ctx, activities, cp, reviews, input.MaxParallelTools)
+			if err != nil {
+				return fail(err)
+			}
+			outcomes = append(outcomes, more...)
+		}
+
+		if err := workflow.ExecuteActivity(ctx, activities.ObserveAgentCallsActivity, cp, outcomes).Get(ctx, &cp); err != nil {
+			return fail(err)
+		}
+		progress.Observations, progress.Usage = cp.Observed, cp.Usage.Add(cp.Delegated)
+		update()
+	}
+}
+
+// executeAgentCalls runs reviewed calls as activities, at most parallel at a
+// time, and returns their outcomes in call order.
+func executeAgentCalls(ctx workflow.Context, activities *AgentActivities, cp agentengine.RunCheckpoint, reviews []agentengine.CallReview, parallel int) ([]agentengine.CallOutcome, error) {
+	if parallel <= 0 {
+		parallel = 1
+	}
+	outcomes := make([]agentengine.CallOutcome, len(reviews))
+	for start := 0; start < len(reviews); start += parallel {
+		end := min(start+parallel, len(reviews))
+		futures := make([]workflow.Future, 0, end-start)
+		for _, review := range reviews[start:end] {
+			futures = append(futures, workflow.ExecuteActivity(ctx, activities.ExecuteAgentCallActivity, cp, review))
+		}
+		for i, future := range futures {
+			if err := future.Get(ctx, &outcomes[start+i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return outcomes, nil
+}
+
+// awaitDecisions waits on AgentApprovalSignal until every pending call is decided.
+// Decisions naming unknown or already decided calls are ignored as a whole.
+func awaitDecisions(ctx workflow.Context, progress *AgentRunProgress, update func(), runID string, pending []agentengine.PendingCall) ([]agentengine.PendingCall, error) {
+	logger := workflow.GetLogger(ctx)
+	run := &agentengine.PendingRun{ID: runID, Calls: pending}
+	progress.Status, progress.PendingCalls = agentengine.RunAwaitingApproval, run.Pending()
+	update()
+
+	var decisions []agentengine.ApprovalDecision
+	selector := workflow.NewSelector(ctx)
+	selector.AddReceive(workflow.GetSignalChannel(ctx, AgentApprovalSignal), func(c workflow.ReceiveChannel, more bool) {
+		c.Receive(ctx, &decisions)
+	})
+	selector.AddReceive(ctx.Done(), func(c workflow.ReceiveChannel, more bool) {})
+	for len(run.Pending()) > 0 {
+		decisions = nil
+		selector.Select(ctx)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		calls := append([]agentengine.PendingCall(nil), run.Calls...)
+		if err := run.ApplyDecisions(decisions); err != nil {
+			logger.Warn("Ignoring approval decisions", "error", err)
+			run.Calls = calls
+			continue
+		}
+		progress.PendingCalls = run.Pending()
+		update()
+	}
+
+	progress.Status, progress.PendingCalls = agentengine.RunRunning, nil
+	update()
+	return run.Calls, nil
+}
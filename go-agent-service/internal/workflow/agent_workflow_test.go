@@ -0,0 +1,215 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/antigravity/go-agent-service/internal/agentengine"
+)
+
+// ticketPlanner searches and creates a ticket, then answers.
+type ticketPlanner struct{}
+
+func (ticketPlanner) Plan(ctx context.Context, input agentengine.PlanInput) (agentengine.Plan, error) {
+	if len(input.Observations) > 0 {
+		return agentengine.Plan{Type: agentengine.PlanDirect}, nil
+	}
+	return agentengine.Plan{Type: agentengine.PlanToolCalls, ToolCalls: []agentengine.ToolCall{
+		{Name: "jira", Action: "search", Args: map[string]any{"jql": "bug"}},
+		{Name: "jira", Action: "create", Args: map[string]any{"summary": "Fix it"}},
+	}}, nil
+}
+
+type fixedLLM struct{}
+
+func (fixedLLM) Respond(ctx context.Context, input agentengine.LLMRequest) (agentengine.LLMResponse, error) {
+	return agentengine.LLMResponse{Text: "created PROJ-2", Usage: agentengine.Usage{TotalTokens: 5}}, nil
+}
+
+type jiraTools struct{}
+
+func (jiraTools) ListTools(ctx context.Context, userID, projectID string) ([]agentengine.ToolDef, error) {
+	return []agentengine.ToolDef{{Name: "jira", Actions: []agentengine.ToolAction{
+		{Name: "search", Access: agentengine.AccessRead},
+		{Name: "create", Access: agentengine.AccessWrite},
+	}}}, nil
+}
+
+// countingJira counts executions per action.
+type countingJira struct {
+	searches, creates atomic.Int32
+}
+
+func (j *countingJira) Execute(ctx context.Context, call agentengine.ToolCall) (*agentengine.ToolResult, error) {
+	if call.Action == "create" {
+		j.creates.Add(1)
+		return &agentengine.ToolResult{Success: true, Data: map[string]any{"key": "PROJ-2"}}, nil
+	}
+	j.searches.Add(1)
+	return &agentengine.ToolResult{Success: true, Data: map[string]any{"issues": []any{"PROJ-1"}}}, nil
+}
+
+type plainContext struct{}
+
+func (plainContext) Build(ctx context.Context, req agentengine.Request, tools []agentengine.ToolDef) (string, error) {
+	return req.Query, nil
+}
+
+func (plainContext) AppendObservations(prompt string, observations []agentengine.Observation) (string, error) {
+	return prompt, nil
+}
+
+func newAgentRunTestEnv(t *testing.T, jira *countingJira, traces agentengine.TraceStore, checkpoints agentengine.CheckpointStore) *testsuite.TestWorkflowEnvironment {
+	t.Helper()
+	engine, err := agentengine.NewEngine(agentengine.Config{
+		Planner:       ticketPlanner{},
+		LLM:           fixedLLM{},
+		Tools:         jiraTools{},
+		Executor:      jira,
+		Context:       plainContext{},
+		ApproveWrites: true,
+		Traces:        traces,
+		Checkpoints:   checkpoints,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(AgentRunWorkflow)
+	env.RegisterActivity(NewAgentActivities(engine))
+	return env
+}
+
+func queryProgress(t *testing.T, env *testsuite.TestWorkflowEnvironment) AgentRunProgress {
+	t.Helper()
+	value, err := env.QueryWorkflow(AgentProgressQuery)
+	if err != nil {
+		t.Fatalf("query progress: %v", err)
+	}
+	var progress AgentRunProgress
+	if err := value.Get(&progress); err != nil {
+		t.Fatalf("decode progress: %v", err)
+	}
+	return progress
+}
+
+func TestAgentRunWorkflowWaitsForApprovalSignal(t *testing.T) {
+	jira := &countingJira{}
+	traces := agentengine.NewMemoryTraceStore(0)
+	env := newAgentRunTestEnv(t, jira, traces, nil)
+
+	var paused AgentRunProgress
+	env.RegisterDelayedCallback(func() {
+		paused = queryProgress(t, env)
+		decisions := []agentengine.ApprovalDecision{{CallID: "unknown:0", Approved: true}}
+		env.SignalWorkflow(AgentApprovalSignal, decisions)
+		if len(paused.PendingCalls) == 1 {
+			decisions[0].CallID = paused.PendingCalls[0].ID
+		}
+		env.SignalWorkflow(AgentApprovalSignal, decisions)
+	}, time.Hour)
+	env.ExecuteWorkflow(AgentRunWorkflow, AgentRunInput{Request: agentengine.Request{Query: "file the bug", SessionID: "s1", UserID: "u1", ProjectID: "p1"}})
+
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
+		t.Fatalf("expected completed workflow, got %v", env.GetWorkflowError())
+	}
+	if paused.Status != agentengine.RunAwaitingApproval || len(paused.PendingCalls) != 1 || paused.PendingCalls[0].Call.Action != "create" {
+		t.Fatalf("expected the create call awaiting approval, got %+v", paused)
+	}
+	if paused.Owner != (agentengine.RunOwner{UserID: "u1", ProjectID: "p1"}) {
+		t.Fatalf("expected the run's owner in its progress, got %+v", paused.Owner)
+	}
+	if jira.searches.Load() != 1 || jira.creates.Load() != 1 {
+		t.Fatalf("expected one search and one approved create, got %d and %d", jira.searches.Load(), jira.creates.Load())
+	}
+
+	var resp agentengine.Response
+	if err := env.GetWorkflowResult(&resp); err != nil {
+		t.Fatalf("result: %v", err)
+	}
+	if resp.Text != "created PROJ-2" || resp.Status != agentengine.RunCompleted || len(resp.Observations) != 2 || resp.Observations[1].ID != "obs2" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	trace, err := traces.GetTrace(context.Background(), resp.Trace.ID)
+	if err != nil || trace.Status != string(agentengine.RunCompleted) || trace.RunID != paused.RunID {
+		t.Fatalf("expected the completed trace persisted, got %+v, %v", trace, err)
+	}
+	if progress := queryProgress(t, env); progress.Status != agentengine.RunCompleted || progress.Usage.TotalTokens != 5 {
+		t.Fatalf("unexpected final progress: %+v", progress)
+	}
+}
+
+func TestAgentRunWorkflowCanBeCancelledWhilePaused(t *testing.T) {
+	jira := &countingJira{}
+	traces := agentengine.NewMemoryTraceStore(0)
+	env := newAgentRunTestEnv(t, jira, traces, nil)
+
+	env.RegisterDelayedCallback(env.CancelWorkflow, time.Hour)
+	env.ExecuteWorkflow(AgentRunWorkflow, AgentRunInput{Request: agentengine.Request{Query: "file the bug", SessionID: "s1"}})
+
+	if err := env.GetWorkflowError(); !temporal.IsCanceledError(err) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if jira.creates.Load() != 0 {
+		t.Fatalf("expected the pending create not to run")
+	}
+	progress := queryProgress(t, env)
+	if progress.Status != agentengine.RunCanceled {
+		t.Fatalf("expected canceled progress, got %+v", progress)
+	}
+	trace, err := traces.GetTrace(context.Background(), progress.TraceID)
+	if err != nil || trace.Status != "failed" {
+		t.Fatalf("expected the cancelled run's trace persisted, got %+v, %v", trace, err)
+	}
+}
+
+func TestAgentRunWorkflowKeepsCheckpointStateInStore(t *testing.T) {
+	jira := &countingJira{}
+	checkpoints := agentengine.NewMemoryCheckpointStore()
+	env := newAgentRunTestEnv(t, jira, agentengine.NewMemoryTraceStore(0), checkpoints)
+
+	var inline []string
+	env.SetOnActivityStartedListener(func(info *activity.Info, ctx context.Context, args converter.EncodedValues) {
+		if info.ActivityType.Name == "BeginAgentRunActivity" {
+			return
+		}
+		var cp agentengine.RunCheckpoint
+		if err := args.Get(&cp); err != nil {
+			t.Errorf("decode %s checkpoint: %v", info.ActivityType.Name, err)
+		}
+		if cp.Version == 0 || cp.Prompt != "" || len(cp.Observations) > 0 || len(cp.Events) > 0 {
+			inline = append(inline, info.ActivityType.Name)
+		}
+	})
+	env.RegisterDelayedCallback(func() {
+		paused := queryProgress(t, env)
+		env.SignalWorkflow(AgentApprovalSignal, []agentengine.ApprovalDecision{{CallID: paused.PendingCalls[0].ID, Approved: true}})
+	}, time.Hour)
+	env.ExecuteWorkflow(AgentRunWorkflow, AgentRunInput{Request: agentengine.Request{Query: "file the bug", SessionID: "s1"}})
+
+	if !env.IsWorkflowCompleted() || env.GetWorkflowError() != nil {
+		t.Fatalf("expected completed workflow, got %v", env.GetWorkflowError())
+	}
+	if len(inline) > 0 {
+		t.Fatalf("expected checkpoints to carry only a version, got inline state for %v", inline)
+	}
+	var resp agentengine.Response
+	if err := env.GetWorkflowResult(&resp); err != nil || len(resp.Observations) != 2 {
+		t.Fatalf("expected both observations in the result, got %+v, %v", resp, err)
+	}
+	if progress := queryProgress(t, env); progress.Observations != 2 {
+		t.Fatalf("expected the observation count in progress, got %+v", progress)
+	}
+	if _, err := checkpoints.LoadCheckpoint(context.Background(), resp.RunID, 1); !errors.Is(err, agentengine.ErrCheckpointNotFound) {
+		t.Fatalf("expected the finished run's checkpoints dropped, got %v", err)
+	}
+}
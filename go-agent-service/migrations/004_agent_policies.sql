@@ -0,0 +1,22 @@
+-- Agent Tool Policy Schema
+-- Migration: 004_agent_policies.sql
+
+-- =================
+-- Policy Rules (declarative allow/deny/approval + per-run budgets)
+-- =================
+CREATE TABLE IF NOT EXISTS agent_policy_rules (
+    id VARCHAR(255) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    project_id VARCHAR(255) NOT NULL DEFAULT '', -- '' applies to all projects
+    users JSONB,                                 -- user IDs; null/empty applies to all users
+    tools JSONB,                                 -- target patterns, e.g. "app/*/create_*"
+    access VARCHAR(16) NOT NULL DEFAULT '',      -- read, write, '' for both
+    effect VARCHAR(32) NOT NULL DEFAULT '',      -- allow, deny, require_approval, '' for budget-only
+    max_calls_per_run INTEGER NOT NULL DEFAULT 0,
+    priority INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_policy_rules_project ON agent_policy_rules(project_id) WHERE enabled;
@@ -0,0 +1,17 @@
+-- Agent Run Approval Schema
+-- Migration: 005_agent_runs.sql
+
+-- =================
+-- Pending Runs (paused for human approval of write actions)
+-- =================
+CREATE TABLE IF NOT EXISTS agent_pending_runs (
+    id VARCHAR(64) PRIMARY KEY,
+    session_id VARCHAR(255),
+    user_id VARCHAR(255),
+    project_id VARCHAR(255),
+    payload JSONB NOT NULL, -- request, prompt, observations, pending calls
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_pending_runs_session ON agent_pending_runs(session_id);
@@ -0,0 +1,23 @@
+-- Agent Run Trace Schema
+-- Migration: 006_agent_traces.sql
+
+-- =================
+-- Run Traces (plan decisions, tool calls, LLM calls with timings)
+-- =================
+CREATE TABLE IF NOT EXISTS agent_traces (
+    id VARCHAR(64) PRIMARY KEY,
+    run_id VARCHAR(64), -- paused/resumed run, if any
+    session_id VARCHAR(255),
+    user_id VARCHAR(255),
+    project_id VARCHAR(255),
+    query TEXT,
+    status VARCHAR(32) NOT NULL, -- completed, awaiting_approval, failed
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    duration_ms BIGINT,
+    events JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_traces_session ON agent_traces(session_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_agent_traces_run ON agent_traces(run_id);
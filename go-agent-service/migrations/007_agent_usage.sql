@@ -0,0 +1,25 @@
+-- Agent Token Usage Schema
+-- Migration: 007_agent_usage.sql
+
+-- =================
+-- Per-run LLM token usage and estimated cost (budgets and chargeback)
+-- =================
+CREATE TABLE IF NOT EXISTS agent_usage (
+    id BIGSERIAL PRIMARY KEY,
+    trace_id VARCHAR(64) NOT NULL,
+    run_id VARCHAR(64), -- paused/resumed run, if any
+    session_id VARCHAR(255),
+    user_id VARCHAR(255),
+    project_id VARCHAR(255),
+    provider VARCHAR(64),
+    model VARCHAR(255),
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    total_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd NUMERIC(14, 6) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_usage_session ON agent_usage(session_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_agent_usage_user ON agent_usage(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_agent_usage_project ON agent_usage(project_id, created_at);
@@ -0,0 +1,9 @@
+-- Sub-agent Trace Links
+-- Migration: 008_agent_trace_parents.sql
+
+-- =================
+-- Sub-agent runs link their trace to the delegating run's trace
+-- =================
+ALTER TABLE agent_traces ADD COLUMN IF NOT EXISTS parent_id VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_agent_traces_parent ON agent_traces(parent_id);
@@ -0,0 +1,24 @@
+-- Agent Prompt Templates Schema
+-- Migration: 009_agent_prompt_templates.sql
+
+-- =================
+-- Versioned system prompt templates per project (Go text/template)
+-- =================
+CREATE TABLE IF NOT EXISTS agent_prompt_templates (
+    id VARCHAR(64) PRIMARY KEY,
+    project_id VARCHAR(255) NOT NULL DEFAULT '', -- '' applies to projects without their own template
+    version INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (project_id, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_prompt_templates_active ON agent_prompt_templates(project_id) WHERE active;
+
+-- Runs record the template version they used, to compare versions
+ALTER TABLE agent_traces ADD COLUMN IF NOT EXISTS prompt_version VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_agent_traces_prompt_version ON agent_traces(prompt_version, started_at DESC);
@@ -0,0 +1,13 @@
+-- Durable Agent Run Checkpoints
+-- Migration: 010_agent_run_checkpoints.sql
+
+-- =================
+-- Checkpoints (prompt, observations and trace events of durable runs between steps)
+-- =================
+CREATE TABLE IF NOT EXISTS agent_run_checkpoints (
+    run_id VARCHAR(64) NOT NULL,
+    version INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (run_id, version)
+);